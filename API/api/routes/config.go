@@ -7,6 +7,7 @@ import (
 	"github.com/Fesaa/Media-Provider/db/models"
 	"github.com/Fesaa/Media-Provider/http/payload"
 	"github.com/Fesaa/Media-Provider/internal/contextkey"
+	"github.com/Fesaa/Media-Provider/providers/yoitsu"
 	"github.com/Fesaa/Media-Provider/services"
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/dig"
@@ -21,6 +22,7 @@ type configRoutes struct {
 	Val                 services.ValidationService
 	SettingsService     services.SettingsService
 	SubscriptionService services.SubscriptionService
+	YS                  yoitsu.Client
 	SignalR             services.SignalRService
 	TransLoco           services.TranslocoService
 }
@@ -43,6 +45,10 @@ func (cr *configRoutes) getConfig(ctx *fiber.Ctx) error {
 		dto.Oidc.ClientSecret = strings.Repeat("*", len(dto.Oidc.ClientSecret))
 	}
 
+	if dto.Torrent.Remote.Password != "" {
+		dto.Torrent.Remote.Password = strings.Repeat("*", len(dto.Torrent.Remote.Password))
+	}
+
 	return ctx.JSON(dto)
 }
 
@@ -74,6 +80,8 @@ func (cr *configRoutes) updateConfig(ctx *fiber.Ctx, dto payload.Settings) error
 		return InternalError(err)
 	}
 
+	cr.YS.UpdateTorrentSettings(dto.Torrent)
+
 	if cur.SubscriptionRefreshHour != dto.SubscriptionRefreshHour {
 		if err = cr.SubscriptionService.UpdateHour(ctx.UserContext()); err != nil {
 			log.Error().Err(err).Msg("failed to update subscription refresh hour")
@@ -1,16 +1,24 @@
 package routes
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"slices"
 	"strings"
 
+	"github.com/Fesaa/Media-Provider/db"
 	"github.com/Fesaa/Media-Provider/db/models"
 	"github.com/Fesaa/Media-Provider/http/payload"
 	"github.com/Fesaa/Media-Provider/internal/contextkey"
 	"github.com/Fesaa/Media-Provider/providers/pasloe/publication"
 	"github.com/Fesaa/Media-Provider/providers/yoitsu"
 	"github.com/Fesaa/Media-Provider/services"
+	"github.com/Fesaa/Media-Provider/utils"
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/dig"
 )
@@ -18,11 +26,12 @@ import (
 type contentRoutes struct {
 	dig.In
 
-	Router fiber.Router
-	Cache  fiber.Handler `name:"cache"`
-	Auth   services.AuthService
-	YS     yoitsu.Client
-	PS     publication.Client
+	Router     fiber.Router
+	Cache      fiber.Handler `name:"cache"`
+	Auth       services.AuthService
+	YS         yoitsu.Client
+	PS         publication.Client
+	UnitOfWork *db.UnitOfWork
 
 	Val            services.ValidationService
 	ContentService services.ContentService
@@ -33,6 +42,7 @@ func RegisterContentRoutes(cr contentRoutes) {
 	cr.Router.Group("/content", cr.Auth.Middleware).
 		Post("/search", cr.Cache, withBodyValidation(cr.Search)).
 		Post("/download", withBodyValidation(cr.Download)).
+		Post("/download/torrent", cr.DownloadTorrent).
 		Post("/stop", withBodyValidation(cr.Stop)).
 		Get("/stats", withParams(cr.Stats, newQueryParam("all", withAllowEmpty(false)))).
 		Post("/message", withBody(cr.Message))
@@ -88,9 +98,24 @@ func (cr *contentRoutes) Download(ctx *fiber.Ctx, req payload.DownloadRequest) e
 		return BadRequest()
 	}
 
+	if yoitsu.IsTorrentUrl(req.Id) {
+		allowed, err := cr.allowedTorrentUrl(ctx.UserContext(), user, req.Id)
+		if err != nil {
+			return InternalError(err)
+		}
+		if !allowed {
+			log.Warn().Str("user", user.Name).Str("url", req.Id).Msg("user tried to download a torrent url not from an indexer")
+			return Forbidden(errors.New(cr.Transloco.GetTranslation("torrent-url-not-allowed")))
+		}
+	}
+
 	req.OwnerId = user.ID
 
 	if err := cr.ContentService.Download(req); err != nil {
+		if errors.Is(err, yoitsu.ErrInvalidTorrentId) {
+			return BadRequest(err)
+		}
+
 		log.Error().
 			Err(err).
 			Str("debug_info", fmt.Sprintf("%#v", req)).
@@ -101,6 +126,85 @@ func (cr *contentRoutes) Download(ctx *fiber.Ctx, req payload.DownloadRequest) e
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{})
 }
 
+// allowedTorrentUrl returns true if the url is on the host of a configured indexer. The server fetches the url, so
+// only users managing the server may download from any url
+func (cr *contentRoutes) allowedTorrentUrl(ctx context.Context, user models.User, uri string) (bool, error) {
+	if user.HasRole(models.ManageServerConfigs) {
+		return true, nil
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return false, nil
+	}
+
+	indexers, err := cr.UnitOfWork.Torznab.GetAll(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return slices.ContainsFunc(indexers, func(indexer models.TorznabIndexer) bool {
+		indexerUrl, err := url.Parse(indexer.Url)
+		return err == nil && strings.EqualFold(indexerUrl.Host, u.Host)
+	}), nil
+}
+
+// DownloadTorrent adds an uploaded .torrent file. Expects a multipart form with the file as torrent, and the
+// download request as json in request. The id of the request is taken from the torrent file
+func (cr *contentRoutes) DownloadTorrent(ctx *fiber.Ctx) error {
+	log := contextkey.GetFromContext(ctx, contextkey.Logger)
+	user := contextkey.GetFromContext(ctx, contextkey.User)
+
+	fileHeader, err := ctx.FormFile("torrent")
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	var req payload.DownloadRequest
+	if err = json.Unmarshal([]byte(ctx.FormValue("request")), &req); err != nil {
+		return BadRequest(err)
+	}
+
+	// Replaced by the infohash once the file has been parsed
+	req.Id = fileHeader.Filename
+	req.TempTitle = utils.NonEmpty(req.TempTitle, strings.TrimSuffix(fileHeader.Filename, path.Ext(fileHeader.Filename)))
+	if err = cr.Val.Validate(req); err != nil {
+		return BadRequest(err)
+	}
+
+	if req.BaseDir == "" {
+		return BadRequest(errors.New(cr.Transloco.GetTranslation("base-dir-not-empty")))
+	}
+
+	if strings.Contains(req.BaseDir, "..") {
+		return BadRequest()
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return InternalError(err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	req.OwnerId = user.ID
+
+	if err = cr.ContentService.DownloadTorrentFile(req, data); err != nil {
+		if errors.Is(err, yoitsu.ErrInvalidTorrentFile) || errors.Is(err, services.ErrTorrentFileNotSupported) {
+			return BadRequest(err)
+		}
+
+		log.Error().Err(err).Str("file", fileHeader.Filename).Msg("error while downloading torrent file")
+		return InternalError(err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{})
+}
+
 func (cr *contentRoutes) Stop(ctx *fiber.Ctx, req payload.StopRequest) error {
 	log := contextkey.GetFromContext(ctx, contextkey.Logger)
 
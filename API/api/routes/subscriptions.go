@@ -1,20 +1,29 @@
 package routes
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"path"
 	"slices"
 
 	"github.com/Fesaa/Media-Provider/db"
 	"github.com/Fesaa/Media-Provider/db/models"
+	"github.com/Fesaa/Media-Provider/http/payload"
 	"github.com/Fesaa/Media-Provider/internal/contextkey"
+	"github.com/Fesaa/Media-Provider/internal/naming"
+	"github.com/Fesaa/Media-Provider/internal/tachiyomi"
+	"github.com/Fesaa/Media-Provider/providers/pasloe/publication"
+	"github.com/Fesaa/Media-Provider/providers/yoitsu"
 	"github.com/Fesaa/Media-Provider/services"
+	"github.com/Fesaa/Media-Provider/utils"
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/dig"
 )
 
 var (
-	allowedProviders      = []models.Provider{models.MANGADEX, models.WEBTOON, models.DYNASTY, models.BATO, models.MANGA_BUDDY}
+	allowedProviders = []models.Provider{models.MANGADEX, models.WEBTOON, models.DYNASTY, models.BATO, models.MANGA_BUDDY,
+		models.NYAA, models.YTS, models.LIME, models.SUBSPLEASE, models.TORZNAB}
 	errDisallowedProvider = errors.New("the passed provider does not support subscription")
 )
 
@@ -35,8 +44,16 @@ func RegisterSubscriptionRoutes(sr subscriptionRoutes) {
 	sr.Router.Group("/subscriptions", sr.Auth.Middleware).
 		Get("/providers", sr.providers).
 		Get("/all", withParams(sr.all, newQueryParam("allUsers", withAllowEmpty(false)))).
+		Get("/export", withParams(sr.export, newQueryParam("allUsers", withAllowEmpty(false)))).
+		Post("/import", withBody(sr.importSubscriptions)).
+		Post("/import/tachiyomi", sr.importTachiyomi).
 		Get("/:id", withParams(sr.get, newIdPathParam())).
+		Get("/:id/history", withParams(sr.history, newIdPathParam(),
+			newQueryParam("pageNumber", withAllowEmpty(0)),
+			newQueryParam("pageSize", withAllowEmpty(utils.DefaultPageSize)))).
 		Post("/run-once/:id", withParams(sr.runOnce, newIdPathParam())).
+		Post("/:id/reorganise", withParams(sr.reorganise, newIdPathParam(),
+			newQueryParam("dryRun", withAllowEmpty(true)))).
 		Post("/update", withBody(sr.update)).
 		Post("/new", withBody(sr.new)).
 		Post("/run-all", withParams(sr.runAll, newQueryParam("allUsers", withAllowEmpty(false)))).
@@ -69,7 +86,7 @@ func (sr *subscriptionRoutes) runAll(ctx *fiber.Ctx, allUsers bool) error {
 	}
 
 	for _, sub := range subs {
-		err = sr.ContentService.DownloadSubscription(&sub, false) // This was manually triggered
+		err = sr.SubscriptionService.RunOnce(ctx.UserContext(), &sub, models.TriggerRunAll)
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to download subscription")
 			return InternalError(err, fiber.Map{"subscription": sub.ID})
@@ -94,7 +111,7 @@ func (sr *subscriptionRoutes) runOnce(ctx *fiber.Ctx, id int) error {
 		return Forbidden()
 	}
 
-	err = sr.ContentService.DownloadSubscription(sub, false) // This was manually triggered
+	err = sr.SubscriptionService.RunOnce(ctx.UserContext(), sub, models.TriggerRunOnce)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to download subscription")
 		return InternalError(errors.New(sr.Transloco.GetTranslation("failed-to-run-once", err)))
@@ -103,6 +120,37 @@ func (sr *subscriptionRoutes) runOnce(ctx *fiber.Ctx, id int) error {
 	return ctx.Status(fiber.StatusAccepted).JSON(fiber.Map{})
 }
 
+// reorganise returns the report of moving the content of the subscription to the current naming scheme when dryRun
+// is true. Otherwise, the content is moved in the background
+func (sr *subscriptionRoutes) reorganise(ctx *fiber.Ctx, id int, dryRun bool) error {
+	log := contextkey.GetFromContext(ctx, contextkey.Logger)
+	user := contextkey.GetFromContext(ctx, contextkey.User)
+	allowAny := user.HasRole(models.ManageSubscriptions)
+
+	sub, err := sr.UnitOfWork.Subscriptions.Get(ctx.UserContext(), id)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get subscription")
+		return InternalError(err)
+	}
+
+	if sub.Owner != user.ID && !allowAny {
+		return Forbidden()
+	}
+
+	if !dryRun {
+		sr.SubscriptionService.Reorganise(*sub)
+		return ctx.Status(fiber.StatusAccepted).JSON(fiber.Map{})
+	}
+
+	report, err := sr.ContentService.ReorganiseSubscription(ctx.UserContext(), sub, true)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to reorganise subscription")
+		return InternalError(err)
+	}
+
+	return ctx.JSON(report)
+}
+
 func (sr *subscriptionRoutes) all(ctx *fiber.Ctx, allUsers bool) error {
 	log := contextkey.GetFromContext(ctx, contextkey.Logger)
 
@@ -115,6 +163,65 @@ func (sr *subscriptionRoutes) all(ctx *fiber.Ctx, allUsers bool) error {
 	return ctx.JSON(subs)
 }
 
+// export returns the subscriptions of the authenticated user as a payload.SubscriptionExport, see getAll
+func (sr *subscriptionRoutes) export(ctx *fiber.Ctx, allUsers bool) error {
+	log := contextkey.GetFromContext(ctx, contextkey.Logger)
+
+	subs, err := sr.getAll(ctx, allUsers)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get subscriptions")
+		return InternalError(err)
+	}
+
+	return ctx.JSON(payload.NewSubscriptionExport(subs))
+}
+
+// importSubscriptions adds all subscriptions in the export for the authenticated user, and reports the outcome of
+// each entry
+func (sr *subscriptionRoutes) importSubscriptions(ctx *fiber.Ctx, export payload.SubscriptionExport) error {
+	user := contextkey.GetFromContext(ctx, contextkey.User)
+
+	if export.Version < 1 || export.Version > payload.SubscriptionExportVersion {
+		return BadRequest(fmt.Errorf("unsupported export version %d", export.Version))
+	}
+
+	allowAny := user.HasRole(models.ManageSubscriptions)
+	report := sr.SubscriptionService.Import(ctx.UserContext(), user.ID, allowAny, export.Subscriptions, sr.validatorSubscription)
+
+	return ctx.JSON(report)
+}
+
+// importTachiyomi proposes subscriptions for the library of an uploaded Tachiyomi, or Mihon, backup. Expects a
+// multipart form with the backup as backup. Nothing is created, the proposals are to be imported after review
+func (sr *subscriptionRoutes) importTachiyomi(ctx *fiber.Ctx) error {
+	log := contextkey.GetFromContext(ctx, contextkey.Logger)
+	user := contextkey.GetFromContext(ctx, contextkey.User)
+
+	fileHeader, err := ctx.FormFile("backup")
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return InternalError(err)
+	}
+	defer file.Close()
+
+	backup, err := tachiyomi.Parse(file)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	proposals, err := sr.SubscriptionService.ProposeTachiyomi(ctx.UserContext(), user.ID, backup)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to propose subscriptions for backup")
+		return InternalError(err)
+	}
+
+	return ctx.JSON(proposals)
+}
+
 func (sr *subscriptionRoutes) get(ctx *fiber.Ctx, id int) error {
 	log := contextkey.GetFromContext(ctx, contextkey.Logger)
 	user := contextkey.GetFromContext(ctx, contextkey.User)
@@ -133,6 +240,34 @@ func (sr *subscriptionRoutes) get(ctx *fiber.Ctx, id int) error {
 	return ctx.JSON(sub)
 }
 
+// history returns the recorded runs of the subscription, most recent first
+func (sr *subscriptionRoutes) history(ctx *fiber.Ctx, id, pageNumber, pageSize int) error {
+	log := contextkey.GetFromContext(ctx, contextkey.Logger)
+	user := contextkey.GetFromContext(ctx, contextkey.User)
+	allowAny := user.HasRole(models.ManageSubscriptions)
+
+	sub, err := sr.UnitOfWork.Subscriptions.Get(ctx.UserContext(), id)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get subscription")
+		return InternalError(err)
+	}
+
+	if sub.Owner != user.ID && !allowAny {
+		return NotFound()
+	}
+
+	runs, err := sr.SubscriptionService.History(ctx.UserContext(), id, utils.UserParams{
+		PageSize:   pageSize,
+		PageNumber: pageNumber,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get subscription history")
+		return InternalError(err)
+	}
+
+	return ctx.JSON(runs)
+}
+
 func (sr *subscriptionRoutes) update(ctx *fiber.Ctx, sub models.Subscription) error {
 	log := contextkey.GetFromContext(ctx, contextkey.Logger)
 
@@ -182,7 +317,7 @@ func (sr *subscriptionRoutes) new(ctx *fiber.Ctx, sub models.Subscription) error
 	}
 
 	go func() {
-		if err = sr.ContentService.DownloadSubscription(subscription, false); err != nil {
+		if err = sr.SubscriptionService.RunOnce(context.Background(), subscription, models.TriggerRunOnce); err != nil {
 			log.Warn().Err(err).Msg("failed to download subscription, will run again as scheduled. May have issues?")
 		}
 	}()
@@ -199,6 +334,29 @@ func (sr *subscriptionRoutes) validatorSubscription(sub models.Subscription) err
 		return errDisallowedProvider
 	}
 
+	if err := sub.ValidateSchedule(); err != nil {
+		return err
+	}
+
+	if err := sub.ValidateAdaptive(); err != nil {
+		return err
+	}
+
+	templateKeys := []string{publication.FileNameTemplateKey, publication.VolumeDirTemplateKey}
+	if sub.Provider.IsTorrent() {
+		if err := sub.TorrentSearch.Filter.Validate(); err != nil {
+			return err
+		}
+		templateKeys = []string{yoitsu.EpisodeTemplateKey, yoitsu.SeasonDirTemplateKey}
+	}
+
+	for _, key := range templateKeys {
+		validate := utils.Ternary(key == publication.FileNameTemplateKey, naming.ValidateFileName, naming.Validate)
+		if err := validate(sub.Payload.Extra.GetStringOrDefault(key, "")); err != nil {
+			return fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+
 	return nil
 }
 
@@ -0,0 +1,132 @@
+package routes
+
+import (
+	"strings"
+
+	"github.com/Fesaa/Media-Provider/db"
+	"github.com/Fesaa/Media-Provider/db/models"
+	"github.com/Fesaa/Media-Provider/http/menou"
+	"github.com/Fesaa/Media-Provider/internal/contextkey"
+	"github.com/Fesaa/Media-Provider/providers/yoitsu/torznab"
+	"github.com/Fesaa/Media-Provider/services"
+	"github.com/Fesaa/Media-Provider/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/dig"
+)
+
+// maskedApiKey is returned instead of the api key, sending it back keeps the stored key
+const maskedApiKey = "********"
+
+type torznabRoutes struct {
+	dig.In
+
+	Router     fiber.Router
+	UnitOfWork *db.UnitOfWork
+	Auth       services.AuthService
+	HttpClient *menou.Client
+}
+
+func RegisterTorznabRoutes(tr torznabRoutes) {
+	indexers := tr.Router.Group("/torznab", tr.Auth.Middleware)
+
+	indexers.
+		Get("/", hasRole(models.ManagePages), tr.indexers).
+		Get("/:id/modifiers", hasRole(models.ManagePages), withParams(tr.modifiers, newIdPathParam()))
+
+	indexers.Use(hasRole(models.ManageServerConfigs)).
+		Post("/new", withBodyValidation(tr.newIndexer)).
+		Post("/update", withBodyValidation(tr.updateIndexer)).
+		Delete("/:id", withParams(tr.deleteIndexer, newIdPathParam()))
+}
+
+func maskIndexer(indexer models.TorznabIndexer) models.TorznabIndexer {
+	if indexer.ApiKey != "" {
+		indexer.ApiKey = maskedApiKey
+	}
+	return indexer
+}
+
+func (tr *torznabRoutes) indexers(ctx *fiber.Ctx) error {
+	log := contextkey.GetFromContext(ctx, contextkey.Logger)
+
+	indexers, err := tr.UnitOfWork.Torznab.GetAll(ctx.UserContext())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get torznab indexers")
+		return InternalError(err)
+	}
+
+	return ctx.JSON(utils.Map(indexers, maskIndexer))
+}
+
+func (tr *torznabRoutes) modifiers(ctx *fiber.Ctx, id int) error {
+	log := contextkey.GetFromContext(ctx, contextkey.Logger)
+
+	indexer, err := tr.UnitOfWork.Torznab.Get(ctx.UserContext(), id)
+	if err != nil {
+		log.Error().Err(err).Int("id", id).Msg("Failed to get torznab indexer")
+		return InternalError(err)
+	}
+
+	if indexer == nil {
+		return NotFound()
+	}
+
+	modifiers, err := torznab.Modifiers(ctx.UserContext(), tr.HttpClient, *indexer)
+	if err != nil {
+		log.Warn().Err(err).Str("indexer", indexer.Name).Msg("Failed to get indexer capabilities")
+		return BadRequest(err)
+	}
+
+	return ctx.JSON(modifiers)
+}
+
+func (tr *torznabRoutes) newIndexer(ctx *fiber.Ctx, indexer models.TorznabIndexer) error {
+	log := contextkey.GetFromContext(ctx, contextkey.Logger)
+
+	indexer.ID = 0
+	indexer.Url = strings.TrimSpace(indexer.Url)
+	if err := tr.UnitOfWork.Torznab.Create(ctx.UserContext(), &indexer); err != nil {
+		log.Error().Err(err).Msg("Failed to create torznab indexer")
+		return InternalError(err)
+	}
+
+	return ctx.JSON(maskIndexer(indexer))
+}
+
+func (tr *torznabRoutes) updateIndexer(ctx *fiber.Ctx, indexer models.TorznabIndexer) error {
+	log := contextkey.GetFromContext(ctx, contextkey.Logger)
+
+	cur, err := tr.UnitOfWork.Torznab.Get(ctx.UserContext(), indexer.ID)
+	if err != nil {
+		log.Error().Err(err).Int("id", indexer.ID).Msg("Failed to get torznab indexer")
+		return InternalError(err)
+	}
+
+	if cur == nil {
+		return NotFound()
+	}
+
+	if indexer.ApiKey == maskedApiKey {
+		indexer.ApiKey = cur.ApiKey
+	}
+	indexer.Url = strings.TrimSpace(indexer.Url)
+	indexer.CreatedAt = cur.CreatedAt
+
+	if err = tr.UnitOfWork.Torznab.Update(ctx.UserContext(), &indexer); err != nil {
+		log.Error().Err(err).Int("id", indexer.ID).Msg("Failed to update torznab indexer")
+		return InternalError(err)
+	}
+
+	return ctx.JSON(maskIndexer(indexer))
+}
+
+func (tr *torznabRoutes) deleteIndexer(ctx *fiber.Ctx, id int) error {
+	log := contextkey.GetFromContext(ctx, contextkey.Logger)
+
+	if err := tr.UnitOfWork.Torznab.Delete(ctx.UserContext(), id); err != nil {
+		log.Error().Err(err).Int("id", id).Msg("Failed to delete torznab indexer")
+		return InternalError(err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{})
+}
@@ -77,6 +77,7 @@ func Setup(
 	utils2.Must(scope.Invoke(routes.RegisterSubscriptionRoutes))
 	utils2.Must(scope.Invoke(routes.RegisterPreferencesRoutes))
 	utils2.Must(scope.Invoke(routes.RegisterNotificationRoutes))
+	utils2.Must(scope.Invoke(routes.RegisterTorznabRoutes))
 
 	return nil
 }
@@ -14,9 +14,10 @@ var (
 	// DisableVolumeDirs Kavita has a bug where it rescans series on each scan loop when they have subfolders
 	// until this has been resolved, we will not be adding the volume dir
 	// See https://github.com/Kareadita/Kavita/issues/3557
+	// Ignored for downloads with a volume dir template
 	DisableVolumeDirs = boolFeature("DISABLE_VOLUME_DIRS")
 
-	// DisableOneShotInFileName removed the (OneShot) in the filename
+	// DisableOneShotInFileName removed the (OneShot) in the filename, ignored for downloads with a file name template
 	DisableOneShotInFileName = boolFeature("DISABLE_ONE_SHOT_IN_FILE_NAME")
 
 	// SkipTagsOnFailure will skip tag writing if preferences fail to load
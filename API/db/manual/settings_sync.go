@@ -27,6 +27,122 @@ var defaults = []models.ServerSetting{
 		Key:   models.MaxConcurrentImages,
 		Value: "5",
 	},
+	{
+		Key:   models.MaxConcurrentChapters,
+		Value: "1",
+	},
+	{
+		Key:   models.SeedRatio,
+		Value: "0",
+	},
+	{
+		Key:   models.MinSeedTime,
+		Value: "0",
+	},
+	{
+		Key:   models.MaxSeedingTorrents,
+		Value: "0",
+	},
+	{
+		Key:   models.UploadRateLimit,
+		Value: "0",
+	},
+	{
+		Key:   models.DownloadRateLimit,
+		Value: "0",
+	},
+	{
+		Key:   models.TorrentUploadRateLimit,
+		Value: "0",
+	},
+	{
+		Key:   models.TorrentDownloadRateLimit,
+		Value: "0",
+	},
+	{
+		Key:   models.AltUploadRateLimit,
+		Value: "0",
+	},
+	{
+		Key:   models.AltDownloadRateLimit,
+		Value: "0",
+	},
+	{
+		Key:   models.AltSpeedScheduled,
+		Value: "false",
+	},
+	{
+		Key:   models.AltSpeedDays,
+		Value: "1,2,3,4,5",
+	},
+	{
+		Key:   models.AltSpeedFrom,
+		Value: "09:00",
+	},
+	{
+		Key:   models.AltSpeedTo,
+		Value: "17:00",
+	},
+	{
+		Key:   models.TorrentListenPort,
+		Value: "0",
+	},
+	{
+		Key:   models.MaxPeersPerTorrent,
+		Value: "0",
+	},
+	{
+		Key:   models.EncryptionPolicy,
+		Value: "prefer",
+	},
+	{
+		Key:   models.DisableDht,
+		Value: "false",
+	},
+	{
+		Key:   models.DisablePex,
+		Value: "false",
+	},
+	{
+		Key:   models.TorrentBackend,
+		Value: "embedded",
+	},
+	{
+		Key:   models.RemoteTorrentUrl,
+		Value: "",
+	},
+	{
+		Key:   models.RemoteTorrentUsername,
+		Value: "",
+	},
+	{
+		Key:   models.RemoteTorrentPassword,
+		Value: "",
+	},
+	{
+		Key:   models.RemoteTorrentDir,
+		Value: "",
+	},
+	{
+		Key:   models.StallMetadataTimeout,
+		Value: "30",
+	},
+	{
+		Key:   models.StallProgressTimeout,
+		Value: "60",
+	},
+	{
+		Key:   models.StallAction,
+		Value: "requeue",
+	},
+	{
+		Key:   models.SubscriptionHistoryDays,
+		Value: "30",
+	},
+	{
+		Key:   models.SubscriptionHistoryRuns,
+		Value: "50",
+	},
 	{
 		Key:   models.DisableIpv6,
 		Value: "false",
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+const (
+	// releaseGap is the minimum time between two releases, chapters released closer together are one release
+	releaseGap = 24 * time.Hour
+	// cadenceReleases is the amount of recent releases the cadence is estimated from
+	cadenceReleases = 10
+)
+
+// EstimateCadence returns the typical time between releases, and the date of the last release.
+// The cadence is 0 when there are too few releases to estimate it from
+func EstimateCadence(releases []time.Time) (time.Duration, time.Time) {
+	sorted := make([]time.Time, 0, len(releases))
+	for _, release := range releases {
+		if !release.IsZero() {
+			sorted = append(sorted, release)
+		}
+	}
+
+	if len(sorted) == 0 {
+		return 0, time.Time{}
+	}
+
+	slices.SortFunc(sorted, func(a, b time.Time) int {
+		return a.Compare(b)
+	})
+
+	// Batch releases, and multiple groups releasing the same chapter, count as one release
+	grouped := []time.Time{sorted[0]}
+	for _, release := range sorted[1:] {
+		if release.Sub(grouped[len(grouped)-1]) >= releaseGap {
+			grouped = append(grouped, release)
+		}
+	}
+
+	last := sorted[len(sorted)-1]
+	if len(grouped) > cadenceReleases+1 {
+		grouped = grouped[len(grouped)-cadenceReleases-1:]
+	}
+
+	// At least two gaps are needed for the estimate to mean anything
+	if len(grouped) < 3 {
+		return 0, last
+	}
+
+	gaps := make([]time.Duration, 0, len(grouped)-1)
+	for i := 1; i < len(grouped); i++ {
+		gaps = append(gaps, grouped[i].Sub(grouped[i-1]))
+	}
+
+	// The median ignores the odd hiatus, or double release
+	slices.Sort(gaps)
+	return gaps[len(gaps)/2], last
+}
+
+// ObserveReleases updates the cadence, and predicted release, of the subscription from the release dates of its
+// chapters. The next execution of adaptive subscriptions is moved to match
+func (s *Subscription) ObserveReleases(releases []time.Time, now time.Time) {
+	cadence, last := EstimateCadence(releases)
+
+	s.Cadence = int(cadence.Round(time.Hour) / time.Hour)
+	s.PredictedRelease = time.Time{}
+	if s.Cadence > 0 {
+		s.PredictedRelease = last.Add(cadence)
+	}
+
+	if next := s.adaptiveNext(now); !next.IsZero() {
+		s.NextExecution = next
+	}
+}
+
+// IsAdaptive returns true if the subscription picks when to run from its release cadence
+func (s *Subscription) IsAdaptive() bool {
+	return s.Adaptive && !s.HasSchedule()
+}
+
+// ValidateAdaptive checks the bounds of adaptive subscriptions
+func (s *Subscription) ValidateAdaptive() error {
+	if !s.Adaptive {
+		return nil
+	}
+
+	if s.HasSchedule() {
+		return errors.New("adaptive refresh can't be combined with a cron expression or interval")
+	}
+
+	if s.Provider.IsTorrent() {
+		return errors.New("adaptive refresh is not supported for torrent subscriptions")
+	}
+
+	if s.AdaptiveMin < 1 || s.AdaptiveMax < s.AdaptiveMin {
+		return errors.New("adaptive bounds must be at least 1 hour, and the maximum can't be below the minimum")
+	}
+
+	return nil
+}
+
+// adaptiveNext returns when an adaptive subscription should run next, zero if its cadence is unknown.
+// It runs around the predicted release, once late it checks a few times per cadence, backing off with
+// every empty run. The result always lies between AdaptiveMin and AdaptiveMax hours from now
+func (s *Subscription) adaptiveNext(now time.Time) time.Time {
+	if !s.IsAdaptive() || s.Cadence <= 0 {
+		return time.Time{}
+	}
+
+	next := s.PredictedRelease
+	if !next.After(now) {
+		cadence := time.Duration(s.Cadence) * time.Hour
+		next = now.Add(cadence / 4 * time.Duration(min(s.NoDownloadCount+1, 8)))
+	}
+
+	lower := now.Add(time.Duration(s.AdaptiveMin) * time.Hour)
+	upper := now.Add(time.Duration(s.AdaptiveMax) * time.Hour)
+	if next.Before(lower) {
+		return lower
+	}
+	if next.After(upper) {
+		return upper
+	}
+	return next
+}
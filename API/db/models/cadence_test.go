@@ -0,0 +1,142 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEstimateCadence(t *testing.T) {
+	start := time.Date(2025, 1, 7, 17, 0, 0, 0, time.UTC)
+	week := 7 * 24 * time.Hour
+
+	weekly := func(n int) []time.Time {
+		var releases []time.Time
+		for i := range n {
+			releases = append(releases, start.Add(time.Duration(i)*week))
+		}
+		return releases
+	}
+
+	tests := []struct {
+		name        string
+		releases    []time.Time
+		wantCadence time.Duration
+		wantLast    time.Time
+	}{
+		{
+			name: "no releases",
+		},
+		{
+			name:     "too few releases",
+			releases: weekly(2),
+			wantLast: start.Add(week),
+		},
+		{
+			name:        "weekly",
+			releases:    weekly(5),
+			wantCadence: week,
+			wantLast:    start.Add(4 * week),
+		},
+		{
+			name: "unordered with a double release and a hiatus",
+			releases: append(weekly(6),
+				start.Add(5*week+time.Hour),
+				start.Add(15*week),
+				time.Time{},
+			),
+			wantCadence: week,
+			wantLast:    start.Add(15 * week),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cadence, last := EstimateCadence(tt.releases)
+			if cadence != tt.wantCadence {
+				t.Errorf("Got cadence %v; expected %v", cadence, tt.wantCadence)
+			}
+			if !last.Equal(tt.wantLast) {
+				t.Errorf("Got last release %v; expected %v", last, tt.wantLast)
+			}
+		})
+	}
+}
+
+func TestSubscription_ObserveReleases(t *testing.T) {
+	start := time.Date(2025, 1, 7, 17, 0, 0, 0, time.UTC)
+	week := 7 * 24 * time.Hour
+	releases := []time.Time{start, start.Add(week), start.Add(2 * week)}
+
+	sub := Subscription{Adaptive: true, AdaptiveMin: 6, AdaptiveMax: 14 * 24}
+
+	now := start.Add(2*week + 24*time.Hour)
+	sub.ObserveReleases(releases, now)
+
+	if sub.Cadence != 7*24 {
+		t.Errorf("Got cadence %d; expected a week", sub.Cadence)
+	}
+	if want := start.Add(3 * week); !sub.PredictedRelease.Equal(want) {
+		t.Errorf("Got predicted release %v; expected %v", sub.PredictedRelease, want)
+	}
+	if !sub.NextExecution.Equal(sub.PredictedRelease) {
+		t.Errorf("Got next execution %v; expected the predicted release", sub.NextExecution)
+	}
+
+	// Late releases are checked for a few times per cadence, backing off with every empty run
+	late := start.Add(3*week + time.Hour)
+	sub.ObserveReleases(releases, late)
+	if want := late.Add(42 * time.Hour); !sub.NextExecution.Equal(want) {
+		t.Errorf("Got next execution %v; expected %v", sub.NextExecution, want)
+	}
+
+	sub.NoDownloadCount = 3
+	sub.ObserveReleases(releases, late)
+	if want := late.Add(4 * 42 * time.Hour); !sub.NextExecution.Equal(want) {
+		t.Errorf("Got next execution %v; expected %v", sub.NextExecution, want)
+	}
+
+	// Never further away than the maximum
+	sub.NoDownloadCount = 100
+	sub.ObserveReleases(releases, late)
+	if want := late.Add(14 * 24 * time.Hour); !sub.NextExecution.Equal(want) {
+		t.Errorf("Got next execution %v; expected the maximum %v", sub.NextExecution, want)
+	}
+
+	// Nor closer than the minimum
+	sub.NoDownloadCount = 0
+	almost := sub.PredictedRelease.Add(-time.Hour)
+	sub.ObserveReleases(releases, almost)
+	if want := almost.Add(6 * time.Hour); !sub.NextExecution.Equal(want) {
+		t.Errorf("Got next execution %v; expected the minimum %v", sub.NextExecution, want)
+	}
+
+	// The next execution is left alone when the subscription isn't adaptive
+	sub = Subscription{}
+	sub.ObserveReleases(releases, now)
+	if !sub.NextExecution.IsZero() || sub.Cadence != 7*24 {
+		t.Errorf("Got %+v; expected only the cadence to be set", sub)
+	}
+}
+
+func TestSubscription_ValidateAdaptive(t *testing.T) {
+	tests := []struct {
+		name    string
+		sub     Subscription
+		wantErr bool
+	}{
+		{name: "disabled", sub: Subscription{}},
+		{name: "valid", sub: Subscription{Adaptive: true, AdaptiveMin: 6, AdaptiveMax: 168}},
+		{name: "no minimum", sub: Subscription{Adaptive: true, AdaptiveMax: 168}, wantErr: true},
+		{name: "maximum below minimum", sub: Subscription{Adaptive: true, AdaptiveMin: 12, AdaptiveMax: 6}, wantErr: true},
+		{name: "with interval", sub: Subscription{Adaptive: true, AdaptiveMin: 6, AdaptiveMax: 168, Interval: 60}, wantErr: true},
+		{name: "torrent", sub: Subscription{Adaptive: true, AdaptiveMin: 6, AdaptiveMax: 168, Provider: NYAA}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.sub.ValidateAdaptive(); (err != nil) != tt.wantErr {
+				t.Errorf("Got %v; expected error: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
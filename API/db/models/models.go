@@ -9,4 +9,7 @@ var MODELS = []any{
 	&UserPreferences{},
 	&Notification{},
 	&ServerSetting{},
+	&QueuedContent{},
+	&TorznabIndexer{},
+	&SubscriptionRun{},
 }
@@ -2,6 +2,12 @@ package models
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/lib/pq"
 	"github.com/rs/zerolog"
@@ -24,11 +30,22 @@ const (
 	DYNASTY
 	BATO
 	MANGA_BUDDY
+	TORZNAB
 
 	MinProvider = NYAA
-	MaxProvider = MANGA_BUDDY
+	MaxProvider = TORZNAB
 )
 
+// IsTorrent returns true if the provider searches for torrents
+func (p Provider) IsTorrent() bool {
+	switch p {
+	case NYAA, YTS, LIME, SUBSPLEASE, TORZNAB:
+		return true
+	default:
+		return false
+	}
+}
+
 func (p Provider) String() string {
 	switch p {
 	case NYAA:
@@ -49,6 +66,8 @@ func (p Provider) String() string {
 		return "Bato"
 	case MANGA_BUDDY:
 		return "MangaBuddy"
+	case TORZNAB:
+		return "Torznab"
 	default:
 		return "Unknown Provider"
 	}
@@ -65,14 +84,37 @@ type Page struct {
 	Modifiers     []Modifier      `gorm:"-" json:"modifiers"`
 	Dirs          pq.StringArray  `gorm:"type:text[]" json:"dirs"`
 	CustomRootDir string          `json:"customRootDir"`
+	// FileNameTemplate and VolumeDirTemplate are the default naming templates for downloads started from the page
+	FileNameTemplate  string `json:"fileNameTemplate" validate:"file_name_template"`
+	VolumeDirTemplate string `json:"volumeDirTemplate" validate:"naming_template"`
+	// SeedRatio and SeedTime (minutes) override the server seeding rules for torrents downloaded from the page
+	SeedRatio *float64 `json:"seedRatio" validate:"omitempty,min=0"`
+	SeedTime  *int     `json:"seedTime" validate:"omitempty,min=0"`
+	// EpisodeTemplate and SeasonDirTemplate are the default templates used when renaming episodes of torrents
+	EpisodeTemplate   string `json:"episodeTemplate" validate:"naming_template"`
+	SeasonDirTemplate string `json:"seasonDirTemplate" validate:"naming_template"`
+	// FileRules are the default file selection rules for torrents downloaded from the page
+	FileRulesData json.RawMessage `gorm:"type:jsonb" json:"-"`
+	FileRules     FileRules       `gorm:"-" json:"fileRules" validate:"file_rules"`
 }
 
 func (p *Page) BeforeSave(tx *gorm.DB) (err error) {
 	p.ModifierData, err = json.Marshal(p.Modifiers)
+	if err != nil {
+		return
+	}
+
+	p.FileRulesData, err = json.Marshal(p.FileRules)
 	return
 }
 
 func (p *Page) AfterFind(tx *gorm.DB) (err error) {
+	if p.FileRulesData != nil {
+		if err = json.Unmarshal(p.FileRulesData, &p.FileRules); err != nil {
+			return
+		}
+	}
+
 	if p.ModifierData == nil {
 		p.Modifiers = []Modifier{}
 		return
@@ -81,6 +123,59 @@ func (p *Page) AfterFind(tx *gorm.DB) (err error) {
 	return json.Unmarshal(p.ModifierData, &p.Modifiers)
 }
 
+// FileRules select the files of a torrent to download when no manual selection was made. Patterns are globs
+// matched against the path and file name, or regexes when wrapped in slashes, i.e. /S01E\d+/
+type FileRules struct {
+	Include []string `json:"include"`
+	Exclude []string `json:"exclude"`
+	// Extensions only allows files with these extensions, if not empty
+	Extensions []string `json:"extensions"`
+	// MinSize and MaxSize are in MiB, 0 is no limit
+	MinSize int64 `json:"minSize"`
+	MaxSize int64 `json:"maxSize"`
+	// LargestVideoOnly only downloads the largest video file left after the other rules
+	LargestVideoOnly bool `json:"largestVideoOnly"`
+}
+
+// IsEmpty returns true if the rules would select every file
+func (r FileRules) IsEmpty() bool {
+	return len(r.Include) == 0 && len(r.Exclude) == 0 && len(r.Extensions) == 0 &&
+		r.MinSize == 0 && r.MaxSize == 0 && !r.LargestVideoOnly
+}
+
+func (r FileRules) Validate() error {
+	for _, pattern := range slices.Concat(r.Include, r.Exclude) {
+		if expr, ok := RegexPattern(pattern); ok {
+			if _, err := regexp.Compile(expr); err != nil {
+				return fmt.Errorf("invalid regex %s: %w", pattern, err)
+			}
+			continue
+		}
+
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid glob %s: %w", pattern, err)
+		}
+	}
+
+	if r.MinSize < 0 || r.MaxSize < 0 {
+		return errors.New("sizes cannot be negative")
+	}
+
+	if r.MaxSize > 0 && r.MinSize > r.MaxSize {
+		return errors.New("minimum size cannot be larger than the maximum size")
+	}
+
+	return nil
+}
+
+// RegexPattern returns the regex of a file rule pattern wrapped in slashes, false for globs
+func RegexPattern(pattern string) (string, bool) {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		return pattern[1 : len(pattern)-1], true
+	}
+	return "", false
+}
+
 type ModifierType int
 
 const (
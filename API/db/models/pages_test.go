@@ -45,6 +45,11 @@ func TestProvider_String(t *testing.T) {
 			provider: DYNASTY,
 			expected: "Dynasty",
 		},
+		{
+			name:     "TORZNAB",
+			provider: TORZNAB,
+			expected: "Torznab",
+		},
 		{
 			name:     "Unknown Provider",
 			provider: Provider(100), // An unknown provider
@@ -61,3 +66,42 @@ func TestProvider_String(t *testing.T) {
 		})
 	}
 }
+
+func TestFileRules_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   FileRules
+		wantErr bool
+	}{
+		{name: "empty", rules: FileRules{}},
+		{name: "glob and regex", rules: FileRules{Include: []string{"*.mkv"}, Exclude: []string{`/(?i)sample/`}}},
+		{name: "invalid glob", rules: FileRules{Include: []string{"[.mkv"}}, wantErr: true},
+		{name: "invalid regex", rules: FileRules{Exclude: []string{"/(/"}}, wantErr: true},
+		{name: "negative size", rules: FileRules{MinSize: -1}, wantErr: true},
+		{name: "min above max", rules: FileRules{MinSize: 10, MaxSize: 5}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rules.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPage_FileRules(t *testing.T) {
+	page := Page{FileRules: FileRules{Extensions: []string{"mkv"}, LargestVideoOnly: true}}
+	if err := page.BeforeSave(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	found := Page{FileRulesData: page.FileRulesData}
+	if err := found.AfterFind(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if !found.FileRules.LargestVideoOnly || len(found.FileRules.Extensions) != 1 {
+		t.Errorf("Got %+v; expected %+v", found.FileRules, page.FileRules)
+	}
+}
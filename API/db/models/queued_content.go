@@ -0,0 +1,24 @@
+package models
+
+import (
+	"encoding/json"
+)
+
+// QueuedContent is content that was added to a providers' queue, and has not finished yet.
+// It is used to resume downloads after a restart
+type QueuedContent struct {
+	Model
+
+	Provider  Provider `gorm:"type:int;uniqueIndex:idx_queued_content" json:"provider"`
+	ContentId string   `gorm:"uniqueIndex:idx_queued_content" json:"contentId"`
+	Owner     int      `json:"owner"`
+	// State is the payload.ContentState the content was last in
+	State          int  `json:"state"`
+	IsSubscription bool `json:"isSubscription"`
+	// SubscriptionId is zero if the download was not started by a subscription
+	SubscriptionId int `json:"subscriptionId"`
+	// Request is the original payload.DownloadRequest
+	Request json.RawMessage `gorm:"type:jsonb" json:"-"`
+	// UserSelection are the ids of the sub content selected by the user
+	UserSelection json.RawMessage `gorm:"type:jsonb" json:"-"`
+}
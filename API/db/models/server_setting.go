@@ -21,6 +21,35 @@ const (
 	SubscriptionRefreshHour
 	DbDriver
 	LastUpdateDate
+	MaxConcurrentChapters
+	SeedRatio
+	MinSeedTime
+	MaxSeedingTorrents
+	UploadRateLimit
+	DownloadRateLimit
+	TorrentUploadRateLimit
+	TorrentDownloadRateLimit
+	AltUploadRateLimit
+	AltDownloadRateLimit
+	AltSpeedScheduled
+	AltSpeedDays
+	AltSpeedFrom
+	AltSpeedTo
+	TorrentListenPort
+	MaxPeersPerTorrent
+	EncryptionPolicy
+	DisableDht
+	DisablePex
+	TorrentBackend
+	RemoteTorrentUrl
+	RemoteTorrentUsername
+	RemoteTorrentPassword
+	RemoteTorrentDir
+	StallMetadataTimeout
+	StallProgressTimeout
+	StallAction
+	SubscriptionHistoryDays
+	SubscriptionHistoryRuns
 )
 
 type ServerSetting struct {
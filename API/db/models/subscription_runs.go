@@ -0,0 +1,102 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// RunTrigger is what started a subscription run
+type RunTrigger string
+
+const (
+	// TriggerCron runs are started by the schedule of the subscription
+	TriggerCron    RunTrigger = "cron"
+	TriggerRunOnce RunTrigger = "run-once"
+	TriggerRunAll  RunTrigger = "run-all"
+)
+
+// SkipReason is why content found during a run wasn't downloaded
+type SkipReason string
+
+const (
+	// SkipOnDisk content has been downloaded before
+	SkipOnDisk SkipReason = "on-disk"
+	// SkipNoChapter content is a volume without chapter, see publication.SkipVolumeWithoutChapter
+	SkipNoChapter SkipReason = "no-chapter"
+	// SkipFailed content failed to download, it's retried on the next run
+	SkipFailed SkipReason = "failed"
+	// SkipGrabbed torrents have been downloaded by the subscription before
+	SkipGrabbed SkipReason = "grabbed"
+	// SkipFiltered torrents don't match the filter of the subscription
+	SkipFiltered SkipReason = "filtered"
+)
+
+type SkippedContent struct {
+	Name   string     `json:"name"`
+	Reason SkipReason `json:"reason"`
+}
+
+// SubscriptionRun is the outcome of a single run of a subscription
+type SubscriptionRun struct {
+	Model
+
+	SubscriptionID int        `gorm:"index" json:"subscriptionId"`
+	Trigger        RunTrigger `json:"trigger"`
+	StartedAt      time.Time  `json:"startedAt"`
+	// FinishedAt is zero while the run is in progress
+	FinishedAt time.Time `json:"finishedAt"`
+	// Duration is the time the run took in milliseconds
+	Duration int64 `json:"duration"`
+	// Found is the amount of chapters, or search results, found
+	Found       int              `json:"found"`
+	Downloaded  int              `json:"downloaded"`
+	Skipped     []SkippedContent `gorm:"-" json:"skipped"`
+	SkippedData json.RawMessage  `gorm:"type:jsonb" json:"-"`
+	Errors      pq.StringArray   `gorm:"type:text[]" json:"errors"`
+	// Files are the paths of the files written, relative to the root dir
+	Files pq.StringArray `gorm:"type:text[]" json:"files"`
+}
+
+func NewSubscriptionRun(subscriptionId int, trigger RunTrigger) *SubscriptionRun {
+	return &SubscriptionRun{
+		SubscriptionID: subscriptionId,
+		Trigger:        trigger,
+		StartedAt:      time.Now(),
+	}
+}
+
+func (r *SubscriptionRun) BeforeSave(tx *gorm.DB) (err error) {
+	r.SkippedData, err = json.Marshal(r.Skipped)
+	return
+}
+
+func (r *SubscriptionRun) AfterFind(tx *gorm.DB) (err error) {
+	if r.SkippedData == nil {
+		return
+	}
+
+	return json.Unmarshal(r.SkippedData, &r.Skipped)
+}
+
+func (r *SubscriptionRun) Skip(name string, reason SkipReason) {
+	r.Skipped = append(r.Skipped, SkippedContent{Name: name, Reason: reason})
+}
+
+func (r *SubscriptionRun) AddError(err error) {
+	if err != nil {
+		r.Errors = append(r.Errors, err.Error())
+	}
+}
+
+// Finish marks the run as done, errors are added to the run
+func (r *SubscriptionRun) Finish(errs ...error) {
+	for _, err := range errs {
+		r.AddError(err)
+	}
+
+	r.FinishedAt = time.Now()
+	r.Duration = r.FinishedAt.Sub(r.StartedAt).Milliseconds()
+}
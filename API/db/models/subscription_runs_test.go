@@ -0,0 +1,28 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSubscriptionRun_Finish(t *testing.T) {
+	run := NewSubscriptionRun(1, TriggerRunAll)
+	run.StartedAt = time.Now().Add(-2 * time.Second)
+
+	run.AddError(nil)
+	run.AddError(errors.New("chapter 1: bad status: 404"))
+	run.Finish(nil, errors.New("failed to zip chapter 2"))
+
+	want := []string{"chapter 1: bad status: 404", "failed to zip chapter 2"}
+	if !slices.Equal(run.Errors, want) {
+		t.Errorf("Got errors %v; expected %v", run.Errors, want)
+	}
+	if run.FinishedAt.IsZero() {
+		t.Error("Expected the run to be finished")
+	}
+	if run.Duration < 2000 {
+		t.Errorf("Got duration %dms; expected at least 2 seconds", run.Duration)
+	}
+}
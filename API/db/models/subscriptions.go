@@ -2,19 +2,36 @@ package models
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
 	"time"
 
 	"github.com/Fesaa/Media-Provider/utils"
+	"github.com/lib/pq"
+	"github.com/robfig/cron/v3"
 	"gorm.io/gorm"
 )
 
 type Subscription struct {
 	Model
 
-	Owner            int                     `json:"owner"`
-	Provider         Provider                `gorm:"type:int" json:"provider"`
-	ContentId        string                  `json:"contentId"`
-	RefreshFrequency RefreshFrequency        `gorm:"type:int" json:"refreshFrequency"`
+	Owner            int              `json:"owner"`
+	Provider         Provider         `gorm:"type:int" json:"provider"`
+	ContentId        string           `json:"contentId"`
+	RefreshFrequency RefreshFrequency `gorm:"type:int" json:"refreshFrequency"`
+	// CronExpression and Interval give the subscription a schedule of its own, instead of running on the refresh hour
+	// every RefreshFrequency. The cron expression takes precedence, the interval is in minutes
+	CronExpression string `json:"cronExpression"`
+	Interval       int    `json:"interval"`
+	// Adaptive picks when to run from the release cadence of the series, between AdaptiveMin and AdaptiveMax hours.
+	// The refresh frequency is used while the cadence is unknown
+	Adaptive    bool `json:"adaptive"`
+	AdaptiveMin int  `json:"adaptiveMin"`
+	AdaptiveMax int  `json:"adaptiveMax"`
+	// Cadence is the estimated time between releases in hours, 0 if unknown. See ObserveReleases
+	Cadence          int                     `json:"cadence"`
+	PredictedRelease time.Time               `json:"predictedRelease"`
 	Title            string                  `json:"title"`
 	BaseDir          string                  `json:"baseDir"`
 	LastDownloadDir  string                  `json:"lastDownloadDir"`
@@ -25,6 +42,48 @@ type Subscription struct {
 	Payload          DownloadRequestMetadata `gorm:"-" json:"metadata"`
 	// The amount of consecutive downloads with no chapters downloaded
 	NoDownloadCount int `json:"noDownloadCount"`
+	// FailedChapters are the ids of the chapters that failed during the last run. They're downloaded again on the
+	// next, replacing the content that was kept for them
+	FailedChapters pq.StringArray `gorm:"type:text[]" json:"failedChapters"`
+	// TorrentSearch is the saved search of a torrent subscription, its query is the ContentId
+	TorrentSearch     TorrentSearch   `gorm:"-" json:"torrentSearch"`
+	TorrentSearchData json.RawMessage `gorm:"type:jsonb" json:"-"`
+	// Grabbed are the infohashes, or links if unknown, already downloaded by a torrent subscription
+	Grabbed pq.StringArray `gorm:"type:text[]" json:"grabbed"`
+	// Run is the run in progress, its outcome is filled in by the content downloading the subscription
+	Run *SubscriptionRun `gorm:"-" json:"-"`
+}
+
+type TorrentSearch struct {
+	Modifiers utils.SmartMap `json:"modifiers,omitempty"`
+	Filter    TorrentFilter  `json:"filter"`
+}
+
+// TorrentFilter decides which search results of a torrent subscription are downloaded, empty fields match anything
+type TorrentFilter struct {
+	TitleRegex   string `json:"titleRegex"`
+	Resolution   string `json:"resolution"`
+	ReleaseGroup string `json:"releaseGroup"`
+	MinSeeders   int    `json:"minSeeders"`
+	// MinSize and MaxSize are in MiB, 0 is no limit
+	MinSize int64 `json:"minSize"`
+	MaxSize int64 `json:"maxSize"`
+}
+
+func (f TorrentFilter) Validate() error {
+	if _, err := regexp.Compile(f.TitleRegex); err != nil {
+		return fmt.Errorf("invalid title regex: %w", err)
+	}
+
+	if f.MinSeeders < 0 || f.MinSize < 0 || f.MaxSize < 0 {
+		return errors.New("seeders and sizes cannot be negative")
+	}
+
+	if f.MaxSize > 0 && f.MinSize > f.MaxSize {
+		return errors.New("minimum size cannot be larger than the maximum size")
+	}
+
+	return nil
 }
 
 type DownloadRequestMetadata struct {
@@ -34,12 +93,25 @@ type DownloadRequestMetadata struct {
 
 func (s *Subscription) BeforeSave(tx *gorm.DB) (err error) {
 	s.Metadata, err = json.Marshal(s.Payload.Extra)
+	if err != nil {
+		return
+	}
+
+	if s.Provider.IsTorrent() {
+		s.TorrentSearchData, err = json.Marshal(s.TorrentSearch)
+	}
 	return
 }
 
 func (s *Subscription) AfterFind(tx *gorm.DB) (err error) {
 	s.Payload.StartImmediately = true
 
+	if s.TorrentSearchData != nil {
+		if err = json.Unmarshal(s.TorrentSearchData, &s.TorrentSearch); err != nil {
+			return
+		}
+	}
+
 	if s.Metadata == nil {
 		return
 	}
@@ -48,7 +120,33 @@ func (s *Subscription) AfterFind(tx *gorm.DB) (err error) {
 }
 
 func (s *Subscription) shouldRefresh(old *Subscription) bool {
-	return s.RefreshFrequency != old.RefreshFrequency
+	return s.RefreshFrequency != old.RefreshFrequency ||
+		s.CronExpression != old.CronExpression ||
+		s.Interval != old.Interval ||
+		s.Adaptive != old.Adaptive
+}
+
+// MinInterval is the shortest interval, in minutes, a subscription may run on
+const MinInterval = 15
+
+// HasSchedule returns true if the subscription runs on its own schedule, rather than the refresh hour
+func (s *Subscription) HasSchedule() bool {
+	return s.CronExpression != "" || s.Interval > 0
+}
+
+// ValidateSchedule checks the cron expression, and interval
+func (s *Subscription) ValidateSchedule() error {
+	if s.CronExpression != "" {
+		if _, err := cron.ParseStandard(s.CronExpression); err != nil {
+			return fmt.Errorf("invalid cron expression: %w", err)
+		}
+	}
+
+	if s.Interval < 0 || (s.Interval > 0 && s.Interval < MinInterval) {
+		return fmt.Errorf("interval must be at least %d minutes", MinInterval)
+	}
+
+	return nil
 }
 
 func (s *Subscription) Normalize(hour int) {
@@ -60,6 +158,14 @@ func (s *Subscription) normalize(t time.Time, hour int) time.Time {
 }
 
 func (s *Subscription) GetNextExecution(hour int) time.Time {
+	if s.HasSchedule() {
+		return s.nextScheduled(time.Now())
+	}
+
+	if next := s.adaptiveNext(time.Now()); !next.IsZero() {
+		return next
+	}
+
 	diff := time.Since(s.LastCheck)
 
 	if diff > s.RefreshFrequency.asDuration() {
@@ -83,6 +189,23 @@ func (s *Subscription) GetNextExecution(hour int) time.Time {
 	return next
 }
 
+// nextScheduled returns the first run of the subscription's own schedule after now. Overdue intervals run at now
+func (s *Subscription) nextScheduled(now time.Time) time.Time {
+	if s.CronExpression != "" {
+		schedule, err := cron.ParseStandard(s.CronExpression)
+		if err != nil {
+			return time.Time{}
+		}
+		return schedule.Next(now)
+	}
+
+	next := s.LastCheck.Add(time.Duration(s.Interval) * time.Minute)
+	if next.Before(now) {
+		return now
+	}
+	return next
+}
+
 type RefreshFrequency int
 
 const (
@@ -118,6 +118,12 @@ func TestSubscription_ShouldRefresh(t *testing.T) {
 			OldSub:   Subscription{RefreshFrequency: Day},
 			Expected: true,
 		},
+		{
+			Name:     "Different cron expression",
+			Sub:      Subscription{RefreshFrequency: Day, CronExpression: "0 17 * * 2"},
+			OldSub:   Subscription{RefreshFrequency: Day},
+			Expected: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -152,3 +158,96 @@ func TestSubscription_normalize(t *testing.T) {
 		t.Errorf("normalize time mismatch: expected %v, got %v", expectedTime, normalizedTime)
 	}
 }
+
+func TestSubscription_TorrentSearch(t *testing.T) {
+	sub := Subscription{
+		Provider: NYAA,
+		TorrentSearch: TorrentSearch{
+			Filter: TorrentFilter{Resolution: "1080p", MinSeeders: 5},
+		},
+	}
+
+	if err := sub.BeforeSave(nil); err != nil {
+		t.Fatalf("BeforeSave failed: %v", err)
+	}
+
+	found := Subscription{TorrentSearchData: sub.TorrentSearchData}
+	if err := found.AfterFind(nil); err != nil {
+		t.Fatalf("AfterFind failed: %v", err)
+	}
+
+	if found.TorrentSearch.Filter != sub.TorrentSearch.Filter {
+		t.Errorf("TorrentSearch mismatch: expected %v, got %v", sub.TorrentSearch.Filter, found.TorrentSearch.Filter)
+	}
+}
+
+func TestTorrentFilter_Validate(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Filter  TorrentFilter
+		WantErr bool
+	}{
+		{Name: "Empty", Filter: TorrentFilter{}},
+		{Name: "Valid", Filter: TorrentFilter{TitleRegex: `- \d+`, MinSize: 100, MaxSize: 200}},
+		{Name: "Invalid regex", Filter: TorrentFilter{TitleRegex: `(`}, WantErr: true},
+		{Name: "Negative seeders", Filter: TorrentFilter{MinSeeders: -1}, WantErr: true},
+		{Name: "Min above max", Filter: TorrentFilter{MinSize: 200, MaxSize: 100}, WantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if err := tt.Filter.Validate(); (err != nil) != tt.WantErr {
+				t.Errorf("Validate mismatch: expected error %v, got %v", tt.WantErr, err)
+			}
+		})
+	}
+}
+
+func TestSubscription_ValidateSchedule(t *testing.T) {
+	tests := []struct {
+		name    string
+		sub     Subscription
+		wantErr bool
+	}{
+		{name: "no schedule", sub: Subscription{}},
+		{name: "cron", sub: Subscription{CronExpression: "0 17 * * 2"}},
+		{name: "interval", sub: Subscription{Interval: 360}},
+		{name: "invalid cron", sub: Subscription{CronExpression: "every tuesday"}, wantErr: true},
+		{name: "interval too short", sub: Subscription{Interval: MinInterval - 1}, wantErr: true},
+		{name: "negative interval", sub: Subscription{Interval: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.sub.ValidateSchedule(); (err != nil) != tt.wantErr {
+				t.Errorf("Got %v; expected error: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSubscription_NextScheduled(t *testing.T) {
+	// A Monday
+	now := time.Date(2025, 1, 6, 12, 0, 0, 0, time.Local)
+
+	sub := Subscription{CronExpression: "0 17 * * 2"}
+	if next := sub.nextScheduled(now); !next.Equal(time.Date(2025, 1, 7, 17, 0, 0, 0, time.Local)) {
+		t.Errorf("Got %v; expected Tuesday at 17:00", next)
+	}
+
+	sub = Subscription{Interval: 360, LastCheck: now.Add(-time.Hour)}
+	if next := sub.nextScheduled(now); !next.Equal(now.Add(5 * time.Hour)) {
+		t.Errorf("Got %v; expected the interval to continue from the last check", next)
+	}
+
+	sub.LastCheck = now.Add(-24 * time.Hour)
+	if next := sub.nextScheduled(now); !next.Equal(now) {
+		t.Errorf("Got %v; expected an overdue interval to run now", next)
+	}
+
+	// The cron expression takes precedence
+	sub.CronExpression = "0 17 * * 2"
+	if next := sub.nextScheduled(now); !next.Equal(time.Date(2025, 1, 7, 17, 0, 0, 0, time.Local)) {
+		t.Errorf("Got %v; expected the cron expression to be used", next)
+	}
+}
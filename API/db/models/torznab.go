@@ -0,0 +1,12 @@
+package models
+
+// TorznabIndexer is a Torznab compatible endpoint, for example from Jackett or Prowlarr, searched by the
+// Torznab provider
+type TorznabIndexer struct {
+	Model
+
+	Name string `json:"name" validate:"required"`
+	// Url is the full url of the api endpoint, e.g. http://localhost:9117/api/v2.0/indexers/all/results/torznab/api
+	Url    string `json:"url" validate:"required,url"`
+	ApiKey string `json:"apiKey"`
+}
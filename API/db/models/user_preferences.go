@@ -5,6 +5,7 @@ import (
 
 	"github.com/Fesaa/Media-Provider/internal/comicinfo"
 	"github.com/Fesaa/Media-Provider/utils"
+	"github.com/Fesaa/go-metroninfo"
 	"github.com/lib/pq"
 	"gorm.io/gorm"
 )
@@ -32,6 +33,8 @@ type UserPreferences struct {
 	TagMappingsSql       json.RawMessage     `gorm:"type:jsonb" json:"-"`
 	TagMappings          []TagMapping        `gorm:"-" json:"tagMappings"`
 	LogSubNoDownloads    bool                `json:"logSubNoDownloads" validate:"boolean"`
+	// WriteMetronInfo writes a MetronInfo.xml next to the ComicInfo.xml for all downloads of the user
+	WriteMetronInfo bool `json:"writeMetronInfo" validate:"boolean"`
 }
 
 func (p *UserPreferences) BeforeSave(tx *gorm.DB) (err error) {
@@ -72,7 +75,9 @@ func (p *UserPreferences) AfterFind(tx *gorm.DB) (err error) {
 type AgeRatingMapping struct {
 	Tag                string              `json:"tag"`
 	ComicInfoAgeRating comicinfo.AgeRating `json:"comicInfoAgeRating"`
-	// MetronAgeRating    metroninfo.AgeRating `json:"metronAgeRating"`
+	// MetronAgeRating may be empty for mappings created before MetronInfo support, the ComicInfoAgeRating is
+	// converted in that case
+	MetronAgeRating metroninfo.AgeRating `json:"metronAgeRating,omitempty"`
 }
 
 type TagMapping struct {
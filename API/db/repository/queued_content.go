@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Fesaa/Media-Provider/db/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type QueuedContentRepository interface {
+	// All returns all queued content, oldest first
+	All(context.Context) ([]models.QueuedContent, error)
+	// Upsert creates the queued content, or updates it if content with the same provider and id exists
+	Upsert(context.Context, models.QueuedContent) error
+	// Delete removes the queued content by provider and content id
+	Delete(context.Context, models.Provider, string) error
+}
+
+type queuedContentRepository struct {
+	db *gorm.DB
+}
+
+func (r queuedContentRepository) All(ctx context.Context) ([]models.QueuedContent, error) {
+	var all []models.QueuedContent
+	err := r.db.WithContext(ctx).Order("created_at asc").Find(&all).Error
+	if err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+func (r queuedContentRepository) Upsert(ctx context.Context, content models.QueuedContent) error {
+	content.ID = 0
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "provider"}, {Name: "content_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"updated_at", "owner", "state", "is_subscription", "subscription_id", "request", "user_selection",
+		}),
+	}).Create(&content).Error
+}
+
+func (r queuedContentRepository) Delete(ctx context.Context, provider models.Provider, contentId string) error {
+	return r.db.WithContext(ctx).
+		Where(&models.QueuedContent{Provider: provider, ContentId: contentId}).
+		Delete(&models.QueuedContent{}).Error
+}
+
+func NewQueuedContentRepository(db *gorm.DB) QueuedContentRepository {
+	return &queuedContentRepository{db: db}
+}
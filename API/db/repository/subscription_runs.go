@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Fesaa/Media-Provider/db/models"
+	"github.com/Fesaa/Media-Provider/utils"
+	"gorm.io/gorm"
+)
+
+type SubscriptionRunsRepository interface {
+	// ForSubscription returns the runs of a subscription, most recent first
+	ForSubscription(context.Context, int, utils.UserParams) (utils.PagedList[models.SubscriptionRun], error)
+	// New creates a new run, the ID is set on the passed run
+	New(context.Context, *models.SubscriptionRun) error
+	// Update updates an existing run
+	Update(context.Context, models.SubscriptionRun) error
+	// DeleteBefore removes all runs started before the given time, returns the amount removed
+	DeleteBefore(context.Context, time.Time) (int64, error)
+	// Prune removes all but the most recent runs of a subscription, returns the amount removed
+	Prune(context.Context, int, int) (int64, error)
+	// DeleteForSubscription removes all runs of a subscription
+	DeleteForSubscription(context.Context, int) error
+}
+
+type subscriptionRunsRepository struct {
+	db *gorm.DB
+}
+
+func (r subscriptionRunsRepository) ForSubscription(ctx context.Context, subscriptionId int, params utils.UserParams) (utils.PagedList[models.SubscriptionRun], error) {
+	query := r.db.WithContext(ctx).Model(&models.SubscriptionRun{}).
+		Where(&models.SubscriptionRun{SubscriptionID: subscriptionId}).
+		Order("started_at desc, id desc")
+	return utils.NewPageListFromUserParams[models.SubscriptionRun](ctx, query, params)
+}
+
+func (r subscriptionRunsRepository) New(ctx context.Context, run *models.SubscriptionRun) error {
+	run.ID = 0
+	return r.db.WithContext(ctx).Create(run).Error
+}
+
+func (r subscriptionRunsRepository) Update(ctx context.Context, run models.SubscriptionRun) error {
+	return r.db.WithContext(ctx).Save(&run).Error
+}
+
+func (r subscriptionRunsRepository) DeleteBefore(ctx context.Context, t time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).Where("started_at < ?", t).Delete(&models.SubscriptionRun{})
+	return res.RowsAffected, res.Error
+}
+
+func (r subscriptionRunsRepository) Prune(ctx context.Context, subscriptionId int, keep int) (int64, error) {
+	recent := r.db.Model(&models.SubscriptionRun{}).Select("id").
+		Where("subscription_id = ?", subscriptionId).
+		Order("started_at desc, id desc").
+		Limit(keep)
+
+	res := r.db.WithContext(ctx).
+		Where("subscription_id = ? AND id NOT IN (?)", subscriptionId, recent).
+		Delete(&models.SubscriptionRun{})
+	return res.RowsAffected, res.Error
+}
+
+func (r subscriptionRunsRepository) DeleteForSubscription(ctx context.Context, subscriptionId int) error {
+	return r.db.WithContext(ctx).Where("subscription_id = ?", subscriptionId).Delete(&models.SubscriptionRun{}).Error
+}
+
+func NewSubscriptionRunsRepository(db *gorm.DB) SubscriptionRunsRepository {
+	return &subscriptionRunsRepository{db: db}
+}
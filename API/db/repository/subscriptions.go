@@ -30,6 +30,8 @@ type SubscriptionsRepository interface {
 	New(context.Context, models.Subscription) (*models.Subscription, error)
 	// Update updates an existing subscription
 	Update(context.Context, models.Subscription) error
+	// UpdateColumns updates only the given columns of an existing subscription, leaving others as they are in the db
+	UpdateColumns(context.Context, models.Subscription, ...string) error
 	// Delete deletes a subscription by ID
 	Delete(context.Context, int) error
 }
@@ -128,6 +130,10 @@ func (r subscriptionsRepository) Update(ctx context.Context, subscription models
 	return r.db.WithContext(ctx).Save(&subscription).Error
 }
 
+func (r subscriptionsRepository) UpdateColumns(ctx context.Context, subscription models.Subscription, columns ...string) error {
+	return r.db.WithContext(ctx).Model(&subscription).Select(columns).Updates(&subscription).Error
+}
+
 func (r subscriptionsRepository) Delete(ctx context.Context, id int) error {
 	return r.db.WithContext(ctx).Delete(&models.Subscription{Model: models.Model{ID: id}}).Error
 }
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Fesaa/Media-Provider/db/models"
+	"gorm.io/gorm"
+)
+
+type TorznabRepository interface {
+	GetAll(context.Context) ([]models.TorznabIndexer, error)
+	Get(context.Context, int) (*models.TorznabIndexer, error)
+
+	Create(context.Context, *models.TorznabIndexer) error
+	Update(context.Context, *models.TorznabIndexer) error
+	Delete(context.Context, int) error
+}
+
+type torznabRepository struct {
+	db *gorm.DB
+}
+
+func (t torznabRepository) GetAll(ctx context.Context) ([]models.TorznabIndexer, error) {
+	var indexers []models.TorznabIndexer
+	if err := t.db.WithContext(ctx).Order("name").Find(&indexers).Error; err != nil {
+		return nil, err
+	}
+
+	return indexers, nil
+}
+
+func (t torznabRepository) Get(ctx context.Context, id int) (*models.TorznabIndexer, error) {
+	var indexer models.TorznabIndexer
+	result := t.db.WithContext(ctx).First(&indexer, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, result.Error
+	}
+
+	return &indexer, nil
+}
+
+func (t torznabRepository) Create(ctx context.Context, indexer *models.TorznabIndexer) error {
+	indexer.ID = 0
+	return t.db.WithContext(ctx).Create(indexer).Error
+}
+
+func (t torznabRepository) Update(ctx context.Context, indexer *models.TorznabIndexer) error {
+	return t.db.WithContext(ctx).Save(indexer).Error
+}
+
+func (t torznabRepository) Delete(ctx context.Context, id int) error {
+	return t.db.WithContext(ctx).Delete(&models.TorznabIndexer{}, id).Error
+}
+
+func NewTorznabRepository(db *gorm.DB) TorznabRepository {
+	return &torznabRepository{db: db}
+}
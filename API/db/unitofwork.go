@@ -18,6 +18,10 @@ type UnitOfWork struct {
 	Notifications repository.NotificationsRepository
 	Settings      repository.SettingsRepository
 	Users         repository.UserRepository
+	QueuedContent repository.QueuedContentRepository
+	Torznab       repository.TorznabRepository
+	// SubscriptionRuns is the run history of subscriptions
+	SubscriptionRuns repository.SubscriptionRunsRepository
 }
 
 func NewUnitOfWork(db *gorm.DB) *UnitOfWork {
@@ -29,6 +33,10 @@ func NewUnitOfWork(db *gorm.DB) *UnitOfWork {
 		Notifications: repository.NewNotificationsRepository(db),
 		Settings:      repository.NewSettingsRepository(db),
 		Users:         repository.NewUserRepository(db),
+		QueuedContent: repository.NewQueuedContentRepository(db),
+		Torznab:       repository.NewTorznabRepository(db),
+
+		SubscriptionRuns: repository.NewSubscriptionRunsRepository(db),
 	}
 }
 
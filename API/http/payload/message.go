@@ -19,6 +19,8 @@ const (
 	MessageListContent MessageType = iota
 	SetToDownload
 	StartDownload
+	// PreviewEpisodes returns where the episodes of a torrent end up once it completes
+	PreviewEpisodes
 )
 
 type ListContentData struct {
@@ -27,3 +29,11 @@ type ListContentData struct {
 	Selected     bool              `json:"selected"`
 	Children     []ListContentData `json:"children,omitempty"`
 }
+
+// EpisodeMove is where a file of a torrent ends up after renaming episodes. From is relative to the torrent,
+// To to the download directory
+type EpisodeMove struct {
+	Episode string `json:"episode"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+}
@@ -0,0 +1,22 @@
+package payload
+
+// ReorganiseReport lists the changes made, or that would be made during a dry run, to bring content on disk in line
+// with the current naming scheme
+type ReorganiseReport struct {
+	Title  string `json:"title"`
+	DryRun bool   `json:"dryRun"`
+	// UpToDate is the amount of files already named correctly
+	UpToDate int              `json:"upToDate"`
+	Moves    []ReorganiseMove `json:"moves"`
+	// Conflicts are moves that were not made, as their destination is already taken
+	Conflicts []ReorganiseMove `json:"conflicts"`
+	// Unmatched are files that could not be matched to a chapter
+	Unmatched []string `json:"unmatched"`
+	Errors    []string `json:"errors"`
+}
+
+type ReorganiseMove struct {
+	Chapter string `json:"chapter"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+}
@@ -66,6 +66,9 @@ type StopRequest struct {
 	Provider    models.Provider `json:"provider" validate:"required,provider"`
 	Id          string          `json:"id" validate:"required"`
 	DeleteFiles bool            `json:"delete" validate:"required"`
+	// KeepPages keeps the complete pages of partially downloaded chapters when deleting files, so a later
+	// download resumes them. Only set internally, for content that is expected to be downloaded again
+	KeepPages bool `json:"-"`
 }
 
 type ListDirsRequest struct {
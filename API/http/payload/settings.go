@@ -13,13 +13,125 @@ type Settings struct {
 	RedisAddr               string           `json:"redisAddr"`
 	MaxConcurrentTorrents   int              `json:"maxConcurrentTorrents" validate:"required,min=1,max=10"`
 	MaxConcurrentImages     int              `json:"maxConcurrentImages" validate:"required,min=1,max=5"`
+	MaxConcurrentChapters   int              `json:"maxConcurrentChapters" validate:"required,min=1,max=5"`
 	SubscriptionRefreshHour int              `json:"subscriptionRefreshHour" validate:"min=0,max=23"`
+	SubscriptionHistory     HistorySettings  `json:"subscriptionHistory"`
 	DisableIpv6             bool             `json:"disableIpv6"`
 	RootDir                 string           `json:"rootDir"`
+	Seeding                 SeedingSettings  `json:"seeding"`
+	Torrent                 TorrentSettings  `json:"torrent"`
 	Oidc                    OidcSettings     `json:"oidc"`
 	Metadata                Metadata         `json:"metadata"`
 }
 
+// HistorySettings decide how long the run history of subscriptions is kept
+type HistorySettings struct {
+	// MaxAge is the amount of days runs are kept, 0 keeps them forever
+	MaxAge int `json:"maxAge" validate:"min=0"`
+	// MaxRuns is the amount of runs kept per subscription, 0 is no limit
+	MaxRuns int `json:"maxRuns" validate:"min=0"`
+}
+
+// SeedingSettings are the global seeding rules for completed torrents, pages may override the ratio and seed time.
+// The rules of a torrent are fixed once it completes. Seeding isn't persisted, torrents stop seeding on shutdown
+type SeedingSettings struct {
+	// Ratio is the upload ratio to reach before a torrent stops seeding
+	Ratio float64 `json:"ratio" validate:"min=0"`
+	// MinSeedTime is the time in minutes a torrent seeds at least
+	MinSeedTime int `json:"minSeedTime" validate:"min=0"`
+	// MaxTorrents is the maximum amount of torrents seeding at once, 0 is no limit
+	MaxTorrents int `json:"maxTorrents" validate:"min=0,max=100"`
+}
+
+// TorrentSettings configure the torrent client. Rate limits are in KiB/s, 0 is unlimited. Changes to the listen
+// port, peers, encryption, DHT and PEX are applied after a restart
+type TorrentSettings struct {
+	UploadLimit   int `json:"uploadLimit" validate:"min=0"`
+	DownloadLimit int `json:"downloadLimit" validate:"min=0"`
+	// TorrentUploadLimit and TorrentDownloadLimit cap each torrent separately
+	TorrentUploadLimit   int              `json:"torrentUploadLimit" validate:"min=0"`
+	TorrentDownloadLimit int              `json:"torrentDownloadLimit" validate:"min=0"`
+	AltSpeed             AltSpeedSettings `json:"altSpeed"`
+	// ListenPort is the port peers connect to, a random port is picked if 0
+	ListenPort int `json:"listenPort" validate:"min=0,max=65535"`
+	// MaxPeers is the maximum amount of peers connected to per torrent, the client default is used if 0
+	MaxPeers   int              `json:"maxPeers" validate:"min=0,max=1000"`
+	Encryption EncryptionPolicy `json:"encryption" validate:"oneof=prefer require disable"`
+	DisableDht bool             `json:"disableDht"`
+	DisablePex bool             `json:"disablePex"`
+	// Backend is the client torrents are downloaded with, changes are applied after a restart
+	Backend TorrentBackend       `json:"backend" validate:"oneof=embedded qbittorrent transmission"`
+	Remote  RemoteClientSettings `json:"remote"`
+	Stall   StallSettings        `json:"stall"`
+}
+
+// StallSettings decide when a torrent is considered stalled, and what happens to it. Timeouts are in minutes,
+// 0 disables the check
+type StallSettings struct {
+	// MetadataTimeout is how long a torrent may take to receive its metadata
+	MetadataTimeout int `json:"metadataTimeout" validate:"min=0"`
+	// ProgressTimeout is how long a downloading torrent may go without downloading anything
+	ProgressTimeout int         `json:"progressTimeout" validate:"min=0"`
+	Action          StallAction `json:"action" validate:"oneof=pause requeue remove"`
+}
+
+type StallAction string
+
+const (
+	// StallPause stops the torrent until the user starts it again
+	StallPause StallAction = "pause"
+	// StallRequeue stops the torrent, and adds it to the back of the queue
+	StallRequeue StallAction = "requeue"
+	// StallRemove removes the torrent and its downloaded files
+	StallRemove StallAction = "remove"
+)
+
+// RemoteClientSettings configure the external client used when the backend isn't the embedded client. Only the
+// seeding rules apply to it, rate limits and connection settings are managed in the client itself
+type RemoteClientSettings struct {
+	// Url is the address of the Web UI or RPC, e.g. http://localhost:8080 or http://localhost:9091
+	Url      string `json:"url"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// RootDir is the root directory as seen by the remote client, both must point to the same storage. The root
+	// directory of Media-Provider is used if empty
+	RootDir string `json:"rootDir"`
+}
+
+// AltSpeedSettings is an alternative speed profile, used instead of the normal limits during its weekly schedule
+type AltSpeedSettings struct {
+	UploadLimit   int  `json:"uploadLimit" validate:"min=0"`
+	DownloadLimit int  `json:"downloadLimit" validate:"min=0"`
+	Scheduled     bool `json:"scheduled"`
+	// Days the schedule starts on, 0 is Sunday
+	Days []time.Weekday `json:"days" validate:"dive,min=0,max=6"`
+	// From and To are formatted as 15:04, the schedule runs past midnight when To is before From
+	From string `json:"from" validate:"datetime=15:04"`
+	To   string `json:"to" validate:"datetime=15:04"`
+}
+
+type TorrentBackend string
+
+const (
+	// BackendEmbedded downloads torrents in process
+	BackendEmbedded TorrentBackend = "embedded"
+	// BackendQBittorrent delegates torrents to qBittorrent over its Web API
+	BackendQBittorrent TorrentBackend = "qbittorrent"
+	// BackendTransmission delegates torrents to Transmission over its RPC
+	BackendTransmission TorrentBackend = "transmission"
+)
+
+type EncryptionPolicy string
+
+const (
+	// EncryptionPrefer prefers encrypted connections, but accepts plain text peers
+	EncryptionPrefer EncryptionPolicy = "prefer"
+	// EncryptionRequire only connects to peers supporting encryption
+	EncryptionRequire EncryptionPolicy = "require"
+	// EncryptionDisable only uses plain text connections
+	EncryptionDisable EncryptionPolicy = "disable"
+)
+
 type Metadata struct {
 	Version               metadata.SemanticVersion `json:"version"`
 	FirstInstalledVersion string                   `json:"firstInstalledVersion"`
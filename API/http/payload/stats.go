@@ -24,6 +24,9 @@ type InfoStat struct {
 	SpeedType    SpeedType       `json:"speed_type"`
 	Speed        int64           `json:"speed"`
 	DownloadDir  string          `json:"download_dir"`
+	Seeding      bool            `json:"seeding"`
+	// Ratio is the upload ratio, only set for seeding torrents
+	Ratio float64 `json:"ratio,omitempty"`
 }
 
 type ContentState int
@@ -42,6 +45,11 @@ const (
 	ContentStateDownloading
 	// ContentStateCleanup indicates the content is being zipped
 	ContentStateCleanup
+	// ContentStateSeeding indicates the content has been moved to its final location, and is being seeded until
+	// the seeding rules are met
+	ContentStateSeeding
+	// ContentStatePaused indicates the content was stopped, and waits for the user to start it again
+	ContentStatePaused
 )
 
 type SpeedType int
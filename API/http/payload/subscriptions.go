@@ -0,0 +1,174 @@
+package payload
+
+import (
+	"path"
+	"time"
+
+	"github.com/Fesaa/Media-Provider/db/models"
+)
+
+// SubscriptionExportVersion is the current version of SubscriptionExport, documents with a newer version are refused
+const SubscriptionExportVersion = 1
+
+// SubscriptionExport is a versioned document of subscriptions, used to move them between servers and users
+type SubscriptionExport struct {
+	Version       int                    `json:"version"`
+	ExportedAt    time.Time              `json:"exportedAt"`
+	Subscriptions []ExportedSubscription `json:"subscriptions"`
+}
+
+// ExportedSubscription is the configuration of a subscription, anything tied to the server it ran on is left out
+type ExportedSubscription struct {
+	Provider         models.Provider                `json:"provider"`
+	ContentId        string                         `json:"contentId"`
+	Title            string                         `json:"title"`
+	BaseDir          string                         `json:"baseDir"`
+	RefreshFrequency models.RefreshFrequency        `json:"refreshFrequency"`
+	CronExpression   string                         `json:"cronExpression,omitempty"`
+	Interval         int                            `json:"interval,omitempty"`
+	Adaptive         bool                           `json:"adaptive,omitempty"`
+	AdaptiveMin      int                            `json:"adaptiveMin,omitempty"`
+	AdaptiveMax      int                            `json:"adaptiveMax,omitempty"`
+	Metadata         models.DownloadRequestMetadata `json:"metadata"`
+	TorrentSearch    *models.TorrentSearch          `json:"torrentSearch,omitempty"`
+	// Grabbed are the torrents already downloaded, so they aren't grabbed again after importing
+	Grabbed []string `json:"grabbed,omitempty"`
+}
+
+func NewSubscriptionExport(subs []models.Subscription) SubscriptionExport {
+	exported := make([]ExportedSubscription, 0, len(subs))
+	for _, sub := range subs {
+		exported = append(exported, NewExportedSubscription(sub))
+	}
+
+	return SubscriptionExport{
+		Version:       SubscriptionExportVersion,
+		ExportedAt:    time.Now(),
+		Subscriptions: exported,
+	}
+}
+
+func NewExportedSubscription(sub models.Subscription) ExportedSubscription {
+	exported := ExportedSubscription{
+		Provider:         sub.Provider,
+		ContentId:        sub.ContentId,
+		Title:            sub.Title,
+		BaseDir:          sub.BaseDir,
+		RefreshFrequency: sub.RefreshFrequency,
+		CronExpression:   sub.CronExpression,
+		Interval:         sub.Interval,
+		Adaptive:         sub.Adaptive,
+		AdaptiveMin:      sub.AdaptiveMin,
+		AdaptiveMax:      sub.AdaptiveMax,
+		Metadata:         sub.Payload,
+	}
+
+	if sub.Provider.IsTorrent() {
+		exported.TorrentSearch = &sub.TorrentSearch
+		exported.Grabbed = sub.Grabbed
+	}
+
+	return exported
+}
+
+// Subscription returns the subscription to create for the export, without owner
+func (e ExportedSubscription) Subscription() models.Subscription {
+	sub := models.Subscription{
+		Provider:         e.Provider,
+		ContentId:        e.ContentId,
+		Title:            e.Title,
+		BaseDir:          path.Clean(e.BaseDir),
+		RefreshFrequency: e.RefreshFrequency,
+		CronExpression:   e.CronExpression,
+		Interval:         e.Interval,
+		Adaptive:         e.Adaptive,
+		AdaptiveMin:      e.AdaptiveMin,
+		AdaptiveMax:      e.AdaptiveMax,
+		Payload:          e.Metadata,
+	}
+
+	if e.TorrentSearch != nil {
+		sub.TorrentSearch = *e.TorrentSearch
+		sub.Grabbed = e.Grabbed
+	}
+
+	return sub
+}
+
+// ImportStatus is the outcome of importing a single subscription
+type ImportStatus string
+
+const (
+	ImportCreated ImportStatus = "created"
+	// ImportDuplicate subscriptions already exist for the content, and are left untouched
+	ImportDuplicate ImportStatus = "duplicate"
+	// ImportInvalid subscriptions failed validation
+	ImportInvalid ImportStatus = "invalid"
+	ImportFailed  ImportStatus = "failed"
+)
+
+// SubscriptionImportResult is the outcome of the entry at Index of the imported document
+type SubscriptionImportResult struct {
+	Index     int          `json:"index"`
+	ContentId string       `json:"contentId"`
+	Title     string       `json:"title"`
+	Status    ImportStatus `json:"status"`
+	// ID is the id of the created, or existing, subscription
+	ID    int    `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type SubscriptionImportReport struct {
+	Created    int                        `json:"created"`
+	Duplicates int                        `json:"duplicates"`
+	Failed     int                        `json:"failed"`
+	Entries    []SubscriptionImportResult `json:"entries"`
+}
+
+func (r *SubscriptionImportReport) Add(result SubscriptionImportResult) {
+	switch result.Status {
+	case ImportCreated:
+		r.Created++
+	case ImportDuplicate:
+		r.Duplicates++
+	default:
+		r.Failed++
+	}
+
+	r.Entries = append(r.Entries, result)
+}
+
+// TachiyomiImport are the subscriptions proposed for the library of a Tachiyomi, or Mihon, backup. Nothing is
+// created until the proposals are imported
+type TachiyomiImport struct {
+	Proposals []ProposedSubscription `json:"proposals"`
+	// Unmapped are the entries of the library no subscription could be proposed for
+	Unmapped []UnmappedEntry `json:"unmapped"`
+}
+
+// ProposedSubscription is a subscription for an entry of the backup, with a suggested base dir
+type ProposedSubscription struct {
+	ExportedSubscription
+	// Source is the name of the source the entry was added from in the app
+	Source     string   `json:"source"`
+	Categories []string `json:"categories"`
+	// SubscriptionID is the id of the existing subscription for the content, 0 if there is none
+	SubscriptionID int `json:"subscriptionId,omitempty"`
+}
+
+// UnmappedReason is why no subscription could be proposed for an entry of the backup
+type UnmappedReason string
+
+const (
+	// UnmappedUnsupportedSource entries are from a source without matching provider
+	UnmappedUnsupportedSource UnmappedReason = "unsupported-source"
+	// UnmappedInvalidUrl entries are from a supported source, but their content id can't be found in the url
+	UnmappedInvalidUrl UnmappedReason = "invalid-url"
+)
+
+type UnmappedEntry struct {
+	Title  string         `json:"title"`
+	Source string         `json:"source"`
+	Url    string         `json:"url"`
+	Reason UnmappedReason `json:"reason"`
+}
@@ -0,0 +1,248 @@
+// Package epub writes fixed-layout EPUB 3 publications for image based content, and can read back
+// the metadata it wrote. It is not a general purpose EPUB library.
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Fesaa/Media-Provider/utils"
+	"github.com/spf13/afero"
+)
+
+const (
+	MimeType = "application/epub+zip"
+
+	packageDir  = "OEBPS"
+	packageFile = "content.opf"
+)
+
+var (
+	ErrNoPages            = errors.New("epub must contain at least one page")
+	ErrUnsupportedImage   = errors.New("unsupported image type")
+	defaultViewportWidth  = 800
+	defaultViewportHeight = 1200
+)
+
+// Creator is a contributor of the publication. Role is a MARC relator code (aut, art, clr, ...)
+type Creator struct {
+	Name string
+	Role string
+}
+
+type Metadata struct {
+	Identifier  string
+	Title       string
+	Language    string
+	Description string
+	Publisher   string
+	Source      string
+	// Series is written as a belongs-to-collection, and calibre:series
+	Series string
+	// SeriesIndex is the position inside Series. Media-Provider writes the volume here, as Kavita does
+	SeriesIndex string
+	Creators    []Creator
+	Subjects    []string
+	Modified    time.Time
+	RightToLeft bool
+}
+
+// Image is a single image inside the publication. Open is called exactly once while writing
+type Image struct {
+	// Name is the original file name, only its extension is used. Images are stored under generated names, as
+	// OCF requires hrefs to be valid URLs
+	Name   string
+	Width  int
+	Height int
+	Open   func() (io.ReadCloser, error)
+}
+
+func (i Image) mediaType() (string, error) {
+	switch strings.ToLower(path.Ext(i.Name)) {
+	case ".jpg", ".jpeg":
+		return "image/jpeg", nil
+	case ".png":
+		return "image/png", nil
+	case ".gif":
+		return "image/gif", nil
+	case ".webp":
+		return "image/webp", nil
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrUnsupportedImage, i.Name)
+}
+
+func (i Image) viewport() (int, int) {
+	if i.Width <= 0 || i.Height <= 0 {
+		return defaultViewportWidth, defaultViewportHeight
+	}
+	return i.Width, i.Height
+}
+
+// Book is a fixed-layout publication, each image is rendered on its own page in the given order
+type Book struct {
+	Metadata Metadata
+	Cover    *Image
+	Pages    []Image
+}
+
+type manifestItem struct {
+	Id         string
+	Href       string
+	MediaType  string
+	Properties string
+}
+
+type page struct {
+	Id     string
+	Href   string
+	Image  string
+	Title  string
+	Width  int
+	Height int
+}
+
+// Write writes the EPUB container to w. The mimetype is always the first, uncompressed, entry as required by OCF
+func (b *Book) Write(w io.Writer) error {
+	if len(b.Pages) == 0 {
+		return ErrNoPages
+	}
+
+	zw := zip.NewWriter(w)
+
+	mimeWriter, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err = io.WriteString(mimeWriter, MimeType); err != nil {
+		return err
+	}
+
+	if err = writeTemplate(zw, "META-INF/container.xml", containerTemplate, path.Join(packageDir, packageFile)); err != nil {
+		return err
+	}
+
+	images := b.Pages
+	if b.Cover != nil {
+		images = append([]Image{*b.Cover}, images...)
+	}
+
+	var items []manifestItem
+	var pages []page
+	for idx, img := range images {
+		mediaType, err := img.mediaType()
+		if err != nil {
+			return err
+		}
+
+		isCover := b.Cover != nil && idx == 0
+		id := fmt.Sprintf("page-%04d", idx)
+		imageId := fmt.Sprintf("image-%04d", idx)
+		if isCover {
+			id, imageId = "page-cover", "cover-image"
+		}
+
+		imageHref := path.Join("images", fmt.Sprintf("%04d%s", idx, strings.ToLower(path.Ext(img.Name))))
+		if err = writeImage(zw, path.Join(packageDir, imageHref), img); err != nil {
+			return err
+		}
+
+		// The cover isn't counted, the first content page is page 1
+		number := utils.Ternary(b.Cover != nil, idx, idx+1)
+
+		width, height := img.viewport()
+		p := page{
+			Id:     id,
+			Href:   path.Join("pages", id+".xhtml"),
+			Image:  path.Join("..", imageHref),
+			Title:  utils.Ternary(isCover, "Cover", fmt.Sprintf("Page %d", number)),
+			Width:  width,
+			Height: height,
+		}
+		if err = writeTemplate(zw, path.Join(packageDir, p.Href), pageTemplate, p); err != nil {
+			return err
+		}
+
+		items = append(items,
+			manifestItem{Id: imageId, Href: imageHref, MediaType: mediaType, Properties: utils.Ternary(isCover, "cover-image", "")},
+			manifestItem{Id: id, Href: p.Href, MediaType: "application/xhtml+xml"},
+		)
+		pages = append(pages, p)
+	}
+
+	if err = writeTemplate(zw, path.Join(packageDir, "nav.xhtml"), navTemplate, navData{
+		Title: b.Metadata.Title,
+		Pages: pages,
+	}); err != nil {
+		return err
+	}
+
+	if err = writeTemplate(zw, path.Join(packageDir, packageFile), packageTemplate, packageData{
+		Metadata:  b.Metadata,
+		Modified:  utils.Ternary(b.Metadata.Modified.IsZero(), time.Now(), b.Metadata.Modified).UTC().Format(time.RFC3339),
+		Language:  utils.Ternary(b.Metadata.Language == "", "und", b.Metadata.Language),
+		HasCover:  b.Cover != nil,
+		Items:     items,
+		Pages:     pages,
+		Direction: utils.Ternary(b.Metadata.RightToLeft, "rtl", "ltr"),
+	}); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// Save writes the Book to the specified path
+func Save(fs afero.Afero, b *Book, path string) error {
+	f, err := fs.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating file: %w", err)
+	}
+	defer f.Close()
+
+	if err = b.Write(f); err != nil {
+		return fmt.Errorf("error writing epub to file: %w", err)
+	}
+
+	return nil
+}
+
+func writeImage(zw *zip.Writer, name string, img Image) error {
+	rc, err := img.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	// Images are already compressed, deflating them again only costs time
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, rc)
+	return err
+}
+
+func writeTemplate(zw *zip.Writer, name string, tmpl *template.Template, data any) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+	if err != nil {
+		return err
+	}
+
+	return tmpl.Execute(w, data)
+}
+
+func escape(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
@@ -0,0 +1,158 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func image(name string) Image {
+	return Image{
+		Name:   name,
+		Width:  100,
+		Height: 200,
+		Open: func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(name)), nil
+		},
+	}
+}
+
+func readFile(t *testing.T, file *zip.File) string {
+	t.Helper()
+
+	r, err := file.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestBook_Write(t *testing.T) {
+	cover := image("!0000 cover.jpg")
+	book := &Book{
+		Metadata: Metadata{
+			Identifier:  "urn:uuid:test",
+			Title:       "Spice & Wolf Ch. 0001",
+			Language:    "en",
+			Series:      "Spice & Wolf",
+			SeriesIndex: "1",
+			Creators: []Creator{
+				{Name: "Isuna Hasekura", Role: "aut"},
+				{Name: "Keito Koume", Role: "art"},
+			},
+			Subjects:    []string{"Romance"},
+			RightToLeft: true,
+		},
+		Cover: &cover,
+		Pages: []Image{image("page 0001.jpg"), image("page 0002.png")},
+	}
+
+	var buf bytes.Buffer
+	if err := book.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reader.File[0].Name != "mimetype" || reader.File[0].Method != zip.Store {
+		t.Fatalf("first entry must be an uncompressed mimetype, got %s", reader.File[0].Name)
+	}
+
+	var opf io.ReadCloser
+	var nav string
+	pages := 0
+	for _, file := range reader.File {
+		if strings.HasSuffix(file.Name, ".opf") {
+			opf, err = file.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if strings.HasPrefix(file.Name, "OEBPS/pages/") {
+			pages++
+		}
+		if strings.HasSuffix(file.Name, "nav.xhtml") {
+			nav = readFile(t, file)
+		}
+		if strings.HasPrefix(file.Name, "OEBPS/images/") && strings.ContainsAny(file.Name, " !") {
+			t.Errorf("Got image %s; expected a name that's a valid URL", file.Name)
+		}
+	}
+
+	if pages != 3 {
+		t.Fatalf("Got %d pages; expected %d", pages, 3)
+	}
+
+	// The cover isn't counted as a page
+	for _, title := range []string{">Cover<", ">Page 1<", ">Page 2<"} {
+		if !strings.Contains(nav, title) {
+			t.Errorf("Got nav %s; expected an entry %s", nav, title)
+		}
+	}
+	if strings.Contains(nav, ">Page 3<") {
+		t.Error("Got Page 3; expected the pages to be numbered from the first page after the cover")
+	}
+
+	if opf == nil {
+		t.Fatal("no package document found")
+	}
+	defer opf.Close()
+
+	metadata, err := ReadMetadata(opf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if metadata.Title != book.Metadata.Title {
+		t.Errorf("Got %s; expected %s", metadata.Title, book.Metadata.Title)
+	}
+	if metadata.Series != book.Metadata.Series || metadata.SeriesIndex != book.Metadata.SeriesIndex {
+		t.Errorf("Got %s (%s); expected %s (%s)", metadata.Series, metadata.SeriesIndex, book.Metadata.Series, book.Metadata.SeriesIndex)
+	}
+	if len(metadata.Creators) != 2 || metadata.Creators[1].Role != "art" {
+		t.Errorf("Got %+v; expected %+v", metadata.Creators, book.Metadata.Creators)
+	}
+	if !metadata.RightToLeft {
+		t.Error("expected right to left page progression")
+	}
+}
+
+func TestBook_WriteErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		book *Book
+		want error
+	}{
+		{
+			name: "No pages",
+			book: &Book{},
+			want: ErrNoPages,
+		},
+		{
+			name: "Unsupported image",
+			book: &Book{Pages: []Image{image("page 0001.avif")}},
+			want: ErrUnsupportedImage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.book.Write(io.Discard)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Got %v; expected %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package epub
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// The namespace is left out of the tags on purpose, the package document can be read regardless
+// of the prefixes used
+type opfPackage struct {
+	Metadata struct {
+		Identifier  string   `xml:"identifier"`
+		Title       string   `xml:"title"`
+		Language    string   `xml:"language"`
+		Description string   `xml:"description"`
+		Publisher   string   `xml:"publisher"`
+		Source      string   `xml:"source"`
+		Subjects    []string `xml:"subject"`
+		Creators    []struct {
+			Id   string `xml:"id,attr"`
+			Name string `xml:",chardata"`
+		} `xml:"creator"`
+		Meta []struct {
+			Name     string `xml:"name,attr"`
+			Content  string `xml:"content,attr"`
+			Property string `xml:"property,attr"`
+			Refines  string `xml:"refines,attr"`
+			Value    string `xml:",chardata"`
+		} `xml:"meta"`
+	} `xml:"metadata"`
+	Spine struct {
+		Direction string `xml:"page-progression-direction,attr"`
+	} `xml:"spine"`
+}
+
+// ReadMetadata parses the package document (content.opf) in r
+func ReadMetadata(r io.Reader) (*Metadata, error) {
+	var opf opfPackage
+	if err := xml.NewDecoder(r).Decode(&opf); err != nil {
+		return nil, fmt.Errorf("failed to decode package document: %w", err)
+	}
+
+	m := &Metadata{
+		Identifier:  strings.TrimSpace(opf.Metadata.Identifier),
+		Title:       strings.TrimSpace(opf.Metadata.Title),
+		Language:    strings.TrimSpace(opf.Metadata.Language),
+		Description: strings.TrimSpace(opf.Metadata.Description),
+		Publisher:   strings.TrimSpace(opf.Metadata.Publisher),
+		Source:      strings.TrimSpace(opf.Metadata.Source),
+		Subjects:    opf.Metadata.Subjects,
+		RightToLeft: opf.Spine.Direction == "rtl",
+	}
+
+	roles := make(map[string]string)
+	for _, meta := range opf.Metadata.Meta {
+		value := strings.TrimSpace(meta.Value)
+		switch {
+		case meta.Property == "role" && meta.Refines != "":
+			roles[strings.TrimPrefix(meta.Refines, "#")] = value
+		case meta.Property == "belongs-to-collection" && m.Series == "":
+			m.Series = value
+		case meta.Property == "group-position" && m.SeriesIndex == "":
+			m.SeriesIndex = value
+		case meta.Property == "dcterms:modified":
+			if t, err := time.Parse(time.RFC3339, value); err == nil {
+				m.Modified = t
+			}
+		case meta.Name == "calibre:series" && m.Series == "":
+			m.Series = meta.Content
+		case meta.Name == "calibre:series_index" && m.SeriesIndex == "":
+			m.SeriesIndex = meta.Content
+		}
+	}
+
+	for _, creator := range opf.Metadata.Creators {
+		m.Creators = append(m.Creators, Creator{
+			Name: strings.TrimSpace(creator.Name),
+			Role: roles[creator.Id],
+		})
+	}
+
+	return m, nil
+}
@@ -0,0 +1,119 @@
+package epub
+
+import "text/template"
+
+var funcs = template.FuncMap{
+	"escape": escape,
+}
+
+type navData struct {
+	Title string
+	Pages []page
+}
+
+type packageData struct {
+	Metadata  Metadata
+	Modified  string
+	Language  string
+	HasCover  bool
+	Items     []manifestItem
+	Pages     []page
+	Direction string
+}
+
+var containerTemplate = template.Must(template.New("container").Funcs(funcs).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="{{ escape . }}" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`))
+
+var pageTemplate = template.Must(template.New("page").Funcs(funcs).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<head>
+  <title>{{ escape .Title }}</title>
+  <meta name="viewport" content="width={{ .Width }}, height={{ .Height }}"/>
+  <style>html, body { margin: 0; padding: 0; } img { display: block; width: 100%; height: 100%; object-fit: contain; }</style>
+</head>
+<body>
+  <img src="{{ escape .Image }}" alt="{{ escape .Title }}"/>
+</body>
+</html>
+`))
+
+var navTemplate = template.Must(template.New("nav").Funcs(funcs).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<head>
+  <title>{{ escape .Title }}</title>
+</head>
+<body>
+  <nav epub:type="toc" id="toc">
+    <ol>
+{{- range .Pages }}
+      <li><a href="{{ escape .Href }}">{{ escape .Title }}</a></li>
+{{- end }}
+    </ol>
+  </nav>
+</body>
+</html>
+`))
+
+var packageTemplate = template.Must(template.New("package").Funcs(funcs).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id" prefix="rendition: http://www.idpf.org/vocab/rendition/#">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:opf="http://www.idpf.org/2007/opf">
+    <dc:identifier id="book-id">{{ escape .Metadata.Identifier }}</dc:identifier>
+    <dc:title>{{ escape .Metadata.Title }}</dc:title>
+    <dc:language>{{ escape .Language }}</dc:language>
+{{- if .Metadata.Description }}
+    <dc:description>{{ escape .Metadata.Description }}</dc:description>
+{{- end }}
+{{- if .Metadata.Publisher }}
+    <dc:publisher>{{ escape .Metadata.Publisher }}</dc:publisher>
+{{- end }}
+{{- if .Metadata.Source }}
+    <dc:source>{{ escape .Metadata.Source }}</dc:source>
+{{- end }}
+{{- range $i, $c := .Metadata.Creators }}
+    <dc:creator id="creator-{{ $i }}">{{ escape $c.Name }}</dc:creator>
+{{- if $c.Role }}
+    <meta refines="#creator-{{ $i }}" property="role" scheme="marc:relators">{{ escape $c.Role }}</meta>
+{{- end }}
+{{- end }}
+{{- range .Metadata.Subjects }}
+    <dc:subject>{{ escape . }}</dc:subject>
+{{- end }}
+{{- if .Metadata.Series }}
+    <meta property="belongs-to-collection" id="series">{{ escape .Metadata.Series }}</meta>
+    <meta refines="#series" property="collection-type">series</meta>
+{{- if .Metadata.SeriesIndex }}
+    <meta refines="#series" property="group-position">{{ escape .Metadata.SeriesIndex }}</meta>
+{{- end }}
+    <meta name="calibre:series" content="{{ escape .Metadata.Series }}"/>
+{{- if .Metadata.SeriesIndex }}
+    <meta name="calibre:series_index" content="{{ escape .Metadata.SeriesIndex }}"/>
+{{- end }}
+{{- end }}
+{{- if .HasCover }}
+    <meta name="cover" content="cover-image"/>
+{{- end }}
+    <meta property="dcterms:modified">{{ .Modified }}</meta>
+    <meta property="rendition:layout">pre-paginated</meta>
+    <meta property="rendition:orientation">auto</meta>
+    <meta property="rendition:spread">none</meta>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+{{- range .Items }}
+    <item id="{{ .Id }}" href="{{ escape .Href }}" media-type="{{ .MediaType }}"{{ if .Properties }} properties="{{ .Properties }}"{{ end }}/>
+{{- end }}
+  </manifest>
+  <spine page-progression-direction="{{ .Direction }}">
+{{- range .Pages }}
+    <itemref idref="{{ .Id }}"/>
+{{- end }}
+  </spine>
+</package>
+`))
@@ -0,0 +1,253 @@
+package naming
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Placeholders usable in a Template, written as {name}
+const (
+	Title         = "title"
+	Volume        = "volume"
+	Chapter       = "chapter"
+	ChapterPadded = "chapter_padded"
+	ChapterTitle  = "chapter_title"
+	Group         = "group"
+	Provider      = "provider"
+	// Season, Episode and Resolution are used when renaming episodes of torrents
+	Season     = "season"
+	Episode    = "episode"
+	Resolution = "resolution"
+)
+
+// Placeholders are all placeholders known to Parse
+var Placeholders = []string{Title, Volume, Chapter, ChapterPadded, ChapterTitle, Group, Provider, Season, Episode, Resolution}
+
+// ContentPlaceholders identify a chapter or volume, a file name template must use at least one of them
+var ContentPlaceholders = []string{Volume, Chapter, ChapterPadded}
+
+var (
+	ErrUnknownPlaceholder   = errors.New("unknown placeholder")
+	ErrUnclosedPlaceholder  = errors.New("unclosed placeholder")
+	ErrUnclosedSection      = errors.New("unclosed optional section")
+	ErrNestedSection        = errors.New("optional sections cannot be nested")
+	ErrUnexpectedClose      = errors.New("unexpected closing character")
+	ErrPathSeparator        = errors.New("templates cannot contain path separators")
+	ErrNoContentPlaceholder = errors.New("file name templates must contain a {chapter}, {chapter_padded} or {volume} placeholder")
+)
+
+// sanitizer removes path separators from values, so they can't introduce new directories
+var sanitizer = strings.NewReplacer("/", "-", "\\", "-")
+
+// Values maps placeholders to the value they're replaced with
+type Values map[string]string
+
+type part struct {
+	literal     string
+	placeholder string
+	// section holds the parts of an optional section, which is left out if any of its placeholders are empty
+	section []part
+}
+
+// Template is a user defined file or directory name. Placeholders are written as {title}, text between < and >
+// is an optional section. Which is only included when all its placeholders have a value.
+// For example; "{title}< Vol. {volume}> Ch. {chapter_padded}"
+type Template struct {
+	raw   string
+	parts []part
+}
+
+// Parse parses the template, returning an error if it uses unknown placeholders or is malformed
+func Parse(s string) (*Template, error) {
+	if strings.ContainsAny(s, "/\\") {
+		return nil, ErrPathSeparator
+	}
+
+	var parts []part
+	var section []part
+	inSection := false
+
+	add := func(p part) {
+		if inSection {
+			section = append(section, p)
+		} else {
+			parts = append(parts, p)
+		}
+	}
+
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			add(part{literal: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end == -1 {
+				return nil, fmt.Errorf("%w at %d", ErrUnclosedPlaceholder, i)
+			}
+
+			name := s[i+1 : i+end]
+			if !slices.Contains(Placeholders, name) {
+				return nil, fmt.Errorf("%w: {%s}", ErrUnknownPlaceholder, name)
+			}
+
+			flush()
+			add(part{placeholder: name})
+			i += end
+		case '<':
+			if inSection {
+				return nil, fmt.Errorf("%w at %d", ErrNestedSection, i)
+			}
+			flush()
+			inSection = true
+		case '>':
+			if !inSection {
+				return nil, fmt.Errorf("%w '>' at %d", ErrUnexpectedClose, i)
+			}
+			flush()
+			parts = append(parts, part{section: section})
+			section = nil
+			inSection = false
+		case '}':
+			return nil, fmt.Errorf("%w '}' at %d", ErrUnexpectedClose, i)
+		default:
+			literal.WriteByte(s[i])
+		}
+	}
+
+	if inSection {
+		return nil, ErrUnclosedSection
+	}
+	flush()
+
+	return &Template{raw: s, parts: parts}, nil
+}
+
+// Validate returns an error if the template cannot be parsed, empty templates are valid
+func Validate(s string) error {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+
+	_, err := Parse(s)
+	return err
+}
+
+// ValidateFileName is Validate for file name templates, which must also use one of ContentPlaceholders.
+// Otherwise, all chapters are rendered to the same file name
+func ValidateFileName(s string) error {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+
+	t, err := Parse(s)
+	if err != nil {
+		return err
+	}
+
+	if !t.Uses(ContentPlaceholders...) {
+		return ErrNoContentPlaceholder
+	}
+	return nil
+}
+
+// Sanitize replaces path separators in s, the same way placeholder values are when rendering
+func Sanitize(s string) string {
+	return sanitizer.Replace(s)
+}
+
+func (t *Template) String() string {
+	return t.raw
+}
+
+// Uses returns true if any of the placeholders is in the template, optional sections included
+func (t *Template) Uses(placeholders ...string) bool {
+	return slices.ContainsFunc(t.parts, func(p part) bool {
+		return slices.Contains(placeholders, p.placeholder) || slices.ContainsFunc(p.section, func(sp part) bool {
+			return slices.Contains(placeholders, sp.placeholder)
+		})
+	})
+}
+
+// Render replaces all placeholders with their value, path separators in values are replaced by a dash.
+// Leading and trailing whitespace is removed from the result
+func (t *Template) Render(values Values) string {
+	var sb strings.Builder
+
+	for _, p := range t.parts {
+		if p.section == nil {
+			sb.WriteString(p.render(values))
+			continue
+		}
+
+		complete := !slices.ContainsFunc(p.section, func(sp part) bool {
+			return sp.placeholder != "" && values[sp.placeholder] == ""
+		})
+		if !complete {
+			continue
+		}
+
+		for _, sp := range p.section {
+			sb.WriteString(sp.render(values))
+		}
+	}
+
+	return strings.TrimSpace(sb.String())
+}
+
+func (p part) render(values Values) string {
+	if p.placeholder == "" {
+		return p.literal
+	}
+	return sanitizer.Replace(values[p.placeholder])
+}
+
+// Regexp returns a regex matching file names rendered by the template, with the given extension appended.
+// The first volume and chapter placeholders are captured in the "volume" and "chapter" groups
+func (t *Template) Regexp(ext string) *regexp.Regexp {
+	var sb strings.Builder
+	captured := make(map[string]bool)
+
+	var write func([]part)
+	write = func(parts []part) {
+		for _, p := range parts {
+			switch {
+			case p.section != nil:
+				sb.WriteString("(?:")
+				write(p.section)
+				sb.WriteString(")?")
+			case p.placeholder == "":
+				sb.WriteString(regexp.QuoteMeta(p.literal))
+			case p.placeholder == Volume:
+				sb.WriteString(numberGroup(Volume, captured))
+			case p.placeholder == Chapter, p.placeholder == ChapterPadded:
+				sb.WriteString(numberGroup(Chapter, captured))
+			default:
+				sb.WriteString(".*?")
+			}
+		}
+	}
+
+	sb.WriteString("^")
+	write(t.parts)
+	sb.WriteString(regexp.QuoteMeta(ext))
+	sb.WriteString("$")
+
+	return regexp.MustCompile(sb.String())
+}
+
+func numberGroup(name string, captured map[string]bool) string {
+	if captured[name] {
+		return `[\d\.]+`
+	}
+	captured[name] = true
+	return `(?P<` + name + `>[\d\.]+)`
+}
@@ -0,0 +1,194 @@
+package naming
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		err      error
+	}{
+		{name: "Default", template: "{title}< Vol. {volume}> Ch. {chapter_padded}"},
+		{name: "All placeholders", template: "[{group}] {title} {volume} {chapter} {chapter_padded} {chapter_title} ({provider})"},
+		{name: "Unknown placeholder", template: "{title} {author}", err: ErrUnknownPlaceholder},
+		{name: "Unclosed placeholder", template: "{title", err: ErrUnclosedPlaceholder},
+		{name: "Unclosed section", template: "{title}< Vol. {volume}", err: ErrUnclosedSection},
+		{name: "Nested section", template: "<{title}< Vol. {volume}>>", err: ErrNestedSection},
+		{name: "Stray close", template: "{title}}", err: ErrUnexpectedClose},
+		{name: "Path separator", template: "{title}/{chapter}", err: ErrPathSeparator},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.template)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Got error %v; expected %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestValidateFileName(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		err      error
+	}{
+		{name: "Empty", template: ""},
+		{name: "Chapter", template: "{title} Ch. {chapter}"},
+		{name: "Optional volume", template: "{title}< Vol. {volume}>"},
+		{name: "Title only", template: "{title}", err: ErrNoContentPlaceholder},
+		{name: "Chapter title", template: "{title} - {chapter_title}", err: ErrNoContentPlaceholder},
+		{name: "Invalid", template: "{title} {author}", err: ErrUnknownPlaceholder},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateFileName(tt.template); !errors.Is(err, tt.err) {
+				t.Errorf("Got error %v; expected %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestTemplate_Render(t *testing.T) {
+	values := Values{
+		Title:         "Spice and Wolf",
+		Volume:        "2",
+		Chapter:       "7",
+		ChapterPadded: "0007",
+		ChapterTitle:  "Wolf/Spice",
+		Group:         "Hakugin Scans",
+		Provider:      "MangaDex",
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		values   Values
+		want     string
+	}{
+		{
+			name:     "Default",
+			template: "{title}< Vol. {volume}> Ch. {chapter_padded}",
+			values:   values,
+			want:     "Spice and Wolf Vol. 2 Ch. 0007",
+		},
+		{
+			name:     "Missing volume drops section",
+			template: "{title}< Vol. {volume}> Ch. {chapter_padded}",
+			values:   Values{Title: "Spice and Wolf", ChapterPadded: "0007"},
+			want:     "Spice and Wolf Ch. 0007",
+		},
+		{
+			name:     "Path separators are replaced",
+			template: "{title} - {chapter_title}",
+			values:   values,
+			want:     "Spice and Wolf - Wolf-Spice",
+		},
+		{
+			name:     "Whitespace is trimmed",
+			template: "<[{group}] >{title} ",
+			values:   Values{Title: "Spice and Wolf"},
+			want:     "Spice and Wolf",
+		},
+		{
+			name:     "Group and provider",
+			template: "[{group}] {title} {chapter} ({provider})",
+			values:   values,
+			want:     "[Hakugin Scans] Spice and Wolf 7 (MangaDex)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := Parse(tt.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := tmpl.Render(tt.values); got != tt.want {
+				t.Errorf("Got %q; expected %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplate_Regexp(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		fileName string
+		match    bool
+		volume   string
+		chapter  string
+	}{
+		{
+			name:     "Volume and chapter",
+			template: "{title}< Vol. {volume}> Ch. {chapter_padded}",
+			fileName: "Spice and Wolf Vol. 2 Ch. 0007.cbz",
+			match:    true,
+			volume:   "2",
+			chapter:  "0007",
+		},
+		{
+			name:     "Optional volume missing",
+			template: "{title}< Vol. {volume}> Ch. {chapter_padded}",
+			fileName: "Spice and Wolf Ch. 0007.5.cbz",
+			match:    true,
+			chapter:  "0007.5",
+		},
+		{
+			name:     "Chapter used twice",
+			template: "[{group}] {title} #{chapter} ({chapter_padded})",
+			fileName: "[Hakugin Scans] Spice and Wolf #7 (0007).cbz",
+			match:    true,
+			chapter:  "7",
+		},
+		{
+			name:     "Literals are escaped",
+			template: "{title} (c{chapter})",
+			fileName: "Spice and Wolf xc7).cbz",
+		},
+		{
+			name:     "Wrong extension",
+			template: "{title} Ch. {chapter}",
+			fileName: "Spice and Wolf Ch. 7.epub",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := Parse(tt.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rgx := tmpl.Regexp(".cbz")
+			matches := rgx.FindStringSubmatch(tt.fileName)
+			if (matches != nil) != tt.match {
+				t.Fatalf("Got match %t; expected %t (%s)", matches != nil, tt.match, rgx)
+			}
+			if matches == nil {
+				return
+			}
+
+			group := func(name string) string {
+				if idx := rgx.SubexpIndex(name); idx != -1 {
+					return matches[idx]
+				}
+				return ""
+			}
+
+			if got := group(Volume); got != tt.volume {
+				t.Errorf("Got volume %q; expected %q", got, tt.volume)
+			}
+			if got := group(Chapter); got != tt.chapter {
+				t.Errorf("Got chapter %q; expected %q", got, tt.chapter)
+			}
+		})
+	}
+}
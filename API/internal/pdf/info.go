@@ -0,0 +1,211 @@
+package pdf
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+var (
+	ErrNoInfo = errors.New("no document information found")
+)
+
+// maxInfoSize is the amount of bytes ReadInfo reads before giving up
+const maxInfoSize = 64 * 1024
+
+const dateLayout = "20060102150405"
+
+func (i Info) dictionary() string {
+	var sb strings.Builder
+	sb.WriteString("<<")
+
+	entry := func(key, value string) {
+		if value == "" {
+			return
+		}
+		sb.WriteString(" /")
+		sb.WriteString(key)
+		sb.WriteString(" ")
+		sb.WriteString(encodeString(value))
+	}
+
+	entry("Title", i.Title)
+	entry("Author", i.Author)
+	entry("Subject", i.Subject)
+	entry("Keywords", i.Keywords)
+	entry("Creator", i.Creator)
+	entry("Producer", i.Producer)
+	entry("Series", i.Series)
+	entry("Volume", i.Volume)
+	if !i.CreationDate.IsZero() {
+		entry("CreationDate", "D:"+i.CreationDate.UTC().Format(dateLayout)+"Z")
+	}
+
+	sb.WriteString(" >>")
+	return sb.String()
+}
+
+// encodeString returns a PDF string. ASCII is written as a literal string, anything else as
+// UTF-16BE hex string, as required for text strings
+func encodeString(s string) string {
+	ascii := true
+	for _, r := range s {
+		if r > 126 || (r < 32 && r != '\n') {
+			ascii = false
+			break
+		}
+	}
+
+	if ascii {
+		r := strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`, "\n", `\n`)
+		return "(" + r.Replace(s) + ")"
+	}
+
+	units := utf16.Encode([]rune(s))
+	b := make([]byte, 2, 2+len(units)*2)
+	b[0], b[1] = 0xFE, 0xFF
+	for _, u := range units {
+		b = append(b, byte(u>>8), byte(u))
+	}
+	return "<" + strings.ToUpper(hex.EncodeToString(b)) + ">"
+}
+
+// ReadInfo reads the document information of a PDF written by Document.Write. Only the start of
+// the file is read, as the information dictionary is always written first
+func ReadInfo(r io.Reader) (*Info, error) {
+	head := make([]byte, maxInfoSize)
+	n, err := io.ReadFull(bufio.NewReader(r), head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	head = head[:n]
+
+	start := bytes.Index(head, fmt.Appendf(nil, "%d 0 obj", infoObj))
+	if start == -1 {
+		return nil, ErrNoInfo
+	}
+	end := bytes.Index(head[start:], []byte("endobj"))
+	if end == -1 {
+		return nil, ErrNoInfo
+	}
+
+	entries, err := parseDictionary(head[start : start+end])
+	if err != nil {
+		return nil, err
+	}
+
+	info := &Info{
+		Title:    entries["Title"],
+		Author:   entries["Author"],
+		Subject:  entries["Subject"],
+		Keywords: entries["Keywords"],
+		Creator:  entries["Creator"],
+		Producer: entries["Producer"],
+		Series:   entries["Series"],
+		Volume:   entries["Volume"],
+	}
+
+	if date := strings.TrimSuffix(strings.TrimPrefix(entries["CreationDate"], "D:"), "Z"); date != "" {
+		if t, err := time.Parse(dateLayout, date); err == nil {
+			info.CreationDate = t
+		}
+	}
+
+	return info, nil
+}
+
+// parseDictionary parses a flat dictionary with string values
+func parseDictionary(b []byte) (map[string]string, error) {
+	entries := make(map[string]string)
+
+	i := bytes.Index(b, []byte("<<"))
+	if i == -1 {
+		return nil, ErrNoInfo
+	}
+	i += 2
+
+	for i < len(b) {
+		switch {
+		case b[i] == '/':
+			keyEnd := i + 1
+			for keyEnd < len(b) && !isDelimiter(b[keyEnd]) {
+				keyEnd++
+			}
+			key := string(b[i+1 : keyEnd])
+
+			value, next, err := parseString(b, keyEnd)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", key, err)
+			}
+			entries[key] = value
+			i = next
+		case bytes.HasPrefix(b[i:], []byte(">>")):
+			return entries, nil
+		default:
+			i++
+		}
+	}
+
+	return nil, ErrNoInfo
+}
+
+func parseString(b []byte, i int) (string, int, error) {
+	for i < len(b) && (b[i] == ' ' || b[i] == '\n' || b[i] == '\r') {
+		i++
+	}
+	if i >= len(b) {
+		return "", i, io.ErrUnexpectedEOF
+	}
+
+	switch b[i] {
+	case '(':
+		var sb strings.Builder
+		for i++; i < len(b); i++ {
+			switch b[i] {
+			case '\\':
+				i++
+				if i < len(b) && b[i] == 'n' {
+					sb.WriteByte('\n')
+				} else if i < len(b) {
+					sb.WriteByte(b[i])
+				}
+			case ')':
+				return sb.String(), i + 1, nil
+			default:
+				sb.WriteByte(b[i])
+			}
+		}
+		return "", i, io.ErrUnexpectedEOF
+	case '<':
+		end := bytes.IndexByte(b[i:], '>')
+		if end == -1 {
+			return "", i, io.ErrUnexpectedEOF
+		}
+		raw, err := hex.DecodeString(string(b[i+1 : i+end]))
+		if err != nil {
+			return "", i, err
+		}
+		return decodeUtf16(raw), i + end + 1, nil
+	}
+
+	return "", i, fmt.Errorf("unexpected value starting with %q", b[i])
+}
+
+func decodeUtf16(raw []byte) string {
+	raw = bytes.TrimPrefix(raw, []byte{0xFE, 0xFF})
+	units := make([]uint16, 0, len(raw)/2)
+	for j := 0; j+1 < len(raw); j += 2 {
+		units = append(units, uint16(raw[j])<<8|uint16(raw[j+1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+func isDelimiter(c byte) bool {
+	return c == ' ' || c == '(' || c == '<' || c == '/' || c == '\n' || c == '\r'
+}
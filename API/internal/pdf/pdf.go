@@ -0,0 +1,247 @@
+// Package pdf writes image only PDF documents, one image per page, and can read back the document
+// information it wrote. It is not a general purpose PDF library.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // register decoder
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"time"
+
+	_ "github.com/chai2010/webp" // register decoder
+	"github.com/spf13/afero"
+)
+
+var (
+	ErrNoPages = errors.New("pdf must contain at least one page")
+)
+
+// Info is the document information dictionary. Series and Volume are custom entries, Media-Provider
+// uses them to recognise content on disk
+type Info struct {
+	Title        string
+	Author       string
+	Subject      string
+	Keywords     string
+	Creator      string
+	Producer     string
+	Series       string
+	Volume       string
+	CreationDate time.Time
+}
+
+// Image is a single page. Open is called exactly once while writing
+type Image struct {
+	Name string
+	Open func() (io.ReadCloser, error)
+}
+
+type Document struct {
+	Info  Info
+	Pages []Image
+}
+
+const (
+	infoObj    = 1
+	catalogObj = 2
+	pagesObj   = 3
+	// firstPageObj each page uses three objects; image, content stream and the page itself
+	firstPageObj = 4
+)
+
+func pageObjects(idx int) (img, content, page int) {
+	base := firstPageObj + idx*3
+	return base, base + 1, base + 2
+}
+
+type writer struct {
+	w       io.Writer
+	n       int64
+	offsets map[int]int64
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+func (w *writer) printf(format string, args ...any) error {
+	_, err := fmt.Fprintf(w, format, args...)
+	return err
+}
+
+func (w *writer) startObj(id int) error {
+	w.offsets[id] = w.n
+	return w.printf("%d 0 obj\n", id)
+}
+
+func (w *writer) stream(id int, dict string, data []byte) error {
+	if err := w.startObj(id); err != nil {
+		return err
+	}
+	if err := w.printf("<< %s /Length %d >>\nstream\n", dict, len(data)); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	return w.printf("\nendstream\nendobj\n")
+}
+
+// Write writes the document to w. The information dictionary is always the first object, see ReadInfo
+func (d *Document) Write(out io.Writer) error {
+	if len(d.Pages) == 0 {
+		return ErrNoPages
+	}
+
+	w := &writer{w: out, offsets: make(map[int]int64)}
+
+	// Binary comment, marks the file as binary for transfer programs
+	if err := w.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n"); err != nil {
+		return err
+	}
+
+	if err := w.startObj(infoObj); err != nil {
+		return err
+	}
+	if err := w.printf("%s\nendobj\n", d.Info.dictionary()); err != nil {
+		return err
+	}
+
+	kids := make([]byte, 0, len(d.Pages)*8)
+	for idx, page := range d.Pages {
+		imgObj, contentObj, pageObj := pageObjects(idx)
+		width, height, err := w.image(imgObj, page)
+		if err != nil {
+			return fmt.Errorf("failed to write page %s: %w", page.Name, err)
+		}
+
+		content := fmt.Appendf(nil, "q %d 0 0 %d 0 0 cm /Im0 Do Q", width, height)
+		if err = w.stream(contentObj, "", content); err != nil {
+			return err
+		}
+
+		if err = w.startObj(pageObj); err != nil {
+			return err
+		}
+		if err = w.printf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %d %d] /Resources << /XObject << /Im0 %d 0 R >> >> /Contents %d 0 R >>\nendobj\n",
+			pagesObj, width, height, imgObj, contentObj); err != nil {
+			return err
+		}
+
+		kids = fmt.Appendf(kids, "%d 0 R ", pageObj)
+	}
+
+	if err := w.startObj(pagesObj); err != nil {
+		return err
+	}
+	if err := w.printf("<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", bytes.TrimSpace(kids), len(d.Pages)); err != nil {
+		return err
+	}
+
+	if err := w.startObj(catalogObj); err != nil {
+		return err
+	}
+	if err := w.printf("<< /Type /Catalog /Pages %d 0 R >>\nendobj\n", pagesObj); err != nil {
+		return err
+	}
+
+	size := len(w.offsets) + 1
+	xref := w.n
+	if err := w.printf("xref\n0 %d\n0000000000 65535 f \n", size); err != nil {
+		return err
+	}
+	for id := 1; id < size; id++ {
+		if err := w.printf("%010d 00000 n \n", w.offsets[id]); err != nil {
+			return err
+		}
+	}
+
+	return w.printf("trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", size, catalogObj, infoObj, xref)
+}
+
+// image writes the image XObject, and returns its dimensions. JPEGs are embedded as is, anything
+// else is decoded and stored as deflated RGB
+func (w *writer) image(id int, img Image) (int, int, error) {
+	rc, err := img.Open()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if format == "jpeg" && (cfg.ColorModel == color.YCbCrModel || cfg.ColorModel == color.GrayModel) {
+		colorSpace := "/DeviceRGB"
+		if cfg.ColorModel == color.GrayModel {
+			colorSpace = "/DeviceGray"
+		}
+
+		dict := fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace %s /BitsPerComponent 8 /Filter /DCTDecode",
+			cfg.Width, cfg.Height, colorSpace)
+		return cfg.Width, cfg.Height, w.stream(id, dict, data)
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// Transparent pixels are drawn onto white, as they'd be shown in a reader
+	bounds := decoded.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(rgba, rgba.Bounds(), decoded, bounds.Min, draw.Over)
+
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	row := make([]byte, bounds.Dx()*3)
+	for y := 0; y < bounds.Dy(); y++ {
+		pix := rgba.Pix[y*rgba.Stride : y*rgba.Stride+bounds.Dx()*4]
+		for x := 0; x < bounds.Dx(); x++ {
+			copy(row[x*3:x*3+3], pix[x*4:x*4+3])
+		}
+		if _, err = zw.Write(row); err != nil {
+			return 0, 0, err
+		}
+	}
+	if err = zw.Close(); err != nil {
+		return 0, 0, err
+	}
+
+	dict := fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /FlateDecode",
+		bounds.Dx(), bounds.Dy())
+	return bounds.Dx(), bounds.Dy(), w.stream(id, dict, buf.Bytes())
+}
+
+// Save writes the Document to the specified path
+func Save(fs afero.Afero, d *Document, path string) error {
+	f, err := fs.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating file: %w", err)
+	}
+	defer f.Close()
+
+	if err = d.Write(f); err != nil {
+		return fmt.Errorf("error writing pdf to file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"testing"
+	"time"
+)
+
+func encoded(t *testing.T, encode func(io.Writer, image.Image) error) Image {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, 4, 6))
+	img.Set(1, 1, color.RGBA{R: 255, A: 255})
+
+	var buf bytes.Buffer
+	if err := encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	return Image{
+		Name: "page",
+		Open: func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(buf.Bytes())), nil
+		},
+	}
+}
+
+func TestDocument_Write(t *testing.T) {
+	doc := &Document{
+		Info: Info{
+			Title:        "Spice and Wolf (Vol. 1)",
+			Author:       "Isuna Hasekura",
+			Keywords:     "Romance, Fantasy",
+			Series:       "狼と香辛料",
+			Volume:       "1",
+			CreationDate: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+		Pages: []Image{
+			encoded(t, func(w io.Writer, img image.Image) error { return jpeg.Encode(w, img, nil) }),
+			encoded(t, png.Encode),
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.Bytes()
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Fatal("output is not a pdf")
+	}
+
+	if c := bytes.Count(out, []byte("/Type /Page ")); c != 2 {
+		t.Errorf("Got %d pages; expected %d", c, 2)
+	}
+	if !bytes.Contains(out, []byte("/DCTDecode")) || !bytes.Contains(out, []byte("/FlateDecode")) {
+		t.Error("expected the jpeg to be embedded, and the png to be deflated")
+	}
+
+	info, err := ReadInfo(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *info != doc.Info {
+		t.Errorf("Got %+v; expected %+v", *info, doc.Info)
+	}
+}
+
+func TestDocument_WriteErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  *Document
+		want error
+	}{
+		{
+			name: "No pages",
+			doc:  &Document{},
+			want: ErrNoPages,
+		},
+		{
+			name: "Not an image",
+			doc: &Document{Pages: []Image{{
+				Name: "page 0001.jpg",
+				Open: func() (io.ReadCloser, error) {
+					return io.NopCloser(bytes.NewReader([]byte("not an image"))), nil
+				},
+			}}},
+			want: image.ErrFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.doc.Write(io.Discard)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Got %v; expected %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadInfo_NoInfo(t *testing.T) {
+	if _, err := ReadInfo(bytes.NewReader([]byte("%PDF-1.7\n"))); !errors.Is(err, ErrNoInfo) {
+		t.Errorf("Got %v; expected %v", err, ErrNoInfo)
+	}
+}
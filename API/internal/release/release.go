@@ -0,0 +1,164 @@
+// Package release parses the names of anime and TV releases, as found in torrent and file names
+package release
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	resolutionRegex = regexp.MustCompile(`(?i)\b(\d{3,4}p|4k)\b`)
+	// Anime releases start with the group in brackets, others end with it after a dash
+	leadingGroupRegex  = regexp.MustCompile(`^\s*\[([^\]]+)\]`)
+	trailingGroupRegex = regexp.MustCompile(`-([A-Za-z0-9]+)(?:\.[A-Za-z0-9]{2,4})?\s*$`)
+	extensionRegex     = regexp.MustCompile(`(?i)\.(?:mkv|mp4|m4v|avi|webm|ts|ass|ssa|srt|vtt|sub|idx|sup|nfo|torrent)$`)
+	tagRegex           = regexp.MustCompile(`\[[^\]]*\]|\([^)]*\)|\{[^}]*\}`)
+
+	// S01E05, S01E05-E06, S01E05E06
+	seasonEpisodeRegex = regexp.MustCompile(`(?i)\bS(\d{1,2})\s?E(\d{1,4})(?:-?E?(\d{1,4}))?\b`)
+	// 1x05
+	crossEpisodeRegex = regexp.MustCompile(`(?i)\b(\d{1,2})x(\d{2,3})\b`)
+	// Show - 05, Show - 05v2, Show - 01-12, Show - 01 ~ 12
+	absoluteEpisodeRegex = regexp.MustCompile(`(?i)\s-\s(\d{1,4})(?:v\d)?(?:\s*[-~]\s*(\d{1,4})(?:v\d)?)?(?:\s|$)`)
+	// Episode 05, Ep05
+	wordEpisodeRegex = regexp.MustCompile(`(?i)\b(?:episode|ep)\s?(\d{1,4})\b`)
+
+	seasonRegexes = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\bS(\d{1,2})\b`),
+		regexp.MustCompile(`(?i)\bseason\s?(\d{1,2})\b`),
+		regexp.MustCompile(`(?i)\b(\d{1,2})(?:st|nd|rd|th)\sseason\b`),
+	}
+
+	yearRegex    = regexp.MustCompile(`\s(?:19|20)\d{2}$`)
+	noiseRegex   = regexp.MustCompile(`(?i)\b(?:batch|complete)\b`)
+	spacesRegex  = regexp.MustCompile(`\s{2,}`)
+	titleTrimSet = " -_.~"
+)
+
+// Release holds the information found in a release name. Numbers are 0 when not present
+type Release struct {
+	Group   string
+	Title   string
+	Season  int
+	Episode int
+	// EpisodeEnd is the last episode of a batch or multi episode file, and equal to Episode otherwise
+	EpisodeEnd int
+	Resolution string
+}
+
+// HasEpisode returns true if an episode number was found
+func (r Release) HasEpisode() bool {
+	return r.Episode > 0
+}
+
+// IsBatch returns true if the release holds more than one episode
+func (r Release) IsBatch() bool {
+	return r.EpisodeEnd > r.Episode
+}
+
+// Parse extracts as much information as possible from the name. Absolute numbered anime releases have no
+// season, callers decide what season those belong to
+func Parse(name string) Release {
+	name = strings.TrimSpace(extensionRegex.ReplaceAllString(strings.TrimSpace(name), ""))
+
+	r := Release{
+		Group:      Group(name),
+		Resolution: Resolution(name),
+	}
+
+	clean := tagRegex.ReplaceAllString(name, " ")
+	// Scene releases use dots or underscores instead of spaces
+	if !strings.Contains(strings.TrimSpace(clean), " ") {
+		clean = strings.NewReplacer(".", " ", "_", " ").Replace(clean)
+	}
+	if r.Group != "" {
+		clean = strings.TrimSuffix(strings.TrimSpace(clean), "-"+r.Group)
+	}
+	clean = resolutionRegex.ReplaceAllString(clean, " ")
+	clean = spacesRegex.ReplaceAllString(clean, " ")
+
+	title := clean
+	switch {
+	case seasonEpisodeRegex.MatchString(clean):
+		m := seasonEpisodeRegex.FindStringSubmatchIndex(clean)
+		r.Season = atoi(clean[m[2]:m[3]])
+		r.Episode = atoi(clean[m[4]:m[5]])
+		if m[6] != -1 {
+			r.EpisodeEnd = atoi(clean[m[6]:m[7]])
+		}
+		title = clean[:m[0]]
+	case crossEpisodeRegex.MatchString(clean):
+		m := crossEpisodeRegex.FindStringSubmatchIndex(clean)
+		r.Season = atoi(clean[m[2]:m[3]])
+		r.Episode = atoi(clean[m[4]:m[5]])
+		title = clean[:m[0]]
+	case absoluteEpisodeRegex.MatchString(clean):
+		m := absoluteEpisodeRegex.FindStringSubmatchIndex(clean)
+		r.Episode = atoi(clean[m[2]:m[3]])
+		if m[4] != -1 {
+			r.EpisodeEnd = atoi(clean[m[4]:m[5]])
+		}
+		title = clean[:m[0]]
+	case wordEpisodeRegex.MatchString(clean):
+		m := wordEpisodeRegex.FindStringSubmatchIndex(clean)
+		r.Episode = atoi(clean[m[2]:m[3]])
+		title = clean[:m[0]]
+	}
+
+	for _, re := range seasonRegexes {
+		m := re.FindStringSubmatchIndex(title)
+		if m == nil {
+			continue
+		}
+
+		if r.Season == 0 {
+			r.Season = atoi(title[m[2]:m[3]])
+		}
+		title = title[:m[0]] + title[m[1]:]
+	}
+
+	// Seasons are sometimes only mentioned in a tag, i.e. (Season 2)
+	if r.Season == 0 {
+		for _, re := range seasonRegexes {
+			if m := re.FindStringSubmatch(strings.NewReplacer("(", " ", ")", " ", "[", " ", "]", " ").Replace(name)); m != nil {
+				r.Season = atoi(m[1])
+				break
+			}
+		}
+	}
+
+	if r.EpisodeEnd < r.Episode {
+		r.EpisodeEnd = r.Episode
+	}
+
+	title = noiseRegex.ReplaceAllString(title, " ")
+	title = spacesRegex.ReplaceAllString(strings.Trim(title, titleTrimSet), " ")
+	r.Title = strings.Trim(yearRegex.ReplaceAllString(title, ""), titleTrimSet)
+
+	return r
+}
+
+// Resolution returns the resolution in the release name, i.e. 1080p. Empty if not present
+func Resolution(name string) string {
+	return strings.ToLower(resolutionRegex.FindString(name))
+}
+
+// Group returns the group that made the release, empty if it can't be found in the name
+func Group(name string) string {
+	if m := leadingGroupRegex.FindStringSubmatch(name); m != nil {
+		return strings.TrimSpace(m[1])
+	}
+
+	// Numbers after a dash are episode ranges, not groups
+	if m := trailingGroupRegex.FindStringSubmatch(name); m != nil && strings.Trim(m[1], "0123456789") != "" {
+		return m[1]
+	}
+
+	return ""
+}
+
+func atoi(s string) int {
+	i, _ := strconv.Atoi(s)
+	return i
+}
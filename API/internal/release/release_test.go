@@ -0,0 +1,76 @@
+package release
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		want Release
+	}{
+		{
+			name: "[SubsPlease] Sousou no Frieren - 12 (1080p) [ABCD1234].mkv",
+			want: Release{Group: "SubsPlease", Title: "Sousou no Frieren", Episode: 12, EpisodeEnd: 12, Resolution: "1080p"},
+		},
+		{
+			name: "[Erai-raws] Spy x Family Season 2 - 05v2 [720p][Multiple Subtitle].mkv",
+			want: Release{Group: "Erai-raws", Title: "Spy x Family", Season: 2, Episode: 5, EpisodeEnd: 5, Resolution: "720p"},
+		},
+		{
+			name: "[Judas] Mushoku Tensei S2 - 01-12 (Batch) [1080p]",
+			want: Release{Group: "Judas", Title: "Mushoku Tensei", Season: 2, Episode: 1, EpisodeEnd: 12, Resolution: "1080p"},
+		},
+		{
+			name: "Frieren.Beyond.Journeys.End.S01E12.1080p.WEB.H264-VARYG.mkv",
+			want: Release{Group: "VARYG", Title: "Frieren Beyond Journeys End", Season: 1, Episode: 12, EpisodeEnd: 12, Resolution: "1080p"},
+		},
+		{
+			name: "The.Bear.2022.S02E01-E02.2160p.WEB-DL-NTb",
+			want: Release{Group: "NTb", Title: "The Bear", Season: 2, Episode: 1, EpisodeEnd: 2, Resolution: "2160p"},
+		},
+		{
+			name: "Show Name 3x07 720p",
+			want: Release{Title: "Show Name", Season: 3, Episode: 7, EpisodeEnd: 7, Resolution: "720p"},
+		},
+		{
+			name: "[Judas] Spice and Wolf (Season 1) [1080p][HEVC x265 10bit]",
+			want: Release{Group: "Judas", Title: "Spice and Wolf", Season: 1, Resolution: "1080p"},
+		},
+		{
+			name: "Spice and Wolf Season 1 Complete",
+			want: Release{Title: "Spice and Wolf", Season: 1},
+		},
+		{
+			name: "Bocchi the Rock! Episode 03",
+			want: Release{Title: "Bocchi the Rock!", Episode: 3, EpisodeEnd: 3},
+		},
+		{
+			name: "Show - 01-12",
+			want: Release{Title: "Show", Episode: 1, EpisodeEnd: 12},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.name); got != tt.want {
+				t.Errorf("Got %+v; expected %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroup(t *testing.T) {
+	tests := map[string]string{
+		"[Erai-raws] Frieren - 12 [1080p].mkv":          "Erai-raws",
+		"Frieren.S01E12.1080p.WEB.H264-VARYG":           "VARYG",
+		"Frieren.S01E12.1080p.WEB.H264-VARYG.mkv":       "VARYG",
+		"Spice and Wolf Season 1 Complete":              "",
+		"Spice and Wolf - 01-12":                        "",
+		"  [ Judas ] Spice and Wolf (Season 1) [1080p]": "Judas",
+	}
+
+	for name, want := range tests {
+		if got := Group(name); got != want {
+			t.Errorf("Group(%q) = %q; want %q", name, got, want)
+		}
+	}
+}
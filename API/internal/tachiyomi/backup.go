@@ -0,0 +1,255 @@
+// Package tachiyomi reads the backups of Tachiyomi, and its forks such as Mihon. Only the parts needed to find the
+// followed series are decoded, everything else in the backup is skipped
+package tachiyomi
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+var ErrInvalidBackup = errors.New("not a valid Tachiyomi backup")
+
+// maxBackupSize is the largest backup read, after decompressing. Backups are gzipped, so a small upload may
+// decompress to far more
+var maxBackupSize int64 = 256 << 20
+
+// Field numbers of the backup messages, as defined in the app
+const (
+	backupMangaField      protowire.Number = 1
+	backupCategoriesField protowire.Number = 2
+	backupSourcesField    protowire.Number = 101
+
+	mangaSourceField     protowire.Number = 1
+	mangaUrlField        protowire.Number = 2
+	mangaTitleField      protowire.Number = 3
+	mangaAuthorField     protowire.Number = 5
+	mangaStatusField     protowire.Number = 8
+	mangaCategoriesField protowire.Number = 17
+	mangaFavoriteField   protowire.Number = 100
+
+	categoryNameField  protowire.Number = 1
+	categoryOrderField protowire.Number = 2
+
+	sourceNameField protowire.Number = 1
+	sourceIdField   protowire.Number = 2
+)
+
+type Backup struct {
+	Manga      []Manga
+	Categories []Category
+	Sources    []Source
+}
+
+type Manga struct {
+	// Source is the id of the extension source the manga was added from
+	Source int64
+	// Url is the path of the manga on the source, some sources store the full url
+	Url    string
+	Title  string
+	Author string
+	Status int
+	// Categories are the Category.Order of the categories the manga is in
+	Categories []int64
+	// Favorite is false for manga that have been removed from the library, but still have history
+	Favorite bool
+}
+
+type Category struct {
+	Name  string
+	Order int64
+}
+
+type Source struct {
+	Name string
+	ID   int64
+}
+
+// Parse reads a backup, both gzipped (.tachibk, .proto.gz) and plain protobuf backups are supported
+func Parse(r io.Reader) (Backup, error) {
+	br := bufio.NewReader(r)
+
+	var src io.Reader = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return Backup{}, fmt.Errorf("%w: %w", ErrInvalidBackup, err)
+		}
+		defer gz.Close()
+		src = gz
+	}
+
+	data, err := io.ReadAll(io.LimitReader(src, maxBackupSize+1))
+	if err != nil {
+		return Backup{}, fmt.Errorf("%w: %w", ErrInvalidBackup, err)
+	}
+	if int64(len(data)) > maxBackupSize {
+		return Backup{}, fmt.Errorf("%w: larger than %d bytes", ErrInvalidBackup, maxBackupSize)
+	}
+
+	if len(data) == 0 {
+		return Backup{}, ErrInvalidBackup
+	}
+
+	var backup Backup
+	err = consumeMessage(data, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+
+		switch num {
+		case backupMangaField:
+			manga, err := parseManga(value)
+			if err != nil {
+				return err
+			}
+			backup.Manga = append(backup.Manga, manga)
+		case backupCategoriesField:
+			category, err := parseCategory(value)
+			if err != nil {
+				return err
+			}
+			backup.Categories = append(backup.Categories, category)
+		case backupSourcesField:
+			source, err := parseSource(value)
+			if err != nil {
+				return err
+			}
+			backup.Sources = append(backup.Sources, source)
+		}
+		return nil
+	})
+	if err != nil {
+		return Backup{}, fmt.Errorf("%w: %w", ErrInvalidBackup, err)
+	}
+
+	return backup, nil
+}
+
+// SourceName returns the name of the source with id, empty if the backup doesn't include it
+func (b Backup) SourceName(id int64) string {
+	for _, source := range b.Sources {
+		if source.ID == id {
+			return source.Name
+		}
+	}
+	return ""
+}
+
+// CategoryNames returns the names of the categories the manga is in
+func (b Backup) CategoryNames(manga Manga) []string {
+	names := make([]string, 0, len(manga.Categories))
+	for _, order := range manga.Categories {
+		for _, category := range b.Categories {
+			if category.Order == order {
+				names = append(names, category.Name)
+				break
+			}
+		}
+	}
+	return names
+}
+
+func parseManga(data []byte) (Manga, error) {
+	// Favorite is only written when it differs from its default
+	manga := Manga{Favorite: true}
+	err := consumeMessage(data, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		switch {
+		case num == mangaSourceField && typ == protowire.VarintType:
+			manga.Source = int64(decodeVarint(value))
+		case num == mangaUrlField && typ == protowire.BytesType:
+			manga.Url = string(value)
+		case num == mangaTitleField && typ == protowire.BytesType:
+			manga.Title = string(value)
+		case num == mangaAuthorField && typ == protowire.BytesType:
+			manga.Author = string(value)
+		case num == mangaStatusField && typ == protowire.VarintType:
+			manga.Status = int(decodeVarint(value))
+		case num == mangaCategoriesField && typ == protowire.VarintType:
+			manga.Categories = append(manga.Categories, int64(decodeVarint(value)))
+		case num == mangaCategoriesField && typ == protowire.BytesType:
+			// Packed repeated field
+			for len(value) > 0 {
+				v, n := protowire.ConsumeVarint(value)
+				if n < 0 {
+					return protowire.ParseError(n)
+				}
+				manga.Categories = append(manga.Categories, int64(v))
+				value = value[n:]
+			}
+		case num == mangaFavoriteField && typ == protowire.VarintType:
+			manga.Favorite = protowire.DecodeBool(decodeVarint(value))
+		}
+		return nil
+	})
+	return manga, err
+}
+
+func parseCategory(data []byte) (Category, error) {
+	var category Category
+	err := consumeMessage(data, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		switch {
+		case num == categoryNameField && typ == protowire.BytesType:
+			category.Name = string(value)
+		case num == categoryOrderField && typ == protowire.VarintType:
+			category.Order = int64(decodeVarint(value))
+		}
+		return nil
+	})
+	return category, err
+}
+
+func parseSource(data []byte) (Source, error) {
+	var source Source
+	err := consumeMessage(data, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		switch {
+		case num == sourceNameField && typ == protowire.BytesType:
+			source.Name = string(value)
+		case num == sourceIdField && typ == protowire.VarintType:
+			source.ID = int64(decodeVarint(value))
+		}
+		return nil
+	})
+	return source, err
+}
+
+// consumeMessage calls f for each field of the message. Varint values are passed still encoded, bytes values
+// without their length prefix. Groups and fixed width fields are passed as is
+func consumeMessage(data []byte, f func(protowire.Number, protowire.Type, []byte) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		n = protowire.ConsumeFieldValue(num, typ, data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+
+		value := data[:n]
+		if typ == protowire.BytesType {
+			var m int
+			value, m = protowire.ConsumeBytes(value)
+			if m < 0 {
+				return protowire.ParseError(m)
+			}
+		}
+
+		if err := f(num, typ, value); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+func decodeVarint(data []byte) uint64 {
+	v, _ := protowire.ConsumeVarint(data)
+	return v
+}
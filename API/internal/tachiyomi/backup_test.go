@@ -0,0 +1,151 @@
+package tachiyomi
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"slices"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendMessage(b []byte, num protowire.Number, m []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, m)
+}
+
+func testBackup() []byte {
+	var manga []byte
+	manga = appendVarint(manga, mangaSourceField, 2499283573021220255)
+	manga = appendString(manga, mangaUrlField, "/manga/a96676e5-8ae2-425e-b549-7f15dd34a6d8")
+	manga = appendString(manga, mangaTitleField, "Komi-san wa Komyushou desu")
+	manga = appendString(manga, mangaAuthorField, "Oda Tomohito")
+	manga = appendVarint(manga, mangaStatusField, 2)
+	// Chapters and other fields are skipped
+	manga = appendMessage(manga, 16, appendString(nil, 1, "/chapter/1"))
+	manga = appendVarint(manga, mangaCategoriesField, 0)
+	manga = appendVarint(manga, mangaCategoriesField, 2)
+
+	var removed []byte
+	removed = appendVarint(removed, mangaSourceField, 1)
+	removed = appendString(removed, mangaTitleField, "Removed")
+	removed = appendVarint(removed, mangaFavoriteField, 0)
+	// Packed categories
+	removed = appendMessage(removed, mangaCategoriesField, protowire.AppendVarint(nil, 1))
+
+	var backup []byte
+	backup = appendMessage(backup, backupMangaField, manga)
+	backup = appendMessage(backup, backupMangaField, removed)
+	for i, name := range []string{"Reading", "Plan to read", "Weekly"} {
+		backup = appendMessage(backup, backupCategoriesField,
+			appendVarint(appendString(nil, categoryNameField, name), categoryOrderField, uint64(i)))
+	}
+	backup = appendMessage(backup, backupSourcesField,
+		appendVarint(appendString(nil, sourceNameField, "MangaDex"), sourceIdField, 2499283573021220255))
+
+	return backup
+}
+
+func TestParse(t *testing.T) {
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	if _, err := gz.Write(testBackup()); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, data := range map[string][]byte{"plain": testBackup(), "gzip": gzipped.Bytes()} {
+		t.Run(name, func(t *testing.T) {
+			backup, err := Parse(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(backup.Manga) != 2 || len(backup.Categories) != 3 || len(backup.Sources) != 1 {
+				t.Fatalf("Got %+v; expected 2 manga, 3 categories and 1 source", backup)
+			}
+
+			manga := backup.Manga[0]
+			if manga.Title != "Komi-san wa Komyushou desu" || manga.Author != "Oda Tomohito" || manga.Status != 2 ||
+				manga.Url != "/manga/a96676e5-8ae2-425e-b549-7f15dd34a6d8" || !manga.Favorite {
+				t.Errorf("Got %+v; expected the manga to be decoded", manga)
+			}
+			if name := backup.SourceName(manga.Source); name != "MangaDex" {
+				t.Errorf("Got source %q; expected MangaDex", name)
+			}
+			if got := backup.CategoryNames(manga); !slices.Equal(got, []string{"Reading", "Weekly"}) {
+				t.Errorf("Got categories %v; expected Reading and Weekly", got)
+			}
+
+			removed := backup.Manga[1]
+			if removed.Favorite {
+				t.Error("Expected the removed manga not to be a favorite")
+			}
+			if got := backup.CategoryNames(removed); !slices.Equal(got, []string{"Plan to read"}) {
+				t.Errorf("Got categories %v; expected the packed categories to be decoded", got)
+			}
+			if name := backup.SourceName(removed.Source); name != "" {
+				t.Errorf("Got source %q; expected unknown sources to have no name", name)
+			}
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"empty":     {},
+		"truncated": testBackup()[:20],
+		"gzip":      {0x1f, 0x8b, 0x00},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Parse(bytes.NewReader(data)); !errors.Is(err, ErrInvalidBackup) {
+				t.Errorf("Got %v; expected ErrInvalidBackup", err)
+			}
+		})
+	}
+}
+
+func TestParse_TooLarge(t *testing.T) {
+	limit := maxBackupSize
+	t.Cleanup(func() {
+		maxBackupSize = limit
+	})
+
+	// Concatenated backups are still a valid backup
+	var backup []byte
+	for len(backup) <= 1<<10 {
+		backup = append(backup, testBackup()...)
+	}
+
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	if _, err := gz.Write(backup); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	maxBackupSize = int64(len(backup))
+	if _, err := Parse(bytes.NewReader(gzipped.Bytes())); err != nil {
+		t.Fatalf("Got %v; expected backups within the limit to be read", err)
+	}
+
+	maxBackupSize = 1 << 10
+	if _, err := Parse(bytes.NewReader(gzipped.Bytes())); !errors.Is(err, ErrInvalidBackup) {
+		t.Errorf("Got %v; expected backups decompressing past the limit to be refused", err)
+	}
+}
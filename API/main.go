@@ -70,6 +70,7 @@ func main() {
 	utils.Must(c.Provide(services.CacheServiceProvider))
 	utils.Must(c.Provide(services.DirectoryServiceProvider))
 	utils.Must(c.Provide(services.ArchiveServiceProvider))
+	utils.Must(c.Provide(services.QueueServiceProvider))
 	utils.Must(c.Provide(services.SettingsServiceProvider))
 	utils.Must(c.Provide(services.UserServiceProvider))
 	utils.Must(c.Provide(applicationProvider))
@@ -77,6 +78,7 @@ func main() {
 	utils.Must(c.Invoke(services.RegisterSignalREndPoint))
 	utils.Must(c.Invoke(registerCallback))
 	utils.Must(c.Invoke(providers.RegisterProviders))
+	utils.Must(c.Invoke(services.ResumeQueuedContent))
 	utils.Must(c.Invoke(updateBaseUrlInIndex))
 	utils.Must(c.Invoke(updateInstalledVersion))
 
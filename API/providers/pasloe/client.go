@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"path"
 	"strings"
 	"sync"
@@ -20,9 +21,13 @@ import (
 	"go.uber.org/dig"
 )
 
+// errDownloadCancelled is recorded on subscription runs whose content was removed before it finished
+var errDownloadCancelled = errors.New("download was cancelled")
+
 func New(s services.SettingsService, container *dig.Container, log zerolog.Logger,
 	dirService services.DirectoryService, signalR services.SignalRService, notify services.NotificationService,
 	unitOfWork *db.UnitOfWork, transLoco services.TranslocoService, fs afero.Afero, ctx context.Context,
+	queueService services.QueueService,
 ) (publication.Client, error) {
 	settings, err := s.GetSettingsDto(ctx)
 	if err != nil {
@@ -41,9 +46,11 @@ func New(s services.SettingsService, container *dig.Container, log zerolog.Logge
 		notify:     notify,
 		unitOfWork: unitOfWork,
 		transLoco:  transLoco,
+		queue:      queueService,
 		fs:         fs,
 
 		content:        utils.NewSafeMap[string, publication.Publication](),
+		reorganising:   utils.NewSafeMap[string, struct{}](),
 		providerQueues: utils.NewSafeMap[models.Provider, *ProviderQueue](),
 		rootDir:        settings.RootDir,
 		ctx:            ctx,
@@ -61,12 +68,15 @@ type client struct {
 	signalR    services.SignalRService
 	notify     services.NotificationService
 	transLoco  services.TranslocoService
+	queue      services.QueueService
 	unitOfWork *db.UnitOfWork
 	fs         afero.Afero
 
 	rootDir string
 
-	content        utils.SafeMap[string, publication.Publication]
+	content utils.SafeMap[string, publication.Publication]
+	// reorganising holds the ids of content being reorganised, these cannot be downloaded at the same time
+	reorganising   utils.SafeMap[string, struct{}]
 	providerQueues utils.SafeMap[models.Provider, *ProviderQueue]
 	mu             sync.RWMutex
 
@@ -100,16 +110,38 @@ func (c *client) getOrCreateProviderQueue(provider models.Provider) *ProviderQue
 
 // Download queues content to be downloaded
 func (c *client) Download(req payload.DownloadRequest) error {
-	if c.content.Has(req.Id) {
+	return c.download(req, nil)
+}
+
+// Resume queues content persisted before a restart, with the users' selection restored
+func (c *client) Resume(req services.QueuedRequest) error {
+	return c.download(req.Request, req.UserSelection)
+}
+
+func (c *client) download(req payload.DownloadRequest, userSelection []string) error {
+	if c.busy(req.Id) {
 		return c.wrapError(services.ErrContentAlreadyExists)
 	}
 
+	// Created outside the lock, this goes through the DI scope and reads settings from the db
 	content, err := c.registry.Create(c, req)
 	if err != nil {
 		return c.wrapError(err)
 	}
 
+	if len(userSelection) > 0 {
+		content.RestoreUserSelection(userSelection)
+	}
+
+	// Checked again and set under the same lock as Reorganise, so content is never downloaded and reorganised at once
+	c.mu.Lock()
+	if c.content.Has(req.Id) || c.reorganising.Has(req.Id) {
+		c.mu.Unlock()
+		return c.wrapError(services.ErrContentAlreadyExists)
+	}
 	c.content.Set(content.Id(), content)
+	c.mu.Unlock()
+
 	c.signalR.AddContent(content.Request().OwnerId, content.GetInfo())
 
 	pq := c.getOrCreateProviderQueue(content.Provider())
@@ -121,9 +153,54 @@ func (c *client) Download(req payload.DownloadRequest) error {
 		return c.wrapError(err)
 	}
 
+	c.Persist(content)
 	return nil
 }
 
+// Persist saves the content, so it's resumed after a restart. Content is checked and saved under the same lock
+// RemoveDownload removes it under, so removed content is never saved again
+func (c *client) Persist(content publication.Publication) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !c.content.Has(content.Id()) {
+		return
+	}
+
+	if err := c.queue.Save(c.clientCtx, content, content.UserSelection()); err != nil {
+		c.log.Warn().Err(err).Str("id", content.Id()).
+			Msg("failed to persist queued content, it will not be resumed after a restart")
+	}
+}
+
+// busy returns true if the content with id is being downloaded or reorganised
+func (c *client) busy(id string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.content.Has(id) || c.reorganising.Has(id)
+}
+
+// Reorganise moves the content of the request on disk to its current naming scheme. Content that is being
+// downloaded cannot be reorganised
+func (c *client) Reorganise(ctx context.Context, req payload.DownloadRequest, dryRun bool) (payload.ReorganiseReport, error) {
+	c.mu.Lock()
+	if c.content.Has(req.Id) || c.reorganising.Has(req.Id) {
+		c.mu.Unlock()
+		return payload.ReorganiseReport{}, c.wrapError(services.ErrContentAlreadyExists)
+	}
+	c.reorganising.Set(req.Id, struct{}{})
+	c.mu.Unlock()
+
+	defer c.reorganising.Delete(req.Id)
+
+	content, err := c.registry.Create(c, req)
+	if err != nil {
+		return payload.ReorganiseReport{}, c.wrapError(err)
+	}
+
+	return content.Reorganise(ctx, dryRun)
+}
+
 // MoveToDownloadQueue forcefully move content with the given id to the download queue
 func (c *client) MoveToDownloadQueue(id string) error {
 	content, ok := c.content.Get(id)
@@ -142,7 +219,14 @@ func (c *client) RemoveDownload(req payload.StopRequest) error {
 		return c.wrapError(services.ErrContentNotFound)
 	}
 
+	c.mu.Lock()
 	c.content.Delete(content.Id())
+	err := c.queue.Remove(c.clientCtx, content)
+	c.mu.Unlock()
+
+	if err != nil {
+		c.log.Warn().Err(err).Str("id", req.Id).Msg("failed to remove persisted queued content")
+	}
 
 	c.log.Info().
 		Str("id", req.Id).
@@ -158,15 +242,20 @@ func (c *client) RemoveDownload(req payload.StopRequest) error {
 		c.signalR.StateUpdate(content.Request().OwnerId, content.Id(), payload.ContentStateCleanup)
 
 		if req.DeleteFiles {
-			c.deleteFiles(content)
+			c.deleteFiles(content, req.KeepPages)
+			c.finishRun(content, true)
 		} else {
 			c.logContentCompletion(content)
-			c.cleanup(content)
+			cleanupErrs := c.cleanup(content)
+			c.finishRun(content, false, cleanupErrs...)
 
 			downloadDir := strings.TrimSpace(content.GetDownloadDir())
 			if content.Request().IsSubscription && downloadDir != "" {
 				sub := content.Request().Sub
 				sub.LastDownloadDir = path.Join(c.GetBaseDir(), downloadDir)
+				sub.FailedChapters = utils.Map(content.FailedChapters(), func(f publication.FailedChapter) string {
+					return f.Id
+				})
 
 				if err := c.unitOfWork.Subscriptions.Update(c.ctx, *sub); err != nil {
 					c.log.Error().Err(err).Msg("Failed to update subscription")
@@ -188,23 +277,36 @@ func (c *client) Content(id string) services.Content {
 	return content
 }
 
-// Shutdown gracefully shuts down the client
+// Shutdown gracefully shuts down the client. Content is persisted, and resumed on the next start.
+// Files already written are kept, so partially downloaded content does not have to start over
 func (c *client) Shutdown() error {
 	c.log.Debug().Msg("pasloe shutting down")
 
+	// Save before cancelling, cancelled content may change state while stopping
+	contents := c.content.Values()
+	for _, content := range contents {
+		if err := c.queue.Save(c.clientCtx, content, content.UserSelection()); err != nil {
+			c.log.Warn().Err(err).Str("id", content.Id()).Msg("failed to persist queued content")
+		}
+	}
+
+	// Removing content from the client first prevents the content from removing itself, and its files, on cancel
+	c.content.Clear()
 	c.cancel()
 
 	c.providerQueues.ForEach(func(k models.Provider, v *ProviderQueue) {
 		v.Shutdown()
 	})
 
-	c.content.ForEach(func(k string, v publication.Publication) {
-		if err := c.RemoveDownload(payload.StopRequest{Id: k, DeleteFiles: true, Provider: v.Provider()}); err != nil {
-			c.log.Warn().Err(err).Msg("failed to remove download")
-		}
-	})
+	for _, content := range contents {
+		c.deletionWg.Add(1)
+		go func() {
+			defer c.deletionWg.Done()
+			content.Cancel()
+		}()
+	}
 
-	c.log.Debug().Msg("Stop requests send out, waiting for all deletion to finish")
+	c.log.Debug().Msg("Cancelled all content, waiting for downloads to stop")
 	utils.WaitFor(c.deletionWg, time.Second*45)
 
 	c.log.Debug().Msg("pasloe shutdown complete")
@@ -224,8 +326,9 @@ func (c *client) alwaysLog(userId int) bool {
 
 func (c *client) logContentCompletion(content publication.Publication) {
 	alwaysLog := c.alwaysLog(content.Request().OwnerId)
+	failedChapters := content.FailedChapters()
 
-	if len(content.GetNewContent()) == 0 && !alwaysLog {
+	if len(content.GetNewContent()) == 0 && len(failedChapters) == 0 && !alwaysLog {
 		return
 	}
 
@@ -242,10 +345,17 @@ func (c *client) logContentCompletion(content publication.Publication) {
 		summary += c.transLoco.GetTranslation("failed-downloads", content.FailedDownloads())
 	}
 
+	if len(failedChapters) > 0 {
+		summary += c.transLoco.GetTranslation("failed-chapters", len(failedChapters))
+	}
+
 	body = summary
 	for _, newContent := range content.GetNewContentNamed() {
 		body += c.transLoco.GetTranslation("content-line", path.Base(newContent))
 	}
+	for _, failed := range failedChapters {
+		body += c.transLoco.GetTranslation("failed-chapter-line", failed.Label, failed.Err)
+	}
 
 	c.notifier(content.Request()).Notify(c.clientCtx, models.NewNotification().
 		WithTitle(c.transLoco.GetTranslation("download-finished-title")).
@@ -273,7 +383,7 @@ func (c *client) notifier(req payload.DownloadRequest) services.Notifier {
 	return c.signalR
 }
 
-func (c *client) deleteFiles(content publication.Publication) {
+func (c *client) deleteFiles(content publication.Publication, keepPages bool) {
 	defer c.signalR.DeleteContent(content.Request().OwnerId, content.Id())
 
 	downloadDir := strings.TrimSpace(content.GetDownloadDir())
@@ -291,7 +401,7 @@ func (c *client) deleteFiles(content publication.Publication) {
 	// will be completely spammed if an error would occur here
 	if ok, err := c.fs.DirExists(dir); ok && err == nil {
 
-		cleanupErrs := c.deleteNewContent(content, l)
+		cleanupErrs := c.deleteNewContent(content, keepPages, l)
 		cleanupErrs = append(cleanupErrs, c.deleteEmptyDirectories(dir, l)...)
 
 		c.notifyCleanUpError(content, cleanupErrs...)
@@ -300,11 +410,20 @@ func (c *client) deleteFiles(content publication.Publication) {
 	l.Debug().Dur("elapsed", time.Since(start)).Msg("finished removing newly downloaded files")
 }
 
-func (c *client) deleteNewContent(content publication.Publication, l zerolog.Logger) (cleanupErrs []error) {
+// deleteNewContent removes the directories of new content. If keepPages is true, only the partially written files
+// are removed, so the chapters resume when the content is downloaded again
+func (c *client) deleteNewContent(content publication.Publication, keepPages bool, l zerolog.Logger) (cleanupErrs []error) {
 	for _, contentPath := range content.GetNewContent() {
-		l.Trace().Str("path", contentPath).Msg("deleting new content dir")
+		l.Trace().Str("path", contentPath).Bool("keepPages", keepPages).Msg("deleting new content dir")
 
-		if err := c.fs.RemoveAll(contentPath); err != nil {
+		var err error
+		if keepPages {
+			err = publication.RemovePartialChapter(c.fs, contentPath)
+		} else {
+			err = c.fs.RemoveAll(contentPath)
+		}
+
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
 			l.Error().Err(err).Str("path", contentPath).Msg("error while removing new content dir")
 			cleanupErrs = append(cleanupErrs, fmt.Errorf("error removing new content dir %s: %w", contentPath, err))
 		}
@@ -364,13 +483,13 @@ func (c *client) deleteEmptyDirectories(dir string, l zerolog.Logger) (cleanupEr
 	return cleanupErrs
 }
 
-func (c *client) cleanup(content publication.Publication) {
+func (c *client) cleanup(content publication.Publication) []error {
 	defer c.signalR.DeleteContent(content.Request().OwnerId, content.Id())
 
 	l := c.log.With().Str("contentId", content.Id()).Logger()
 	if len(content.GetNewContent()) == 0 {
 		l.Debug().Msg("no new content to delete")
-		return
+		return nil
 	}
 
 	start := time.Now()
@@ -384,6 +503,35 @@ func (c *client) cleanup(content publication.Publication) {
 	}
 
 	l.Debug().Dur("elapsed", time.Since(start)).Msg("finished cleanup")
+	return cleanupErrs
+}
+
+// finishRun records the outcome of the subscription run the content was downloaded for, if any. Nothing is counted
+// as downloaded for cancelled content, as its files have been removed
+func (c *client) finishRun(content publication.Publication, cancelled bool, errs ...error) {
+	sub := content.Request().Sub
+	if sub == nil || sub.Run == nil {
+		return
+	}
+
+	run := sub.Run
+	if cancelled {
+		if len(run.Errors) == 0 {
+			run.AddError(errDownloadCancelled)
+		}
+	} else {
+		run.Downloaded = len(content.GetNewContentNamed())
+		run.Files = content.WrittenFiles()
+		for _, failed := range content.FailedChapters() {
+			run.Skip(failed.Label, models.SkipFailed)
+			run.AddError(fmt.Errorf("%s: %w", failed.Label, failed.Err))
+		}
+	}
+
+	run.Finish(errs...)
+	if err := c.unitOfWork.SubscriptionRuns.Update(c.ctx, *run); err != nil {
+		c.log.Error().Err(err).Int("id", sub.ID).Msg("Failed to record subscription run")
+	}
 }
 
 func (c *client) removeOldContent(content publication.Publication, l zerolog.Logger) (cleanupErrs []error) {
@@ -145,6 +145,33 @@ func (b *Builder) DownloadMetadata() payload.DownloadMetadata {
 				FormType:      payload.SWITCH,
 				DefaultOption: "true",
 			},
+			{
+				Key:           publication.OutputFormatKey,
+				Advanced:      true,
+				FormType:      payload.DROPDOWN,
+				DefaultOption: string(publication.FormatCbz),
+				Options:       publication.FormatOptions,
+			},
+			{
+				Key:      publication.SkipFailedChaptersKey,
+				Advanced: true,
+				FormType: payload.SWITCH,
+			},
+			{
+				Key:      publication.FileNameTemplateKey,
+				Advanced: true,
+				FormType: payload.TEXT,
+			},
+			{
+				Key:      publication.VolumeDirTemplateKey,
+				Advanced: true,
+				FormType: payload.TEXT,
+			},
+			{
+				Key:      publication.WriteMetronInfoKey,
+				Advanced: true,
+				FormType: payload.SWITCH,
+			},
 			{
 				Key:      publication.UpdateCover,
 				Advanced: true,
@@ -97,6 +97,7 @@ func (p *publication) SetUserFiltered(msg json.RawMessage) error {
 	}
 	p.toDownloadUserSelected = filter
 	p.signalR.SizeUpdate(p.req.OwnerId, p.Id(), p.readableSize())
+	p.persist()
 	return nil
 }
 
@@ -3,12 +3,22 @@ package publication
 import (
 	"context"
 	"fmt"
+	"image"
+	"io"
 	"path"
 	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/Fesaa/Media-Provider/internal/comicinfo"
+	"github.com/Fesaa/Media-Provider/internal/epub"
+	"github.com/Fesaa/Media-Provider/internal/naming"
+	"github.com/Fesaa/Media-Provider/internal/pdf"
 	"github.com/Fesaa/Media-Provider/utils"
+	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 )
 
@@ -30,16 +40,97 @@ type Extensions struct {
 	volumeFunc         volumeFunc
 }
 
+// Format is the file format finished content is written in
+type Format string
+
+const (
+	FormatCbz       Format = "cbz"
+	FormatCbzStream Format = "cbz-stream"
+	FormatEpub      Format = "epub"
+	FormatPdf       Format = "pdf"
+)
+
+// FormatOptions are the options for the OutputFormatKey dropdown
+var FormatOptions = []payload.MetadataOption{
+	{Key: string(FormatCbz), Value: "CBZ"},
+	{Key: string(FormatCbzStream), Value: "CBZ (streamed)"},
+	{Key: string(FormatEpub), Value: "EPUB (fixed layout)"},
+	{Key: string(FormatPdf), Value: "PDF"},
+}
+
+// FormatFor returns the requested output format, defaults to FormatCbz
+func FormatFor(req payload.DownloadRequest) Format {
+	return Format(req.GetStringOrDefault(OutputFormatKey, string(FormatCbz)))
+}
+
+// Ext returns the extension of the files written in the format
+func (f Format) Ext() string {
+	switch f {
+	case FormatEpub:
+		return ".epub"
+	case FormatPdf:
+		return ".pdf"
+	default:
+		return ".cbz"
+	}
+}
+
+// ExtensionsFor returns the Extensions matching the requested output format, defaults to CbzExt
+func ExtensionsFor(req payload.DownloadRequest) Extensions {
+	switch FormatFor(req) {
+	case FormatCbzStream:
+		return CbzStreamExt()
+	case FormatEpub:
+		return EpubExt()
+	case FormatPdf:
+		return PdfExt()
+	default:
+		return CbzExt()
+	}
+}
+
 func CbzExt() Extensions {
 	return Extensions{
 		ioTaskFunc:         imageIoTask,
 		contentCleanupFunc: cbzCleanup,
-		isContentFunc:      isCbz,
-		volumeFunc:         getVolumeFromComicInfo,
+		isContentFunc:      isContent,
+		volumeFunc:         getVolume,
 	}
 }
 
-func imageIoTask(p *publication, ctx context.Context, log zerolog.Logger, task ioTask) error {
+// CbzStreamExt writes pages straight into the cbz archive, instead of zipping the chapter directory afterward
+func CbzStreamExt() Extensions {
+	return Extensions{
+		ioTaskFunc:         cbzStreamIoTask,
+		contentCleanupFunc: cbzStreamCleanup,
+		isContentFunc:      isContent,
+		volumeFunc:         getVolume,
+	}
+}
+
+func EpubExt() Extensions {
+	return Extensions{
+		ioTaskFunc:         imageIoTask,
+		contentCleanupFunc: epubCleanup,
+		isContentFunc:      isContent,
+		volumeFunc:         getVolume,
+	}
+}
+
+func PdfExt() Extensions {
+	return Extensions{
+		ioTaskFunc:         imageIoTask,
+		contentCleanupFunc: pdfCleanup,
+		isContentFunc:      isContent,
+		volumeFunc:         getVolume,
+	}
+}
+
+// partialPageExt is appended to pages while they're being written
+const partialPageExt = ".part"
+
+// pageFile returns the file name and content of the page, converted to webp if the user prefers so
+func pageFile(p *publication, ctx context.Context, task ioTask) (string, []byte) {
 	data := task.Data
 	ok := false
 
@@ -49,7 +140,12 @@ func imageIoTask(p *publication, ctx context.Context, log zerolog.Logger, task i
 	}
 
 	ext := utils.Ternary(ok, ".webp", utils.Ext(task.Task.Url.Url))
-	filePath := path.Join(task.Path, fmt.Sprintf("page %s"+ext, utils.PadInt(task.Task.Idx, 4)))
+	return fmt.Sprintf("page %s"+ext, utils.PadInt(task.Task.Idx, 4)), data
+}
+
+func imageIoTask(p *publication, ctx context.Context, log zerolog.Logger, task ioTask) error {
+	name, data := pageFile(p, ctx, task)
+	filePath := path.Join(task.Path, name)
 
 	select {
 	case <-ctx.Done():
@@ -57,7 +153,11 @@ func imageIoTask(p *publication, ctx context.Context, log zerolog.Logger, task i
 	default:
 	}
 
-	err = p.fs.WriteFile(filePath, data, 0755)
+	// Write to a temporary file first, any page on disk is complete and can be skipped when resuming
+	err := p.fs.WriteFile(filePath+partialPageExt, data, 0755)
+	if err == nil {
+		err = p.fs.Rename(filePath+partialPageExt, filePath)
+	}
 	if err == nil {
 		return nil
 	}
@@ -72,37 +172,103 @@ func imageIoTask(p *publication, ctx context.Context, log zerolog.Logger, task i
 	return err
 }
 
-var (
-	contentVolumeAndChapterRegex = regexp.MustCompile(".* (?:Vol\\. ([\\d\\.]+)) (?:Ch)\\. ([\\d\\.]+).cbz")
-	contentChapterRegex          = regexp.MustCompile(".* Ch\\. ([\\d\\.]+).cbz")
-	contentVolumeRegex           = regexp.MustCompile(".* Vol\\. ([\\d\\.]+).cbz")
-)
+// contentMatcher recognises downloaded content with a specific file extension
+type contentMatcher struct {
+	ext string
+	// rgxs are tried in order, the volume and chapter are read from the "volume" and "chapter" groups
+	rgxs []*regexp.Regexp
+}
 
-func isCbz(name string) (Content, bool) {
-	matches := contentVolumeAndChapterRegex.FindStringSubmatch(name)
-	if len(matches) == 3 {
-		return Content{
-			Volume:  utils.TrimLeadingZero(matches[1]),
-			Chapter: utils.TrimLeadingZero(matches[2]),
-		}, true
-	}
+// newContentMatcher returns a matcher for the default file names. File names rendered by the given templates
+// are tried first
+func newContentMatcher(ext string, templates ...*naming.Template) contentMatcher {
+	quotedExt := regexp.QuoteMeta(ext)
 
-	matches = contentVolumeRegex.FindStringSubmatch(name)
-	if len(matches) == 2 {
-		return Content{
-			Volume: utils.TrimLeadingZero(matches[1]),
-		}, true
+	rgxs := utils.Map(templates, func(t *naming.Template) *regexp.Regexp {
+		return t.Regexp(ext)
+	})
+	rgxs = append(rgxs,
+		regexp.MustCompile(".* (?:Vol\\. (?P<volume>[\\d\\.]+)) (?:Ch)\\. (?P<chapter>[\\d\\.]+)"+quotedExt),
+		regexp.MustCompile(".* Vol\\. (?P<volume>[\\d\\.]+)"+quotedExt),
+		regexp.MustCompile(".* Ch\\. (?P<chapter>[\\d\\.]+)"+quotedExt),
+	)
+
+	return contentMatcher{
+		ext:  ext,
+		rgxs: rgxs,
 	}
+}
+
+func (m contentMatcher) match(name string) (Content, bool) {
+	for _, rgx := range m.rgxs {
+		matches := rgx.FindStringSubmatch(name)
+		if matches == nil {
+			continue
+		}
+
+		var volume, chapter string
+		if idx := rgx.SubexpIndex("volume"); idx != -1 {
+			volume = matches[idx]
+		}
+		if idx := rgx.SubexpIndex("chapter"); idx != -1 {
+			chapter = matches[idx]
+		}
+
+		// Templates may leave the volume and chapter in optional sections, a name matching without either
+		// isn't identified by the template. The next regexes, and the default names, are tried instead
+		if volume == "" && chapter == "" {
+			continue
+		}
 
-	matches = contentChapterRegex.FindStringSubmatch(name)
-	if len(matches) == 2 {
 		return Content{
-			Chapter: utils.TrimLeadingZero(matches[1]),
+			Volume:  utils.TrimLeadingZero(volume),
+			Chapter: utils.TrimLeadingZero(chapter),
 		}, true
 	}
 
 	// Fallback to simple ext check
-	return Content{}, filepath.Ext(name) == ".cbz"
+	return Content{}, filepath.Ext(name) == m.ext
+}
+
+var (
+	cbzMatcher  = newContentMatcher(".cbz")
+	epubMatcher = newContentMatcher(".epub")
+	pdfMatcher  = newContentMatcher(".pdf")
+)
+
+func isCbz(name string) (Content, bool) {
+	return cbzMatcher.match(name)
+}
+
+func isEpub(name string) (Content, bool) {
+	return epubMatcher.match(name)
+}
+
+func isPdf(name string) (Content, bool) {
+	return pdfMatcher.match(name)
+}
+
+// isContent recognises content in any of the supported formats. Used by all extensions, so changing the
+// output format of a series doesn't cause everything to be downloaded again
+func isContent(name string) (Content, bool) {
+	for _, f := range []isContentFunc{isCbz, isEpub, isPdf} {
+		if c, ok := f(name); ok {
+			return c, true
+		}
+	}
+	return Content{}, false
+}
+
+// getVolume reads the volume from the metadata of the content, based on its file extension
+func getVolume(p *publication, content Content) (string, error) {
+	switch filepath.Ext(content.Path) {
+	case ".epub":
+		return getVolumeFromEpub(p, content)
+	case ".pdf":
+		return getVolumeFromPdf(p, content)
+	default:
+		return getVolumeFromComicInfo(p, content)
+	}
 }
 
 func getVolumeFromComicInfo(p *publication, content Content) (string, error) {
@@ -122,3 +288,232 @@ func cbzCleanup(p *publication, path string) error {
 
 	return p.fs.RemoveAll(path)
 }
+
+func getVolumeFromEpub(p *publication, content Content) (string, error) {
+	fullPath := path.Join(p.client.GetBaseDir(), content.Path)
+	metadata, err := p.archiveService.GetEpubMetadata(fullPath)
+	if err != nil {
+		return "", err
+	}
+
+	return metadata.SeriesIndex, nil
+}
+
+// epubCleanup converts the downloaded directory into a fixed layout epub. Metadata is taken from the ComicInfo.xml
+// written by writeMetadata, so both formats carry the same information
+func epubCleanup(p *publication, dir string) error {
+	entries, err := p.fs.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	book := &epub.Book{
+		Metadata: epub.Metadata{
+			Identifier: "urn:uuid:" + uuid.NewString(),
+			Title:      path.Base(dir),
+			Language:   "und",
+		},
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		filePath := path.Join(dir, entry.Name())
+		if strings.EqualFold(entry.Name(), "ComicInfo.xml") {
+			if err = fillEpubMetadata(p, filePath, &book.Metadata); err != nil {
+				return err
+			}
+			continue
+		}
+
+		img, ok := epubImage(p, filePath)
+		if !ok {
+			p.log.Trace().Str("file", filePath).Msg("skipping non-image file while building epub")
+			continue
+		}
+
+		if strings.HasPrefix(entry.Name(), "!0000 cover") {
+			book.Cover = &img
+			continue
+		}
+
+		book.Pages = append(book.Pages, img)
+	}
+
+	if err = epub.Save(p.fs, book, dir+".epub"); err != nil {
+		return err
+	}
+
+	return p.fs.RemoveAll(dir)
+}
+
+func epubImage(p *publication, filePath string) (epub.Image, bool) {
+	if !isImageFile(filePath) {
+		return epub.Image{}, false
+	}
+
+	img := epub.Image{
+		Name: path.Base(filePath),
+		Open: func() (io.ReadCloser, error) {
+			return p.fs.Open(filePath)
+		},
+	}
+
+	f, err := p.fs.Open(filePath)
+	if err != nil {
+		return img, true
+	}
+	defer f.Close()
+
+	// Dimensions are only used for the page viewport, epub falls back to a default when unknown
+	if cfg, _, err := image.DecodeConfig(f); err == nil {
+		img.Width, img.Height = cfg.Width, cfg.Height
+	} else {
+		p.log.Debug().Err(err).Str("file", filePath).Msg("failed to read image dimensions")
+	}
+
+	return img, true
+}
+
+func readComicInfo(p *publication, filePath string) (*comicinfo.ComicInfo, error) {
+	f, err := p.fs.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return comicinfo.Read(f)
+}
+
+func fillEpubMetadata(p *publication, filePath string, metadata *epub.Metadata) error {
+	ci, err := readComicInfo(p, filePath)
+	if err != nil {
+		return err
+	}
+
+	metadata.Series = ci.Series
+	metadata.Description = ci.Summary
+	metadata.Publisher = ci.Publisher
+	metadata.RightToLeft = ci.Manga == comicinfo.MangeYesAndRightToLeft
+	if ci.LanguageISO != "" {
+		metadata.Language = ci.LanguageISO
+	}
+	if ci.Volume > 0 {
+		metadata.SeriesIndex = strconv.Itoa(ci.Volume)
+	}
+	if ci.Web != "" {
+		metadata.Source = strings.Split(ci.Web, ",")[0]
+	}
+
+	creators := []struct {
+		names string
+		role  string
+	}{
+		{ci.Writer, "aut"},
+		{ci.Penciller, "art"},
+		{ci.Inker, "ink"},
+		{ci.Colorist, "clr"},
+		{ci.Letterer, "ill"},
+		{ci.CoverArtist, "cov"},
+		{ci.Editor, "edt"},
+	}
+	for _, c := range creators {
+		for _, name := range splitList(c.names) {
+			metadata.Creators = append(metadata.Creators, epub.Creator{Name: name, Role: c.role})
+		}
+	}
+
+	metadata.Subjects = append(splitList(ci.Genre), splitList(ci.Tags)...)
+	return nil
+}
+
+func isImageFile(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".jpg", ".jpeg", ".png", ".gif", ".webp":
+		return true
+	}
+	return false
+}
+
+func splitList(s string) []string {
+	return utils.MaybeMap(strings.Split(s, ","), func(part string) (string, bool) {
+		part = strings.TrimSpace(part)
+		return part, part != ""
+	})
+}
+
+func getVolumeFromPdf(p *publication, content Content) (string, error) {
+	f, err := p.fs.Open(path.Join(p.client.GetBaseDir(), content.Path))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	info, err := pdf.ReadInfo(f)
+	if err != nil {
+		return "", err
+	}
+
+	return info.Volume, nil
+}
+
+// pdfCleanup converts the downloaded directory into a pdf with one page per image. The cover, if downloaded,
+// is the first page. Document information is taken from the ComicInfo.xml written by writeMetadata
+func pdfCleanup(p *publication, dir string) error {
+	entries, err := p.fs.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	doc := &pdf.Document{
+		Info: pdf.Info{
+			Title:        path.Base(dir),
+			Creator:      "Media-Provider",
+			Producer:     "Media-Provider",
+			CreationDate: time.Now(),
+		},
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		filePath := path.Join(dir, entry.Name())
+		if strings.EqualFold(entry.Name(), "ComicInfo.xml") {
+			ci, err := readComicInfo(p, filePath)
+			if err != nil {
+				return err
+			}
+
+			doc.Info.Series = ci.Series
+			doc.Info.Author = ci.Writer
+			doc.Info.Subject = ci.Summary
+			doc.Info.Keywords = strings.Join(append(splitList(ci.Genre), splitList(ci.Tags)...), ", ")
+			if ci.Volume > 0 {
+				doc.Info.Volume = strconv.Itoa(ci.Volume)
+			}
+			continue
+		}
+
+		if !isImageFile(filePath) {
+			p.log.Trace().Str("file", filePath).Msg("skipping non-image file while building pdf")
+			continue
+		}
+
+		doc.Pages = append(doc.Pages, pdf.Image{
+			Name: entry.Name(),
+			Open: func() (io.ReadCloser, error) {
+				return p.fs.Open(filePath)
+			},
+		})
+	}
+
+	if err = pdf.Save(p.fs, doc, dir+".pdf"); err != nil {
+		return err
+	}
+
+	return p.fs.RemoveAll(dir)
+}
@@ -72,3 +72,95 @@ func TestCore_IsContent(t *testing.T) {
 		})
 	}
 }
+
+func TestCore_IsContentAnyFormat(t *testing.T) {
+	type testCase struct {
+		name     string
+		diskName string
+		f        isContentFunc
+		want     bool
+		chapter  string
+		volume   string
+	}
+	tests := []testCase{
+		{
+			name:     "Epub chapter",
+			diskName: "My Manga Ch. 0012.epub",
+			f:        isEpub,
+			want:     true,
+			chapter:  "12",
+		},
+		{
+			name:     "Epub volume and chapter",
+			diskName: "My Manga Vol. 5 Ch. 0007.epub",
+			f:        isEpub,
+			want:     true,
+			volume:   "5",
+			chapter:  "7",
+		},
+		{
+			name:     "Epub does not match cbz",
+			diskName: "My Manga Ch. 0012.cbz",
+			f:        isEpub,
+			want:     false,
+		},
+		{
+			name:     "Cbz does not match epub",
+			diskName: "My Manga Vol. 05.epub",
+			f:        isCbz,
+			want:     false,
+		},
+		{
+			name:     "Any format - cbz",
+			diskName: "My Manga Vol. 05.cbz",
+			f:        isContent,
+			want:     true,
+			volume:   "5",
+		},
+		{
+			name:     "Any format - epub",
+			diskName: "My Manga Vol. 05.epub",
+			f:        isContent,
+			want:     true,
+			volume:   "5",
+		},
+		{
+			name:     "Pdf chapter",
+			diskName: "My Manga Vol. 2 Ch. 0010.5.pdf",
+			f:        isPdf,
+			want:     true,
+			volume:   "2",
+			chapter:  "10.5",
+		},
+		{
+			name:     "Any format - pdf",
+			diskName: "My Manga Vol. 05.pdf",
+			f:        isContent,
+			want:     true,
+			volume:   "5",
+		},
+		{
+			name:     "Any format - unsupported",
+			diskName: "My Manga Vol. 05.zip",
+			f:        isContent,
+			want:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, got := tt.f(tt.diskName)
+
+			if got != tt.want {
+				t.Errorf("IsContent() = %v, want %v", got, tt.want)
+			}
+
+			if content.Volume != tt.volume {
+				t.Errorf("IsContent() = %v,\n want %v", content.Volume, tt.volume)
+			}
+
+			if content.Chapter != tt.chapter {
+				t.Errorf("IsContent() = %v,\n want %v", content.Chapter, tt.chapter)
+			}
+		})
+	}
+}
@@ -31,7 +31,16 @@ func (p *publication) writeMetadata(ctx context.Context, chapter Chapter) error
 		}
 	}
 
-	return comicinfo.Save(p.fs, p.buildComicInfoForChapter(ctx, chapter), path.Join(p.ContentPath(chapter), "ComicInfo.xml"))
+	err := comicinfo.Save(p.fs, p.buildComicInfoForChapter(ctx, chapter), path.Join(p.ContentPath(chapter), "ComicInfo.xml"))
+	if err != nil {
+		return err
+	}
+
+	if !p.shouldWriteMetronInfo() {
+		return nil
+	}
+
+	return p.saveMetronInfo(p.buildMetronInfoForChapter(ctx, chapter), path.Join(p.ContentPath(chapter), "MetronInfo.xml"))
 }
 
 func (p *publication) buildComicInfoForChapter(ctx context.Context, chapter Chapter) *comicinfo.ComicInfo {
@@ -71,7 +80,7 @@ func (p *publication) buildComicInfoForChapter(ctx context.Context, chapter Chap
 		ci.Web += fmt.Sprintf(",%s", p.series.RefUrl)
 	}
 
-	tags := append(p.series.Tags, chapter.Tags...) //nolint: gocritic
+	tags := slices.Concat(p.series.Tags, chapter.Tags)
 	ci.Genre, ci.Tags = p.GetGenreAndTags(ctx, tags)
 	if ar, ok := p.GetAgeRating(tags); ok {
 		ci.AgeRating = ar
@@ -142,6 +151,12 @@ func MapTags(mappings []models.TagMapping, tags []Tag) []Tag {
 //   - It is not mapped as a genre.
 //   - It is either in the whitelist or the request has IncludeNotMatchedTagsKey set to true.
 func (p *publication) GetGenreAndTags(ctx context.Context, tags []Tag) (string, string) {
+	genres, filteredTags := p.genresAndTags(ctx, tags)
+	return strings.Join(genres, ", "), strings.Join(filteredTags, ", ")
+}
+
+// genresAndTags is GetGenreAndTags without joining the results
+func (p *publication) genresAndTags(ctx context.Context, tags []Tag) ([]string, []string) {
 	var genres, blackList, whitelist []string
 	var tagMappings []models.TagMapping
 	preferencesLoaded := p.preferences != nil
@@ -172,7 +187,7 @@ func (p *publication) GetGenreAndTags(ctx context.Context, tags []Tag) (string,
 		}
 
 		if config.SkipTagsOnFailure {
-			return "", ""
+			return nil, nil
 		}
 	}
 
@@ -206,7 +221,7 @@ func (p *publication) GetGenreAndTags(ctx context.Context, tags []Tag) (string,
 	filteredGenres := utils.Distinct(filterTags(tags, tagAllowedAsGenre), utils.IdentityFunc[string]())
 	filteredTags := utils.Distinct(filterTags(tags, tagAllowedAsTag), utils.IdentityFunc[string]())
 
-	return strings.Join(filteredGenres, ", "), strings.Join(filteredTags, ", ")
+	return filteredGenres, filteredTags
 }
 
 // GetAgeRating returns the highest comicinfo.AgeRating that is mapped under the models.AgeRatingMappings
@@ -0,0 +1,299 @@
+package publication
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/Fesaa/Media-Provider/db/models"
+	"github.com/Fesaa/Media-Provider/internal/comicinfo"
+	"github.com/Fesaa/Media-Provider/utils"
+	"github.com/Fesaa/go-metroninfo"
+)
+
+const metronInfoNote = "This MetronInfo.xml was auto generated by Media-Provider, with information from %s. Source code can be found here: https://github.com/Fesaa/Media-Provider/"
+
+// metronAgeRatings are all metroninfo.AgeRating ordered from least to most restrictive
+var metronAgeRatings = []metroninfo.AgeRating{
+	metroninfo.AgeRatingUnknown,
+	metroninfo.AgeRatingEveryone,
+	metroninfo.AgeRatingTeen,
+	metroninfo.AgeRatingTeenPlus,
+	metroninfo.AgeRatingMature,
+	metroninfo.AgeRatingExplicit,
+	metroninfo.AgeRatingAdult,
+}
+
+var comicInfoToMetronAgeRating = map[comicinfo.AgeRating]metroninfo.AgeRating{
+	comicinfo.AgeRatingUnknown:          metroninfo.AgeRatingUnknown,
+	comicinfo.AgeRatingPending:          metroninfo.AgeRatingUnknown,
+	comicinfo.AgeRatingEarlyChildhood:   metroninfo.AgeRatingEveryone,
+	comicinfo.AgeRatingEveryone:         metroninfo.AgeRatingEveryone,
+	comicinfo.AgeRatingG:                metroninfo.AgeRatingEveryone,
+	comicinfo.AgeRatingEveryone10Plus:   metroninfo.AgeRatingEveryone,
+	comicinfo.AgeRatingPG:               metroninfo.AgeRatingEveryone,
+	comicinfo.AgeRatingKidsToAdults:     metroninfo.AgeRatingEveryone,
+	comicinfo.AgeRatingTeen:             metroninfo.AgeRatingTeen,
+	comicinfo.AgeRatingMAPlus15:         metroninfo.AgeRatingTeenPlus,
+	comicinfo.AgeRatingMaturePlus17:     metroninfo.AgeRatingMature,
+	comicinfo.AgeRatingM:                metroninfo.AgeRatingMature,
+	comicinfo.AgeRatingRPlus18:          metroninfo.AgeRatingExplicit,
+	comicinfo.AgeRatingAdultsOnlyPlus18: metroninfo.AgeRatingAdult,
+	comicinfo.AgeRatingXPlus18:          metroninfo.AgeRatingAdult,
+}
+
+var comicInfoToMetronRole = map[comicinfo.Role]metroninfo.RoleValue{
+	comicinfo.Writer:      metroninfo.RoleWriter,
+	comicinfo.Penciller:   metroninfo.RolePenciller,
+	comicinfo.Inker:       metroninfo.RoleInker,
+	comicinfo.Colorist:    metroninfo.RoleColorist,
+	comicinfo.Letterer:    metroninfo.RoleLetterer,
+	comicinfo.CoverArtist: metroninfo.RoleCover,
+	comicinfo.Editor:      metroninfo.RoleEditor,
+}
+
+// linkIdMatchers extract the id of a series on known information sources from its links
+var linkIdMatchers = []struct {
+	source metroninfo.InformationSource
+	rgx    *regexp.Regexp
+}{
+	{metroninfo.SourceAniList, regexp.MustCompile(`anilist\.co/manga/(\d+)`)},
+	{metroninfo.SourceMyAnimeList, regexp.MustCompile(`myanimelist\.net/manga/(\d+)`)},
+	{metroninfo.SourceMangaUpdates, regexp.MustCompile(`mangaupdates\.com/series(?:\.html\?id=|/)(\w+)`)},
+	{metroninfo.SourceKitsu, regexp.MustCompile(`kitsu\.(?:io|app)/(?:api/edge/)?manga/([\w-]+)`)},
+	{metroninfo.SourceMangaDex, regexp.MustCompile(`mangadex\.org/title/([\w-]+)`)},
+}
+
+// shouldWriteMetronInfo returns true if either the request, or the preferences of its owner ask for a MetronInfo.xml
+func (p *publication) shouldWriteMetronInfo() bool {
+	if p.req.GetBool(WriteMetronInfoKey, false) {
+		return true
+	}
+
+	return p.preferences != nil && p.preferences.WriteMetronInfo
+}
+
+func (p *publication) saveMetronInfo(mi *metroninfo.MetronInfo, path string) error {
+	f, err := p.fs.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating file: %w", err)
+	}
+	defer f.Close()
+
+	if err = mi.Write(f, true); err != nil {
+		return fmt.Errorf("error writing MetronInfo to file: %w", err)
+	}
+
+	return nil
+}
+
+func (p *publication) buildMetronInfoForChapter(ctx context.Context, chapter Chapter) *metroninfo.MetronInfo {
+	mi := metroninfo.NewMetronInfo()
+
+	mi.Notes = fmt.Sprintf(metronInfoNote, p.Provider().String())
+	mi.IDS = p.metronIds()
+
+	mi.Series = metroninfo.Series{
+		Name:      p.Title(),
+		Format:    metronFormat(chapter),
+		StartYear: p.series.Year,
+	}
+	if p.series.AltTitle != "" {
+		mi.Series.AlternativeNames = metroninfo.AlternativeNames{{Value: p.series.AltTitle}}
+	}
+
+	mi.MangaVolume = chapter.Volume
+	mi.Number = chapter.Chapter
+	mi.Summary = utils.NonEmpty(chapter.Summary, p.series.Description)
+	if chapter.Title != "" {
+		mi.Stories = metroninfo.Stories{{Value: chapter.Title}}
+	}
+
+	if chapter.ReleaseDate != nil {
+		date := metroninfo.Date(*chapter.ReleaseDate)
+		mi.StoreDate = &date
+	}
+
+	tags := slices.Concat(p.series.Tags, chapter.Tags)
+	genres, filteredTags := p.genresAndTags(ctx, tags)
+	mi.Genres = utils.Map(genres, func(genre string) metroninfo.Genre {
+		return metroninfo.Genre{Value: genre}
+	})
+	mi.Tags = utils.Map(filteredTags, func(tag string) metroninfo.Tag {
+		return metroninfo.Tag{Value: tag}
+	})
+	if ar, ok := p.GetMetronAgeRating(tags); ok {
+		mi.AgeRating = ar
+	}
+
+	mi.URLs = p.metronUrls()
+	mi.Credits = p.metronCredits(chapter)
+
+	now := time.Now()
+	mi.LastModified = &now
+
+	return mi
+}
+
+func metronFormat(chapter Chapter) metroninfo.Format {
+	switch {
+	case chapter.Chapter != "":
+		return metroninfo.DigitalChapterFormat
+	case chapter.Volume != "":
+		return metroninfo.GraphicNovelFormat
+	default:
+		return metroninfo.OneShotFormat
+	}
+}
+
+// metronIds returns the ids of the series on all information sources that could be found. The id on the provider
+// is marked as primary, if it is a known source
+func (p *publication) metronIds() metroninfo.IDS {
+	var ids metroninfo.IDS
+
+	if p.Provider() == models.MANGADEX && p.series.Id != "" {
+		ids = append(ids, metroninfo.ID{Source: metroninfo.SourceMangaDex, Primary: true, Value: p.series.Id})
+	}
+
+	for _, link := range p.series.Links {
+		for _, matcher := range linkIdMatchers {
+			matches := matcher.rgx.FindStringSubmatch(link)
+			if matches == nil {
+				continue
+			}
+
+			if !slices.ContainsFunc(ids, func(id metroninfo.ID) bool { return id.Source == matcher.source }) {
+				ids = append(ids, metroninfo.ID{Source: matcher.source, Value: matches[1]})
+			}
+			break
+		}
+	}
+
+	return ids
+}
+
+func (p *publication) metronUrls() metroninfo.URLs {
+	var urls metroninfo.URLs
+
+	if p.series.RefUrl != "" {
+		urls = append(urls, metroninfo.URL{Primary: true, Value: p.series.RefUrl})
+	}
+
+	for _, link := range p.series.Links {
+		if link == "" || link == p.series.RefUrl {
+			continue
+		}
+		urls = append(urls, metroninfo.URL{Value: link})
+	}
+
+	return urls
+}
+
+// metronCredits returns the credits of the series and chapter, scanlation groups are credited as translator
+func (p *publication) metronCredits(chapter Chapter) metroninfo.Credits {
+	var credits metroninfo.Credits
+
+	people := utils.Distinct(slices.Concat(p.series.People, chapter.People), func(person Person) string {
+		return person.Name
+	})
+
+	for _, person := range people {
+		roles := utils.MaybeMap(person.Roles, func(role comicinfo.Role) (metroninfo.Role, bool) {
+			value, ok := comicInfoToMetronRole[role]
+			return metroninfo.Role{Value: value}, ok
+		})
+		if person.Name == "" || len(roles) == 0 {
+			continue
+		}
+
+		credits = append(credits, metroninfo.Credit{
+			Creator: metroninfo.Resource{Value: person.Name},
+			Roles:   roles,
+		})
+	}
+
+	for _, translator := range chapter.Translator {
+		if translator == "" {
+			continue
+		}
+
+		credits = append(credits, metroninfo.Credit{
+			Creator: metroninfo.Resource{Value: translator},
+			Roles:   metroninfo.Roles{{Value: metroninfo.RoleTranslator}},
+		})
+	}
+
+	return credits
+}
+
+// GetMetronAgeRating returns the highest metroninfo.AgeRating that is mapped under the models.AgeRatingMappings.
+// Falls back to the content rating of the series, returns false if neither is present
+func (p *publication) GetMetronAgeRating(tags []Tag) (metroninfo.AgeRating, bool) {
+	fallback := func() (metroninfo.AgeRating, bool) {
+		if p.series.ContentRating == "" {
+			return "", false
+		}
+		return MetronAgeRating(p.series.ContentRating), true
+	}
+
+	if p.preferences == nil {
+		return fallback()
+	}
+
+	tagMappings := utils.Map(p.preferences.TagMappings, func(t models.TagMapping) models.TagMapping {
+		return models.TagMapping{
+			OriginTag:      utils.Normalize(t.OriginTag),
+			DestinationTag: t.DestinationTag,
+		}
+	})
+	tags = MapTags(tagMappings, tags)
+
+	weights := utils.MaybeMap(tags, func(t Tag) (int, bool) {
+		ar, ok := GetMetronAgeRating(p.preferences.AgeRatingMappings, utils.Normalize(t.Value))
+		if !ok {
+			return 0, false
+		}
+
+		return slices.Index(metronAgeRatings, ar), true
+	})
+
+	if len(weights) == 0 {
+		return fallback()
+	}
+
+	return metronAgeRatings[slices.Max(weights)], true
+}
+
+// GetMetronAgeRating returns the highest metroninfo.AgeRating mapped to the tag. Mappings without a metron
+// age rating use their converted comicinfo.AgeRating
+func GetMetronAgeRating(arm []models.AgeRatingMapping, tag string) (metroninfo.AgeRating, bool) {
+	ageRating := -1
+	for _, ageRatingMapping := range arm {
+		if utils.Normalize(ageRatingMapping.Tag) != tag {
+			continue
+		}
+
+		ar := ageRatingMapping.MetronAgeRating
+		if ar == "" {
+			ar = MetronAgeRating(ageRatingMapping.ComicInfoAgeRating)
+		}
+
+		ageRating = max(ageRating, slices.Index(metronAgeRatings, ar))
+	}
+
+	if ageRating > -1 {
+		return metronAgeRatings[ageRating], true
+	}
+
+	return "", false
+}
+
+// MetronAgeRating converts a comicinfo.AgeRating to the closest metroninfo.AgeRating
+func MetronAgeRating(ar comicinfo.AgeRating) metroninfo.AgeRating {
+	if mar, ok := comicInfoToMetronAgeRating[ar]; ok {
+		return mar
+	}
+	return metroninfo.AgeRatingUnknown
+}
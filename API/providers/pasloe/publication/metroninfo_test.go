@@ -0,0 +1,166 @@
+package publication
+
+import (
+	"bytes"
+	"context"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Fesaa/Media-Provider/db/models"
+	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/Fesaa/Media-Provider/internal/comicinfo"
+	"github.com/Fesaa/Media-Provider/utils"
+	"github.com/Fesaa/go-metroninfo"
+	"github.com/rs/zerolog"
+	"github.com/spf13/afero"
+)
+
+func TestGetMetronAgeRating(t *testing.T) {
+	mappings := []models.AgeRatingMapping{
+		{Tag: "Gore", ComicInfoAgeRating: comicinfo.AgeRatingMaturePlus17, MetronAgeRating: metroninfo.AgeRatingExplicit},
+		{Tag: "Gore", ComicInfoAgeRating: comicinfo.AgeRatingTeen, MetronAgeRating: metroninfo.AgeRatingTeen},
+		{Tag: "Romance", ComicInfoAgeRating: comicinfo.AgeRatingMAPlus15},
+	}
+
+	tests := []struct {
+		tag  string
+		want metroninfo.AgeRating
+		ok   bool
+	}{
+		{tag: "gore", want: metroninfo.AgeRatingExplicit, ok: true},
+		{tag: "romance", want: metroninfo.AgeRatingTeenPlus, ok: true},
+		{tag: "action", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			got, ok := GetMetronAgeRating(mappings, tt.tag)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("Got (%s, %v); expected (%s, %v)", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestPublication_BuildMetronInfoForChapter(t *testing.T) {
+	release := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+	p := &publication{
+		log:     zerolog.Nop(),
+		toggles: utils.NewToggles[string](),
+		req:     payload.DownloadRequest{Provider: models.MANGADEX},
+		preferences: &models.UserPreferences{
+			GenreList: []string{"Romance"},
+			AgeRatingMappings: []models.AgeRatingMapping{
+				{Tag: "Romance", ComicInfoAgeRating: comicinfo.AgeRatingTeen},
+			},
+		},
+		series: &Series{
+			Id:     "a1c7c817-4e59-43b7-9365-09675a149a6f",
+			Title:  "Spice and Wolf",
+			RefUrl: "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f",
+			Links: []string{
+				"https://anilist.co/manga/30933",
+				"https://www.mangaupdates.com/series.html?id=3312",
+				"https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f",
+			},
+			ContentRating: comicinfo.AgeRatingEveryone,
+			Tags:          []Tag{{Value: "Romance"}},
+			People: []Person{
+				{Name: "Hasekura Isuna", Roles: comicinfo.Roles{comicinfo.Writer}},
+				{Name: "Koume Keito", Roles: comicinfo.Roles{comicinfo.Penciller, comicinfo.Inker}},
+			},
+		},
+	}
+
+	chapter := Chapter{
+		Volume:      "2",
+		Chapter:     "7",
+		Title:       "Wolf",
+		ReleaseDate: &release,
+		Translator:  []string{"Hakugin Scans"},
+	}
+
+	mi := p.buildMetronInfoForChapter(context.Background(), chapter)
+
+	if mi.Series.Name != "Spice and Wolf" || mi.Series.Format != metroninfo.DigitalChapterFormat {
+		t.Errorf("Got series %+v; expected digital chapter of Spice and Wolf", mi.Series)
+	}
+
+	if mi.MangaVolume != "2" || mi.Number != "7" {
+		t.Errorf("Got volume %s, number %s; expected 2, 7", mi.MangaVolume, mi.Number)
+	}
+
+	if mi.AgeRating != metroninfo.AgeRatingTeen {
+		t.Errorf("Got age rating %s; expected %s", mi.AgeRating, metroninfo.AgeRatingTeen)
+	}
+
+	if len(mi.Genres) != 1 || mi.Genres[0].Value != "Romance" {
+		t.Errorf("Got genres %v; expected [Romance]", mi.Genres)
+	}
+
+	wantIds := metroninfo.IDS{
+		{Source: metroninfo.SourceMangaDex, Primary: true, Value: "a1c7c817-4e59-43b7-9365-09675a149a6f"},
+		{Source: metroninfo.SourceAniList, Value: "30933"},
+		{Source: metroninfo.SourceMangaUpdates, Value: "3312"},
+	}
+	if len(mi.IDS) != len(wantIds) {
+		t.Fatalf("Got ids %v; expected %v", mi.IDS, wantIds)
+	}
+	for i := range wantIds {
+		if mi.IDS[i] != wantIds[i] {
+			t.Errorf("Got id %v; expected %v", mi.IDS[i], wantIds[i])
+		}
+	}
+
+	if len(mi.URLs) != 3 || !mi.URLs[0].Primary || mi.URLs[0].Value != p.series.RefUrl {
+		t.Errorf("Got urls %v; expected the ref url as primary followed by the links", mi.URLs)
+	}
+
+	if len(mi.Credits) != 3 {
+		t.Fatalf("Got credits %v; expected 3", mi.Credits)
+	}
+	if roles := mi.Credits[1].Roles; len(roles) != 2 || roles[1].Value != metroninfo.RoleInker {
+		t.Errorf("Got roles %v; expected penciller and inker", roles)
+	}
+	if c := mi.Credits[2]; c.Creator.Value != "Hakugin Scans" || c.Roles[0].Value != metroninfo.RoleTranslator {
+		t.Errorf("Got credit %v; expected Hakugin Scans as translator", c)
+	}
+
+	var buf bytes.Buffer
+	if err := mi.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "<StoreDate>2024-03-05</StoreDate>") {
+		t.Errorf("StoreDate missing from %s", buf.String())
+	}
+}
+
+func TestPublication_ShouldWriteMetronInfo(t *testing.T) {
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+	p := &publication{fs: fs, log: zerolog.Nop()}
+
+	if p.shouldWriteMetronInfo() {
+		t.Error("MetronInfo should not be written by default")
+	}
+
+	p.preferences = &models.UserPreferences{WriteMetronInfo: true}
+	if !p.shouldWriteMetronInfo() {
+		t.Error("MetronInfo should be written when enabled in preferences")
+	}
+
+	p.preferences = nil
+	p.req.DownloadMetadata.Extra = utils.SmartMap{WriteMetronInfoKey: {"true"}}
+	if !p.shouldWriteMetronInfo() {
+		t.Error("MetronInfo should be written when requested")
+	}
+
+	file := path.Join("Manga", "MetronInfo.xml")
+	if err := p.saveMetronInfo(metroninfo.NewMetronInfo(), file); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := fs.Exists(file); !ok {
+		t.Error("MetronInfo.xml was not written")
+	}
+}
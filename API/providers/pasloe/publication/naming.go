@@ -0,0 +1,74 @@
+package publication
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/Fesaa/Media-Provider/internal/naming"
+	"github.com/Fesaa/Media-Provider/utils"
+)
+
+// contentExts are the extensions of all supported output formats, see isContent
+var contentExts = []string{".cbz", ".epub", ".pdf"}
+
+// templateFromRequest parses the naming template set under key, returns nil if none was set.
+// The file name template must identify the chapter, see naming.ValidateFileName
+func templateFromRequest(req payload.DownloadRequest, key string) (*naming.Template, error) {
+	raw := req.GetStringOrDefault(key, "")
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	t, err := naming.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: %w", key, raw, err)
+	}
+
+	if key == FileNameTemplateKey && !t.Uses(naming.ContentPlaceholders...) {
+		return nil, fmt.Errorf("invalid %s %q: %w", key, raw, naming.ErrNoContentPlaceholder)
+	}
+	return t, nil
+}
+
+// templateMatchers returns content matchers for all formats, recognising file names rendered by the template
+func templateMatchers(t *naming.Template) []contentMatcher {
+	if t == nil {
+		return nil
+	}
+
+	return utils.Map(contentExts, func(ext string) contentMatcher {
+		return newContentMatcher(ext, t)
+	})
+}
+
+// isContent recognises content named after the file name template, falling back to Extensions.isContentFunc
+// so content downloaded before the template was set is still found
+func (p *publication) isContent(name string) (Content, bool) {
+	for _, matcher := range p.contentMatchers {
+		if c, ok := matcher.match(name); ok {
+			return c, true
+		}
+	}
+
+	return p.ext.isContentFunc(name)
+}
+
+// namingValues returns the values for all placeholders of a naming.Template for the chapter
+func (p *publication) namingValues(chapter Chapter) naming.Values {
+	padded := chapter.Chapter
+	if _, err := strconv.ParseFloat(chapter.Chapter, 32); err == nil {
+		padded = utils.PadFloatFromString(chapter.Chapter, 4)
+	}
+
+	return naming.Values{
+		naming.Title:         p.Title(),
+		naming.Volume:        chapter.Volume,
+		naming.Chapter:       chapter.Chapter,
+		naming.ChapterPadded: padded,
+		naming.ChapterTitle:  chapter.Title,
+		naming.Group:         strings.Join(chapter.Translator, ", "),
+		naming.Provider:      p.Provider().String(),
+	}
+}
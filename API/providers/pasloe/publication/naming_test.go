@@ -0,0 +1,153 @@
+package publication
+
+import (
+	"testing"
+
+	"github.com/Fesaa/Media-Provider/db/models"
+	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/Fesaa/Media-Provider/utils"
+	"github.com/rs/zerolog"
+)
+
+func templatedPublication(t *testing.T, fileName, volumeDir string) *publication {
+	t.Helper()
+
+	req := payload.DownloadRequest{
+		Provider: models.MANGADEX,
+		BaseDir:  "Manga",
+		DownloadMetadata: models.DownloadRequestMetadata{
+			Extra: utils.SmartMap{
+				FileNameTemplateKey:  {fileName},
+				VolumeDirTemplateKey: {volumeDir},
+			},
+		},
+	}
+
+	fileNameTemplate, err := templateFromRequest(req, FileNameTemplateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	volumeDirTemplate, err := templateFromRequest(req, VolumeDirTemplateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &publication{
+		log:               zerolog.Nop(),
+		client:            baseDirClient{},
+		req:               req,
+		ext:               CbzExt(),
+		series:            &Series{Title: "Spice and Wolf"},
+		fileNameTemplate:  fileNameTemplate,
+		volumeDirTemplate: volumeDirTemplate,
+		contentMatchers:   templateMatchers(fileNameTemplate),
+	}
+}
+
+func TestPublication_ContentPathTemplate(t *testing.T) {
+	p := templatedPublication(t, "<[{group}] >{title} - {chapter_padded}< {chapter_title}>", "Volume {volume}")
+
+	chapter := Chapter{Volume: "2", Chapter: "7", Title: "Wolf", Translator: []string{"Hakugin Scans"}}
+	want := "temp/Manga/Spice and Wolf/Volume 2/[Hakugin Scans] Spice and Wolf - 0007 Wolf"
+	if got := p.ContentPath(chapter); got != want {
+		t.Errorf("Got %q; expected %q", got, want)
+	}
+
+	chapter = Chapter{Chapter: "8"}
+	want = "temp/Manga/Spice and Wolf/Spice and Wolf - 0008"
+	if got := p.ContentPath(chapter); got != want {
+		t.Errorf("Got %q; expected %q", got, want)
+	}
+}
+
+func TestPublication_ContentFileNameDefault(t *testing.T) {
+	p := templatedPublication(t, "", "")
+
+	chapter := Chapter{Volume: "2", Chapter: "7"}
+	if got, want := p.ContentFileName(chapter), "Spice and Wolf Ch. 0007"; got != want {
+		t.Errorf("Got %q; expected %q", got, want)
+	}
+	if got, want := p.VolumeDir(chapter), "Spice and Wolf Vol. 2"; got != want {
+		t.Errorf("Got %q; expected %q", got, want)
+	}
+}
+
+func TestPublication_IsContentTemplate(t *testing.T) {
+	p := templatedPublication(t, "{title} #{chapter_padded}< (v{volume})>", "")
+
+	tests := []struct {
+		fileName string
+		volume   string
+		chapter  string
+	}{
+		{fileName: "Spice and Wolf #0007 (v2).cbz", volume: "2", chapter: "7"},
+		{fileName: "Spice and Wolf #0008.epub", chapter: "8"},
+		// Content downloaded before the template was set
+		{fileName: "Spice and Wolf Vol. 1 Ch. 0001.cbz", volume: "1", chapter: "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			content, ok := p.isContent(tt.fileName)
+			if !ok {
+				t.Fatal("content was not recognised")
+			}
+			if content.Volume != tt.volume || content.Chapter != tt.chapter {
+				t.Errorf("Got %+v; expected volume %q and chapter %q", content, tt.volume, tt.chapter)
+			}
+		})
+	}
+
+	if _, ok := p.isContent("Spice and Wolf #0007.txt"); ok {
+		t.Error("non-content file was recognised as content")
+	}
+}
+
+func TestPublication_IsContentTemplateWithoutNumbers(t *testing.T) {
+	p := templatedPublication(t, "{title}< Ch. {chapter}>", "")
+
+	// The template matches, but doesn't capture a volume or chapter. The default names are tried instead
+	content, ok := p.isContent("Spice and Wolf Vol. 3.cbz")
+	if !ok {
+		t.Fatal("content was not recognised")
+	}
+	if content.Volume != "3" || content.Chapter != "" {
+		t.Errorf("Got %+v; expected volume 3 from the default names", content)
+	}
+}
+
+func TestTemplateFromRequest_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		template string
+	}{
+		{name: "Unknown placeholder", key: FileNameTemplateKey, template: "{title} {author}"},
+		{name: "File name without chapter", key: FileNameTemplateKey, template: "{title} - {chapter_title}"},
+		{name: "Unknown placeholder in volume dir", key: VolumeDirTemplateKey, template: "{author}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := payload.DownloadRequest{
+				DownloadMetadata: models.DownloadRequestMetadata{
+					Extra: utils.SmartMap{tt.key: {tt.template}},
+				},
+			}
+
+			if _, err := templateFromRequest(req, tt.key); err == nil {
+				t.Errorf("expected an error for %q", tt.template)
+			}
+		})
+	}
+
+	// Volume directories are shared by the chapters of a volume
+	req := payload.DownloadRequest{
+		DownloadMetadata: models.DownloadRequestMetadata{
+			Extra: utils.SmartMap{VolumeDirTemplateKey: {"{title}"}},
+		},
+	}
+	if _, err := templateFromRequest(req, VolumeDirTemplateKey); err != nil {
+		t.Errorf("Got %v; expected volume directories without a chapter to be valid", err)
+	}
+}
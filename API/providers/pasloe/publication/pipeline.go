@@ -4,7 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"path"
+	"regexp"
 	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -14,11 +18,16 @@ import (
 	"github.com/Fesaa/Media-Provider/internal/tracing"
 	"github.com/Fesaa/Media-Provider/utils"
 	"github.com/rs/zerolog"
+	"github.com/spf13/afero"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
 	"golang.org/x/time/rate"
 )
 
+// pageRgx matches pages written by imageIoTask
+var pageRgx = regexp.MustCompile(`^page (\d+)\.[^.]+$`)
+
 // pipeline convince wrapper around the download of a chapter
 type pipeline struct {
 	Ctx    context.Context
@@ -27,10 +36,11 @@ type pipeline struct {
 	Publication *publication
 	Chapter     Chapter
 	Urls        []DownloadUrl
+	// Downloaded contains the page indices already on disk from a previous, partial, download
+	Downloaded map[int]bool
 
-	RateLimiter *rate.Limiter
-	DownloadWg  *sync.WaitGroup
-	DownloadCh  chan downloadTask
+	DownloadWg *sync.WaitGroup
+	DownloadCh chan downloadTask
 
 	ErrCh chan error
 
@@ -54,6 +64,10 @@ func (pl *pipeline) ProduceUrls() {
 			return
 		}
 
+		if pl.Downloaded[idx+1] {
+			continue
+		}
+
 		select {
 		case pl.DownloadCh <- downloadTask{idx + 1, url}:
 		case <-pl.Ctx.Done():
@@ -104,7 +118,7 @@ func (pl *pipeline) processDownloads(ctx context.Context, log zerolog.Logger, ch
 			return failedTasks
 		}
 
-		if err := pl.RateLimiter.Wait(ctx); err != nil {
+		if err := pl.Publication.rateLimiter.Wait(ctx); err != nil {
 			if !errors.Is(err, context.Canceled) {
 				log.Error().Err(err).Msg("rate limiter wait failed")
 			}
@@ -146,10 +160,10 @@ func (pl *pipeline) processDownloads(ctx context.Context, log zerolog.Logger, ch
 			return failedTasks
 		}
 
-		pl.Publication.speedTracker.IncrementIntermediate()
+		pl.Publication.speedTracker.IncrementIntermediateFor(pl.Chapter.Id)
 
 		select {
-		case pl.Publication.iOWorkCh <- ioTask{data, pl.Publication.ContentPath(pl.Chapter), task}:
+		case pl.Publication.iOWorkCh <- ioTask{data, pl.Publication.ContentPath(pl.Chapter), len(pl.Urls), pl.Chapter, task}:
 		case <-pl.Ctx.Done():
 			return failedTasks
 		}
@@ -166,7 +180,10 @@ type downloadTask struct {
 type ioTask struct {
 	Data []byte
 	Path string
-	Task downloadTask
+	// Pages is the amount of pages in the chapter
+	Pages   int
+	Chapter Chapter
+	Task    downloadTask
 }
 
 func (p *publication) getChapterById(id string) (Chapter, bool) {
@@ -207,10 +224,11 @@ func (p *publication) filterContentByUserSelection() {
 			return "", false
 		}
 
-		return p.ContentPath(chapter) + ".cbz", true
+		return p.ContentPath(chapter), true
 	})
-	p.toRemoveContent = utils.Filter(p.toRemoveContent, func(path string) bool {
-		return slices.Contains(paths, path)
+	p.toRemoveContent = utils.Filter(p.toRemoveContent, func(toRemove string) bool {
+		// ignore file ext, content may be in any supported format
+		return slices.Contains(paths, strings.TrimSuffix(toRemove, path.Ext(toRemove)))
 	})
 }
 
@@ -243,7 +261,10 @@ func (p *publication) startDownloadPipeline(ctx context.Context) {
 
 	p.wg = &sync.WaitGroup{}
 	p.ioWg = &sync.WaitGroup{}
-	p.iOWorkCh = make(chan ioTask, p.maxImages*2) // Allow for some buffer as I/O may be slower than downloading (webp)
+	// Allow for some buffer as I/O may be slower than downloading (webp). Chapters downloaded in parallel share
+	// the buffer, and the I/O workers, so neither grows with maxChapters
+	p.iOWorkCh = make(chan ioTask, p.maxImages*2)
+	p.rateLimiter = rate.NewLimiter(rate.Limit(p.maxImages), 1)
 
 	go p.signalRUpdateLoop(ctx)
 	p.StartIOWorkers(ctx)
@@ -263,6 +284,8 @@ func (p *publication) startDownloadPipeline(ctx context.Context) {
 	p.SetState(payload.ContentStateCleanup)
 	p.ioWg.Wait()
 
+	p.removeFailedChapters()
+
 	// Prevent waiting on them again
 	p.wg = nil
 	p.ioWg = nil
@@ -271,30 +294,113 @@ func (p *publication) startDownloadPipeline(ctx context.Context) {
 	p.StopDownload()
 }
 
-// processDownloads loops through all chapters to download, and downloads them. Returning early when one fails
+// processDownloads loops through all chapters to download, and downloads up to maxChapters at once.
+// When one fails, no new chapters are started, and the ones in progress are cancelled. Always returns after
+// all started chapters have stopped
 func (p *publication) processDownloads(ctx context.Context, wg *sync.WaitGroup) error {
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(p.maxChapters)
+
 	for _, chapterId := range p.toDownload {
 		chapter, ok := p.getChapterById(chapterId)
 		if !ok {
 			continue
 		}
 
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			wg.Add(1)
-			err := func() error {
-				defer wg.Done()
-				return p.downloadChapter(ctx, chapter)
-			}()
-			if err != nil {
+		if gCtx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		g.Go(func() error {
+			defer wg.Done()
+
+			err := p.downloadChapter(gCtx, chapter)
+			if err == nil || gCtx.Err() != nil || !p.req.GetBool(SkipFailedChaptersKey, false) {
 				return err
 			}
+
+			p.skipFailedChapter(chapter, err)
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// skipFailedChapter records the chapter as failed after its download failed, and counts it as done
+func (p *publication) skipFailedChapter(chapter Chapter, reason error) {
+	p.markChapterFailed(chapter, reason)
+
+	// Count the chapter as done, so progress still reaches 100%
+	p.speedTracker.ClearIntermediateFor(chapter.Id)
+	p.speedTracker.Increment()
+}
+
+// markChapterFailed records the chapter as failed, and excludes it from cleanup. Its files are removed by
+// removeFailedChapters once all I/O has finished, as pages may still be queued. Chapters are recorded once,
+// as several of their pages may fail
+func (p *publication) markChapterFailed(chapter Chapter, reason error) {
+	p.failedChaptersLock.Lock()
+	if slices.ContainsFunc(p.failedChapters, func(f FailedChapter) bool { return f.Id == chapter.Id }) {
+		p.failedChaptersLock.Unlock()
+		return
+	}
+	p.failedChapters = append(p.failedChapters, FailedChapter{
+		Id:    chapter.Id,
+		Label: chapter.Label(),
+		Err:   reason,
+	})
+	p.failedChaptersLock.Unlock()
+
+	log := p.ChapterLogger(chapter)
+	log.Warn().Err(reason).Msg("chapter failed to download, skipping it")
+
+	chapterPath := p.ContentPath(chapter)
+
+	p.hasDownloadedLock.Lock()
+	p.hasDownloaded = slices.DeleteFunc(p.hasDownloaded, func(s string) bool {
+		return s == chapterPath
+	})
+	p.hasDownloadedLock.Unlock()
+}
+
+// removeFailedChapters removes the files of chapters skipped by skipFailedChapter, and ensures the content they were
+// going to replace is kept
+func (p *publication) removeFailedChapters() {
+	failed := p.FailedChapters()
+	if len(failed) == 0 {
+		return
+	}
+
+	paths := utils.MaybeMap(failed, func(f FailedChapter) (string, bool) {
+		chapter, ok := p.getChapterById(f.Id)
+		if !ok {
+			return "", false
+		}
+		return p.ContentPath(chapter), true
+	})
+
+	for _, chapterPath := range paths {
+		if stream, ok := p.streams.remove(chapterPath); ok {
+			if err := stream.abort(); err != nil {
+				p.log.Warn().Err(err).Str("path", chapterPath).Msg("failed to close streamed archive")
+			}
+		}
+
+		if err := p.fs.RemoveAll(chapterPath); err != nil {
+			p.log.Warn().Err(err).Str("path", chapterPath).Msg("failed to remove files of failed chapter")
 		}
 	}
 
-	return nil
+	p.toRemoveContent = utils.Filter(p.toRemoveContent, func(toRemove string) bool {
+		// ignore file ext, content may be in any supported format
+		return !slices.Contains(paths, strings.TrimSuffix(toRemove, path.Ext(toRemove)))
+	})
 }
 
 func (p *publication) downloadChapter(ctx context.Context, chapter Chapter) error {
@@ -314,19 +420,30 @@ func (p *publication) downloadChapter(ctx context.Context, chapter Chapter) erro
 	}
 
 	// Mark as downloaded as soon as the directory is created as we need to remove it in case of an error
+	p.hasDownloadedLock.Lock()
 	p.hasDownloaded = append(p.hasDownloaded, chapterPath)
+	p.hasDownloadedLock.Unlock()
 
 	if err = p.writeMetadata(ctx, chapter); err != nil {
 		p.log.Warn().Err(err).Msg("failed to write metadata")
 	}
 
 	span.AddEvent("write.metadata")
-	span.SetAttributes(attribute.Int("size", len(pl.Urls)))
 
-	pl.log.Debug().Int("size", len(pl.Urls)).Msg("starting download")
+	pl.Downloaded = p.downloadedPages(chapterPath, len(pl.Urls))
+	toDownload := len(pl.Urls) - len(pl.Downloaded)
+
+	span.SetAttributes(attribute.Int("size", len(pl.Urls)), attribute.Int("resumed", len(pl.Downloaded)))
+
+	if len(pl.Downloaded) > 0 {
+		pl.log.Debug().Int("size", len(pl.Urls)).Int("onDisk", len(pl.Downloaded)).
+			Msg("resuming partially downloaded chapter")
+	}
+
+	pl.log.Debug().Int("size", toDownload).Msg("starting download")
 	start := time.Now()
 
-	p.speedTracker.SetIntermediate(len(pl.Urls))
+	p.speedTracker.SetIntermediateFor(chapter.Id, toDownload)
 	go pl.ProduceUrls()
 
 	pl.StartDownloadWorkers() //nolint: contextcheck
@@ -340,12 +457,12 @@ func (p *publication) downloadChapter(ctx context.Context, chapter Chapter) erro
 	default:
 	}
 
-	if len(pl.Urls) < 5 {
+	if toDownload > 0 && toDownload < 5 {
 		time.Sleep(1 * time.Second)
 	}
 
 	// Reset chapter progress and increment series progress
-	p.speedTracker.ClearIntermediate()
+	p.speedTracker.ClearIntermediateFor(chapter.Id)
 	p.speedTracker.Increment()
 	return nil
 }
@@ -358,7 +475,6 @@ func (p *publication) newPipeline(ctx context.Context, chapter Chapter) (*pipeli
 		Cancel:      pipelineCancel,
 		Publication: p,
 		Chapter:     chapter,
-		RateLimiter: rate.NewLimiter(rate.Limit(p.maxImages), 1),
 		DownloadWg:  &sync.WaitGroup{},
 		DownloadCh:  make(chan downloadTask, p.maxImages),
 		ErrCh:       make(chan error, 1),
@@ -380,6 +496,73 @@ func (p *publication) newPipeline(ctx context.Context, chapter Chapter) (*pipeli
 	return pl, nil
 }
 
+// downloadedPages returns the indices of the pages already written to the chapter directory. Only indices
+// up to size are considered, the chapter may have changed since. Left over partial writes are removed
+func (p *publication) downloadedPages(chapterPath string, size int) map[int]bool {
+	pages := make(map[int]bool)
+
+	entries, err := p.fs.ReadDir(chapterPath)
+	if err != nil {
+		p.log.Debug().Err(err).Str("path", chapterPath).Msg("unable to read chapter directory, downloading all pages")
+		return pages
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		if strings.HasSuffix(entry.Name(), partialPageExt) {
+			if err = p.fs.Remove(path.Join(chapterPath, entry.Name())); err != nil {
+				p.log.Warn().Err(err).Str("file", entry.Name()).Msg("failed to remove partially written page")
+			}
+			continue
+		}
+
+		matches := pageRgx.FindStringSubmatch(entry.Name())
+		if matches == nil || entry.Size() == 0 {
+			continue
+		}
+
+		idx, err := strconv.Atoi(matches[1])
+		if err != nil || idx < 1 || idx > size {
+			continue
+		}
+
+		pages[idx] = true
+	}
+
+	return pages
+}
+
+// RemovePartialChapter removes the files in the chapter directory a new download can't resume from, partially
+// written pages and streamed archives. The directory is removed if no complete pages remain
+func RemovePartialChapter(fs afero.Afero, chapterPath string) error {
+	entries, err := fs.ReadDir(chapterPath)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	hasPages := false
+	for _, entry := range entries {
+		switch {
+		case entry.IsDir():
+			continue
+		case strings.HasSuffix(entry.Name(), partialPageExt), entry.Name() == streamArchiveName:
+			errs = append(errs, fs.Remove(path.Join(chapterPath, entry.Name())))
+		case pageRgx.MatchString(entry.Name()) && entry.Size() > 0:
+			hasPages = true
+		}
+	}
+
+	if !hasPages {
+		errs = append(errs, fs.RemoveAll(chapterPath))
+	}
+
+	return errors.Join(errs...)
+}
+
 func (p *publication) ChapterLogger(chapter Chapter) zerolog.Logger {
 	builder := p.log.With().
 		Str("chapterId", chapter.Id).
@@ -422,10 +605,19 @@ func (p *publication) IOWorker(ctx context.Context, id string) {
 		}
 
 		err := p.ext.ioTaskFunc(p, ctx, log, task)
-		if err != nil {
-			p.abortDownload(ctx, fmt.Errorf("unable to run task '%v': %w", task.Path, err))
-			return
+		if err == nil {
+			continue
 		}
+
+		err = fmt.Errorf("unable to run task '%v': %w", task.Path, err)
+		// The download of the chapter counts its progress, only its failure is recorded
+		if p.req.GetBool(SkipFailedChaptersKey, false) {
+			p.markChapterFailed(task.Chapter, err)
+			continue
+		}
+
+		p.abortDownload(ctx, err)
+		return
 	}
 }
 
@@ -435,6 +627,7 @@ func (p *publication) abortDownload(ctx context.Context, reason error) {
 	}
 
 	p.log.Error().Err(reason).Msg("error while downloading content")
+	p.runError(reason)
 
 	if p.cancel != nil {
 		p.cancel()
@@ -444,10 +637,12 @@ func (p *publication) abortDownload(ctx context.Context, reason error) {
 	utils.WaitFor(p.wg, time.Minute*2)
 	utils.WaitFor(p.ioWg, time.Minute*2)
 
+	// Subscriptions download the content again on their next run, complete pages are kept to resume from
 	req := payload.StopRequest{
 		Provider:    p.Provider(),
 		Id:          p.Id(),
 		DeleteFiles: true,
+		KeepPages:   p.req.IsSubscription,
 	}
 
 	//nolint: contextcheck
@@ -0,0 +1,283 @@
+package publication
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"path"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/Fesaa/Media-Provider/db/models"
+	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/Fesaa/Media-Provider/internal/comicinfo"
+	"github.com/Fesaa/Media-Provider/services"
+	"github.com/Fesaa/Media-Provider/utils"
+	"github.com/rs/zerolog"
+	"github.com/spf13/afero"
+)
+
+func TestPublication_DownloadedPages(t *testing.T) {
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+	p := &publication{fs: fs, log: zerolog.Nop()}
+
+	dir := "Manga/Spice and Wolf Ch. 0001"
+	files := map[string][]byte{
+		"page 0001.jpg":      []byte("data"),
+		"page 0002.webp":     []byte("data"),
+		"page 0003.jpg.part": []byte("da"),
+		"page 0004.png":      {},
+		"page 0009.jpg":      []byte("data"),
+		"ComicInfo.xml":      []byte("<ComicInfo/>"),
+	}
+	for name, data := range files {
+		if err := fs.WriteFile(path.Join(dir, name), data, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := slices.Sorted(maps.Keys(p.downloadedPages(dir, 5)))
+	if want := []int{1, 2}; !slices.Equal(got, want) {
+		t.Errorf("Got %v; expected %v", got, want)
+	}
+
+	if ok, _ := fs.Exists(path.Join(dir, "page 0003.jpg.part")); ok {
+		t.Error("partially written page was not removed")
+	}
+
+	if got = slices.Collect(maps.Keys(p.downloadedPages("Manga/Missing", 5))); len(got) != 0 {
+		t.Errorf("Got %v; expected no pages", got)
+	}
+}
+
+func TestRemovePartialChapter(t *testing.T) {
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+
+	resumable := "Manga/Spice and Wolf Ch. 0001"
+	partial := "Manga/Spice and Wolf Ch. 0002"
+	files := map[string][]byte{
+		path.Join(resumable, "page 0001.jpg"):      []byte("data"),
+		path.Join(resumable, "page 0002.jpg.part"): []byte("da"),
+		path.Join(resumable, "ComicInfo.xml"):      []byte("<ComicInfo/>"),
+		path.Join(partial, streamArchiveName):      []byte("PK"),
+		path.Join(partial, "ComicInfo.xml"):        []byte("<ComicInfo/>"),
+	}
+	for name, data := range files {
+		if err := fs.WriteFile(name, data, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, dir := range []string{resumable, partial} {
+		if err := RemovePartialChapter(fs, dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if ok, _ := fs.Exists(path.Join(resumable, "page 0001.jpg")); !ok {
+		t.Error("Expected complete pages to be kept")
+	}
+	if ok, _ := fs.Exists(path.Join(resumable, "page 0002.jpg.part")); ok {
+		t.Error("Expected partially written pages to be removed")
+	}
+	if ok, _ := fs.DirExists(partial); ok {
+		t.Error("Expected chapters without complete pages to be removed")
+	}
+}
+
+type baseDirClient struct {
+	Client
+}
+
+func (baseDirClient) GetBaseDir() string {
+	return "temp"
+}
+
+func TestPublication_SkipFailedChapter(t *testing.T) {
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+	chapters := []Chapter{
+		{Id: "1", Chapter: "1", Title: "Wolf"},
+		{Id: "2", Chapter: "2", Title: "Spice"},
+	}
+	p := &publication{
+		fs:           fs,
+		log:          zerolog.Nop(),
+		client:       baseDirClient{},
+		series:       &Series{Title: "Spice and Wolf", Chapters: chapters},
+		speedTracker: utils.NewSpeedTracker(len(chapters)),
+		toDownload:   []string{"1", "2"},
+	}
+
+	failedPath := p.ContentPath(chapters[0])
+	okPath := p.ContentPath(chapters[1])
+	p.hasDownloaded = []string{failedPath, okPath}
+	p.toRemoveContent = []string{failedPath + ".cbz", okPath + ".cbz"}
+
+	if err := fs.WriteFile(path.Join(failedPath, "page 0001.jpg"), []byte("data"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	p.skipFailedChapter(chapters[0], errors.New("bad status: 404"))
+	p.removeFailedChapters()
+
+	if got := p.GetNewContent(); !slices.Equal(got, []string{okPath}) {
+		t.Errorf("Got %v; expected %v", got, []string{okPath})
+	}
+
+	if got := p.GetToRemoveContent(); !slices.Equal(got, []string{okPath + ".cbz"}) {
+		t.Errorf("Got %v; expected content of failed chapters to be kept, got %v", got, p.toRemoveContent)
+	}
+
+	if got := p.GetNewContentNamed(); !slices.Equal(got, []string{chapters[1].Label()}) {
+		t.Errorf("Got %v; expected %v", got, []string{chapters[1].Label()})
+	}
+
+	failed := p.FailedChapters()
+	if len(failed) != 1 || failed[0].Id != "1" {
+		t.Fatalf("Got %v; expected chapter 1 to have failed", failed)
+	}
+
+	if ok, _ := fs.DirExists(failedPath); ok {
+		t.Error("files of failed chapter were not removed")
+	}
+
+	if progress := p.speedTracker.Progress(); progress != 50 {
+		t.Errorf("Got progress %f; expected %f", progress, 50.0)
+	}
+}
+
+func TestPublication_IOWorkerSkipsFailedChapter(t *testing.T) {
+	chapters := []Chapter{
+		{Id: "1", Chapter: "1", Title: "Wolf"},
+		{Id: "2", Chapter: "2", Title: "Spice"},
+	}
+	p := &publication{
+		fs:     afero.Afero{Fs: afero.NewMemMapFs()},
+		log:    zerolog.Nop(),
+		client: baseDirClient{},
+		series: &Series{Title: "Spice and Wolf", Chapters: chapters},
+		ioWg:   &sync.WaitGroup{},
+		ext: Extensions{ioTaskFunc: func(_ *publication, _ context.Context, _ zerolog.Logger, task ioTask) error {
+			if task.Chapter.Id == "1" {
+				return errors.New("disk full")
+			}
+			return nil
+		}},
+	}
+	p.req.DownloadMetadata.Extra = utils.SmartMap{SkipFailedChaptersKey: {"true"}}
+	p.hasDownloaded = []string{p.ContentPath(chapters[0]), p.ContentPath(chapters[1])}
+
+	p.iOWorkCh = make(chan ioTask, 3)
+	p.iOWorkCh <- ioTask{Chapter: chapters[0], Task: downloadTask{Idx: 1}}
+	p.iOWorkCh <- ioTask{Chapter: chapters[0], Task: downloadTask{Idx: 2}}
+	p.iOWorkCh <- ioTask{Chapter: chapters[1], Task: downloadTask{Idx: 1}}
+	close(p.iOWorkCh)
+
+	// Aborting would remove the download through the client, which isn't set
+	p.IOWorker(t.Context(), "test")
+
+	failed := p.FailedChapters()
+	if len(failed) != 1 || failed[0].Id != "1" {
+		t.Fatalf("Got %v; expected chapter 1 to have failed once", failed)
+	}
+
+	if got := p.GetNewContent(); !slices.Equal(got, []string{p.ContentPath(chapters[1])}) {
+		t.Errorf("Got %v; expected only chapter 2 to be new content", got)
+	}
+}
+
+func TestPublication_RetriesChaptersFailedLastRun(t *testing.T) {
+	chapters := []Chapter{
+		{Id: "1", Chapter: "1", Title: "Wolf"},
+		{Id: "2", Chapter: "2", Title: "Spice"},
+	}
+	p := &publication{
+		log:    zerolog.Nop(),
+		client: baseDirClient{},
+		series: &Series{Title: "Spice and Wolf", Chapters: chapters},
+		ext: Extensions{volumeFunc: func(_ *publication, content Content) (string, error) {
+			return content.Volume, nil
+		}},
+	}
+	p.req.IsSubscription = true
+	p.req.Sub = &models.Subscription{FailedChapters: []string{"1"}}
+	for _, chapter := range chapters {
+		name := p.ContentFileName(chapter) + ".cbz"
+		p.existingContent = append(p.existingContent, Content{Name: name, Path: path.Join("Spice and Wolf", name)})
+	}
+
+	if reason := p.skipReason(chapters[0]); reason != "" {
+		t.Errorf("Got %q; expected the chapter that failed to be downloaded again", reason)
+	}
+	if reason := p.skipReason(chapters[1]); reason != models.SkipOnDisk {
+		t.Errorf("Got %q; expected the downloaded chapter to be skipped", reason)
+	}
+
+	want := []string{path.Join("temp", p.existingContent[0].Path)}
+	if !slices.Equal(p.toRemoveContent, want) {
+		t.Errorf("Got %v; expected the kept content of the failed chapter to be replaced", p.toRemoveContent)
+	}
+}
+
+func TestPublication_RecordsRun(t *testing.T) {
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+	chapters := []Chapter{
+		{Id: "1", Chapter: "1", Title: "Wolf"},
+		{Id: "2", Chapter: "2", Title: "Spice"},
+		{Id: "3", Volume: "1", Title: "Extra"},
+	}
+
+	sub := &models.Subscription{Run: models.NewSubscriptionRun(1, models.TriggerCron)}
+	p := &publication{
+		fs:             fs,
+		log:            zerolog.Nop(),
+		client:         baseDirClient{},
+		ext:            CbzExt(),
+		archiveService: services.ArchiveServiceProvider(zerolog.Nop(), fs),
+		series:         &Series{Title: "Spice and Wolf", Chapters: chapters},
+		req: payload.DownloadRequest{
+			BaseDir: "Manga",
+			Sub:     sub,
+			DownloadMetadata: models.DownloadRequestMetadata{
+				Extra: utils.SmartMap{
+					SkipVolumeWithoutChapter: {"true"},
+					OutputFormatKey:          {string(FormatEpub)},
+				},
+			},
+		},
+	}
+
+	writeCbz(t, fs, p.ContentPath(chapters[0])+".cbz", &comicinfo.ComicInfo{Number: "1"})
+
+	if _, err := p.filterAlreadyDownloadedContent(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(p.toDownload, []string{"2"}) {
+		t.Errorf("Got %v; expected only chapter 2 to be downloaded", p.toDownload)
+	}
+
+	run := sub.Run
+	if run.Found != 3 {
+		t.Errorf("Got %d found; expected all chapters", run.Found)
+	}
+
+	want := []models.SkippedContent{
+		{Name: chapters[0].Label(), Reason: models.SkipOnDisk},
+		{Name: chapters[2].Label(), Reason: models.SkipNoChapter},
+	}
+	if !slices.Equal(run.Skipped, want) {
+		t.Errorf("Got skipped %v; expected %v", run.Skipped, want)
+	}
+
+	p.hasDownloaded = []string{p.ContentPath(chapters[1])}
+	if got, want := p.WrittenFiles(), []string{"Manga/Spice and Wolf/Spice and Wolf Ch. 0002.epub"}; !slices.Equal(got, want) {
+		t.Errorf("Got %v; expected %v", got, want)
+	}
+
+	p.runError(errors.New("bad status: 500"))
+	if !slices.Equal(run.Errors, []string{"bad status: 500"}) {
+		t.Errorf("Got errors %v; expected the error to be recorded", run.Errors)
+	}
+}
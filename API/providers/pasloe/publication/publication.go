@@ -16,11 +16,13 @@ import (
 	"github.com/Fesaa/Media-Provider/db/models"
 	"github.com/Fesaa/Media-Provider/http/menou"
 	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/Fesaa/Media-Provider/internal/naming"
 	"github.com/Fesaa/Media-Provider/internal/tracing"
 	"github.com/Fesaa/Media-Provider/services"
 	"github.com/Fesaa/Media-Provider/utils"
 	"github.com/rs/zerolog"
 	"github.com/spf13/afero"
+	"golang.org/x/time/rate"
 )
 
 const (
@@ -32,6 +34,11 @@ const (
 	AssignEmptyVolumes       string = "assign_empty_volumes"
 	ScanlationGroupKey       string = "scanlation_group"
 	SkipVolumeWithoutChapter string = "skip_volume_without_chapter"
+	OutputFormatKey          string = "output_format"
+	SkipFailedChaptersKey    string = "skip_failed_chapters"
+	FileNameTemplateKey      string = "file_name_template"
+	VolumeDirTemplateKey     string = "volume_dir_template"
+	WriteMetronInfoKey       string = "write_metron_info"
 )
 
 const (
@@ -44,6 +51,8 @@ type Client interface {
 	GetBaseDir() string
 	MoveToDownloadQueue(id string) error
 	GetCurrentDownloads() []Publication
+	// Persist saves the publication so it's resumed after a restart, unless it has been removed from the client
+	Persist(Publication)
 }
 
 type Publication interface {
@@ -71,9 +80,28 @@ type Publication interface {
 
 	// GetNewContentNamed returns the names of the downloaded content (chapters)
 	GetNewContentNamed() []string
+	// WrittenFiles returns the paths of the files written for the downloaded content, relative to the root dir
+	WrittenFiles() []string
 
 	FailedDownloads() int
+	// FailedChapters returns the chapters that were skipped after failing to download, see SkipFailedChaptersKey
+	FailedChapters() []FailedChapter
 	UpdateSeriesInfo(f func(*Series))
+
+	// UserSelection returns the ids of the chapters selected by the user, empty if no selection was made
+	UserSelection() []string
+	// RestoreUserSelection restores a selection made before a restart, must be called before metadata is loaded
+	RestoreUserSelection([]string)
+
+	// Reorganise moves content on disk to match the current naming scheme, see publication.Reorganise
+	Reorganise(ctx context.Context, dryRun bool) (payload.ReorganiseReport, error)
+}
+
+// FailedChapter is a chapter that failed to download, and was skipped
+type FailedChapter struct {
+	Id    string
+	Label string
+	Err   error
 }
 
 type Content struct {
@@ -107,6 +135,16 @@ func New(
 		return nil, err
 	}
 
+	fileNameTemplate, err := templateFromRequest(req, FileNameTemplateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	volumeDirTemplate, err := templateFromRequest(req, VolumeDirTemplateKey)
+	if err != nil {
+		return nil, err
+	}
+
 	return &publication{
 		log:                 log.With().Str("handler", req.Provider.String()).Logger(),
 		signalR:             signalR,
@@ -121,9 +159,14 @@ func New(
 		client:              client,
 		ext:                 ext,
 
-		maxImages:  utils.Clamp(settings.MaxConcurrentImages, 1, 5),
-		req:        req,
-		repository: repository,
+		maxImages:   utils.Clamp(settings.MaxConcurrentImages, 1, 5),
+		maxChapters: utils.Clamp(settings.MaxConcurrentChapters, 1, 5),
+		req:         req,
+		repository:  repository,
+
+		fileNameTemplate:  fileNameTemplate,
+		volumeDirTemplate: volumeDirTemplate,
+		contentMatchers:   templateMatchers(fileNameTemplate),
 
 		toggles:               utils.NewToggles[string](),
 		hasDuplicatedChapters: utils.Settable[bool]{},
@@ -146,27 +189,46 @@ type publication struct {
 	ext                 Extensions
 
 	maxImages   int
+	maxChapters int
 	req         payload.DownloadRequest
 	state       payload.ContentState
 	preferences *models.UserPreferences
 	repository  Repository
 	series      *Series
 
+	// fileNameTemplate and volumeDirTemplate replace the default naming when set
+	fileNameTemplate  *naming.Template
+	volumeDirTemplate *naming.Template
+	// contentMatchers recognise content named after fileNameTemplate, empty if no template is set
+	contentMatchers []contentMatcher
+
 	toggles *utils.Toggles[string]
 	// hasDuplicatedChapters is true if the same chapter number is used across different volumes
 	// forcing us to use volumes in the file name
 	hasDuplicatedChapters utils.Settable[bool]
 
 	toDownload      []string  // All chapters id that need to be downloaded
-	hasDownloaded   []string  // path of files on disk we've already downloaded
+	hasDownloaded   []string  // path of files on disk we've already downloaded, guarded by hasDownloadedLock
 	existingContent []Content // Content already on disk before download started
 	toRemoveContent []string  // content on disk that has to be removed as it has been redownloaded
 
 	// toDownloadUserSelected are the ids of the content selected by the user to download in the UI
 	toDownloadUserSelected []string
 
+	hasDownloadedLock sync.RWMutex
+
+	// failedChapters are chapters skipped after failing to download, guarded by failedChaptersLock
+	failedChapters     []FailedChapter
+	failedChaptersLock sync.RWMutex
+	// runLock guards the subscription run of the request, see runError
+	runLock sync.Mutex
+
 	failedDownloads int64
 	speedTracker    *utils.SpeedTracker
+	// rateLimiter is shared by all chapters being downloaded
+	rateLimiter *rate.Limiter
+	// streams are the archives pages are written into by CbzStreamExt
+	streams cbzStreams
 
 	cancel context.CancelFunc
 	// Wait group used to track chapters being downloaded
@@ -204,6 +266,20 @@ func (p *publication) State() payload.ContentState {
 func (p *publication) SetState(state payload.ContentState) {
 	p.state = state
 	p.signalR.StateUpdate(p.req.OwnerId, p.Id(), p.state)
+	p.persist()
+}
+
+// persist saves the current state, so the download can be resumed after a restart
+func (p *publication) persist() {
+	p.client.Persist(p)
+}
+
+func (p *publication) UserSelection() []string {
+	return p.toDownloadUserSelected
+}
+
+func (p *publication) RestoreUserSelection(selection []string) {
+	p.toDownloadUserSelected = selection
 }
 
 func (p *publication) Request() payload.DownloadRequest {
@@ -224,6 +300,8 @@ func (p *publication) Cancel() {
 		utils.WaitFor(p.ioWg, time.Minute)
 	}
 
+	p.streams.abortAll(p.log)
+
 	if p.client.Content(p.Id()) == nil {
 		return
 	}
@@ -259,7 +337,10 @@ func (p *publication) GetOnDiskContent() []Content {
 }
 
 func (p *publication) GetNewContent() []string {
-	return p.hasDownloaded
+	p.hasDownloadedLock.RLock()
+	defer p.hasDownloadedLock.RUnlock()
+
+	return slices.Clone(p.hasDownloaded)
 }
 
 func (p *publication) GetContentByName(name string) (Content, bool) {
@@ -340,6 +421,7 @@ func (p *publication) LoadMetadata(ctx context.Context) {
 	if err = p.loadSeriesInfo(ctx); err != nil {
 		if !errors.Is(err, context.Canceled) {
 			p.log.Error().Err(err).Msg("failed to load series info")
+			p.runError(fmt.Errorf("failed to load series info: %w", err))
 		}
 		p.StopDownload()
 		return
@@ -361,6 +443,7 @@ func (p *publication) LoadMetadata(ctx context.Context) {
 
 		if err = p.ensureSubscriptionDirectoryIsUpToDate(ctx); err != nil {
 			p.log.Error().Err(err).Msg("An error occurred while updating subscription directories. Cancelling download")
+			p.runError(fmt.Errorf("failed to update subscription directories: %w", err))
 			p.StopDownload()
 			return
 		}
@@ -425,8 +508,11 @@ func (p *publication) handleSubscriptionNoDownloadCount(ctx context.Context, res
 		p.req.Sub.NoDownloadCount++
 	}
 
-	// Leave notifications for counts above 5, and only once every 5 days
-	if p.req.Sub.NoDownloadCount >= 5 && p.req.Sub.NoDownloadCount%5 == 0 && p.preferences.LogEmptyDownloads {
+	p.req.Sub.ObserveReleases(p.releaseDates(), time.Now())
+
+	// Leave notifications for counts above 5, and only once every 5 days. Adaptive subscriptions slow down on their own
+	if p.req.Sub.NoDownloadCount >= 5 && p.req.Sub.NoDownloadCount%5 == 0 && p.preferences.LogEmptyDownloads &&
+		!p.req.Sub.IsAdaptive() {
 		p.notificationService.Notify(ctx, models.NewNotification().
 			WithTitle(p.translocoService.GetTranslation("sub-too-frequent")).
 			WithBody(p.translocoService.GetTranslation("sub-too-frequent-body", p.Title())).
@@ -442,6 +528,21 @@ func (p *publication) handleSubscriptionNoDownloadCount(ctx context.Context, res
 	}
 }
 
+// releaseDates returns the release dates of all chapters, none for completed series as no new chapters are expected
+func (p *publication) releaseDates() []time.Time {
+	if p.series == nil || p.series.Status == StatusCompleted {
+		return nil
+	}
+
+	dates := make([]time.Time, 0, len(p.series.Chapters))
+	for _, chapter := range p.series.Chapters {
+		if chapter.ReleaseDate != nil {
+			dates = append(dates, *chapter.ReleaseDate)
+		}
+	}
+	return dates
+}
+
 func (p *publication) loadSeriesInfo(ctx context.Context) error {
 	ctx, span := tracing.TracerPasloe.Start(ctx, tracing.SpanPasLoadContentInfo)
 	defer span.End()
@@ -542,9 +643,20 @@ func (p *publication) filterAlreadyDownloadedContent(ctx context.Context) (time.
 		return time.Since(start), err
 	}
 
+	var run *models.SubscriptionRun
+	if p.req.Sub != nil && p.req.Sub.Run != nil {
+		run = p.req.Sub.Run
+		run.Found = len(p.series.Chapters)
+	}
+
 	p.toDownload = utils.MaybeMap(p.series.Chapters, func(chapter Chapter) (string, bool) {
-		return chapter.Id, p.ShouldDownload(chapter)
+		reason := p.skipReason(chapter)
+		if reason != "" && run != nil {
+			run.Skip(chapter.Label(), reason)
+		}
+		return chapter.Id, reason == ""
 	})
+
 	return time.Since(start), nil
 }
 
@@ -573,7 +685,7 @@ func (p *publication) onDiskContent(ctx context.Context) ([]Content, error) {
 }
 
 // parseDirectoryForContent parses the directory @ Client.GetBaseDir/contentPath for conent
-// A file is considered content if PublicationExtensions.isContentFunc return true
+// A file is considered content if publication.isContent return true
 // The given context.Context is checked for an error on each loop per entry
 func (p *publication) parseDirectoryForContent(ctx context.Context, contentPath string) ([]Content, error) {
 	dirEntries, err := p.fs.ReadDir(path.Join(p.client.GetBaseDir(), contentPath))
@@ -596,7 +708,7 @@ func (p *publication) parseDirectoryForContent(ctx context.Context, contentPath
 			continue
 		}
 
-		c, ok := p.ext.isContentFunc(entry.Name())
+		c, ok := p.isContent(entry.Name())
 		if !ok {
 			p.log.Trace().Str("fileName", entry.Name()).Msg("skipping non-content file")
 			continue
@@ -616,9 +728,14 @@ func (p *publication) parseDirectoryForContent(ctx context.Context, contentPath
 
 // ShouldDownload returns true if the given chapter should be downloaded, this may be overwritten by a user selection
 func (p *publication) ShouldDownload(chapter Chapter) bool {
+	return p.skipReason(chapter) == ""
+}
+
+// skipReason returns why the given chapter should not be downloaded, empty if it should be
+func (p *publication) skipReason(chapter Chapter) models.SkipReason {
 	// Backwards compatibility check if volume has been downloaded
 	if _, ok := p.GetContentByName(p.VolumeDir(chapter)); ok {
-		return false
+		return models.SkipOnDisk
 	}
 
 	content, ok := p.GetContentByName(p.ContentFileName(chapter))
@@ -626,11 +743,11 @@ func (p *publication) ShouldDownload(chapter Chapter) bool {
 		content, ok = p.GetContentByVolumeAndChapter(chapter.Volume, chapter.Chapter)
 		if !ok {
 			// Some providers, *dynasty*, have terrible naming schemes for specials.
-			if p.req.GetBool(SkipVolumeWithoutChapter, false) && chapter.Volume != "" {
-				return chapter.Chapter != ""
+			if p.req.GetBool(SkipVolumeWithoutChapter, false) && chapter.Volume != "" && chapter.Chapter == "" {
+				return models.SkipNoChapter
 			}
 
-			return true
+			return ""
 		}
 	}
 
@@ -638,7 +755,7 @@ func (p *publication) ShouldDownload(chapter Chapter) bool {
 	if err != nil {
 		p.log.Warn().Err(err).Str("path", content.Path).
 			Msg("failed to retrieve volume on disk")
-		return false
+		return models.SkipOnDisk
 	}
 
 	if chapter.Volume != "" && onDiskVolume != chapter.Volume {
@@ -647,10 +764,24 @@ func (p *publication) ShouldDownload(chapter Chapter) bool {
 			Msg("redownloading content")
 
 		p.toRemoveContent = append(p.toRemoveContent, path.Join(p.client.GetBaseDir(), content.Path))
-		return true
+		return ""
 	}
 
-	return false
+	// Content kept for a chapter that failed to replace it during the last run is replaced now
+	if p.failedLastRun(chapter) {
+		p.log.Debug().Str("chapter", chapter.Label()).Msg("retrying chapter that failed during the last run")
+
+		p.toRemoveContent = append(p.toRemoveContent, path.Join(p.client.GetBaseDir(), content.Path))
+		return ""
+	}
+
+	return models.SkipOnDisk
+}
+
+// failedLastRun returns true if the chapter failed during the last run of the subscription downloading it
+func (p *publication) failedLastRun(chapter Chapter) bool {
+	sub := p.req.Sub
+	return p.req.IsSubscription && sub != nil && slices.Contains(sub.FailedChapters, chapter.Id)
 }
 
 func (p *publication) DownloadContent(ctx context.Context) {
@@ -672,16 +803,51 @@ func (p *publication) DownloadContent(ctx context.Context) {
 }
 
 func (p *publication) GetNewContentNamed() []string {
+	failed := p.FailedChapters()
+
 	return utils.MaybeMap(p.toDownload, func(id string) (string, bool) {
 		chapter, ok := p.getChapterById(id)
-		return chapter.Label(), ok
+		if !ok {
+			return "", false
+		}
+
+		return chapter.Label(), !slices.ContainsFunc(failed, func(f FailedChapter) bool {
+			return f.Id == id
+		})
+	})
+}
+
+func (p *publication) WrittenFiles() []string {
+	ext := FormatFor(p.req).Ext()
+	baseDir := p.client.GetBaseDir()
+
+	return utils.Map(p.GetNewContent(), func(dir string) string {
+		return strings.TrimPrefix(dir, baseDir+"/") + ext
 	})
 }
 
+// runError records the error on the subscription run the content is downloaded for, if any
+func (p *publication) runError(err error) {
+	if p.req.Sub == nil || p.req.Sub.Run == nil {
+		return
+	}
+
+	p.runLock.Lock()
+	defer p.runLock.Unlock()
+	p.req.Sub.Run.AddError(err)
+}
+
 func (p *publication) FailedDownloads() int {
 	return int(p.failedDownloads)
 }
 
+func (p *publication) FailedChapters() []FailedChapter {
+	p.failedChaptersLock.RLock()
+	defer p.failedChaptersLock.RUnlock()
+
+	return slices.Clone(p.failedChapters)
+}
+
 func (p *publication) UpdateSeriesInfo(f func(s *Series)) {
 	f(p.series)
 }
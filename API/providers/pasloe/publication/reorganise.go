@@ -0,0 +1,210 @@
+package publication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Fesaa/Media-Provider/http/payload"
+)
+
+// Reorganise moves content on disk to the path it would be downloaded to with the current naming scheme. Content is
+// identified by its file name, falling back to the ComicInfo.xml inside cbz archives. When dryRun is true, nothing
+// is moved, only the report is returned
+func (p *publication) Reorganise(ctx context.Context, dryRun bool) (payload.ReorganiseReport, error) {
+	if err := p.loadSeriesInfo(ctx); err != nil {
+		return payload.ReorganiseReport{}, fmt.Errorf("failed to load series info: %w", err)
+	}
+
+	report := payload.ReorganiseReport{
+		Title:     p.Title(),
+		DryRun:    dryRun,
+		Moves:     []payload.ReorganiseMove{},
+		Conflicts: []payload.ReorganiseMove{},
+		Unmatched: []string{},
+		Errors:    []string{},
+	}
+
+	dirs := p.reorganiseDirs()
+	p.existingContent = []Content{}
+	for _, dir := range dirs {
+		content, err := p.parseDirectoryForContent(ctx, dir)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return payload.ReorganiseReport{}, err
+		}
+		p.existingContent = append(p.existingContent, content...)
+	}
+
+	byName := make(map[string]Chapter, len(p.series.Chapters))
+	for _, chapter := range p.series.Chapters {
+		byName[p.ContentFileName(chapter)] = chapter
+	}
+
+	targets := make(map[string]bool)
+	for _, content := range p.existingContent {
+		if ctx.Err() != nil {
+			return payload.ReorganiseReport{}, ctx.Err()
+		}
+
+		chapter, ok := p.chapterForContent(content, byName)
+		if !ok {
+			report.Unmatched = append(report.Unmatched, content.Path)
+			continue
+		}
+
+		from := path.Join(p.client.GetBaseDir(), content.Path)
+		to := p.ContentPath(chapter) + path.Ext(content.Name)
+		if from == to {
+			report.UpToDate++
+			targets[to] = true
+			continue
+		}
+
+		move := payload.ReorganiseMove{Chapter: chapter.Label(), From: from, To: to}
+		if exists, _ := p.fs.Exists(to); exists || targets[to] {
+			report.Conflicts = append(report.Conflicts, move)
+			continue
+		}
+
+		targets[to] = true
+		report.Moves = append(report.Moves, move)
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	for _, move := range report.Moves {
+		if err := p.moveContent(move.From, move.To); err != nil {
+			p.log.Warn().Err(err).Str("from", move.From).Str("to", move.To).Msg("failed to move content")
+			report.Errors = append(report.Errors, err.Error())
+		}
+	}
+
+	for _, dir := range dirs {
+		p.removeEmptyDirs(path.Join(p.client.GetBaseDir(), dir))
+	}
+
+	// The subscription may have been changed since it was loaded, only the moved directory is written
+	if p.req.Sub != nil {
+		p.req.Sub.LastDownloadDir = path.Join(p.client.GetBaseDir(), p.GetDownloadDir())
+		if err := p.unitOfWork.Subscriptions.UpdateColumns(ctx, *p.req.Sub, "last_download_dir"); err != nil {
+			report.Errors = append(report.Errors, err.Error())
+		}
+	}
+
+	return report, nil
+}
+
+// reorganiseDirs returns the directories, relative to the base dir, content may be found in. Next to the current
+// download directory, this includes the directory a subscription was last downloaded in
+func (p *publication) reorganiseDirs() []string {
+	dirs := []string{p.GetDownloadDir()}
+
+	if p.req.Sub == nil || p.req.Sub.LastDownloadDir == "" {
+		return dirs
+	}
+
+	last, err := filepath.Rel(p.client.GetBaseDir(), p.req.Sub.LastDownloadDir)
+	if err != nil || strings.HasPrefix(last, "..") || last == dirs[0] {
+		return dirs
+	}
+
+	return append(dirs, filepath.ToSlash(last))
+}
+
+// chapterForContent returns the chapter the content on disk belongs to
+func (p *publication) chapterForContent(content Content, byName map[string]Chapter) (Chapter, bool) {
+	if chapter, ok := byName[strings.TrimSuffix(content.Name, path.Ext(content.Name))]; ok {
+		return chapter, true
+	}
+
+	volume, chapter, title := content.Volume, content.Chapter, ""
+	if chapter == "" && path.Ext(content.Name) == ".cbz" {
+		ci, err := p.archiveService.GetComicInfo(path.Join(p.client.GetBaseDir(), content.Path))
+		if err != nil {
+			p.log.Debug().Err(err).Str("path", content.Path).Msg("failed to read ComicInfo")
+		} else {
+			chapter, title = ci.Number, ci.Title
+			if ci.Volume > 0 {
+				volume = strconv.Itoa(ci.Volume)
+			}
+		}
+	}
+
+	var candidates []Chapter
+	for _, c := range p.series.Chapters {
+		switch {
+		case chapter == "" && c.Chapter == "":
+			if title != "" && c.Title == title {
+				candidates = append(candidates, c)
+			}
+		case chapter != "" && sameNumber(c.Chapter, chapter):
+			if volume == "" || c.Volume == "" || sameNumber(c.Volume, volume) {
+				candidates = append(candidates, c)
+			}
+		}
+	}
+
+	if len(candidates) != 1 {
+		if len(candidates) > 1 {
+			p.log.Debug().Str("path", content.Path).Int("candidates", len(candidates)).
+				Msg("content matches several chapters, not moving")
+		}
+		return Chapter{}, false
+	}
+
+	return candidates[0], true
+}
+
+// sameNumber compares chapter or volume markers, ignoring padding
+func sameNumber(a, b string) bool {
+	fa, errA := strconv.ParseFloat(a, 64)
+	fb, errB := strconv.ParseFloat(b, 64)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return fa == fb
+}
+
+func (p *publication) moveContent(from, to string) error {
+	if err := p.fs.MkdirAll(path.Dir(to), 0755); err != nil {
+		return err
+	}
+
+	return p.fs.Rename(from, to)
+}
+
+// removeEmptyDirs removes all empty directories in dir, including dir itself
+func (p *publication) removeEmptyDirs(dir string) {
+	entries, err := p.fs.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	empty := true
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			empty = false
+			continue
+		}
+
+		p.removeEmptyDirs(path.Join(dir, entry.Name()))
+		if ok, _ := p.fs.Exists(path.Join(dir, entry.Name())); ok {
+			empty = false
+		}
+	}
+
+	if !empty {
+		return
+	}
+
+	if err = p.fs.Remove(dir); err != nil {
+		p.log.Warn().Err(err).Str("dir", dir).Msg("failed to remove empty directory")
+	}
+}
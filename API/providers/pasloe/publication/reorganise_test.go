@@ -0,0 +1,138 @@
+package publication
+
+import (
+	"archive/zip"
+	"context"
+	"path"
+	"testing"
+
+	"github.com/Fesaa/Media-Provider/db/models"
+	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/Fesaa/Media-Provider/internal/comicinfo"
+	"github.com/Fesaa/Media-Provider/services"
+	"github.com/Fesaa/Media-Provider/utils"
+	"github.com/rs/zerolog"
+	"github.com/spf13/afero"
+)
+
+type seriesRepository struct {
+	series Series
+}
+
+func (r seriesRepository) SeriesInfo(context.Context, string, payload.DownloadRequest) (Series, error) {
+	return r.series, nil
+}
+
+func (r seriesRepository) ChapterUrls(context.Context, Chapter) ([]DownloadUrl, error) {
+	return nil, nil
+}
+
+func writeCbz(t *testing.T, fs afero.Afero, filePath string, ci *comicinfo.ComicInfo) {
+	t.Helper()
+
+	if err := fs.MkdirAll(path.Dir(filePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := fs.Create(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	if ci != nil {
+		cw, err := w.Create("ComicInfo.xml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = comicinfo.Write(ci, cw); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPublication_Reorganise(t *testing.T) {
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+	log := zerolog.Nop()
+
+	req := payload.DownloadRequest{
+		Provider: models.MANGADEX,
+		BaseDir:  "Manga",
+		DownloadMetadata: models.DownloadRequestMetadata{
+			Extra: utils.SmartMap{
+				FileNameTemplateKey:  {"{title} - {chapter_padded}"},
+				VolumeDirTemplateKey: {"Volume {volume}"},
+			},
+		},
+	}
+	fileNameTemplate, err := templateFromRequest(req, FileNameTemplateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	volumeDirTemplate, err := templateFromRequest(req, VolumeDirTemplateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &publication{
+		fs:             fs,
+		log:            log,
+		client:         baseDirClient{},
+		req:            req,
+		ext:            CbzExt(),
+		archiveService: services.ArchiveServiceProvider(log, fs),
+		repository: seriesRepository{series: Series{
+			Title: "Spice and Wolf",
+			Chapters: []Chapter{
+				{Id: "1", Volume: "1", Chapter: "1"},
+				{Id: "2", Volume: "1", Chapter: "2"},
+			},
+		}},
+		fileNameTemplate:  fileNameTemplate,
+		volumeDirTemplate: volumeDirTemplate,
+		contentMatchers:   templateMatchers(fileNameTemplate),
+	}
+
+	dir := "temp/Manga/Spice and Wolf"
+	oldName := path.Join(dir, "Spice and Wolf Vol. 1", "Spice and Wolf Ch. 0001.cbz")
+	renamed := path.Join(dir, "Chapter two.cbz")
+	unknown := path.Join(dir, "Extras.cbz")
+
+	writeCbz(t, fs, oldName, nil)
+	writeCbz(t, fs, renamed, &comicinfo.ComicInfo{Number: "2", Volume: 1})
+	writeCbz(t, fs, unknown, nil)
+
+	report, err := p.Reorganise(t.Context(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Moves) != 2 || len(report.Unmatched) != 1 || len(report.Conflicts) != 0 {
+		t.Fatalf("Got %+v; expected 2 moves and 1 unmatched file", report)
+	}
+	if ok, _ := fs.Exists(oldName); !ok {
+		t.Fatal("content was moved during a dry run")
+	}
+
+	if _, err = p.Reorganise(t.Context(), false); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		path.Join(dir, "Volume 1", "Spice and Wolf - 0001.cbz"),
+		path.Join(dir, "Volume 1", "Spice and Wolf - 0002.cbz"),
+		unknown,
+	} {
+		if ok, _ := fs.Exists(want); !ok {
+			t.Errorf("expected %s to exist", want)
+		}
+	}
+
+	if ok, _ := fs.DirExists(path.Join(dir, "Spice and Wolf Vol. 1")); ok {
+		t.Error("empty volume directory was not removed")
+	}
+}
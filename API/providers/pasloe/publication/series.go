@@ -88,7 +88,12 @@ func (c Chapter) Label() string {
 	return fmt.Sprintf("OneShot: %s", c.Title)
 }
 
+// VolumeDir returns the name of the directory chapters of the volume are downloaded in, see VolumeDirTemplateKey
 func (p *publication) VolumeDir(chapter Chapter) string {
+	if p.volumeDirTemplate != nil {
+		return p.volumeDirTemplate.Render(p.namingValues(chapter))
+	}
+
 	return fmt.Sprintf("%s Vol. %s", p.Title(), chapter.Volume)
 }
 
@@ -97,15 +102,25 @@ func (p *publication) VolumeDir(chapter Chapter) string {
 func (p *publication) ContentPath(chapter Chapter) string {
 	base := path.Join(p.client.GetBaseDir(), p.req.BaseDir, p.Title())
 
-	if chapter.Volume != "" && !config.DisableVolumeDirs {
+	// A volume dir template overwrites DISABLE_VOLUME_DIRS, as it's set explicitly
+	if chapter.Volume != "" && (p.volumeDirTemplate != nil || !config.DisableVolumeDirs) {
 		base = path.Join(base, p.VolumeDir(chapter))
 	}
 
 	return path.Join(base, p.ContentFileName(chapter))
 }
 
-// ContentFileName return the filename for a chapter
+// ContentFileName return the filename for a chapter, rendered from the file name template if one is set
 func (p *publication) ContentFileName(chapter Chapter) string {
+	if p.fileNameTemplate != nil {
+		if fileName := p.fileNameTemplate.Render(p.namingValues(chapter)); fileName != "" {
+			return utils.Ternary(chapter.Chapter == "", p.uniqueOneShotName(fileName, chapter), fileName)
+		}
+
+		p.log.Warn().Str("template", p.fileNameTemplate.String()).Str("chapter", chapter.Label()).
+			Msg("file name template rendered an empty name, falling back to default")
+	}
+
 	if chapter.Chapter == "" {
 		return p.OneShotFileName(chapter)
 	}
@@ -162,6 +177,14 @@ func (p *publication) OneShotFileName(chapter Chapter) string {
 		oneShotPath += " (One Shot)"
 	}
 
+	return p.uniqueOneShotName(oneShotPath, chapter)
+}
+
+// uniqueOneShotName appends a counter to the name while it's already been used by a downloaded OneShot
+func (p *publication) uniqueOneShotName(oneShotPath string, chapter Chapter) string {
+	p.hasDownloadedLock.RLock()
+	defer p.hasDownloadedLock.RUnlock()
+
 	finalOneShotPath := oneShotPath
 	for i := 0; slices.Contains(p.hasDownloaded, finalOneShotPath); i++ {
 		finalOneShotPath = fmt.Sprintf("%s (%d)", oneShotPath, i)
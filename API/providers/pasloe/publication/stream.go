@@ -0,0 +1,370 @@
+package publication
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/spf13/afero"
+)
+
+// streamArchiveName is the archive pages are streamed into. It lives inside the chapter directory, so it's removed
+// alongside it when the download fails, and is only moved next to it during cleanup
+const streamArchiveName = ".stream.cbz"
+
+var errNoStreamedPages = errors.New("no pages were written to the streamed archive")
+
+// cbzStreams holds the archives of all chapters being streamed, keyed by chapter directory
+type cbzStreams struct {
+	lock    sync.Mutex
+	streams map[string]*cbzStream
+}
+
+// get returns the stream for the chapter directory, creating it if needed
+func (s *cbzStreams) get(p *publication, dir string, size int) (*cbzStream, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if stream, ok := s.streams[dir]; ok {
+		return stream, nil
+	}
+
+	stream, err := newCbzStream(p, dir, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.streams == nil {
+		s.streams = make(map[string]*cbzStream)
+	}
+	s.streams[dir] = stream
+	return stream, nil
+}
+
+// remove returns, and forgets, the stream for the chapter directory
+func (s *cbzStreams) remove(dir string) (*cbzStream, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	stream, ok := s.streams[dir]
+	delete(s.streams, dir)
+	return stream, ok
+}
+
+// abortAll closes all unfinished streams, their archives are left incomplete
+func (s *cbzStreams) abortAll(log zerolog.Logger) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for dir, stream := range s.streams {
+		if err := stream.abort(); err != nil {
+			log.Warn().Err(err).Str("dir", dir).Msg("failed to close streamed archive")
+		}
+	}
+}
+
+type streamPage struct {
+	name string
+	data []byte
+}
+
+// cbzStream writes the pages of one chapter into a zip archive in page order. Pages arriving out of order are
+// kept in memory until all pages before them have been written
+type cbzStream struct {
+	lock sync.Mutex
+
+	fs   afero.Afero
+	dir  string
+	file afero.File
+	zip  *zip.Writer
+	size int
+	next int
+	// written is the amount of pages in the archive
+	written int
+	pending map[int]streamPage
+	// onDisk are pages already written to the chapter directory by a previous download
+	onDisk map[int]string
+	// done is true once the archive has been closed, aborted is true if it was closed before all pages were added
+	done    bool
+	aborted bool
+}
+
+func newCbzStream(p *publication, dir string, size int) (*cbzStream, error) {
+	file, err := p.fs.Create(path.Join(dir, streamArchiveName))
+	if err != nil {
+		return nil, err
+	}
+
+	return &cbzStream{
+		fs:      p.fs,
+		dir:     dir,
+		file:    file,
+		zip:     zip.NewWriter(file),
+		size:    size,
+		next:    1,
+		pending: make(map[int]streamPage),
+		onDisk:  pagesOnDisk(p.fs, dir, size),
+	}, nil
+}
+
+// pagesOnDisk returns the file names of the pages written to the directory, by page index
+func pagesOnDisk(fs afero.Afero, dir string, size int) map[int]string {
+	pages := make(map[int]string)
+
+	entries, err := fs.ReadDir(dir)
+	if err != nil {
+		return pages
+	}
+
+	for _, entry := range entries {
+		matches := pageRgx.FindStringSubmatch(entry.Name())
+		if matches == nil || entry.IsDir() || entry.Size() == 0 {
+			continue
+		}
+
+		if idx, err := strconv.Atoi(matches[1]); err == nil && idx >= 1 && idx <= size {
+			pages[idx] = entry.Name()
+		}
+	}
+
+	return pages
+}
+
+// add writes the page, and all pages following it that are available, to the archive. The archive is finished
+// once the last page has been written
+func (s *cbzStream) add(idx int, name string, data []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.done {
+		return nil
+	}
+
+	s.pending[idx] = streamPage{name: name, data: data}
+
+	for s.next <= s.size {
+		if page, ok := s.pending[s.next]; ok {
+			if err := s.write(page.name, page.data); err != nil {
+				return err
+			}
+			delete(s.pending, s.next)
+		} else if fileName, ok := s.onDisk[s.next]; ok {
+			data, err := s.fs.ReadFile(path.Join(s.dir, fileName))
+			if err != nil {
+				return err
+			}
+			if err = s.write(fileName, data); err != nil {
+				return err
+			}
+		} else {
+			return nil
+		}
+
+		s.next++
+	}
+
+	return s.finish()
+}
+
+func (s *cbzStream) write(name string, data []byte) error {
+	w, err := s.zip.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err = w.Write(data); err != nil {
+		return err
+	}
+
+	s.written++
+	return nil
+}
+
+// flush writes all remaining pages, skipping those that never arrived, and finishes the archive. Used when pages
+// failed to download, the archive then holds all pages that did. The archive is closed, even on error
+func (s *cbzStream) flush() (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.aborted {
+		return 0, errors.New("streamed archive was aborted")
+	}
+	if s.done {
+		return s.written, nil
+	}
+
+	err := s.flushPages()
+	if err == nil && s.written == 0 {
+		err = errNoStreamedPages
+	}
+	if err == nil {
+		err = s.finish()
+	}
+	if err != nil {
+		s.done, s.aborted = true, true
+		s.pending = nil
+		return 0, errors.Join(err, s.file.Close())
+	}
+
+	return s.written, nil
+}
+
+func (s *cbzStream) flushPages() error {
+	for ; s.next <= s.size; s.next++ {
+		if page, ok := s.pending[s.next]; ok {
+			if err := s.write(page.name, page.data); err != nil {
+				return err
+			}
+			delete(s.pending, s.next)
+		} else if fileName, ok := s.onDisk[s.next]; ok {
+			data, err := s.fs.ReadFile(path.Join(s.dir, fileName))
+			if err != nil {
+				return err
+			}
+			if err = s.write(fileName, data); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// finish adds the metadata in the chapter directory, written before the pages, and closes the archive
+func (s *cbzStream) finish() error {
+	entries, err := s.fs.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || name == streamArchiveName || pageRgx.MatchString(name) ||
+			strings.HasSuffix(name, partialPageExt) {
+			continue
+		}
+
+		if err = s.addFile(name); err != nil {
+			return err
+		}
+	}
+
+	if err = s.zip.Close(); err != nil {
+		return err
+	}
+
+	s.done = true
+	return s.file.Close()
+}
+
+func (s *cbzStream) addFile(name string) error {
+	f, err := s.fs.Open(path.Join(s.dir, name))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w, err := s.zip.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, f)
+	return err
+}
+
+func (s *cbzStream) finished() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.done && !s.aborted
+}
+
+func (s *cbzStream) abort() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.done {
+		return nil
+	}
+
+	s.done = true
+	s.aborted = true
+	s.pending = nil
+	return s.file.Close()
+}
+
+// cbzStreamIoTask adds the page to the archive of its chapter, instead of writing it to the chapter directory
+func cbzStreamIoTask(p *publication, ctx context.Context, log zerolog.Logger, task ioTask) error {
+	name, data := pageFile(p, ctx, task)
+
+	select {
+	case <-ctx.Done():
+		return nil
+	default:
+	}
+
+	stream, err := p.streams.get(p, task.Path, task.Pages)
+	if err == nil {
+		err = stream.add(task.Task.Idx, name, data)
+	}
+	if err == nil {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		log.Debug().Err(err).Msg("ignoring write error due to cancellation")
+		return nil
+	default:
+	}
+	log.Error().Err(err).Str("path", task.Path).Msg("error writing page to archive")
+	return err
+}
+
+// cbzStreamCleanup moves the streamed archive next to the chapter directory. When no archive was streamed, as all
+// pages were already on disk, falls back to zipping the directory. An unfinished archive, because pages failed to
+// download, is finished with the pages it has. A chapter without any pages is removed instead
+func cbzStreamCleanup(p *publication, dir string) error {
+	archive := path.Join(dir, streamArchiveName)
+
+	stream, ok := p.streams.remove(dir)
+	if !ok {
+		p.log.Debug().Str("dir", dir).Msg("no streamed archive for chapter, zipping directory instead")
+		return cbzCleanup(p, dir)
+	}
+
+	if !stream.finished() {
+		written, err := stream.flush()
+		if err != nil {
+			if rmErr := p.fs.RemoveAll(dir); rmErr != nil {
+				p.log.Warn().Err(rmErr).Str("dir", dir).Msg("failed to remove chapter directory")
+			}
+			return fmt.Errorf("failed to finish streamed archive: %w", err)
+		}
+
+		p.log.Warn().Str("dir", dir).Int("pages", written).Int("expected", stream.size).
+			Msg("streamed archive is missing pages that failed to download")
+	}
+
+	if err := p.fs.Rename(archive, dir+".cbz"); err != nil {
+		return fmt.Errorf("failed to move streamed archive: %w", err)
+	}
+
+	return p.fs.RemoveAll(dir)
+}
@@ -0,0 +1,174 @@
+package publication
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/spf13/afero"
+)
+
+func TestCbzStream(t *testing.T) {
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+	p := &publication{fs: fs, log: zerolog.Nop()}
+
+	dir := "Manga/Spice and Wolf/Spice and Wolf Ch. 0001"
+	if err := fs.WriteFile(path.Join(dir, "ComicInfo.xml"), []byte("<ComicInfo/>"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Written by an earlier download
+	if err := fs.WriteFile(path.Join(dir, "page 0002.jpg"), []byte("2"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	stream, err := p.streams.get(p, dir, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = stream.add(3, "page 0003.jpg", []byte("3")); err != nil {
+		t.Fatal(err)
+	}
+	if stream.finished() {
+		t.Fatal("stream finished before the first page was written")
+	}
+
+	if err = stream.add(1, "page 0001.jpg", []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	if !stream.finished() {
+		t.Fatal("stream should have finished after all pages were written")
+	}
+
+	if err = cbzStreamCleanup(p, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, _ := fs.DirExists(dir); ok {
+		t.Error("chapter directory was not removed")
+	}
+
+	data, err := fs.ReadFile(dir + ".cbz")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, f := range r.File {
+		names = append(names, f.Name)
+	}
+
+	want := []string{"page 0001.jpg", "page 0002.jpg", "page 0003.jpg", "ComicInfo.xml"}
+	if !slices.Equal(names, want) {
+		t.Errorf("Got %v; expected %v", names, want)
+	}
+}
+
+func TestCbzStream_Abort(t *testing.T) {
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+	p := &publication{fs: fs, log: zerolog.Nop()}
+
+	dir := "Manga/Spice and Wolf Ch. 0002"
+	stream, err := p.streams.get(p, dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = stream.add(2, "page 0002.jpg", []byte("2")); err != nil {
+		t.Fatal(err)
+	}
+
+	p.streams.abortAll(p.log)
+
+	if err = stream.add(1, "page 0001.jpg", []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+
+	if stream.finished() {
+		t.Fatal("aborted stream should not be finished")
+	}
+
+	// An aborted archive can't be finished, the chapter is removed instead of zipping a directory without pages
+	if err = cbzStreamCleanup(p, dir); err == nil {
+		t.Fatal("expected an error for an aborted archive")
+	}
+
+	if ok, _ := fs.Exists(dir + ".cbz"); ok {
+		t.Error("no archive should be written for an aborted stream")
+	}
+	if ok, _ := fs.DirExists(dir); ok {
+		t.Error("chapter directory was not removed")
+	}
+}
+
+func TestCbzStream_MissingPages(t *testing.T) {
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+	p := &publication{fs: fs, log: zerolog.Nop()}
+
+	dir := "Manga/Spice and Wolf Ch. 0003"
+	if err := fs.WriteFile(path.Join(dir, "ComicInfo.xml"), []byte("<ComicInfo/>"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	stream, err := p.streams.get(p, dir, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Page 2 failed to download, page 3 is kept in memory waiting for it
+	for _, idx := range []int{1, 3} {
+		if err = stream.add(idx, fmt.Sprintf("page %04d.jpg", idx), []byte{byte(idx)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = cbzStreamCleanup(p, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := fs.ReadFile(dir + ".cbz")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, f := range r.File {
+		names = append(names, f.Name)
+	}
+
+	want := []string{"page 0001.jpg", "page 0003.jpg", "ComicInfo.xml"}
+	if !slices.Equal(names, want) {
+		t.Errorf("Got %v; expected the pages that did download %v", names, want)
+	}
+
+	// A stream without any pages isn't turned into an archive
+	dir = "Manga/Spice and Wolf Ch. 0004"
+	if err = fs.WriteFile(path.Join(dir, "ComicInfo.xml"), []byte("<ComicInfo/>"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = p.streams.get(p, dir, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = cbzStreamCleanup(p, dir); !errors.Is(err, errNoStreamedPages) {
+		t.Errorf("Got %v; expected errNoStreamedPages", err)
+	}
+	if ok, _ := fs.Exists(dir + ".cbz"); ok {
+		t.Error("no archive should be written without pages")
+	}
+}
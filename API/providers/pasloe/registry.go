@@ -35,8 +35,7 @@ func (r *registry) Create(c publication.Client, req payload.DownloadRequest) (pu
 		scope.Provide(publication.New),
 		scope.Provide(utils.Identity(c)),
 		scope.Provide(utils.Identity(req)),
-		// TODO: This needs to be updated if we have stuff other than cbz
-		scope.Provide(utils.Identity(publication.CbzExt())),
+		scope.Provide(utils.Identity(publication.ExtensionsFor(req))),
 	)
 
 	if err != nil {
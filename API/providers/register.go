@@ -14,6 +14,7 @@ import (
 	"github.com/Fesaa/Media-Provider/providers/yoitsu/limetorrents"
 	"github.com/Fesaa/Media-Provider/providers/yoitsu/nyaa"
 	"github.com/Fesaa/Media-Provider/providers/yoitsu/subsplease"
+	"github.com/Fesaa/Media-Provider/providers/yoitsu/torznab"
 	"github.com/Fesaa/Media-Provider/providers/yoitsu/yts"
 	"github.com/Fesaa/Media-Provider/services"
 	"github.com/Fesaa/Media-Provider/utils"
@@ -40,6 +41,7 @@ func RegisterProviders(s services.ContentService, container *dig.Container) erro
 		scope.Provide(nyaa.NewBuilder),
 		scope.Provide(bato.NewBuilder),
 		scope.Provide(mangabuddy.NewBuilder),
+		scope.Provide(torznab.NewBuilder),
 
 		registerProviderAdapter[*yts.Builder](s, scope),
 		registerProviderAdapter[*subsplease.Builder](s, scope),
@@ -50,6 +52,7 @@ func RegisterProviders(s services.ContentService, container *dig.Container) erro
 		registerProviderAdapter[*nyaa.Builder](s, scope),
 		registerProviderAdapter[*bato.Builder](s, scope),
 		registerProviderAdapter[*mangabuddy.Builder](s, scope),
+		registerProviderAdapter[*torznab.Builder](s, scope),
 	)
 }
 
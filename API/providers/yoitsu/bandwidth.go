@@ -0,0 +1,210 @@
+package yoitsu
+
+import (
+	"math/rand"
+	"slices"
+	"time"
+
+	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/anacrolix/torrent"
+	"golang.org/x/time/rate"
+)
+
+// minRateLimiterBurst is large enough to fit any chunk or read the client rate limits
+const minRateLimiterBurst = 1 << 20
+
+// torrentThrottle tracks the transfer of a torrent between two ticks of the bandwidth loop, to enforce
+// the per torrent rate limits
+type torrentThrottle struct {
+	read, written int64
+
+	// downloadPause and uploadPause are the amount of ticks transfer stays disallowed
+	downloadPause, uploadPause   int
+	downloadPaused, uploadPaused bool
+}
+
+// applyClientSettings configures the parts of the torrent client that can only be set before it is created
+func applyClientSettings(conf *torrent.ClientConfig, settings payload.TorrentSettings) {
+	conf.ListenPort = settings.ListenPort
+	if conf.ListenPort == 0 {
+		conf.ListenPort = rand.Intn(65535-49152) + 49152 //nolint:gosec
+	}
+
+	if settings.MaxPeers > 0 {
+		conf.EstablishedConnsPerTorrent = settings.MaxPeers
+	}
+
+	switch settings.Encryption {
+	case payload.EncryptionRequire:
+		conf.HeaderObfuscationPolicy = torrent.HeaderObfuscationPolicy{RequirePreferred: true, Preferred: true}
+	case payload.EncryptionDisable:
+		conf.HeaderObfuscationPolicy = torrent.HeaderObfuscationPolicy{RequirePreferred: true, Preferred: false}
+	default:
+		conf.HeaderObfuscationPolicy = torrent.HeaderObfuscationPolicy{RequirePreferred: false, Preferred: true}
+	}
+
+	conf.NoDHT = settings.DisableDht
+	conf.DisablePEX = settings.DisablePex
+
+	// The limiters must be set when creating the client to be adjustable afterward
+	conf.UploadRateLimiter = rate.NewLimiter(rate.Inf, minRateLimiterBurst)
+	conf.DownloadRateLimiter = rate.NewLimiter(rate.Inf, minRateLimiterBurst)
+	setRateLimit(conf.UploadRateLimiter, settings.UploadLimit)
+	setRateLimit(conf.DownloadRateLimiter, settings.DownloadLimit)
+}
+
+// rateLimit converts a limit in KiB/s to bytes, 0 is unlimited
+func rateLimit(kib int) rate.Limit {
+	if kib <= 0 {
+		return rate.Inf
+	}
+
+	return rate.Limit(kib * 1024)
+}
+
+// setRateLimit updates the limiter to the limit in KiB/s, if changed
+func setRateLimit(l *rate.Limiter, kib int) {
+	limit := rateLimit(kib)
+	if l.Limit() == limit {
+		return
+	}
+
+	l.SetLimit(limit)
+	if limit == rate.Inf {
+		l.SetBurst(minRateLimiterBurst)
+		return
+	}
+
+	l.SetBurst(max(int(limit), minRateLimiterBurst))
+}
+
+// altSpeedActive returns true if the alternative speed profile should be used at the given time
+func altSpeedActive(s payload.AltSpeedSettings, now time.Time) bool {
+	if !s.Scheduled {
+		return false
+	}
+
+	from, err := time.Parse("15:04", s.From)
+	if err != nil {
+		return false
+	}
+	to, err := time.Parse("15:04", s.To)
+	if err != nil {
+		return false
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+	start := from.Hour()*60 + from.Minute()
+	end := to.Hour()*60 + to.Minute()
+
+	if start <= end {
+		return slices.Contains(s.Days, now.Weekday()) && minute >= start && minute < end
+	}
+
+	// Past midnight, the part after midnight belongs to the schedule of the previous day
+	if minute >= start {
+		return slices.Contains(s.Days, now.Weekday())
+	}
+	return minute < end && slices.Contains(s.Days, now.AddDate(0, 0, -1).Weekday())
+}
+
+// throttlePause returns for how many ticks transfer should be paused, after transferring delta bytes in one
+// tick with the given limit in bytes. An ongoing pause counts down
+func throttlePause(pause int, delta, limit int64) int {
+	if pause > 0 {
+		return pause - 1
+	}
+
+	if limit <= 0 || delta <= limit {
+		return 0
+	}
+
+	return int((delta+limit-1)/limit) - 1
+}
+
+// bandwidthLoop applies the global rate limits, switching to the alternative speed profile during its schedule,
+// and throttles torrents exceeding the per torrent limits
+func (y *yoitsu) bandwidthLoop() {
+	throttles := make(map[string]*torrentThrottle)
+
+	for range time.Tick(time.Second) {
+		settings := y.torrentSettings.Load()
+		y.applyRateLimits(*settings, time.Now())
+		y.throttleTorrents(*settings, throttles)
+	}
+}
+
+// UpdateTorrentSettings refreshes the rate limits the bandwidth loop applies. Settings only read when the
+// client is created require a restart
+func (y *yoitsu) UpdateTorrentSettings(settings payload.TorrentSettings) {
+	y.torrentSettings.Store(&settings)
+}
+
+func (y *yoitsu) applyRateLimits(settings payload.TorrentSettings, now time.Time) {
+	upload, download := settings.UploadLimit, settings.DownloadLimit
+
+	altSpeed := altSpeedActive(settings.AltSpeed, now)
+	if altSpeed {
+		upload, download = settings.AltSpeed.UploadLimit, settings.AltSpeed.DownloadLimit
+	}
+
+	if altSpeed != y.altSpeed {
+		y.altSpeed = altSpeed
+		y.log.Info().Bool("altSpeed", altSpeed).Int("upload", upload).Int("download", download).
+			Msg("switching speed profile")
+	}
+
+	setRateLimit(y.uploadLimiter, upload)
+	setRateLimit(y.downloadLimiter, download)
+}
+
+func (y *yoitsu) throttleTorrents(settings payload.TorrentSettings, throttles map[string]*torrentThrottle) {
+	downloadLimit := int64(settings.TorrentDownloadLimit) * 1024
+	uploadLimit := int64(settings.TorrentUploadLimit) * 1024
+
+	seen := make(map[string]struct{})
+	for _, tor := range y.torrents.Values() {
+		t := tor.GetTorrent()
+		if t == nil {
+			continue
+		}
+
+		seen[tor.Id()] = struct{}{}
+		stats := t.Stats()
+		read, written := stats.BytesReadData.Int64(), stats.BytesWrittenData.Int64()
+
+		th, ok := throttles[tor.Id()]
+		if !ok {
+			throttles[tor.Id()] = &torrentThrottle{read: read, written: written}
+			continue
+		}
+
+		th.downloadPause = throttlePause(th.downloadPause, read-th.read, downloadLimit)
+		th.uploadPause = throttlePause(th.uploadPause, written-th.written, uploadLimit)
+		th.read, th.written = read, written
+
+		if paused := th.downloadPause > 0; paused != th.downloadPaused {
+			th.downloadPaused = paused
+			if paused {
+				t.DisallowDataDownload()
+			} else {
+				t.AllowDataDownload()
+			}
+		}
+
+		if paused := th.uploadPause > 0; paused != th.uploadPaused {
+			th.uploadPaused = paused
+			if paused {
+				t.DisallowDataUpload()
+			} else {
+				t.AllowDataUpload()
+			}
+		}
+	}
+
+	for id := range throttles {
+		if _, ok := seen[id]; !ok {
+			delete(throttles, id)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package yoitsu
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/anacrolix/torrent"
+	"github.com/rs/zerolog"
+	"golang.org/x/time/rate"
+)
+
+func TestAltSpeedActive(t *testing.T) {
+	weekdays := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	// 2024-03-04 is a Monday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 3, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		settings payload.AltSpeedSettings
+		now      time.Time
+		want     bool
+	}{
+		{
+			name:     "not scheduled",
+			settings: payload.AltSpeedSettings{Days: weekdays, From: "09:00", To: "17:00"},
+			now:      at(4, 12, 0),
+			want:     false,
+		},
+		{
+			name:     "during the day",
+			settings: payload.AltSpeedSettings{Scheduled: true, Days: weekdays, From: "09:00", To: "17:00"},
+			now:      at(4, 12, 0),
+			want:     true,
+		},
+		{
+			name:     "end is exclusive",
+			settings: payload.AltSpeedSettings{Scheduled: true, Days: weekdays, From: "09:00", To: "17:00"},
+			now:      at(4, 17, 0),
+			want:     false,
+		},
+		{
+			name:     "weekend",
+			settings: payload.AltSpeedSettings{Scheduled: true, Days: weekdays, From: "09:00", To: "17:00"},
+			now:      at(9, 12, 0),
+			want:     false,
+		},
+		{
+			name:     "past midnight, before",
+			settings: payload.AltSpeedSettings{Scheduled: true, Days: []time.Weekday{time.Friday}, From: "22:00", To: "06:00"},
+			now:      at(8, 23, 30),
+			want:     true,
+		},
+		{
+			name:     "past midnight, after",
+			settings: payload.AltSpeedSettings{Scheduled: true, Days: []time.Weekday{time.Friday}, From: "22:00", To: "06:00"},
+			now:      at(9, 5, 59),
+			want:     true,
+		},
+		{
+			name:     "past midnight, previous day not scheduled",
+			settings: payload.AltSpeedSettings{Scheduled: true, Days: []time.Weekday{time.Friday}, From: "22:00", To: "06:00"},
+			now:      at(8, 5, 0),
+			want:     false,
+		},
+		{
+			name:     "invalid time",
+			settings: payload.AltSpeedSettings{Scheduled: true, Days: weekdays, From: "nine", To: "17:00"},
+			now:      at(4, 12, 0),
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := altSpeedActive(tt.settings, tt.now); got != tt.want {
+				t.Errorf("Got %v; expected %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThrottlePause(t *testing.T) {
+	tests := []struct {
+		name  string
+		pause int
+		delta int64
+		limit int64
+		want  int
+	}{
+		{name: "unlimited", delta: 10_000, limit: 0, want: 0},
+		{name: "under limit", delta: 900, limit: 1000, want: 0},
+		{name: "over limit", delta: 1500, limit: 1000, want: 1},
+		{name: "far over limit", delta: 4000, limit: 1000, want: 3},
+		{name: "counting down", pause: 2, delta: 0, limit: 1000, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := throttlePause(tt.pause, tt.delta, tt.limit); got != tt.want {
+				t.Errorf("Got %d; expected %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyClientSettings(t *testing.T) {
+	conf := torrent.NewDefaultClientConfig()
+	applyClientSettings(conf, payload.TorrentSettings{
+		UploadLimit: 100,
+		ListenPort:  51413,
+		MaxPeers:    20,
+		Encryption:  payload.EncryptionRequire,
+		DisableDht:  true,
+	})
+
+	if conf.ListenPort != 51413 {
+		t.Errorf("Got port %d; expected 51413", conf.ListenPort)
+	}
+	if conf.EstablishedConnsPerTorrent != 20 {
+		t.Errorf("Got %d peers; expected 20", conf.EstablishedConnsPerTorrent)
+	}
+	if !conf.HeaderObfuscationPolicy.RequirePreferred || !conf.HeaderObfuscationPolicy.Preferred {
+		t.Errorf("Got %+v; expected encryption to be required", conf.HeaderObfuscationPolicy)
+	}
+	if !conf.NoDHT || conf.DisablePEX {
+		t.Errorf("Got NoDHT %v, DisablePEX %v; expected only DHT to be disabled", conf.NoDHT, conf.DisablePEX)
+	}
+	if conf.UploadRateLimiter.Limit() != 100*1024 {
+		t.Errorf("Got upload limit %v; expected 100 KiB/s", conf.UploadRateLimiter.Limit())
+	}
+	if conf.DownloadRateLimiter.Limit() != rate.Inf {
+		t.Errorf("Got download limit %v; expected unlimited", conf.DownloadRateLimiter.Limit())
+	}
+
+	conf = torrent.NewDefaultClientConfig()
+	applyClientSettings(conf, payload.TorrentSettings{})
+	if conf.ListenPort < 49152 {
+		t.Errorf("Got port %d; expected a random dynamic port", conf.ListenPort)
+	}
+}
+
+func TestYoitsu_ApplyRateLimits(t *testing.T) {
+	y := &yoitsu{
+		log:             zerolog.Nop(),
+		uploadLimiter:   rate.NewLimiter(rate.Inf, minRateLimiterBurst),
+		downloadLimiter: rate.NewLimiter(rate.Inf, minRateLimiterBurst),
+	}
+
+	settings := payload.TorrentSettings{
+		UploadLimit:   500,
+		DownloadLimit: 2048,
+		AltSpeed: payload.AltSpeedSettings{
+			UploadLimit:   50,
+			DownloadLimit: 0,
+			Scheduled:     true,
+			Days:          []time.Weekday{time.Monday},
+			From:          "09:00",
+			To:            "17:00",
+		},
+	}
+
+	y.applyRateLimits(settings, time.Date(2024, 3, 4, 8, 0, 0, 0, time.UTC))
+	if y.uploadLimiter.Limit() != 500*1024 || y.downloadLimiter.Limit() != 2048*1024 || y.altSpeed {
+		t.Errorf("Got (%v, %v); expected the normal limits", y.uploadLimiter.Limit(), y.downloadLimiter.Limit())
+	}
+	if y.downloadLimiter.Burst() != 2048*1024 {
+		t.Errorf("Got burst %d; expected it to match the limit", y.downloadLimiter.Burst())
+	}
+
+	y.applyRateLimits(settings, time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC))
+	if y.uploadLimiter.Limit() != 50*1024 || y.downloadLimiter.Limit() != rate.Inf || !y.altSpeed {
+		t.Errorf("Got (%v, %v); expected the alternative limits", y.uploadLimiter.Limit(), y.downloadLimiter.Limit())
+	}
+}
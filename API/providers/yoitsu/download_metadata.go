@@ -0,0 +1,93 @@
+package yoitsu
+
+import (
+	"slices"
+
+	"github.com/Fesaa/Media-Provider/http/payload"
+)
+
+// DownloadMetadataDefinitions returns the download options shared by all torrent providers
+func DownloadMetadataDefinitions() []payload.DownloadMetadataDefinition {
+	return []payload.DownloadMetadataDefinition{
+		{
+			Key:           KeyNoSubDir,
+			FormType:      payload.SWITCH,
+			DefaultOption: "",
+		},
+		{
+			Key:           ExtractArchivesKey,
+			FormType:      payload.SWITCH,
+			DefaultOption: "",
+		},
+		{
+			Key:           DeleteArchivesKey,
+			FormType:      payload.SWITCH,
+			DefaultOption: "",
+		},
+		{
+			Key:      SeedRatioKey,
+			Advanced: true,
+			FormType: payload.TEXT,
+		},
+		{
+			Key:      SeedTimeKey,
+			Advanced: true,
+			FormType: payload.TEXT,
+		},
+		{
+			Key:           EpisodeLayoutKey,
+			FormType:      payload.DROPDOWN,
+			DefaultOption: "",
+			Options:       EpisodeLayoutOptions,
+		},
+		{
+			Key:      EpisodeTemplateKey,
+			Advanced: true,
+			FormType: payload.TEXT,
+		},
+		{
+			Key:      SeasonDirTemplateKey,
+			Advanced: true,
+			FormType: payload.TEXT,
+		},
+		{
+			Key:      IncludeFilesKey,
+			Advanced: true,
+			FormType: payload.TEXT,
+		},
+		{
+			Key:      ExcludeFilesKey,
+			Advanced: true,
+			FormType: payload.TEXT,
+		},
+		{
+			Key:      FileExtensionsKey,
+			Advanced: true,
+			FormType: payload.TEXT,
+		},
+		{
+			Key:      MinFileSizeKey,
+			Advanced: true,
+			FormType: payload.TEXT,
+		},
+		{
+			Key:      MaxFileSizeKey,
+			Advanced: true,
+			FormType: payload.TEXT,
+		},
+		{
+			Key:           LargestVideoOnlyKey,
+			Advanced:      true,
+			FormType:      payload.SWITCH,
+			DefaultOption: "",
+		},
+	}
+}
+
+// MovieDownloadMetadataDefinitions returns DownloadMetadataDefinitions without the episode options, for providers
+// only finding movies
+func MovieDownloadMetadataDefinitions() []payload.DownloadMetadataDefinition {
+	return slices.DeleteFunc(DownloadMetadataDefinitions(), func(def payload.DownloadMetadataDefinition) bool {
+		return def.Key == EpisodeLayoutKey || def.Key == EpisodeTemplateKey || def.Key == SeasonDirTemplateKey
+	})
+}
@@ -0,0 +1,27 @@
+package yoitsu
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/Fesaa/Media-Provider/utils"
+)
+
+func TestMovieDownloadMetadataDefinitions(t *testing.T) {
+	key := func(def payload.DownloadMetadataDefinition) string {
+		return def.Key
+	}
+
+	all := utils.Map(DownloadMetadataDefinitions(), key)
+	movies := utils.Map(MovieDownloadMetadataDefinitions(), key)
+
+	if len(movies) != len(all)-3 {
+		t.Fatalf("Got %v; expected all options but the episode options of %v", movies, all)
+	}
+	for _, k := range []string{EpisodeLayoutKey, EpisodeTemplateKey, SeasonDirTemplateKey} {
+		if !slices.Contains(all, k) || slices.Contains(movies, k) {
+			t.Errorf("Expected %s only in the shared options", k)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package yoitsu
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/Fesaa/Media-Provider/internal/naming"
+	"github.com/Fesaa/Media-Provider/internal/release"
+	"github.com/Fesaa/Media-Provider/utils"
+)
+
+const (
+	// EpisodeLayoutKey decides what happens to the episodes of a torrent once it completes, see episodeLayout
+	EpisodeLayoutKey = "episode_layout"
+	// EpisodeTemplateKey is the naming template of episode files, without extension
+	EpisodeTemplateKey = "episode_template"
+	// SeasonDirTemplateKey is the naming template of the season directory inside the show directory
+	SeasonDirTemplateKey = "season_dir_template"
+
+	DefaultEpisodeTemplate   = "{title} - S{season}E{episode}"
+	DefaultSeasonDirTemplate = "Season {season}"
+)
+
+type episodeLayout string
+
+const (
+	// LayoutNone leaves the torrent as it was downloaded
+	LayoutNone episodeLayout = ""
+	// LayoutMove moves episodes into Show/Season/Episode, other files are handled as usual
+	LayoutMove episodeLayout = "move"
+	// LayoutLink hardlinks episodes into Show/Season/Episode, and keeps the torrent as it was downloaded
+	LayoutLink episodeLayout = "link"
+)
+
+// EpisodeLayoutOptions are the options for the EpisodeLayoutKey dropdown
+var EpisodeLayoutOptions = []payload.MetadataOption{
+	{Key: string(LayoutNone), Value: "Off"},
+	{Key: string(LayoutMove), Value: "Move"},
+	{Key: string(LayoutLink), Value: "Hardlink"},
+}
+
+var (
+	videoExtensions    = []string{".mkv", ".mp4", ".m4v", ".avi", ".webm", ".ts"}
+	subtitleExtensions = []string{".ass", ".ssa", ".srt", ".vtt", ".sup"}
+	// languageRegex matches the language of subtitle files, i.e. Show - 01.en.ass
+	languageRegex = regexp.MustCompile(`^\.[a-zA-Z]{2,3}$`)
+)
+
+func episodeLayoutOf(req payload.DownloadRequest) episodeLayout {
+	switch layout := episodeLayout(req.GetStringOrDefault(EpisodeLayoutKey, "")); layout {
+	case LayoutMove, LayoutLink:
+		return layout
+	default:
+		return LayoutNone
+	}
+}
+
+// planEpisodes returns where each episode in files ends up, files that aren't episodes are left out.
+// title is the name of the torrent, and used when the file names don't hold enough information
+func planEpisodes(req payload.DownloadRequest, title string, files []string) ([]payload.EpisodeMove, error) {
+	episodeTemplate, err := naming.Parse(utils.NonEmpty(req.GetStringOrDefault(EpisodeTemplateKey, ""), DefaultEpisodeTemplate))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", EpisodeTemplateKey, err)
+	}
+
+	seasonTemplate, err := naming.Parse(utils.NonEmpty(req.GetStringOrDefault(SeasonDirTemplateKey, ""), DefaultSeasonDirTemplate))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", SeasonDirTemplateKey, err)
+	}
+
+	torrentRelease := release.Parse(title)
+	moves := make([]payload.EpisodeMove, 0)
+	seen := make(map[string]bool)
+
+	for _, file := range files {
+		ext := episodeExtension(path.Base(file))
+		if ext == "" {
+			continue
+		}
+
+		r := release.Parse(strings.TrimSuffix(path.Base(file), ext))
+		if !r.HasEpisode() {
+			continue
+		}
+
+		// Batches often only mention the season on the directory, or the torrent
+		dirRelease := release.Parse(path.Base(path.Dir(file)))
+		season := cmp.Or(r.Season, dirRelease.Season, torrentRelease.Season, 1)
+
+		episode := fmt.Sprintf("%02d", r.Episode)
+		if r.IsBatch() {
+			episode = fmt.Sprintf("%02d-E%02d", r.Episode, r.EpisodeEnd)
+		}
+
+		values := naming.Values{
+			naming.Title:      utils.NonEmpty(r.Title, torrentRelease.Title, title),
+			naming.Season:     fmt.Sprintf("%02d", season),
+			naming.Episode:    episode,
+			naming.Resolution: utils.NonEmpty(r.Resolution, torrentRelease.Resolution),
+			naming.Group:      utils.NonEmpty(r.Group, torrentRelease.Group),
+			naming.Provider:   req.Provider.String(),
+		}
+
+		to := path.Join(naming.Sanitize(values[naming.Title]), seasonTemplate.Render(values),
+			episodeTemplate.Render(values)+ext)
+		if seen[to] {
+			continue
+		}
+		seen[to] = true
+
+		moves = append(moves, payload.EpisodeMove{
+			Episode: fmt.Sprintf("S%02dE%s", season, episode),
+			From:    file,
+			To:      to,
+		})
+	}
+
+	return moves, nil
+}
+
+// episodeExtension returns the extension of video and subtitle files, including the language of subtitles.
+// Empty for any other file
+func episodeExtension(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if slices.Contains(videoExtensions, ext) {
+		return ext
+	}
+
+	if !slices.Contains(subtitleExtensions, ext) {
+		return ""
+	}
+
+	lang := path.Ext(strings.TrimSuffix(name, path.Ext(name)))
+	if languageRegex.MatchString(lang) {
+		return lang + ext
+	}
+	return ext
+}
+
+// wantedFiles returns the files of the torrent the user selected, or all if no selection was made
+func wantedFiles(tor Torrent) []string {
+	selection := tor.UserSelection()
+	if len(selection) == 0 {
+		return tor.FilePaths()
+	}
+
+	return utils.Filter(tor.FilePaths(), func(file string) bool {
+		return slices.Contains(selection, file)
+	})
+}
+
+// previewEpisodes returns the EpisodeMove's of the torrent as json, for the PreviewEpisodes message
+func previewEpisodes(tor Torrent) ([]byte, error) {
+	moves, err := planEpisodes(tor.Request(), tor.Title(), wantedFiles(tor))
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(moves)
+}
+
+// organiseEpisodes places the episodes of the torrent in the layout of the request. Episodes are always linked
+// when keep is true, so the torrent can keep seeding. Returns the episodes that should not be handled
+// by the regular clean-up, and all files that were created
+func (y *yoitsu) organiseEpisodes(tor Torrent, baseDir, hashDir string, keep bool) ([]string, []string, error) {
+	layout := episodeLayoutOf(tor.Request())
+	if layout == LayoutNone {
+		return nil, nil, nil
+	}
+
+	moves, err := planEpisodes(tor.Request(), tor.Title(), wantedFiles(tor))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var handled, created []string
+	var errs []error
+	for _, move := range moves {
+		src := path.Join(hashDir, move.From)
+		dest := path.Join(y.dir, baseDir, move.To)
+
+		if exists, _ := y.fs.Exists(dest); exists {
+			errs = append(errs, fmt.Errorf("%s already exists", move.To))
+			continue
+		}
+
+		if err = y.fs.MkdirAll(path.Dir(dest), 0755); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if layout == LayoutMove && !keep {
+			err = y.fs.Rename(src, dest)
+		} else {
+			err = y.linkFile(src, dest)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to place %s: %w", move.From, err))
+			continue
+		}
+
+		y.log.Debug().Str("infoHash", tor.Id()).Str("src", src).Str("dest", dest).Msg("placed episode")
+		created = append(created, dest)
+		if layout == LayoutMove {
+			handled = append(handled, move.From)
+		}
+	}
+
+	return handled, created, errors.Join(errs...)
+}
+
+// removeEmptyDirs removes all empty directories below dir, dir itself is kept
+func (y *yoitsu) removeEmptyDirs(dir string) {
+	entries, err := y.fs.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		sub := path.Join(dir, entry.Name())
+		y.removeEmptyDirs(sub)
+		if empty, _ := y.fs.IsEmpty(sub); empty {
+			if err = y.fs.Remove(sub); err != nil {
+				y.log.Warn().Err(err).Str("dir", sub).Msg("failed to remove empty directory")
+			}
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package yoitsu
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/Fesaa/Media-Provider/db/models"
+	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/Fesaa/Media-Provider/providers/yoitsu/remote"
+	"github.com/Fesaa/Media-Provider/utils"
+	"github.com/rs/zerolog"
+	"github.com/spf13/afero"
+)
+
+func TestPlanEpisodes(t *testing.T) {
+	tests := []struct {
+		name  string
+		extra utils.SmartMap
+		title string
+		files []string
+		want  []payload.EpisodeMove
+	}{
+		{
+			name:  "anime batch",
+			title: "[Judas] Mushoku Tensei S2 - 01-12 (Batch) [1080p]",
+			files: []string{
+				"Mushoku Tensei S2/[Judas] Mushoku Tensei - 01.mkv",
+				"Mushoku Tensei S2/[Judas] Mushoku Tensei - 02.en.ass",
+				"Mushoku Tensei S2/NCOP.mkv",
+				"Mushoku Tensei S2/cover.jpg",
+			},
+			want: []payload.EpisodeMove{
+				{Episode: "S02E01", From: "Mushoku Tensei S2/[Judas] Mushoku Tensei - 01.mkv", To: "Mushoku Tensei/Season 02/Mushoku Tensei - S02E01.mkv"},
+				{Episode: "S02E02", From: "Mushoku Tensei S2/[Judas] Mushoku Tensei - 02.en.ass", To: "Mushoku Tensei/Season 02/Mushoku Tensei - S02E02.en.ass"},
+			},
+		},
+		{
+			name:  "scene episode",
+			title: "The.Bear.S02E01-E02.1080p.WEB-NTb",
+			files: []string{"The.Bear.S02E01-E02.1080p.WEB-NTb.mkv"},
+			want: []payload.EpisodeMove{
+				{Episode: "S02E01-E02", From: "The.Bear.S02E01-E02.1080p.WEB-NTb.mkv", To: "The Bear/Season 02/The Bear - S02E01-E02.mkv"},
+			},
+		},
+		{
+			name: "custom templates",
+			extra: utils.SmartMap{
+				EpisodeTemplateKey:   {"{title} {season}x{episode}< [{resolution}]>"},
+				SeasonDirTemplateKey: {"S{season}"},
+			},
+			title: "[SubsPlease] Sousou no Frieren - 12 (1080p) [ABCD1234].mkv",
+			files: []string{"[SubsPlease] Sousou no Frieren - 12 (1080p) [ABCD1234].mkv"},
+			want: []payload.EpisodeMove{
+				{Episode: "S01E12", From: "[SubsPlease] Sousou no Frieren - 12 (1080p) [ABCD1234].mkv", To: "Sousou no Frieren/S01/Sousou no Frieren 01x12 [1080p].mkv"},
+			},
+		},
+		{
+			name:  "no season dir",
+			extra: utils.SmartMap{SeasonDirTemplateKey: {"<{volume}>"}},
+			title: "Show",
+			files: []string{"Show - 03.mp4"},
+			want: []payload.EpisodeMove{
+				{Episode: "S01E03", From: "Show - 03.mp4", To: "Show/Show - S01E03.mp4"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := payload.DownloadRequest{Provider: models.NYAA}
+			req.DownloadMetadata.Extra = tt.extra
+
+			got, err := planEpisodes(req, tt.title, tt.files)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Got %+v; expected %+v", got, tt.want)
+			}
+		})
+	}
+
+	req := payload.DownloadRequest{}
+	req.DownloadMetadata.Extra = utils.SmartMap{EpisodeTemplateKey: {"{unknown}"}}
+	if _, err := planEpisodes(req, "", nil); err == nil {
+		t.Error("Expected an invalid template to fail")
+	}
+}
+
+func tempEpisodeTorrent(t *testing.T, layout episodeLayout, files ...string) (*yoitsu, *remoteTorrent) {
+	t.Helper()
+
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+	y := &yoitsu{dir: "root", fs: fs, log: zerolog.Nop()}
+
+	tor := &remoteTorrent{key: "hash", status: &remote.Torrent{HasMetadata: true, Name: "Show S2"}}
+	tor.req.DownloadMetadata.Extra = utils.SmartMap{EpisodeLayoutKey: {string(layout)}}
+
+	for i, file := range files {
+		tor.remoteFiles = append(tor.remoteFiles, remote.File{Index: i, Name: file})
+		if err := fs.WriteFile("root/anime/hash/"+file, []byte(file), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return y, tor
+}
+
+func TestYoitsu_OrganiseEpisodes(t *testing.T) {
+	y, tor := tempEpisodeTorrent(t, LayoutMove, "Show S2/Show - 01.mkv", "Show S2/extras.txt")
+
+	handled, created, err := y.organiseEpisodes(tor, "anime", "root/anime/hash", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(handled, []string{"Show S2/Show - 01.mkv"}) {
+		t.Errorf("Got handled %v; expected the episode", handled)
+	}
+	if !slices.Equal(created, []string{"root/anime/Show/Season 02/Show - S02E01.mkv"}) {
+		t.Errorf("Got created %v; expected the episode in its season dir", created)
+	}
+	if ok, _ := y.fs.Exists("root/anime/hash/Show S2/Show - 01.mkv"); ok {
+		t.Error("Expected the episode to be moved")
+	}
+
+	y.removeEmptyDirs("root/anime/hash")
+	if ok, _ := y.fs.Exists("root/anime/hash/Show S2/extras.txt"); !ok {
+		t.Error("Expected other files to be left alone")
+	}
+
+	// Running again must not overwrite the placed episode
+	_ = y.fs.WriteFile("root/anime/hash/Show S2/Show - 01.mkv", []byte("new"), 0644)
+	if _, _, err = y.organiseEpisodes(tor, "anime", "root/anime/hash", false); err == nil {
+		t.Error("Expected an error when the episode already exists")
+	}
+}
+
+func TestYoitsu_OrganiseEpisodesKeep(t *testing.T) {
+	y, tor := tempEpisodeTorrent(t, LayoutLink, "Show S2/Show - 01.mkv")
+
+	handled, created, err := y.organiseEpisodes(tor, "anime", "root/anime/hash", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(handled) != 0 {
+		t.Errorf("Got handled %v; linked episodes should still be handled by the regular clean-up", handled)
+	}
+	if len(created) != 1 {
+		t.Fatalf("Got created %v; expected the episode to be linked", created)
+	}
+	if ok, _ := y.fs.Exists("root/anime/hash/Show S2/Show - 01.mkv"); !ok {
+		t.Error("Expected the source to be kept to seed from")
+	}
+
+	y2, tor2 := tempEpisodeTorrent(t, LayoutNone, "Show S2/Show - 01.mkv")
+	if handled, created, err = y2.organiseEpisodes(tor2, "anime", "root/anime/hash", false); handled != nil || created != nil || err != nil {
+		t.Error("Expected nothing to happen without a layout")
+	}
+}
+
+func TestRemoteTorrent_PreviewEpisodes(t *testing.T) {
+	_, tor := tempEpisodeTorrent(t, LayoutMove, "Show S2/Show - 01.mkv", "Show S2/Show - 02.mkv")
+	tor.userFilter = []string{"Show S2/Show - 02.mkv"}
+
+	msg, err := tor.Message(payload.Message{MessageType: payload.PreviewEpisodes})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var moves []payload.EpisodeMove
+	if err = json.Unmarshal(msg.Data, &moves); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(moves) != 1 || moves[0].To != "Show/Season 02/Show - S02E02.mkv" {
+		t.Errorf("Got %+v; expected only the selected episode", moves)
+	}
+}
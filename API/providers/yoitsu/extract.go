@@ -0,0 +1,347 @@
+package yoitsu
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	// ExtractArchivesKey extracts the archives in a torrent once it completes, next to the archive
+	ExtractArchivesKey = "extract_archives"
+	// DeleteArchivesKey removes all parts of an archive after it has been extracted successfully
+	DeleteArchivesKey = "delete_archives"
+)
+
+// maxZipRatio and maxZipSize cap the total uncompressed size of a zip, relative to its own size and absolute
+const (
+	maxZipRatio = 100
+	maxZipSize  = 64 << 30
+)
+
+var (
+	errUnsafeArchivePath = errors.New("archive entry points outside the destination directory")
+	errArchiveTooLarge   = errors.New("archive is too large to extract")
+	errNoSevenZip        = errors.New("extracting requires 7z to be installed")
+	errNotOnDisk         = errors.New("extracting requires the os filesystem")
+)
+
+type archiveFormat string
+
+const (
+	formatZip      archiveFormat = "zip"
+	formatRar      archiveFormat = "rar"
+	formatSevenZip archiveFormat = "7z"
+)
+
+var (
+	// rarPartRegex matches new style rar volumes, i.e. Show.part01.rar
+	rarPartRegex = regexp.MustCompile(`(?i)^(.+)\.part0*(\d+)\.rar$`)
+	// splitRegex matches archives split by number, i.e. Show.7z.001
+	splitRegex = regexp.MustCompile(`(?i)^(.+)\.(7z|zip|rar)\.(\d{3})$`)
+	// volumeRegex matches old style rar volumes, and split zips. I.e. Show.r00 and Show.z01
+	volumeRegex = regexp.MustCompile(`(?i)^(.+)\.([rz])\d{2,3}$`)
+)
+
+// archive is a single, or multi-part, archive
+type archive struct {
+	format archiveFormat
+	// main is the part extraction starts from
+	main string
+	// parts are all files of the archive, including main
+	parts []string
+}
+
+// archivePart returns the name shared by all parts of the archive file belongs to, its format,
+// and if it's the part extraction starts from. ok is false if the file isn't an archive
+func archivePart(file string) (name string, format archiveFormat, main bool, ok bool) {
+	if m := rarPartRegex.FindStringSubmatch(file); m != nil {
+		return m[1], formatRar, m[2] == "1", true
+	}
+
+	if m := splitRegex.FindStringSubmatch(file); m != nil {
+		return m[1], archiveFormat(strings.ToLower(m[2])), m[3] == "001", true
+	}
+
+	if m := volumeRegex.FindStringSubmatch(file); m != nil {
+		if strings.EqualFold(m[2], "r") {
+			return m[1], formatRar, false, true
+		}
+		return m[1], formatZip, false, true
+	}
+
+	ext := path.Ext(file)
+	switch strings.ToLower(ext) {
+	case ".rar":
+		return strings.TrimSuffix(file, ext), formatRar, true, true
+	case ".zip":
+		return strings.TrimSuffix(file, ext), formatZip, true, true
+	case ".7z":
+		return strings.TrimSuffix(file, ext), formatSevenZip, true, true
+	default:
+		return "", "", false, false
+	}
+}
+
+// findArchives groups the archive files into archives, other files are ignored. As are volumes of which
+// the first part is missing
+func findArchives(files []string) []archive {
+	byName := make(map[string]*archive)
+	var names []string
+
+	for _, file := range files {
+		name, format, main, ok := archivePart(file)
+		if !ok {
+			continue
+		}
+
+		key := string(format) + ":" + name
+		a, ok := byName[key]
+		if !ok {
+			a = &archive{format: format}
+			byName[key] = a
+			names = append(names, key)
+		}
+
+		a.parts = append(a.parts, file)
+		if main {
+			a.main = file
+		}
+	}
+
+	slices.Sort(names)
+	archives := make([]archive, 0, len(names))
+	for _, name := range names {
+		if a := byName[name]; a.main != "" {
+			archives = append(archives, *a)
+		}
+	}
+	return archives
+}
+
+// extractArchives extracts the archives of the torrent once its files have been placed in dest, if requested.
+// Files in skip were placed elsewhere, see linkContent
+func (y *yoitsu) extractArchives(tor Torrent, hashDir, src, dest string, skip []string) error {
+	req := tor.Request()
+	if !req.GetBool(ExtractArchivesKey, false) {
+		return nil
+	}
+
+	var files []string
+	for _, file := range wantedFiles(tor) {
+		if slices.Contains(skip, file) {
+			continue
+		}
+
+		if target, ok := placedPath(hashDir, src, dest, file); ok {
+			files = append(files, target)
+		}
+	}
+
+	var errs []error
+	for _, a := range findArchives(files) {
+		if err := y.extractArchive(a); err != nil {
+			errs = append(errs, fmt.Errorf("failed to extract %s: %w", path.Base(a.main), err))
+			continue
+		}
+
+		y.log.Debug().Str("infoHash", tor.Id()).Str("archive", a.main).Int("parts", len(a.parts)).
+			Msg("extracted archive")
+
+		if !req.GetBool(DeleteArchivesKey, false) {
+			continue
+		}
+
+		for _, part := range a.parts {
+			if err := y.fs.Remove(part); err != nil {
+				y.log.Warn().Err(err).Str("path", part).Msg("failed to remove extracted archive")
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// extractArchive extracts the archive into the directory holding it. Existing files are skipped, never
+// overwritten, and nothing is kept of archives that fail. Single zips are extracted in process, everything else
+// is passed to 7z
+func (y *yoitsu) extractArchive(a archive) error {
+	dir := path.Dir(a.main)
+
+	if a.format == formatZip && len(a.parts) == 1 {
+		return y.extractZip(a.main, dir)
+	}
+
+	if _, ok := y.fs.Fs.(*afero.OsFs); !ok {
+		return errNotOnDisk
+	}
+
+	bin, err := sevenZip()
+	if err != nil {
+		return err
+	}
+
+	existing, err := y.dirContent(dir)
+	if err != nil {
+		return err
+	}
+
+	// -aos skips files that already exist, 7z exits with an error when an archive fails its checksum
+	out, err := exec.Command(bin, "x", "-y", "-aos", "-o"+dir, a.main).CombinedOutput()
+	if err != nil {
+		if content, walkErr := y.dirContent(dir); walkErr == nil {
+			y.removeExtracted(slices.DeleteFunc(content, func(p string) bool {
+				return slices.Contains(existing, p)
+			}))
+		}
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// dirContent returns all files and dirs in dir, parents before their children
+func (y *yoitsu) dirContent(dir string) ([]string, error) {
+	var content []string
+	err := y.fs.Walk(dir, func(p string, _ os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != dir {
+			content = append(content, p)
+		}
+		return nil
+	})
+	return content, err
+}
+
+// removeExtracted removes what was extracted from an archive that failed to extract. Paths must be in the order
+// they were created, so dirs are removed after their content
+func (y *yoitsu) removeExtracted(paths []string) {
+	for _, p := range slices.Backward(paths) {
+		if err := y.fs.Remove(p); err != nil {
+			y.log.Warn().Err(err).Str("path", p).Msg("failed to remove partially extracted file")
+		}
+	}
+}
+
+func sevenZip() (string, error) {
+	for _, name := range []string{"7z", "7zz"} {
+		if bin, err := exec.LookPath(name); err == nil {
+			return bin, nil
+		}
+	}
+	return "", errNoSevenZip
+}
+
+// extractZip extracts the zip into dir, entries that already exist are skipped. Everything extracted is removed
+// again if an entry fails
+func (y *yoitsu) extractZip(file, dir string) error {
+	f, err := y.fs.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	r, err := zip.NewReader(f, stat.Size())
+	if err != nil {
+		return err
+	}
+
+	// The zip reader fails entries larger than their declared size, so the declared sizes can be trusted
+	var size uint64
+	for _, entry := range r.File {
+		size += entry.UncompressedSize64
+	}
+	if limit := min(uint64(stat.Size())*maxZipRatio, maxZipSize); size > limit {
+		return fmt.Errorf("%w: %d bytes uncompressed", errArchiveTooLarge, size)
+	}
+
+	var created []string
+	for _, entry := range r.File {
+		if err = y.extractZipEntry(entry, dir, &created); err != nil {
+			y.removeExtracted(created)
+			return fmt.Errorf("%s: %w", entry.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// extractZipEntry writes a single entry, its checksum is verified by the zip reader once fully read. Created
+// files and dirs are appended to created
+func (y *yoitsu) extractZipEntry(entry *zip.File, dir string, created *[]string) error {
+	target := path.Join(dir, entry.Name)
+	if !strings.HasPrefix(target, path.Clean(dir)+"/") {
+		return errUnsafeArchivePath
+	}
+
+	if entry.FileInfo().IsDir() {
+		return y.mkdirAll(target, created)
+	}
+
+	if err := y.mkdirAll(path.Dir(target), created); err != nil {
+		return err
+	}
+
+	out, err := y.fs.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, os.ErrExist) {
+		y.log.Debug().Str("path", target).Msg("skipping archive entry, file already exists")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	*created = append(*created, target)
+
+	in, err := entry.Open()
+	if err != nil {
+		out.Close()
+		return err
+	}
+	defer in.Close()
+
+	n, err := io.Copy(out, in)
+	if err == nil && uint64(n) != entry.UncompressedSize64 {
+		err = fmt.Errorf("wrote %d bytes, expected %d", n, entry.UncompressedSize64)
+	}
+
+	if err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// mkdirAll creates dir and its parents, appending the dirs that didn't exist yet to created
+func (y *yoitsu) mkdirAll(dir string, created *[]string) error {
+	var missing []string
+	for d := dir; d != "." && d != "/"; d = path.Dir(d) {
+		if ok, _ := y.fs.DirExists(d); ok {
+			break
+		}
+		missing = append(missing, d)
+	}
+
+	if err := y.fs.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	slices.Reverse(missing)
+	*created = append(*created, missing...)
+	return nil
+}
@@ -0,0 +1,211 @@
+package yoitsu
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Fesaa/Media-Provider/providers/yoitsu/remote"
+	"github.com/Fesaa/Media-Provider/utils"
+	"github.com/rs/zerolog"
+	"github.com/spf13/afero"
+)
+
+func TestFindArchives(t *testing.T) {
+	files := []string{
+		"Show/Show.part01.rar",
+		"Show/Show.part02.rar",
+		"Show/Movie.rar",
+		"Show/Movie.r00",
+		"Show/Movie.r01",
+		"Show/Extras.7z.001",
+		"Show/Extras.7z.002",
+		"Show/Subs.zip",
+		"Show/Orphan.r00",
+		"Show/Show - 01.mkv",
+	}
+
+	got := findArchives(files)
+	want := []archive{
+		{format: formatSevenZip, main: "Show/Extras.7z.001", parts: []string{"Show/Extras.7z.001", "Show/Extras.7z.002"}},
+		{format: formatRar, main: "Show/Movie.rar", parts: []string{"Show/Movie.rar", "Show/Movie.r00", "Show/Movie.r01"}},
+		{format: formatRar, main: "Show/Show.part01.rar", parts: []string{"Show/Show.part01.rar", "Show/Show.part02.rar"}},
+		{format: formatZip, main: "Show/Subs.zip", parts: []string{"Show/Subs.zip"}},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("Got %+v; expected %+v", got, want)
+	}
+
+	for i := range want {
+		if got[i].format != want[i].format || got[i].main != want[i].main || !slices.Equal(got[i].parts, want[i].parts) {
+			t.Errorf("Got %+v; expected %+v", got[i], want[i])
+		}
+	}
+}
+
+func zipData(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	// Entries are written sorted, so their extraction order is known
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = f.Write([]byte(files[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func tempArchiveTorrent(t *testing.T, files map[string][]byte, extra utils.SmartMap) (*yoitsu, *remoteTorrent) {
+	t.Helper()
+
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+	y := &yoitsu{dir: "root", fs: fs, log: zerolog.Nop()}
+
+	tor := &remoteTorrent{key: "hash", status: &remote.Torrent{HasMetadata: true, Name: "Show"}}
+	tor.req.DownloadMetadata.Extra = extra
+
+	i := 0
+	for file, data := range files {
+		tor.remoteFiles = append(tor.remoteFiles, remote.File{Index: i, Name: file})
+		if err := fs.WriteFile("root/anime/"+file, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		i++
+	}
+
+	return y, tor
+}
+
+func TestYoitsu_ExtractArchives(t *testing.T) {
+	data := zipData(t, map[string]string{"Subs/01.ass": "subtitle", "02.ass": "other"})
+	y, tor := tempArchiveTorrent(t, map[string][]byte{"Show/Subs.zip": data}, utils.SmartMap{
+		ExtractArchivesKey: {"true"},
+		DeleteArchivesKey:  {"true"},
+	})
+
+	if err := y.extractArchives(tor, "root/anime/hash", "root/anime/hash", "root/anime", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := y.fs.ReadFile("root/anime/Show/Subs/01.ass")
+	if err != nil || string(content) != "subtitle" {
+		t.Errorf("Got (%q, %v); expected the entry to be extracted next to the archive", content, err)
+	}
+	if ok, _ := y.fs.Exists("root/anime/Show/02.ass"); !ok {
+		t.Error("Expected all entries to be extracted")
+	}
+	if ok, _ := y.fs.Exists("root/anime/Show/Subs.zip"); ok {
+		t.Error("Expected the archive to be removed")
+	}
+}
+
+func TestYoitsu_ExtractArchivesKeep(t *testing.T) {
+	data := zipData(t, map[string]string{"01.ass": "subtitle"})
+	y, tor := tempArchiveTorrent(t, map[string][]byte{"Show/Subs.zip": data}, utils.SmartMap{
+		ExtractArchivesKey: {"true"},
+	})
+
+	if err := y.extractArchives(tor, "root/anime/hash", "root/anime/hash", "root/anime", nil); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := y.fs.Exists("root/anime/Show/Subs.zip"); !ok {
+		t.Error("Expected the archive to be kept")
+	}
+
+	if err := y.fs.WriteFile("root/anime/Show/01.ass", []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Extracting again must skip, and not overwrite, the extracted files
+	if err := y.extractArchives(tor, "root/anime/hash", "root/anime/hash", "root/anime", nil); err != nil {
+		t.Errorf("Got %v; expected existing files to be skipped", err)
+	}
+	if content, _ := y.fs.ReadFile("root/anime/Show/01.ass"); string(content) != "edited" {
+		t.Errorf("Got %q; expected the existing file to be kept", content)
+	}
+}
+
+func TestYoitsu_ExtractZipRollback(t *testing.T) {
+	data := zipData(t, map[string]string{
+		"A/01.ass":              "subtitle",
+		"B/../../../escape.txt": "evil",
+	})
+	y, tor := tempArchiveTorrent(t, map[string][]byte{"Show/Subs.zip": data}, utils.SmartMap{
+		ExtractArchivesKey: {"true"},
+	})
+
+	err := y.extractArchives(tor, "root/anime/hash", "root/anime/hash", "root/anime", nil)
+	if !errors.Is(err, errUnsafeArchivePath) {
+		t.Fatalf("Got %v; expected the second entry to fail", err)
+	}
+
+	if ok, _ := y.fs.Exists("root/anime/Show/A"); ok {
+		t.Error("Expected the entries extracted before the failure to be removed")
+	}
+	if ok, _ := y.fs.Exists("root/anime/Show/Subs.zip"); !ok {
+		t.Error("Expected the archive to be kept")
+	}
+}
+
+func TestYoitsu_ExtractZipTooLarge(t *testing.T) {
+	data := zipData(t, map[string]string{"bomb.txt": strings.Repeat("0", 1<<20)})
+	y, tor := tempArchiveTorrent(t, map[string][]byte{"Show/Bomb.zip": data}, utils.SmartMap{
+		ExtractArchivesKey: {"true"},
+	})
+
+	err := y.extractArchives(tor, "root/anime/hash", "root/anime/hash", "root/anime", nil)
+	if !errors.Is(err, errArchiveTooLarge) {
+		t.Errorf("Got %v; expected the archive to be refused", err)
+	}
+	if ok, _ := y.fs.Exists("root/anime/Show/bomb.txt"); ok {
+		t.Error("Expected nothing to be extracted")
+	}
+}
+
+func TestYoitsu_ExtractArchivesFailures(t *testing.T) {
+	files := map[string][]byte{
+		"Show/Evil.zip":      zipData(t, map[string]string{"../../escape.txt": "evil"}),
+		"Show/Show.rar":      []byte("rar"),
+		"Show/Broken.zip":    []byte("not a zip"),
+		"Show/Show - 01.mkv": []byte("video"),
+	}
+	y, tor := tempArchiveTorrent(t, files, utils.SmartMap{ExtractArchivesKey: {"true"}, DeleteArchivesKey: {"true"}})
+
+	err := y.extractArchives(tor, "root/anime/hash", "root/anime/hash", "root/anime", nil)
+	if err == nil {
+		t.Fatal("Expected the archives to fail")
+	}
+
+	if !errors.Is(err, errUnsafeArchivePath) {
+		t.Errorf("Got %v; expected entries outside the directory to be refused", err)
+	}
+	if !errors.Is(err, errNotOnDisk) {
+		t.Errorf("Got %v; expected rar archives to require the os filesystem", err)
+	}
+	if ok, _ := y.fs.Exists("root/escape.txt"); ok {
+		t.Error("Expected nothing to be written outside the directory")
+	}
+	if ok, _ := y.fs.Exists("root/anime/Show/Broken.zip"); !ok {
+		t.Error("Expected failed archives to be kept")
+	}
+
+	y, tor = tempArchiveTorrent(t, files, nil)
+	if err = y.extractArchives(tor, "root/anime/hash", "root/anime/hash", "root/anime", nil); err != nil {
+		t.Errorf("Got %v; expected nothing to happen when extracting isn't requested", err)
+	}
+}
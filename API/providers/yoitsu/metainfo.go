@@ -0,0 +1,215 @@
+package yoitsu
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/anacrolix/torrent/types/infohash"
+)
+
+var (
+	ErrInvalidTorrentId   = errors.New("id must be an infohash or magnet uri")
+	ErrInvalidTorrentFile = errors.New("invalid torrent file")
+	ErrRedirectNotAllowed = errors.New("redirect to a local address is not allowed")
+)
+
+const (
+	// maxTorrentFileSize is the largest .torrent file downloaded from a url
+	maxTorrentFileSize = 10 << 20
+	// maxTorrentUrlRedirects is the amount of redirects followed when downloading a .torrent file
+	maxTorrentUrlRedirects = 5
+)
+
+var (
+	hexInfoHashRegex    = regexp.MustCompile(`^[0-9a-fA-F]{40}$`)
+	base32InfoHashRegex = regexp.MustCompile(`^[a-zA-Z2-7]{32}$`)
+)
+
+// isMagnet returns true if the id is a magnet uri, instead of an infohash
+func isMagnet(id string) bool {
+	return strings.HasPrefix(strings.ToLower(id), "magnet:")
+}
+
+// IsTorrentUrl returns true if the id is a link to a .torrent file, as returned by indexers
+func IsTorrentUrl(id string) bool {
+	lower := strings.ToLower(id)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// fetchTorrentUrl downloads the .torrent file at the url. Indexers may redirect to a magnet uri instead,
+// which is returned without data
+func (y *yoitsu) fetchTorrentUrl(uri string) (string, []byte, error) {
+	client := *y.httpClient.Client
+	client.CheckRedirect = checkTorrentRedirect
+
+	res, err := client.Get(uri)
+	if err != nil {
+		return "", nil, err
+	}
+	defer res.Body.Close()
+
+	if location := res.Header.Get("Location"); isMagnet(location) {
+		return location, nil, nil
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("status code error: %d %s", res.StatusCode, res.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxTorrentFileSize+1))
+	if err != nil {
+		return "", nil, err
+	}
+	if len(data) > maxTorrentFileSize {
+		return "", nil, fmt.Errorf("%w: larger than %d bytes", ErrInvalidTorrentFile, maxTorrentFileSize)
+	}
+
+	return "", data, nil
+}
+
+// checkTorrentRedirect stops at redirects to magnet uris, so they can be read from the response. The url the user
+// passed was checked against the configured indexers, redirects to other hosts may not reach the local network
+func checkTorrentRedirect(req *http.Request, via []*http.Request) error {
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return http.ErrUseLastResponse
+	}
+	if len(via) >= maxTorrentUrlRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxTorrentUrlRedirects)
+	}
+	if strings.EqualFold(req.URL.Host, via[0].URL.Host) {
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(req.Context(), "ip", req.URL.Hostname())
+	if err != nil {
+		return err
+	}
+
+	for _, addr := range addrs {
+		addr = addr.Unmap()
+		if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+			addr.IsUnspecified() {
+			return fmt.Errorf("%w: %s", ErrRedirectNotAllowed, req.URL.Host)
+		}
+	}
+
+	return nil
+}
+
+// torrentSpec returns the spec to add the torrent with, id may be a hex or base32 encoded infohash, or a magnet uri.
+// Trackers, web seeds and peers in a magnet uri are kept
+func torrentSpec(id string) (*torrent.TorrentSpec, error) {
+	switch {
+	case isMagnet(id):
+		spec, err := torrent.TorrentSpecFromMagnetUri(id)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidTorrentId, err)
+		}
+		if spec.InfoHash == (infohash.T{}) {
+			return nil, fmt.Errorf("%w: magnet uri has no v1 infohash", ErrInvalidTorrentId)
+		}
+		return spec, nil
+	case hexInfoHashRegex.MatchString(id):
+		return &torrent.TorrentSpec{
+			AddTorrentOpts: torrent.AddTorrentOpts{InfoHash: infohash.FromHexString(strings.ToLower(id))},
+		}, nil
+	case base32InfoHashRegex.MatchString(id):
+		spec, err := torrent.TorrentSpecFromMagnetUri("magnet:?xt=urn:btih:" + strings.ToUpper(id))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidTorrentId, err)
+		}
+		return spec, nil
+	default:
+		return nil, ErrInvalidTorrentId
+	}
+}
+
+// parseTorrentFile parses the contents of a .torrent file, returning the spec to add it with
+func parseTorrentFile(data []byte) (*metainfo.MetaInfo, *torrent.TorrentSpec, error) {
+	mi, err := metainfo.Load(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidTorrentFile, err)
+	}
+
+	spec, err := torrent.TorrentSpecFromMetaInfoErr(mi)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidTorrentFile, err)
+	}
+
+	if spec.InfoHash == (infohash.T{}) {
+		return nil, nil, fmt.Errorf("%w: only v1 and hybrid torrents are supported", ErrInvalidTorrentFile)
+	}
+
+	return mi, spec, nil
+}
+
+// metaInfoPath returns the path the metainfo of the torrent is cached at
+func (y *yoitsu) metaInfoPath(infoHash string) string {
+	return path.Join(y.metaInfoDir, strings.ToLower(infoHash)+".torrent")
+}
+
+// loadMetaInfo returns the cached spec of the torrent, if present
+func (y *yoitsu) loadMetaInfo(infoHash string) (*torrent.TorrentSpec, bool) {
+	data, err := y.fs.ReadFile(y.metaInfoPath(infoHash))
+	if err != nil {
+		return nil, false
+	}
+
+	_, spec, err := parseTorrentFile(data)
+	if err != nil {
+		y.log.Warn().Err(err).Str("infoHash", infoHash).Msg("cached metainfo is invalid, ignoring")
+		return nil, false
+	}
+
+	return spec, true
+}
+
+// saveMetaInfo caches the metainfo, so adding the torrent again doesn't require fetching it from peers
+func (y *yoitsu) saveMetaInfo(mi *metainfo.MetaInfo) error {
+	if err := y.fs.MkdirAll(y.metaInfoDir, 0755); err != nil {
+		return err
+	}
+
+	f, err := y.fs.Create(y.metaInfoPath(mi.HashInfoBytes().HexString()))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return mi.Write(f)
+}
+
+// cacheMetaInfo saves the metainfo of the torrent once it has been received from peers
+func (y *yoitsu) cacheMetaInfo(t *torrent.Torrent) {
+	if ok, _ := y.fs.Exists(y.metaInfoPath(t.InfoHash().HexString())); ok {
+		return
+	}
+
+	select {
+	case <-t.Closed():
+		return
+	case <-t.GotInfo():
+	}
+
+	mi := t.Metainfo()
+	if err := y.saveMetaInfo(&mi); err != nil {
+		y.log.Warn().Err(err).Str("infoHash", t.InfoHash().HexString()).
+			Msg("failed to cache metainfo, it will be fetched from peers again after a restart")
+	}
+}
+
+func (y *yoitsu) removeMetaInfo(infoHash string) {
+	if err := y.fs.Remove(y.metaInfoPath(infoHash)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		y.log.Warn().Err(err).Str("infoHash", infoHash).Msg("failed to remove cached metainfo")
+	}
+}
@@ -0,0 +1,237 @@
+package yoitsu
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Fesaa/Media-Provider/db/models"
+	"github.com/Fesaa/Media-Provider/http/menou"
+	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/Fesaa/Media-Provider/utils"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/rs/zerolog"
+	"github.com/spf13/afero"
+)
+
+const testInfoHash = "c9e15763f722f23e98a29decdfae341b98d53056"
+
+func testTorrentFile(t *testing.T) (*metainfo.MetaInfo, []byte) {
+	t.Helper()
+
+	info := metainfo.Info{
+		Name:        "Spice and Wolf",
+		PieceLength: 16 * 1024,
+		Pieces:      make([]byte, 20),
+		Length:      1024,
+	}
+
+	infoBytes, err := bencode.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mi := &metainfo.MetaInfo{
+		InfoBytes:    infoBytes,
+		AnnounceList: [][]string{{"udp://tracker.example.org:1337/announce"}},
+		UrlList:      []string{"https://seed.example.org/"},
+	}
+
+	var buf bytes.Buffer
+	if err = mi.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	return mi, buf.Bytes()
+}
+
+func TestTorrentSpec(t *testing.T) {
+	magnet := "magnet:?xt=urn:btih:" + testInfoHash +
+		"&dn=Spice+and+Wolf&tr=udp%3A%2F%2Ftracker.example.org%3A1337%2Fannounce&ws=https%3A%2F%2Fseed.example.org%2F"
+
+	spec, err := torrentSpec(magnet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.InfoHash.HexString() != testInfoHash {
+		t.Errorf("Got infohash %s; expected %s", spec.InfoHash.HexString(), testInfoHash)
+	}
+	if len(spec.Trackers) != 1 || !slices.Contains(spec.Trackers[0], "udp://tracker.example.org:1337/announce") {
+		t.Errorf("Got trackers %v; expected the tracker from the magnet", spec.Trackers)
+	}
+	if !slices.Equal(spec.Webseeds, []string{"https://seed.example.org/"}) {
+		t.Errorf("Got web seeds %v; expected the web seed from the magnet", spec.Webseeds)
+	}
+
+	spec, err = torrentSpec("C9E15763F722F23E98A29DECDFAE341B98D53056")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.InfoHash.HexString() != testInfoHash {
+		t.Errorf("Got infohash %s; expected %s", spec.InfoHash.HexString(), testInfoHash)
+	}
+
+	for _, id := range []string{"", "not a hash", "magnet:?dn=missing"} {
+		if _, err = torrentSpec(id); !errors.Is(err, ErrInvalidTorrentId) {
+			t.Errorf("Got %v for %q; expected %v", err, id, ErrInvalidTorrentId)
+		}
+	}
+}
+
+func TestParseTorrentFile(t *testing.T) {
+	mi, data := testTorrentFile(t)
+
+	_, spec, err := parseTorrentFile(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.InfoHash != mi.HashInfoBytes() {
+		t.Errorf("Got infohash %s; expected %s", spec.InfoHash, mi.HashInfoBytes())
+	}
+	if spec.DisplayName != "Spice and Wolf" {
+		t.Errorf("Got name %s; expected Spice and Wolf", spec.DisplayName)
+	}
+
+	if _, _, err = parseTorrentFile([]byte("not a torrent")); !errors.Is(err, ErrInvalidTorrentFile) {
+		t.Errorf("Got %v; expected %v", err, ErrInvalidTorrentFile)
+	}
+}
+
+func TestYoitsu_RequestSpec(t *testing.T) {
+	y := &yoitsu{
+		fs:          afero.Afero{Fs: afero.NewMemMapFs()},
+		log:         zerolog.Nop(),
+		metaInfoDir: "torrents",
+	}
+
+	mi, _ := testTorrentFile(t)
+	infoHash := mi.HashInfoBytes().HexString()
+	magnet := "magnet:?xt=urn:btih:" + infoHash + "&tr=udp%3A%2F%2Fother.example.org%3A1337"
+
+	req := payload.DownloadRequest{Id: magnet}
+	spec, err := y.requestSpec(&req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if req.Id != infoHash {
+		t.Errorf("Got id %s; expected %s", req.Id, infoHash)
+	}
+	if got := req.DownloadMetadata.Extra.GetStringOrDefault(KeyMagnet, ""); got != magnet {
+		t.Errorf("Got magnet %s; expected it to be kept in the request", got)
+	}
+	if spec.InfoBytes != nil {
+		t.Error("Got info bytes; expected none without cached metainfo")
+	}
+
+	if err = y.saveMetaInfo(mi); err != nil {
+		t.Fatal(err)
+	}
+
+	// As if resumed after a restart
+	spec, err = y.requestSpec(&req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(spec.InfoBytes, mi.InfoBytes) {
+		t.Error("Got no info bytes; expected the cached metainfo to be used")
+	}
+	if len(spec.Trackers) != 2 {
+		t.Errorf("Got trackers %v; expected those of the metainfo and magnet", spec.Trackers)
+	}
+
+	y.removeMetaInfo(infoHash)
+	if ok, _ := y.fs.Exists(y.metaInfoPath(infoHash)); ok {
+		t.Error("cached metainfo was not removed")
+	}
+}
+
+func TestYoitsu_DownloadTorrentFileRemovesMetaInfo(t *testing.T) {
+	y := &yoitsu{
+		fs:          afero.Afero{Fs: afero.NewMemMapFs()},
+		log:         zerolog.Nop(),
+		metaInfoDir: "torrents",
+		torrents:    utils.NewSafeMap[string, Torrent](),
+	}
+
+	mi, data := testTorrentFile(t)
+	req := payload.DownloadRequest{
+		DownloadMetadata: models.DownloadRequestMetadata{
+			Extra: utils.SmartMap{EpisodeTemplateKey: {"{Unknown}"}},
+		},
+	}
+
+	if err := y.DownloadTorrentFile(req, data); err == nil {
+		t.Fatal("expected the invalid episode template to fail the download")
+	}
+
+	if ok, _ := y.fs.Exists(y.metaInfoPath(mi.HashInfoBytes().HexString())); ok {
+		t.Error("cached metainfo was not removed after the download failed")
+	}
+}
+
+func TestYoitsu_FetchTorrentUrl(t *testing.T) {
+	_, data := testTorrentFile(t)
+	magnet := "magnet:?xt=urn:btih:" + testInfoHash
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/file.torrent", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(data)
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/file.torrent", http.StatusFound)
+	})
+	mux.HandleFunc("/magnet", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, magnet, http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	local := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	mux.HandleFunc("/local", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, local+"/file.torrent", http.StatusFound)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+
+	y := &yoitsu{httpClient: menou.DefaultClient, log: zerolog.Nop()}
+
+	for _, p := range []string{"/file.torrent", "/redirect"} {
+		gotMagnet, gotData, err := y.fetchTorrentUrl(server.URL + p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gotMagnet != "" || !bytes.Equal(gotData, data) {
+			t.Errorf("Got (%q, %d bytes) for %s; expected the torrent file", gotMagnet, len(gotData), p)
+		}
+	}
+
+	gotMagnet, gotData, err := y.fetchTorrentUrl(server.URL + "/magnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotMagnet != magnet || gotData != nil {
+		t.Errorf("Got (%q, %d bytes); expected the magnet uri", gotMagnet, len(gotData))
+	}
+
+	if _, _, err = y.fetchTorrentUrl(server.URL + "/missing"); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+
+	if _, _, err = y.fetchTorrentUrl(server.URL + "/local"); !errors.Is(err, ErrRedirectNotAllowed) {
+		t.Errorf("Got %v; expected a redirect to another local host to be refused", err)
+	}
+
+	if _, _, err = y.fetchTorrentUrl(server.URL + "/loop"); err == nil {
+		t.Error("Expected an error after too many redirects")
+	}
+}
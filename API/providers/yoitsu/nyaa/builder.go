@@ -83,13 +83,7 @@ func (b *Builder) Search(ctx context.Context, opts nyaa.SearchOptions) ([]types.
 
 func (b *Builder) DownloadMetadata() payload.DownloadMetadata {
 	return payload.DownloadMetadata{
-		Definitions: []payload.DownloadMetadataDefinition{
-			{
-				Key:           "no-sub-dir",
-				FormType:      payload.SWITCH,
-				DefaultOption: "",
-			},
-		},
+		Definitions: yoitsu.DownloadMetadataDefinitions(),
 	}
 }
 
@@ -0,0 +1,329 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Fesaa/Media-Provider/http/menou"
+	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/Fesaa/Media-Provider/utils"
+)
+
+var errQBittorrentLogin = errors.New("qBittorrent login failed")
+
+// qBittorrent talks to the qBittorrent Web API v2, logging in again when the session expires
+type qBittorrent struct {
+	url      string
+	username string
+	password string
+
+	client  *http.Client
+	loginMu sync.Mutex
+}
+
+type qBittorrentTorrent struct {
+	Hash      string `json:"hash"`
+	Name      string `json:"name"`
+	State     string `json:"state"`
+	Completed int64  `json:"completed"`
+	DlSpeed   int64  `json:"dlspeed"`
+	UpSpeed   int64  `json:"upspeed"`
+	Uploaded  int64  `json:"uploaded"`
+}
+
+type qBittorrentFile struct {
+	Index    *int    `json:"index"`
+	Name     string  `json:"name"`
+	Size     int64   `json:"size"`
+	Progress float64 `json:"progress"`
+}
+
+// statusError is returned when the remote client responds with an unexpected status code
+type statusError struct {
+	code int
+	body string
+}
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("status code error: %d %s", e.code, e.body)
+}
+
+func isStatus(err error, code int) bool {
+	var se statusError
+	return errors.As(err, &se) && se.code == code
+}
+
+func newQBittorrent(url string, settings payload.RemoteClientSettings, httpClient *menou.Client) (*qBittorrent, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := *httpClient.Client
+	client.Jar = jar
+
+	return &qBittorrent{
+		url:      url,
+		username: settings.Username,
+		password: settings.Password,
+		client:   &client,
+	}, nil
+}
+
+func (q *qBittorrent) login(ctx context.Context) error {
+	q.loginMu.Lock()
+	defer q.loginMu.Unlock()
+
+	form := url.Values{
+		"username": {q.username},
+		"password": {q.password},
+	}
+
+	data, err := q.do(ctx, http.MethodPost, "/api/v2/auth/login",
+		strings.NewReader(form.Encode()), "application/x-www-form-urlencoded")
+	if err != nil {
+		return fmt.Errorf("%w: %w", errQBittorrentLogin, err)
+	}
+
+	if strings.TrimSpace(string(data)) != "Ok." {
+		return fmt.Errorf("%w: %s", errQBittorrentLogin, data)
+	}
+
+	return nil
+}
+
+func (q *qBittorrent) do(ctx context.Context, method, endpoint string, body io.Reader, contentType string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, q.url+endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	// The Web API rejects requests with a Referer or Origin not matching its host
+	req.Header.Set("Referer", q.url)
+
+	res, err := q.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, statusError{code: res.StatusCode, body: strings.TrimSpace(string(data))}
+	}
+
+	return data, nil
+}
+
+// request sends the request, logging in and retrying once if the session is missing or expired. newBody is
+// called for every attempt
+func (q *qBittorrent) request(ctx context.Context, method, endpoint string, newBody func() (io.Reader, string)) ([]byte, error) {
+	send := func() ([]byte, error) {
+		var body io.Reader
+		var contentType string
+		if newBody != nil {
+			body, contentType = newBody()
+		}
+		return q.do(ctx, method, endpoint, body, contentType)
+	}
+
+	data, err := send()
+	if !isStatus(err, http.StatusForbidden) {
+		return data, err
+	}
+
+	if err = q.login(ctx); err != nil {
+		return nil, err
+	}
+
+	return send()
+}
+
+func (q *qBittorrent) post(ctx context.Context, endpoint string, form url.Values) ([]byte, error) {
+	return q.request(ctx, http.MethodPost, endpoint, func() (io.Reader, string) {
+		return strings.NewReader(form.Encode()), "application/x-www-form-urlencoded"
+	})
+}
+
+func (q *qBittorrent) get(ctx context.Context, endpoint string, params url.Values, dst any) error {
+	data, err := q.request(ctx, http.MethodGet, endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, dst)
+}
+
+func (q *qBittorrent) Add(ctx context.Context, req AddRequest) error {
+	var buf bytes.Buffer
+
+	w := multipart.NewWriter(&buf)
+	fields := map[string]string{
+		"savepath":      req.Dir,
+		"autoTMM":       "false",
+		"contentLayout": "Original",
+	}
+
+	if len(req.MetaInfo) > 0 {
+		part, err := w.CreateFormFile("torrents", req.InfoHash+".torrent")
+		if err != nil {
+			return err
+		}
+		if _, err = part.Write(req.MetaInfo); err != nil {
+			return err
+		}
+		// paused was renamed to stopped in v5
+		fields["paused"] = "true"
+		fields["stopped"] = "true"
+	} else {
+		fields["urls"] = req.Magnet
+		fields["stopCondition"] = "MetadataReceived"
+	}
+
+	for key, value := range fields {
+		if err := w.WriteField(key, value); err != nil {
+			return err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	contentType := w.FormDataContentType()
+
+	data, err := q.request(ctx, http.MethodPost, "/api/v2/torrents/add", func() (io.Reader, string) {
+		return bytes.NewReader(buf.Bytes()), contentType
+	})
+	if err == nil && strings.TrimSpace(string(data)) == "Ok." {
+		return nil
+	}
+
+	// Adding a torrent that is already present fails
+	if _, getErr := q.Get(ctx, req.InfoHash); getErr == nil {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("qBittorrent refused to add the torrent: %s", data)
+}
+
+func (q *qBittorrent) Get(ctx context.Context, infoHash string) (*Torrent, error) {
+	var torrents []qBittorrentTorrent
+	if err := q.get(ctx, "/api/v2/torrents/info", url.Values{"hashes": {infoHash}}, &torrents); err != nil {
+		return nil, err
+	}
+
+	if len(torrents) == 0 {
+		return nil, ErrTorrentNotFound
+	}
+
+	t := torrents[0]
+	return &Torrent{
+		InfoHash:      strings.ToLower(t.Hash),
+		Name:          t.Name,
+		HasMetadata:   t.State != "metaDL" && t.State != "forcedMetaDL",
+		Completed:     t.Completed,
+		DownloadSpeed: t.DlSpeed,
+		UploadSpeed:   t.UpSpeed,
+		Uploaded:      t.Uploaded,
+	}, nil
+}
+
+func (q *qBittorrent) Files(ctx context.Context, infoHash string) ([]File, error) {
+	var files []qBittorrentFile
+	if err := q.get(ctx, "/api/v2/torrents/files", url.Values{"hash": {infoHash}}, &files); err != nil {
+		if isStatus(err, http.StatusNotFound) {
+			return nil, ErrTorrentNotFound
+		}
+		return nil, err
+	}
+
+	out := make([]File, len(files))
+	for i, f := range files {
+		completed := int64(f.Progress * float64(f.Size))
+		if f.Progress >= 1 {
+			completed = f.Size
+		}
+
+		// The index is only present since Web API 2.8.2, files are ordered by index before
+		index := i
+		if f.Index != nil {
+			index = *f.Index
+		}
+
+		out[i] = File{
+			Index:     index,
+			Name:      f.Name,
+			Size:      f.Size,
+			Completed: completed,
+		}
+	}
+
+	return out, nil
+}
+
+func (q *qBittorrent) SetWanted(ctx context.Context, infoHash string, wanted, unwanted []int) error {
+	for priority, indices := range map[int][]int{1: wanted, 0: unwanted} {
+		if len(indices) == 0 {
+			continue
+		}
+
+		ids := utils.Map(indices, strconv.Itoa)
+		_, err := q.post(ctx, "/api/v2/torrents/filePrio", url.Values{
+			"hash":     {infoHash},
+			"id":       {strings.Join(ids, "|")},
+			"priority": {strconv.Itoa(priority)},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Start resumes the torrent, the endpoint was renamed in v5. The old one is used if the new one doesn't exist
+func (q *qBittorrent) Start(ctx context.Context, infoHash string) error {
+	return q.postRenamed(ctx, "/api/v2/torrents/start", "/api/v2/torrents/resume", infoHash)
+}
+
+func (q *qBittorrent) Stop(ctx context.Context, infoHash string) error {
+	return q.postRenamed(ctx, "/api/v2/torrents/stop", "/api/v2/torrents/pause", infoHash)
+}
+
+func (q *qBittorrent) postRenamed(ctx context.Context, endpoint, legacy, infoHash string) error {
+	form := url.Values{"hashes": {infoHash}}
+
+	_, err := q.post(ctx, endpoint, form)
+	if isStatus(err, http.StatusNotFound) {
+		_, err = q.post(ctx, legacy, form)
+	}
+	return err
+}
+
+func (q *qBittorrent) Remove(ctx context.Context, infoHash string, deleteData bool) error {
+	_, err := q.post(ctx, "/api/v2/torrents/delete", url.Values{
+		"hashes":      {infoHash},
+		"deleteFiles": {strconv.FormatBool(deleteData)},
+	})
+	return err
+}
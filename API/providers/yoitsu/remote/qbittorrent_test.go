@@ -0,0 +1,193 @@
+package remote
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/Fesaa/Media-Provider/http/menou"
+	"github.com/Fesaa/Media-Provider/http/payload"
+)
+
+const testInfoHash = "c9e15763f722f23e98a29decdfae341b98d53056"
+
+// standInQBittorrent serves the parts of the Web API used by the client, requests without the session cookie
+// are rejected. Form values of every POST are stored by endpoint
+func standInQBittorrent(t *testing.T, posted map[string][]map[string][]string) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/auth/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("username") != "admin" || r.FormValue("password") != "adminadmin" {
+			_, _ = w.Write([]byte("Fails."))
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "SID", Value: "session", Path: "/"})
+		_, _ = w.Write([]byte("Ok."))
+	})
+
+	authed := func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if c, err := r.Cookie("SID"); err != nil || c.Value != "session" {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			if r.Method == http.MethodPost {
+				if err := r.ParseMultipartForm(1 << 20); err != nil {
+					_ = r.ParseForm()
+				}
+				posted[r.URL.Path] = append(posted[r.URL.Path], r.Form)
+			}
+			handler(w, r)
+		}
+	}
+
+	mux.HandleFunc("/api/v2/torrents/add", authed(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("Ok."))
+	}))
+	mux.HandleFunc("/api/v2/torrents/info", authed(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("hashes") != testInfoHash {
+			_, _ = w.Write([]byte(`[]`))
+			return
+		}
+		_, _ = w.Write([]byte(`[{"hash":"C9E15763F722F23E98A29DECDFAE341B98D53056","name":"Spice and Wolf",
+			"state":"downloading","completed":512,"dlspeed":64,"upspeed":8,"uploaded":128}]`))
+	}))
+	mux.HandleFunc("/api/v2/torrents/files", authed(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"index":0,"name":"Spice and Wolf/01.mkv","size":1000,"progress":1},
+			{"index":1,"name":"Spice and Wolf/02.mkv","size":1000,"progress":0.25}]`))
+	}))
+	mux.HandleFunc("/api/v2/torrents/filePrio", authed(func(w http.ResponseWriter, r *http.Request) {}))
+	// Only the pre v5 endpoint exists
+	mux.HandleFunc("/api/v2/torrents/resume", authed(func(w http.ResponseWriter, r *http.Request) {}))
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func tempQBittorrent(t *testing.T, url, password string) Client {
+	t.Helper()
+
+	client, err := New(payload.TorrentSettings{
+		Backend: payload.BackendQBittorrent,
+		Remote: payload.RemoteClientSettings{
+			Url:      url + "/",
+			Username: "admin",
+			Password: password,
+		},
+	}, menou.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestQBittorrent_Login(t *testing.T) {
+	server := standInQBittorrent(t, map[string][]map[string][]string{})
+
+	if _, err := tempQBittorrent(t, server.URL, "wrong").Get(t.Context(), testInfoHash); !errors.Is(err, errQBittorrentLogin) {
+		t.Errorf("Got %v; expected the login to fail", err)
+	}
+
+	got, err := tempQBittorrent(t, server.URL, "adminadmin").Get(t.Context(), testInfoHash)
+	if err != nil {
+		t.Fatalf("Got %v; expected the client to login and retry", err)
+	}
+
+	want := Torrent{
+		InfoHash:      testInfoHash,
+		Name:          "Spice and Wolf",
+		HasMetadata:   true,
+		Completed:     512,
+		DownloadSpeed: 64,
+		UploadSpeed:   8,
+		Uploaded:      128,
+	}
+	if *got != want {
+		t.Errorf("Got %+v; expected %+v", *got, want)
+	}
+}
+
+func TestQBittorrent_Get(t *testing.T) {
+	server := standInQBittorrent(t, map[string][]map[string][]string{})
+	client := tempQBittorrent(t, server.URL, "adminadmin")
+
+	if _, err := client.Get(t.Context(), "0123456789abcdef0123456789abcdef01234567"); !errors.Is(err, ErrTorrentNotFound) {
+		t.Errorf("Got %v; expected ErrTorrentNotFound", err)
+	}
+}
+
+func TestQBittorrent_Add(t *testing.T) {
+	posted := map[string][]map[string][]string{}
+	server := standInQBittorrent(t, posted)
+	client := tempQBittorrent(t, server.URL, "adminadmin")
+
+	err := client.Add(t.Context(), AddRequest{
+		InfoHash: testInfoHash,
+		Magnet:   "magnet:?xt=urn:btih:" + testInfoHash,
+		Dir:      "/downloads/anime/" + testInfoHash,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forms := posted["/api/v2/torrents/add"]
+	if len(forms) != 1 {
+		t.Fatalf("Got %d add requests; expected 1", len(forms))
+	}
+
+	form := forms[0]
+	if got := form["savepath"]; !slices.Equal(got, []string{"/downloads/anime/" + testInfoHash}) {
+		t.Errorf("Got savepath %v; expected the dir of the request", got)
+	}
+	if got := form["stopCondition"]; !slices.Equal(got, []string{"MetadataReceived"}) {
+		t.Errorf("Got stopCondition %v; expected the torrent to stop once metadata was received", got)
+	}
+}
+
+func TestQBittorrent_Files(t *testing.T) {
+	server := standInQBittorrent(t, map[string][]map[string][]string{})
+	client := tempQBittorrent(t, server.URL, "adminadmin")
+
+	files, err := client.Files(t.Context(), testInfoHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []File{
+		{Index: 0, Name: "Spice and Wolf/01.mkv", Size: 1000, Completed: 1000},
+		{Index: 1, Name: "Spice and Wolf/02.mkv", Size: 1000, Completed: 250},
+	}
+	if !slices.Equal(files, want) {
+		t.Errorf("Got %+v; expected %+v", files, want)
+	}
+}
+
+func TestQBittorrent_SetWantedAndStart(t *testing.T) {
+	posted := map[string][]map[string][]string{}
+	server := standInQBittorrent(t, posted)
+	client := tempQBittorrent(t, server.URL, "adminadmin")
+
+	if err := client.SetWanted(t.Context(), testInfoHash, []int{0, 2}, []int{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	priorities := map[string]string{}
+	for _, form := range posted["/api/v2/torrents/filePrio"] {
+		priorities[form["priority"][0]] = form["id"][0]
+	}
+	if priorities["1"] != "0|2" || priorities["0"] != "1" {
+		t.Errorf("Got priorities %v; expected 0|2 to be wanted, and 1 unwanted", priorities)
+	}
+
+	if err := client.Start(t.Context(), testInfoHash); err != nil {
+		t.Fatalf("Got %v; expected the client to fall back to the legacy endpoint", err)
+	}
+	if len(posted["/api/v2/torrents/resume"]) != 1 {
+		t.Errorf("Expected the torrent to be resumed")
+	}
+}
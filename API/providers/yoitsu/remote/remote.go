@@ -0,0 +1,91 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Fesaa/Media-Provider/http/menou"
+	"github.com/Fesaa/Media-Provider/http/payload"
+)
+
+var (
+	ErrTorrentNotFound    = errors.New("torrent not found in remote client")
+	ErrUnsupportedBackend = errors.New("unsupported torrent backend")
+)
+
+// Client is an external torrent client, torrents are identified by their lowercase hex v1 infohash
+type Client interface {
+	// Add adds the torrent stopped. Magnets are stopped once their metadata has been received by clients
+	// supporting it, otherwise they're added started and the caller must stop them as soon as the metadata has
+	// been received. Adding a torrent already present in the client is not an error
+	Add(ctx context.Context, req AddRequest) error
+	// Get returns the current state of the torrent, or ErrTorrentNotFound
+	Get(ctx context.Context, infoHash string) (*Torrent, error)
+	// Files returns the files of the torrent, empty until its metadata has been received
+	Files(ctx context.Context, infoHash string) ([]File, error)
+	// SetWanted changes which files are downloaded, by their index
+	SetWanted(ctx context.Context, infoHash string, wanted, unwanted []int) error
+	Start(ctx context.Context, infoHash string) error
+	Stop(ctx context.Context, infoHash string) error
+	// Remove removes the torrent from the client, downloaded data is only removed if deleteData is true
+	Remove(ctx context.Context, infoHash string, deleteData bool) error
+}
+
+type AddRequest struct {
+	InfoHash string
+	// Magnet is used to add the torrent if no MetaInfo is present
+	Magnet string
+	// MetaInfo is the content of the .torrent file, if known
+	MetaInfo []byte
+	// Dir is where the client saves the torrent, as seen by the client
+	Dir string
+}
+
+type Torrent struct {
+	InfoHash    string
+	Name        string
+	HasMetadata bool
+	// Completed is the amount of bytes downloaded of the wanted files
+	Completed int64
+	// DownloadSpeed and UploadSpeed are in bytes per second
+	DownloadSpeed int64
+	UploadSpeed   int64
+	// Uploaded is the total amount of bytes uploaded
+	Uploaded int64
+}
+
+type File struct {
+	Index int
+	// Name is the path of the file, including the name of the torrent if it has multiple files
+	Name      string
+	Size      int64
+	Completed int64
+}
+
+func (f File) Path() string {
+	return f.Name
+}
+
+func (f File) Length() int64 {
+	return f.Size
+}
+
+// New returns the client for the backend, the connection is only made on the first request
+func New(settings payload.TorrentSettings, httpClient *menou.Client) (Client, error) {
+	if settings.Remote.Url == "" {
+		return nil, fmt.Errorf("no url configured for the %s backend", settings.Backend)
+	}
+
+	url := strings.TrimSuffix(settings.Remote.Url, "/")
+
+	switch settings.Backend {
+	case payload.BackendQBittorrent:
+		return newQBittorrent(url, settings.Remote, httpClient)
+	case payload.BackendTransmission:
+		return newTransmission(url, settings.Remote, httpClient), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedBackend, settings.Backend)
+	}
+}
@@ -0,0 +1,245 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/Fesaa/Media-Provider/http/menou"
+	"github.com/Fesaa/Media-Provider/http/payload"
+)
+
+const transmissionSessionHeader = "X-Transmission-Session-Id"
+
+// transmission talks to the Transmission RPC, the session id is refreshed whenever it's rejected
+type transmission struct {
+	url      string
+	username string
+	password string
+
+	client *menou.Client
+
+	sessionMu sync.RWMutex
+	sessionId string
+}
+
+type transmissionRequest struct {
+	Method    string `json:"method"`
+	Arguments any    `json:"arguments"`
+}
+
+type transmissionResponse struct {
+	Result    string          `json:"result"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type transmissionIds struct {
+	Ids []string `json:"ids"`
+}
+
+type transmissionTorrent struct {
+	HashString              string  `json:"hashString"`
+	Name                    string  `json:"name"`
+	MetadataPercentComplete float64 `json:"metadataPercentComplete"`
+	SizeWhenDone            int64   `json:"sizeWhenDone"`
+	LeftUntilDone           int64   `json:"leftUntilDone"`
+	RateDownload            int64   `json:"rateDownload"`
+	RateUpload              int64   `json:"rateUpload"`
+	UploadedEver            int64   `json:"uploadedEver"`
+	Files                   []struct {
+		Name           string `json:"name"`
+		Length         int64  `json:"length"`
+		BytesCompleted int64  `json:"bytesCompleted"`
+	} `json:"files"`
+}
+
+// newTransmission returns a client for the RPC at the url, the default rpc path is used if the url has none
+func newTransmission(rpcUrl string, settings payload.RemoteClientSettings, httpClient *menou.Client) *transmission {
+	if u, err := url.Parse(rpcUrl); err == nil && (u.Path == "" || u.Path == "/") {
+		rpcUrl = strings.TrimSuffix(rpcUrl, "/") + "/transmission/rpc"
+	}
+
+	return &transmission{
+		url:      rpcUrl,
+		username: settings.Username,
+		password: settings.Password,
+		client:   httpClient,
+	}
+}
+
+// call invokes the rpc method, and decodes its arguments into dst if not nil
+func (t *transmission) call(ctx context.Context, method string, args, dst any) error {
+	body, err := json.Marshal(transmissionRequest{Method: method, Arguments: args})
+	if err != nil {
+		return err
+	}
+
+	// The first request of a session is always rejected, and returns the session id to use
+	for range 2 {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		if t.username != "" {
+			req.SetBasicAuth(t.username, t.password)
+		}
+
+		t.sessionMu.RLock()
+		req.Header.Set(transmissionSessionHeader, t.sessionId)
+		t.sessionMu.RUnlock()
+
+		res, err := t.client.Do(req)
+		if err != nil {
+			return err
+		}
+
+		data, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		if res.StatusCode == http.StatusConflict {
+			t.sessionMu.Lock()
+			t.sessionId = res.Header.Get(transmissionSessionHeader)
+			t.sessionMu.Unlock()
+			continue
+		}
+
+		if res.StatusCode != http.StatusOK {
+			return statusError{code: res.StatusCode, body: strings.TrimSpace(string(data))}
+		}
+
+		var response transmissionResponse
+		if err = json.Unmarshal(data, &response); err != nil {
+			return err
+		}
+
+		if response.Result != "success" {
+			return fmt.Errorf("transmission %s failed: %s", method, response.Result)
+		}
+
+		if dst == nil {
+			return nil
+		}
+		return json.Unmarshal(response.Arguments, dst)
+	}
+
+	return errors.New("transmission rejected the session id")
+}
+
+// Add adds the torrent started when it's a magnet, as paused torrents don't fetch metadata and Transmission can't
+// stop torrents once it has. The caller stops it as soon as the metadata has been received
+func (t *transmission) Add(ctx context.Context, req AddRequest) error {
+	args := map[string]any{
+		"download-dir": req.Dir,
+	}
+
+	if len(req.MetaInfo) > 0 {
+		args["metainfo"] = base64.StdEncoding.EncodeToString(req.MetaInfo)
+		args["paused"] = true
+	} else {
+		args["filename"] = req.Magnet
+		args["paused"] = false
+	}
+
+	// Duplicates are reported as torrent-duplicate, with the result still being a success
+	return t.call(ctx, "torrent-add", args, nil)
+}
+
+func (t *transmission) torrent(ctx context.Context, infoHash string, fields ...string) (*transmissionTorrent, error) {
+	var res struct {
+		Torrents []transmissionTorrent `json:"torrents"`
+	}
+
+	err := t.call(ctx, "torrent-get", map[string]any{
+		"ids":    []string{infoHash},
+		"fields": fields,
+	}, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res.Torrents) == 0 {
+		return nil, ErrTorrentNotFound
+	}
+
+	return &res.Torrents[0], nil
+}
+
+func (t *transmission) Get(ctx context.Context, infoHash string) (*Torrent, error) {
+	tor, err := t.torrent(ctx, infoHash, "hashString", "name", "metadataPercentComplete", "sizeWhenDone",
+		"leftUntilDone", "rateDownload", "rateUpload", "uploadedEver")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Torrent{
+		InfoHash:      strings.ToLower(tor.HashString),
+		Name:          tor.Name,
+		HasMetadata:   tor.MetadataPercentComplete >= 1,
+		Completed:     tor.SizeWhenDone - tor.LeftUntilDone,
+		DownloadSpeed: tor.RateDownload,
+		UploadSpeed:   tor.RateUpload,
+		Uploaded:      tor.UploadedEver,
+	}, nil
+}
+
+func (t *transmission) Files(ctx context.Context, infoHash string) ([]File, error) {
+	tor, err := t.torrent(ctx, infoHash, "files")
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]File, len(tor.Files))
+	for i, f := range tor.Files {
+		files[i] = File{
+			Index:     i,
+			Name:      f.Name,
+			Size:      f.Length,
+			Completed: f.BytesCompleted,
+		}
+	}
+
+	return files, nil
+}
+
+func (t *transmission) SetWanted(ctx context.Context, infoHash string, wanted, unwanted []int) error {
+	// An empty list selects all files, so they're only sent when not empty
+	args := map[string]any{
+		"ids": []string{infoHash},
+	}
+	if len(wanted) > 0 {
+		args["files-wanted"] = wanted
+	}
+	if len(unwanted) > 0 {
+		args["files-unwanted"] = unwanted
+	}
+
+	return t.call(ctx, "torrent-set", args, nil)
+}
+
+func (t *transmission) Start(ctx context.Context, infoHash string) error {
+	return t.call(ctx, "torrent-start", transmissionIds{Ids: []string{infoHash}}, nil)
+}
+
+func (t *transmission) Stop(ctx context.Context, infoHash string) error {
+	return t.call(ctx, "torrent-stop", transmissionIds{Ids: []string{infoHash}}, nil)
+}
+
+func (t *transmission) Remove(ctx context.Context, infoHash string, deleteData bool) error {
+	return t.call(ctx, "torrent-remove", map[string]any{
+		"ids":               []string{infoHash},
+		"delete-local-data": deleteData,
+	}, nil)
+}
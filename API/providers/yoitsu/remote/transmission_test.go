@@ -0,0 +1,151 @@
+package remote
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/Fesaa/Media-Provider/http/menou"
+	"github.com/Fesaa/Media-Provider/http/payload"
+)
+
+const testSessionId = "session"
+
+// standInTransmission serves the rpc, requests without the session id are rejected. The arguments of
+// every call are stored by method
+func standInTransmission(t *testing.T, calls map[string][]map[string]any) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/transmission/rpc" {
+			http.NotFound(w, r)
+			return
+		}
+
+		if r.Header.Get(transmissionSessionHeader) != testSessionId {
+			w.Header().Set(transmissionSessionHeader, testSessionId)
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+
+		var req struct {
+			Method    string         `json:"method"`
+			Arguments map[string]any `json:"arguments"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		calls[req.Method] = append(calls[req.Method], req.Arguments)
+
+		switch req.Method {
+		case "torrent-get":
+			_, _ = w.Write([]byte(`{"result":"success","arguments":{"torrents":[{
+				"hashString":"C9E15763F722F23E98A29DECDFAE341B98D53056","name":"Spice and Wolf",
+				"metadataPercentComplete":1,"sizeWhenDone":2000,"leftUntilDone":750,
+				"rateDownload":64,"rateUpload":8,"uploadedEver":128,
+				"files":[{"name":"Spice and Wolf/01.mkv","length":1000,"bytesCompleted":1000},
+					{"name":"Spice and Wolf/02.mkv","length":1000,"bytesCompleted":250}]}]}}`))
+		default:
+			_, _ = w.Write([]byte(`{"result":"success","arguments":{}}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func tempTransmission(t *testing.T, url string) Client {
+	t.Helper()
+
+	client, err := New(payload.TorrentSettings{
+		Backend: payload.BackendTransmission,
+		Remote:  payload.RemoteClientSettings{Url: url},
+	}, menou.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestTransmission_Get(t *testing.T) {
+	server := standInTransmission(t, map[string][]map[string]any{})
+	client := tempTransmission(t, server.URL)
+
+	got, err := client.Get(t.Context(), testInfoHash)
+	if err != nil {
+		t.Fatalf("Got %v; expected the session id to be refreshed", err)
+	}
+
+	want := Torrent{
+		InfoHash:      testInfoHash,
+		Name:          "Spice and Wolf",
+		HasMetadata:   true,
+		Completed:     1250,
+		DownloadSpeed: 64,
+		UploadSpeed:   8,
+		Uploaded:      128,
+	}
+	if *got != want {
+		t.Errorf("Got %+v; expected %+v", *got, want)
+	}
+
+	files, err := client.Files(t.Context(), testInfoHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantFiles := []File{
+		{Index: 0, Name: "Spice and Wolf/01.mkv", Size: 1000, Completed: 1000},
+		{Index: 1, Name: "Spice and Wolf/02.mkv", Size: 1000, Completed: 250},
+	}
+	if !slices.Equal(files, wantFiles) {
+		t.Errorf("Got %+v; expected %+v", files, wantFiles)
+	}
+}
+
+func TestTransmission_Add(t *testing.T) {
+	calls := map[string][]map[string]any{}
+	server := standInTransmission(t, calls)
+	client := tempTransmission(t, server.URL)
+
+	err := client.Add(t.Context(), AddRequest{
+		InfoHash: testInfoHash,
+		MetaInfo: []byte("d4:infod4:name4:testee"),
+		Dir:      "/downloads/anime/" + testInfoHash,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args := calls["torrent-add"]
+	if len(args) != 1 {
+		t.Fatalf("Got %d add calls; expected 1", len(args))
+	}
+
+	if args[0]["download-dir"] != "/downloads/anime/"+testInfoHash {
+		t.Errorf("Got download-dir %v; expected the dir of the request", args[0]["download-dir"])
+	}
+	if args[0]["paused"] != true || args[0]["metainfo"] == nil {
+		t.Errorf("Got %v; expected the metainfo to be added paused", args[0])
+	}
+}
+
+func TestTransmission_SetWanted(t *testing.T) {
+	calls := map[string][]map[string]any{}
+	server := standInTransmission(t, calls)
+	client := tempTransmission(t, server.URL)
+
+	if err := client.SetWanted(t.Context(), testInfoHash, []int{0, 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	args := calls["torrent-set"][0]
+	if _, ok := args["files-unwanted"]; ok {
+		t.Errorf("Got %v; expected an empty unwanted list to be omitted, as it means all files", args)
+	}
+	if wanted, ok := args["files-wanted"].([]any); !ok || len(wanted) != 2 {
+		t.Errorf("Got files-wanted %v; expected both files", args["files-wanted"])
+	}
+}
@@ -0,0 +1,527 @@
+package yoitsu
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Fesaa/Media-Provider/db/models"
+	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/Fesaa/Media-Provider/providers/yoitsu/remote"
+	"github.com/Fesaa/Media-Provider/services"
+	"github.com/Fesaa/Media-Provider/utils"
+	"github.com/anacrolix/torrent"
+	"github.com/rs/zerolog"
+	"github.com/spf13/afero"
+)
+
+// metadataPollInterval is how often the remote client is polled while loading info, magnets may download data
+// until they're stopped once their metadata has been received
+const metadataPollInterval = 250 * time.Millisecond
+
+// remoteTorrent is a torrent downloaded by a remote client, its state is polled from the client
+type remoteTorrent struct {
+	remote remote.Client
+	log    zerolog.Logger
+	client Client
+
+	signalR services.SignalRService
+	queue   services.QueueService
+	fs      afero.Afero
+
+	req       payload.DownloadRequest
+	key       string
+	tempTitle string
+	provider  models.Provider
+	state     payload.ContentState
+	files     int
+
+	userFilter []string
+
+	// mu guards status and remoteFiles, which are updated by the progress loop
+	mu          sync.RWMutex
+	status      *remote.Torrent
+	remoteFiles []remote.File
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	progressLoop context.CancelFunc
+
+	seedStart time.Time
+}
+
+func newRemoteTorrent(infoHash string, req payload.DownloadRequest, log zerolog.Logger, client Client,
+	remoteClient remote.Client, signalR services.SignalRService, queue services.QueueService, fs afero.Afero) Torrent {
+	return &remoteTorrent{
+		remote:    remoteClient,
+		log:       log.With().Str("infoHash", infoHash).Logger(),
+		client:    client,
+		signalR:   signalR,
+		queue:     queue,
+		fs:        fs,
+		req:       req,
+		key:       infoHash,
+		tempTitle: req.TempTitle,
+		provider:  req.Provider,
+		state:     payload.ContentStateQueued,
+	}
+}
+
+// refresh polls the state of the torrent, and its files once the metadata has been received
+func (t *remoteTorrent) refresh(ctx context.Context) error {
+	status, err := t.remote.Get(ctx, t.key)
+	if err != nil {
+		return err
+	}
+
+	var files []remote.File
+	if status.HasMetadata {
+		if files, err = t.remote.Files(ctx, t.key); err != nil {
+			return err
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.status = status
+	if files != nil {
+		t.remoteFiles = files
+	}
+	return nil
+}
+
+func (t *remoteTorrent) snapshot() (*remote.Torrent, []remote.File) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.status, t.remoteFiles
+}
+
+func (t *remoteTorrent) Files() int {
+	return t.files
+}
+
+func (t *remoteTorrent) Request() payload.DownloadRequest {
+	return t.req
+}
+
+func (t *remoteTorrent) Id() string {
+	return t.key
+}
+
+func (t *remoteTorrent) Title() string {
+	status, _ := t.snapshot()
+	if status != nil && status.HasMetadata && status.Name != "" {
+		return status.Name
+	}
+	return t.tempTitle
+}
+
+func (t *remoteTorrent) Provider() models.Provider {
+	return t.provider
+}
+
+func (t *remoteTorrent) State() payload.ContentState {
+	return t.state
+}
+
+func (t *remoteTorrent) SetState(state payload.ContentState) {
+	t.state = state
+	t.signalR.StateUpdate(t.req.OwnerId, t.Id(), t.state)
+	t.persist()
+}
+
+func (t *remoteTorrent) persist() {
+	if t.client.Content(t.Id()) == nil {
+		return
+	}
+
+	if err := t.queue.Save(context.Background(), t, t.userFilter); err != nil {
+		t.log.Warn().Err(err).Msg("failed to persist torrent, it will not be resumed after a restart")
+	}
+}
+
+func (t *remoteTorrent) UserSelection() []string {
+	return t.userFilter
+}
+
+func (t *remoteTorrent) RestoreUserSelection(selection []string) {
+	t.userFilter = selection
+}
+
+func (t *remoteTorrent) Message(msg payload.Message) (payload.Message, error) {
+	var jsonData []byte
+	var err error
+	switch msg.MessageType {
+	case payload.MessageListContent:
+		jsonData, err = json.Marshal(t.ContentList())
+	case payload.SetToDownload:
+		err = t.SetUserFiltered(msg.Data)
+	case payload.StartDownload:
+		err = t.MarkReady()
+	case payload.PreviewEpisodes:
+		jsonData, err = previewEpisodes(t)
+	default:
+		err = services.ErrUnknownMessageType
+	}
+
+	if err != nil {
+		return payload.Message{}, err
+	}
+
+	return payload.Message{
+		Provider:    t.Provider(),
+		ContentId:   t.key,
+		MessageType: msg.MessageType,
+		Data:        jsonData,
+	}, nil
+}
+
+func (t *remoteTorrent) MarkReady() error {
+	if t.state == payload.ContentStatePaused {
+		t.client.Requeue(t)
+		return nil
+	}
+
+	if t.state != payload.ContentStateWaiting {
+		return services.ErrWrongState
+	}
+	if t.client.CanStartNext() {
+		go t.StartDownload()
+		return nil
+	}
+
+	t.SetState(payload.ContentStateReady)
+	return nil
+}
+
+func (t *remoteTorrent) SetUserFiltered(data json.RawMessage) error {
+	if t.state != payload.ContentStateWaiting &&
+		t.state != payload.ContentStateReady {
+		return services.ErrWrongState
+	}
+
+	var filter []string
+	if err := json.Unmarshal(data, &filter); err != nil {
+		return err
+	}
+
+	t.userFilter = filter
+	t.signalR.SizeUpdate(t.req.OwnerId, t.Id(), utils.BytesToSize(float64(t.size())))
+	t.persist()
+	return nil
+}
+
+func (t *remoteTorrent) ContentList() []payload.ListContentData {
+	_, files := t.snapshot()
+	return buildTree(t.userFilter, files)
+}
+
+// GetTorrent returns nil, the torrent isn't managed by the embedded client
+func (t *remoteTorrent) GetTorrent() *torrent.Torrent {
+	return nil
+}
+
+// LoadInfo waits until the remote client has received the metadata. The torrent is stopped right away, so
+// (almost) no data is downloaded before files have been selected
+func (t *remoteTorrent) LoadInfo() {
+	if t.cancel != nil {
+		t.log.Debug().Msg("already loading info")
+		return
+	}
+
+	t.SetState(payload.ContentStateLoading)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.ctx = ctx
+	t.cancel = cancel
+	t.log.Trace().Msg("waiting for remote client to load torrent info")
+
+	ticker := time.NewTicker(metadataPollInterval)
+	defer ticker.Stop()
+
+	for {
+		status, err := t.remote.Get(ctx, t.key)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			t.log.Debug().Err(err).Msg("failed to poll remote torrent")
+		}
+
+		if status != nil && status.HasMetadata {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+
+	// Magnets may have been added started, stop before anything else so little data is downloaded
+	if err := t.remote.Stop(ctx, t.key); err != nil {
+		t.log.Warn().Err(err).Msg("failed to stop remote torrent after loading info")
+	}
+
+	if err := t.refresh(ctx); err != nil {
+		t.log.Warn().Err(err).Msg("failed to load files of remote torrent")
+	}
+
+	t.log = t.log.With().Str("name", t.Title()).Logger()
+	t.log.Info().Msg("remote client has downloaded all info")
+
+	t.SetState(utils.Ternary(t.req.DownloadMetadata.StartImmediately,
+		payload.ContentStateReady,
+		payload.ContentStateWaiting))
+	t.signalR.SizeUpdate(t.req.OwnerId, t.Id(), utils.BytesToSize(float64(t.size())))
+}
+
+func (t *remoteTorrent) startProgressLoop() {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.progressLoop = cancel
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := t.refresh(ctx); err != nil && !errors.Is(err, context.Canceled) {
+					t.log.Debug().Err(err).Msg("failed to poll remote torrent")
+					continue
+				}
+
+				progress, estimated, speed := t.Progress()
+				t.signalR.ProgressUpdate(t.req.OwnerId, payload.ContentProgressUpdate{
+					ContentId: t.Id(),
+					Progress:  progress,
+					Estimated: estimated,
+					SpeedType: payload.BYTES,
+					Speed:     speed,
+				})
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// StartDownload sends the file selection to the remote client, and starts the torrent
+func (t *remoteTorrent) StartDownload() {
+	t.log.Info().Str("infoHash", t.key).
+		Str("into", t.GetDownloadDir()).
+		Str("title", t.Title()).
+		Msg("downloading torrent with remote client")
+
+	_, files := t.snapshot()
+	if len(t.userFilter) == 0 {
+		selection, err := autoSelect(t.req, files)
+		if err != nil {
+			t.log.Warn().Err(err).Msg("invalid file rules, downloading all files")
+		}
+		t.userFilter = selection
+	}
+
+	t.SetState(payload.ContentStateDownloading)
+
+	var wanted, unwanted []int
+	for _, file := range files {
+		if len(t.userFilter) == 0 || slices.Contains(t.userFilter, file.Name) {
+			wanted = append(wanted, file.Index)
+		} else {
+			unwanted = append(unwanted, file.Index)
+		}
+	}
+	t.files = len(wanted)
+
+	ctx := context.Background()
+	if len(t.userFilter) > 0 {
+		if err := t.remote.SetWanted(ctx, t.key, wanted, unwanted); err != nil {
+			t.log.Error().Err(err).Msg("failed to send file selection to remote client")
+		}
+	}
+
+	if err := t.remote.Start(ctx, t.key); err != nil {
+		t.log.Error().Err(err).Msg("failed to start remote torrent")
+	}
+
+	t.startProgressLoop()
+}
+
+func (t *remoteTorrent) Cancel() {
+	t.log.Trace().Msg("cancelling torrent")
+	if t.cancel != nil {
+		t.cancel()
+	}
+
+	// The progress loop may outlive the context of loading info, which Stop clears
+	if t.progressLoop != nil {
+		t.progressLoop()
+		t.progressLoop = nil
+	}
+}
+
+// Stop cancels loading info, or stops downloading, and stops the torrent in the remote client. Torrents that were
+// downloading start downloading right away once their info is loaded again
+func (t *remoteTorrent) Stop() {
+	t.Cancel()
+	t.cancel = nil
+
+	if t.state == payload.ContentStateDownloading {
+		t.req.DownloadMetadata.StartImmediately = true
+	}
+
+	if err := t.remote.Stop(context.Background(), t.key); err != nil {
+		t.log.Warn().Err(err).Msg("failed to stop remote torrent")
+	}
+}
+
+func (t *remoteTorrent) BytesCompleted() int64 {
+	status, _ := t.snapshot()
+	if status == nil {
+		return 0
+	}
+	return status.Completed
+}
+
+// Drop removes the torrent from the remote client, its data is kept
+func (t *remoteTorrent) Drop() {
+	err := t.remote.Remove(context.Background(), t.key, false)
+	if err != nil && !errors.Is(err, remote.ErrTorrentNotFound) {
+		t.log.Warn().Err(err).Msg("failed to remove torrent from remote client")
+	}
+}
+
+func (t *remoteTorrent) FilePaths() []string {
+	_, files := t.snapshot()
+	return utils.Map(files, remote.File.Path)
+}
+
+func (t *remoteTorrent) GetDownloadDir() string {
+	return path.Join(t.req.BaseDir, t.key)
+}
+
+func (t *remoteTorrent) GetInfo() payload.InfoStat {
+	progress, estimated, speed := t.Progress()
+	return payload.InfoStat{
+		Provider:     t.provider,
+		Id:           t.key,
+		ContentState: t.state,
+		Name:         t.Title(),
+		Size:         utils.BytesToSize(float64(t.size())),
+		Downloading:  t.state == payload.ContentStateDownloading,
+		Progress:     progress,
+		Estimated:    estimated,
+		SpeedType:    payload.BYTES,
+		Speed:        speed,
+		DownloadDir:  t.GetDownloadDir(),
+		Seeding:      t.state == payload.ContentStateSeeding,
+		Ratio:        utils.Ternary(t.state == payload.ContentStateSeeding, t.ratio(), 0),
+	}
+}
+
+func (t *remoteTorrent) StartSeeding() {
+	t.seedStart = time.Now()
+	t.SetState(payload.ContentStateSeeding)
+}
+
+// SeedStats polls the remote client, as the progress loop has stopped once the torrent started seeding
+func (t *remoteTorrent) SeedStats() (time.Duration, float64) {
+	if t.state != payload.ContentStateSeeding {
+		return 0, 0
+	}
+
+	if err := t.refresh(context.Background()); err != nil {
+		t.log.Debug().Err(err).Msg("failed to poll remote torrent")
+	}
+
+	return time.Since(t.seedStart), t.ratio()
+}
+
+func (t *remoteTorrent) ratio() float64 {
+	size := t.size()
+	status, _ := t.snapshot()
+	if size == 0 || status == nil {
+		return 0
+	}
+
+	return float64(status.Uploaded) / float64(size)
+}
+
+func (t *remoteTorrent) Progress() (int64, int64, int64) {
+	status, _ := t.snapshot()
+	if status == nil {
+		return 0, 0, 0
+	}
+
+	size := t.size()
+	speed := status.DownloadSpeed
+	estimated := int64(0)
+	if speed > 0 {
+		estimated = (size - status.Completed) / speed
+	}
+
+	return utils.Percent(status.Completed, size), estimated, speed
+}
+
+// Cleanup removes files that weren't selected, remote clients may still create them for pieces shared with
+// wanted files
+func (t *remoteTorrent) Cleanup(root string) {
+	if len(t.userFilter) == 0 {
+		return
+	}
+
+	_, files := t.snapshot()
+	for _, file := range files {
+		if slices.Contains(t.userFilter, file.Name) {
+			continue
+		}
+
+		filePath := path.Join(root, file.Name)
+		if ok, _ := t.fs.Exists(filePath); !ok {
+			continue
+		}
+
+		t.log.Debug().Str("path", filePath).Msg("removing file, as it wasn't wanted")
+		if err := t.fs.Remove(filePath); err != nil {
+			t.log.Error().Str("path", filePath).Err(err).Msg("failed to remove file")
+		}
+	}
+}
+
+func (t *remoteTorrent) IsDone() bool {
+	if t.state != payload.ContentStateDownloading {
+		return false
+	}
+
+	_, files := t.snapshot()
+	if len(files) == 0 {
+		return false
+	}
+
+	for _, file := range files {
+		if len(t.userFilter) > 0 && !slices.Contains(t.userFilter, file.Name) {
+			continue
+		}
+
+		if file.Completed < file.Size {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (t *remoteTorrent) size() int64 {
+	_, files := t.snapshot()
+
+	var size int64
+	for _, file := range files {
+		if len(t.userFilter) == 0 || slices.Contains(t.userFilter, file.Name) {
+			size += file.Size
+		}
+	}
+	return size
+}
@@ -0,0 +1,65 @@
+package yoitsu
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/Fesaa/Media-Provider/providers/yoitsu/remote"
+	"github.com/rs/zerolog"
+)
+
+func TestRemoteTorrent_IsDone(t *testing.T) {
+	tor := &remoteTorrent{
+		state:  payload.ContentStateDownloading,
+		status: &remote.Torrent{HasMetadata: true, Completed: 1000, DownloadSpeed: 100, Uploaded: 500},
+		remoteFiles: []remote.File{
+			{Index: 0, Name: "Spice and Wolf/01.mkv", Size: 1000, Completed: 1000},
+			{Index: 1, Name: "Spice and Wolf/02.mkv", Size: 1000, Completed: 0},
+		},
+	}
+
+	if tor.IsDone() {
+		t.Error("Expected the torrent not to be done while a wanted file is incomplete")
+	}
+
+	if progress, estimated, speed := tor.Progress(); progress != 50 || estimated != 10 || speed != 100 {
+		t.Errorf("Got (%d, %d, %d); expected (50, 10, 100)", progress, estimated, speed)
+	}
+
+	tor.userFilter = []string{"Spice and Wolf/01.mkv"}
+	if !tor.IsDone() {
+		t.Error("Expected the torrent to be done once all selected files are complete")
+	}
+
+	if ratio := tor.ratio(); ratio != 0.5 {
+		t.Errorf("Got ratio %v; expected it relative to the selected files", ratio)
+	}
+
+	want := []string{"Spice and Wolf/01.mkv", "Spice and Wolf/02.mkv"}
+	if got := tor.FilePaths(); !slices.Equal(got, want) {
+		t.Errorf("Got %v; expected %v", got, want)
+	}
+
+	tor.state = payload.ContentStateWaiting
+	if tor.IsDone() {
+		t.Error("Expected the torrent not to be done before downloading started")
+	}
+}
+
+func TestRemoteTorrent_CancelAfterStop(t *testing.T) {
+	var cancelled bool
+	tor := &remoteTorrent{
+		log:          zerolog.Nop(),
+		progressLoop: func() { cancelled = true },
+	}
+
+	// Stop clears the context of loading info, the progress loop must still be cancelled
+	tor.Cancel()
+	if !cancelled {
+		t.Error("Expected the progress loop to be cancelled without a load info context")
+	}
+	if tor.progressLoop != nil {
+		t.Error("Expected the progress loop to be cleared once cancelled")
+	}
+}
@@ -0,0 +1,180 @@
+package yoitsu
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Fesaa/Media-Provider/db/models"
+	"github.com/Fesaa/Media-Provider/http/payload"
+)
+
+// Request keys holding the models.FileRules applied when no files were selected by hand. Lists are comma separated
+const (
+	IncludeFilesKey     = "include_files"
+	ExcludeFilesKey     = "exclude_files"
+	FileExtensionsKey   = "file_extensions"
+	MinFileSizeKey      = "min_file_size"
+	MaxFileSizeKey      = "max_file_size"
+	LargestVideoOnlyKey = "largest_video_only"
+)
+
+// fileRulesFromRequest reads the file rules from the request metadata
+func fileRulesFromRequest(req payload.DownloadRequest) (models.FileRules, error) {
+	rules := models.FileRules{
+		Include:          requestList(req, IncludeFilesKey),
+		Exclude:          requestList(req, ExcludeFilesKey),
+		Extensions:       requestList(req, FileExtensionsKey),
+		LargestVideoOnly: req.GetBool(LargestVideoOnlyKey, false),
+	}
+
+	for key, size := range map[string]*int64{MinFileSizeKey: &rules.MinSize, MaxFileSizeKey: &rules.MaxSize} {
+		s := strings.TrimSpace(req.GetStringOrDefault(key, ""))
+		if s == "" {
+			continue
+		}
+
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return models.FileRules{}, fmt.Errorf("invalid %s: %w", key, err)
+		}
+		*size = v
+	}
+
+	return rules, rules.Validate()
+}
+
+func requestList(req payload.DownloadRequest, key string) []string {
+	values, _ := req.GetStrings(key)
+
+	var out []string
+	for _, value := range values {
+		for _, s := range strings.Split(value, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+// fileSelector is the compiled form of models.FileRules
+type fileSelector struct {
+	models.FileRules
+	include []func(string) bool
+	exclude []func(string) bool
+}
+
+func newFileSelector(rules models.FileRules) (*fileSelector, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := &fileSelector{FileRules: rules}
+	for _, pattern := range rules.Include {
+		s.include = append(s.include, compilePattern(pattern))
+	}
+	for _, pattern := range rules.Exclude {
+		s.exclude = append(s.exclude, compilePattern(pattern))
+	}
+
+	return s, nil
+}
+
+// compilePattern returns a matcher for a validated pattern. Globs match if either the full path,
+// or the file name matches
+func compilePattern(pattern string) func(string) bool {
+	if expr, ok := models.RegexPattern(pattern); ok {
+		return regexp.MustCompile(expr).MatchString
+	}
+
+	return func(file string) bool {
+		full, _ := path.Match(pattern, file)
+		base, _ := path.Match(pattern, path.Base(file))
+		return full || base
+	}
+}
+
+func (s *fileSelector) matches(file FileEntry) bool {
+	p := file.Path()
+
+	if len(s.include) > 0 && !slices.ContainsFunc(s.include, func(f func(string) bool) bool { return f(p) }) {
+		return false
+	}
+
+	if slices.ContainsFunc(s.exclude, func(f func(string) bool) bool { return f(p) }) {
+		return false
+	}
+
+	if len(s.Extensions) > 0 {
+		ext := strings.TrimPrefix(strings.ToLower(path.Ext(p)), ".")
+		if !slices.ContainsFunc(s.Extensions, func(e string) bool {
+			return strings.EqualFold(strings.TrimPrefix(e, "."), ext)
+		}) {
+			return false
+		}
+	}
+
+	const mib = 1024 * 1024
+	if s.MinSize > 0 && file.Length() < s.MinSize*mib {
+		return false
+	}
+
+	return s.MaxSize == 0 || file.Length() <= s.MaxSize*mib
+}
+
+// selectFiles returns the paths of the files passing the rules. Returns nil if all files pass, or if none do;
+// in which case everything is downloaded
+func selectFiles[F FileEntry](rules models.FileRules, files []F) ([]string, error) {
+	if rules.IsEmpty() {
+		return nil, nil
+	}
+
+	selector, err := newFileSelector(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []F
+	for _, file := range files {
+		if selector.matches(file) {
+			selected = append(selected, file)
+		}
+	}
+
+	if rules.LargestVideoOnly {
+		var largest []F
+		for _, file := range selected {
+			if !slices.Contains(videoExtensions, strings.ToLower(path.Ext(file.Path()))) {
+				continue
+			}
+			if len(largest) == 0 || file.Length() > largest[0].Length() {
+				largest = []F{file}
+			}
+		}
+		selected = largest
+	}
+
+	if len(selected) == 0 || len(selected) == len(files) {
+		return nil, nil
+	}
+
+	paths := make([]string, len(selected))
+	for i, file := range selected {
+		paths[i] = file.Path()
+	}
+	return paths, nil
+}
+
+// autoSelect returns the files selected by the file rules in the request, nil if everything should be downloaded
+func autoSelect[F FileEntry](req payload.DownloadRequest, files []F) ([]string, error) {
+	rules, err := fileRulesFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return selectFiles(rules, files)
+}
@@ -0,0 +1,113 @@
+package yoitsu
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Fesaa/Media-Provider/db/models"
+	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/Fesaa/Media-Provider/providers/yoitsu/remote"
+	"github.com/Fesaa/Media-Provider/utils"
+)
+
+const mib = 1024 * 1024
+
+var ruleFiles = []remote.File{
+	{Index: 0, Name: "Show/Show - 01.mkv", Size: 1200 * mib},
+	{Index: 1, Name: "Show/Show - 02.mkv", Size: 1400 * mib},
+	{Index: 2, Name: "Show/Show - 01.en.ass", Size: 1 * mib},
+	{Index: 3, Name: "Show/Extras/NCOP.mkv", Size: 100 * mib},
+	{Index: 4, Name: "Show/sample.mkv", Size: 20 * mib},
+	{Index: 5, Name: "Show/info.nfo", Size: 1024},
+}
+
+func TestSelectFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules models.FileRules
+		want  []string
+	}{
+		{name: "no rules", rules: models.FileRules{}, want: nil},
+		{
+			name:  "include glob on name",
+			rules: models.FileRules{Include: []string{"Show - *"}},
+			want:  []string{"Show/Show - 01.mkv", "Show/Show - 02.mkv", "Show/Show - 01.en.ass"},
+		},
+		{
+			name:  "exclude glob on path and regex",
+			rules: models.FileRules{Exclude: []string{"Show/Extras/*", `/(?i)sample/`, "*.nfo"}},
+			want:  []string{"Show/Show - 01.mkv", "Show/Show - 02.mkv", "Show/Show - 01.en.ass"},
+		},
+		{
+			name:  "extensions",
+			rules: models.FileRules{Extensions: []string{".ASS", "nfo"}},
+			want:  []string{"Show/Show - 01.en.ass", "Show/info.nfo"},
+		},
+		{
+			name:  "size range",
+			rules: models.FileRules{MinSize: 50, MaxSize: 1300},
+			want:  []string{"Show/Show - 01.mkv", "Show/Extras/NCOP.mkv"},
+		},
+		{
+			name:  "largest video",
+			rules: models.FileRules{LargestVideoOnly: true},
+			want:  []string{"Show/Show - 02.mkv"},
+		},
+		{
+			name:  "largest video after other rules",
+			rules: models.FileRules{MaxSize: 1300, LargestVideoOnly: true},
+			want:  []string{"Show/Show - 01.mkv"},
+		},
+		{
+			name:  "nothing matches",
+			rules: models.FileRules{Include: []string{"*.mp4"}},
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectFiles(tt.rules, ruleFiles)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Got %v; expected %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileRulesFromRequest(t *testing.T) {
+	req := payload.DownloadRequest{}
+	req.DownloadMetadata.Extra = utils.SmartMap{
+		IncludeFilesKey:     {"*.mkv, *.ass"},
+		ExcludeFilesKey:     {"/sample/", "NCOP*"},
+		MinFileSizeKey:      {"10"},
+		MaxFileSizeKey:      {""},
+		LargestVideoOnlyKey: {"true"},
+	}
+
+	rules, err := fileRulesFromRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(rules.Include, []string{"*.mkv", "*.ass"}) || !slices.Equal(rules.Exclude, []string{"/sample/", "NCOP*"}) {
+		t.Errorf("Got %+v; expected the comma separated patterns", rules)
+	}
+	if rules.MinSize != 10 || rules.MaxSize != 0 || !rules.LargestVideoOnly {
+		t.Errorf("Got %+v; expected the sizes and switch to be read", rules)
+	}
+
+	req.DownloadMetadata.Extra = utils.SmartMap{MinFileSizeKey: {"ten"}}
+	if _, err = fileRulesFromRequest(req); err == nil {
+		t.Error("Expected an invalid size to fail")
+	}
+
+	req.DownloadMetadata.Extra = utils.SmartMap{IncludeFilesKey: {"/(/"}}
+	if _, err = fileRulesFromRequest(req); err == nil {
+		t.Error("Expected an invalid regex to fail")
+	}
+}
@@ -0,0 +1,277 @@
+package yoitsu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Fesaa/Media-Provider/db/models"
+	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/spf13/afero"
+)
+
+const (
+	// SeedRatioKey overrides the global upload ratio a torrent seeds until
+	SeedRatioKey = "seed_ratio"
+	// SeedTimeKey overrides the global minimum time, in minutes, a torrent seeds
+	SeedTimeKey = "seed_time"
+)
+
+var errEmptyTorrent = errors.New("torrent directory is empty")
+
+// seedingRules decide how long a completed torrent keeps seeding
+type seedingRules struct {
+	ratio       float64
+	minSeedTime time.Duration
+}
+
+// enabled returns true if completed torrents should seed at all
+func (r seedingRules) enabled() bool {
+	return r.ratio > 0 || r.minSeedTime > 0
+}
+
+// done returns true once the torrent has seeded for at least the minimum seed time, and reached the target ratio
+func (r seedingRules) done(seedTime time.Duration, ratio float64) bool {
+	return seedTime >= r.minSeedTime && ratio >= r.ratio
+}
+
+// seedingRules returns the seeding rules for the request, and the maximum amount of torrents seeding at once,
+// 0 if there's no maximum. The ratio and seed time in the request take precedence over the server settings
+func (y *yoitsu) seedingRules(req payload.DownloadRequest) (seedingRules, int) {
+	settings, err := y.settings.GetSettingsDto(context.Background())
+	if err != nil {
+		y.log.Warn().Err(err).Msg("failed to load settings, not seeding")
+		return seedingRules{}, 0
+	}
+
+	rules := seedingRules{
+		ratio:       settings.Seeding.Ratio,
+		minSeedTime: time.Duration(settings.Seeding.MinSeedTime) * time.Minute,
+	}
+
+	if s, ok := req.GetString(SeedRatioKey); ok {
+		if ratio, err := strconv.ParseFloat(s, 64); err == nil && ratio >= 0 {
+			rules.ratio = ratio
+		} else {
+			y.log.Warn().Str("value", s).Msg("invalid seed ratio in request, using server setting")
+		}
+	}
+
+	if s, ok := req.GetString(SeedTimeKey); ok {
+		if minutes, err := strconv.Atoi(s); err == nil && minutes >= 0 {
+			rules.minSeedTime = time.Duration(minutes) * time.Minute
+		} else {
+			y.log.Warn().Str("value", s).Msg("invalid seed time in request, using server setting")
+		}
+	}
+
+	return rules, settings.Seeding.MaxTorrents
+}
+
+// complete is called once a torrent has finished downloading. It either starts seeding, or removes the torrent
+// and moves its files right away
+func (y *yoitsu) complete(infoHash string, tor Torrent) error {
+	rules, maxSeeding := y.seedingRules(tor.Request())
+
+	seeding := y.torrents.Count(func(k string, v Torrent) bool {
+		return v.State() == payload.ContentStateSeeding
+	})
+
+	switch {
+	case rules.enabled() && maxSeeding > 0 && seeding >= maxSeeding:
+		y.log.Info().Str("infoHash", infoHash).Int("maxTorrents", maxSeeding).
+			Msg("max seeding torrents reached, moving files without seeding")
+	case rules.enabled():
+		err := y.startSeeding(tor, rules)
+		if err == nil {
+			return nil
+		}
+
+		y.log.Warn().Err(err).Str("infoHash", infoHash).Msg("failed to start seeding, moving files instead")
+	}
+
+	return y.RemoveDownload(payload.StopRequest{
+		Provider:    -1,
+		Id:          infoHash,
+		DeleteFiles: false,
+	})
+}
+
+// startSeeding links the downloaded files to their final location, and keeps the torrent around to seed from
+// its hash dir until rules are met. Seeding torrents are not persisted, they're removed on shutdown
+func (y *yoitsu) startSeeding(tor Torrent, rules seedingRules) error {
+	baseDir, _ := y.baseDirs.Get(tor.Id())
+	hashDir := path.Join(y.dir, baseDir, tor.Id())
+
+	entries, err := y.fs.ReadDir(hashDir)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return errEmptyTorrent
+	}
+
+	handled, created, err := y.organiseEpisodes(tor, baseDir, hashDir, true)
+	if err != nil {
+		y.unlink(created)
+		return err
+	}
+
+	src, dest := y.contentDirs(tor, baseDir, entries)
+	if err = y.linkContent(tor, hashDir, src, dest, handled); err != nil {
+		y.unlink(created)
+		return err
+	}
+
+	if err = y.extractArchives(tor, hashDir, src, dest, handled); err != nil {
+		y.log.Error().Err(err).Str("infoHash", tor.Id()).Msg("error extracting archives")
+		y.notifyCleanUpError(tor, err)
+	}
+
+	tor.Cancel()
+	if err = y.queue.Remove(context.Background(), tor); err != nil {
+		y.log.Warn().Err(err).Str("infoHash", tor.Id()).Msg("failed to remove persisted torrent")
+	}
+	y.seedRules.Set(tor.Id(), rules)
+	tor.StartSeeding()
+
+	y.log.Info().Str("infoHash", tor.Id()).Str("dest", dest).Msg("torrent finished downloading, seeding")
+
+	y.notifier(tor.Request()).Notify(context.Background(), models.NewNotification().
+		WithTitle("Download finished").
+		WithBody(fmt.Sprintf("%s finished downloading %d files(s), and is now seeding", tor.Title(), tor.Files())).
+		WithColour(models.Secondary).
+		WithGroup(models.GroupContent).
+		WithOwner(tor.Request().OwnerId).
+		WithRequiredRoles(models.ViewAllDownloads).
+		Build())
+
+	return nil
+}
+
+// linkContent hardlinks, or copies if not possible, every wanted file of the torrent from src into dest.
+// Files in skip have already been placed elsewhere. All created files are removed again if one fails
+func (y *yoitsu) linkContent(tor Torrent, hashDir, src, dest string, skip []string) error {
+	var linked []string
+	for _, file := range wantedFiles(tor) {
+		if slices.Contains(skip, file) {
+			continue
+		}
+
+		target, ok := placedPath(hashDir, src, dest, file)
+		if !ok {
+			continue
+		}
+
+		filePath := path.Join(hashDir, file)
+		if err := y.fs.MkdirAll(path.Dir(target), 0755); err != nil {
+			y.unlink(linked)
+			return err
+		}
+
+		if err := y.linkFile(filePath, target); err != nil {
+			y.unlink(linked)
+			return err
+		}
+
+		linked = append(linked, target)
+	}
+
+	return nil
+}
+
+// placedPath returns where a file of the torrent ends up, when the content in src is placed in dest.
+// False if the file isn't in src
+func placedPath(hashDir, src, dest, file string) (string, bool) {
+	rel, ok := strings.CutPrefix(path.Join(hashDir, file), src)
+	if !ok {
+		return "", false
+	}
+	return path.Join(dest, rel), true
+}
+
+// linkFile hardlinks src to dest when on the os filesystem, falls back to copying if that fails. For example,
+// when src and dest are on different devices
+func (y *yoitsu) linkFile(src, dest string) error {
+	if _, ok := y.fs.Fs.(*afero.OsFs); ok {
+		err := os.Link(src, dest)
+		if err == nil {
+			return nil
+		}
+
+		y.log.Debug().Err(err).Str("src", src).Str("dest", dest).Msg("failed to hardlink, copying instead")
+	}
+
+	in, err := y.fs.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := y.fs.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+func (y *yoitsu) unlink(files []string) {
+	for _, file := range files {
+		if err := y.fs.Remove(file); err != nil {
+			y.log.Warn().Err(err).Str("path", file).Msg("failed to remove linked file")
+		}
+	}
+}
+
+// seedingDone returns true if the seeding torrent has met the seeding rules it started seeding with
+func (y *yoitsu) seedingDone(tor Torrent) bool {
+	rules, ok := y.seedRules.Get(tor.Id())
+	if !ok {
+		return true
+	}
+	return rules.done(tor.SeedStats())
+}
+
+// stopSeeding drops the torrent and removes its hash dir. Its files have already been linked to their
+// final location when it started seeding
+func (y *yoitsu) stopSeeding(tor Torrent) {
+	infoHash := tor.Id()
+	baseDir, _ := y.baseDirs.Get(infoHash)
+
+	seedTime, ratio := tor.SeedStats()
+	y.log.Info().Str("infoHash", infoHash).
+		Dur("seedTime", seedTime).
+		Float64("ratio", ratio).
+		Msg("stopped seeding torrent")
+
+	tor.Drop()
+	y.torrents.Delete(infoHash)
+	y.baseDirs.Delete(infoHash)
+	y.seedRules.Delete(infoHash)
+	y.removeMetaInfo(infoHash)
+
+	y.deletionWg.Add(1)
+	go func() {
+		defer y.deletionWg.Done()
+		defer y.signalR.DeleteContent(tor.Request().OwnerId, infoHash)
+
+		hashDir := path.Join(y.dir, baseDir, infoHash)
+		if err := y.fs.RemoveAll(hashDir); err != nil {
+			y.log.Error().Err(err).Str("dir", hashDir).Msg("error removing torrent dir")
+			y.notifyCleanUpError(tor, err)
+		}
+	}()
+}
@@ -0,0 +1,102 @@
+package yoitsu
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/Fesaa/Media-Provider/utils"
+	"github.com/Fesaa/Media-Provider/utils/mock"
+	"github.com/rs/zerolog"
+	"github.com/spf13/afero"
+)
+
+func TestSeedingRules_Done(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    seedingRules
+		seedTime time.Duration
+		ratio    float64
+		want     bool
+	}{
+		{name: "ratio reached", rules: seedingRules{ratio: 1}, ratio: 1.2, want: true},
+		{name: "ratio not reached", rules: seedingRules{ratio: 1}, ratio: 0.5, want: false},
+		{name: "seed time not reached", rules: seedingRules{ratio: 1, minSeedTime: time.Hour}, seedTime: time.Minute, ratio: 2, want: false},
+		{name: "both reached", rules: seedingRules{ratio: 1, minSeedTime: time.Hour}, seedTime: 2 * time.Hour, ratio: 1, want: true},
+		{name: "only seed time", rules: seedingRules{minSeedTime: time.Hour}, seedTime: time.Hour, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rules.done(tt.seedTime, tt.ratio); got != tt.want {
+				t.Errorf("Got %v; expected %v", got, tt.want)
+			}
+		})
+	}
+
+	if (seedingRules{}).enabled() {
+		t.Error("Seeding should be disabled without a ratio or seed time")
+	}
+}
+
+func TestYoitsu_SeedingRules(t *testing.T) {
+	settings := &mock.Settings{}
+	if err := settings.UpdateSettingsDto(context.Background(), payload.Settings{
+		Seeding: payload.SeedingSettings{Ratio: 1.5, MinSeedTime: 30, MaxTorrents: 3},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	y := &yoitsu{settings: settings, log: zerolog.Nop()}
+
+	rules, maxSeeding := y.seedingRules(payload.DownloadRequest{})
+	if rules.ratio != 1.5 || rules.minSeedTime != 30*time.Minute || maxSeeding != 3 {
+		t.Errorf("Got (%+v, %d); expected the server settings", rules, maxSeeding)
+	}
+
+	req := payload.DownloadRequest{}
+	req.DownloadMetadata.Extra = utils.SmartMap{
+		SeedRatioKey: {"0.5"},
+		SeedTimeKey:  {"120"},
+	}
+	rules, _ = y.seedingRules(req)
+	if rules.ratio != 0.5 || rules.minSeedTime != 2*time.Hour {
+		t.Errorf("Got %+v; expected the request to override the server settings", rules)
+	}
+
+	req.DownloadMetadata.Extra = utils.SmartMap{SeedRatioKey: {"-1"}}
+	rules, _ = y.seedingRules(req)
+	if rules.ratio != 1.5 {
+		t.Errorf("Got ratio %v; expected invalid overrides to be ignored", rules.ratio)
+	}
+}
+
+func TestYoitsu_LinkFile(t *testing.T) {
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+	y := &yoitsu{fs: fs, log: zerolog.Nop()}
+
+	if err := fs.WriteFile("hash/file.mkv", []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := y.linkFile("hash/file.mkv", "file.mkv"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := fs.ReadFile("file.mkv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "data" {
+		t.Errorf("Got %q; expected the content of the source", data)
+	}
+
+	if err = y.linkFile("hash/file.mkv", "file.mkv"); err == nil {
+		t.Error("Existing files should not be overwritten")
+	}
+
+	if ok, _ := fs.Exists("hash/file.mkv"); !ok {
+		t.Error("Source should be kept to seed from")
+	}
+}
@@ -0,0 +1,123 @@
+package yoitsu
+
+import (
+	"context"
+	"time"
+
+	"github.com/Fesaa/Media-Provider/db/models"
+	"github.com/Fesaa/Media-Provider/http/payload"
+)
+
+// stallWatch is what the previous stall check saw of a torrent
+type stallWatch struct {
+	state     payload.ContentState
+	completed int64
+	since     time.Time
+}
+
+// check returns the translation key describing why the torrent is stalled, and the timeout that expired.
+// Empty if the torrent isn't stalled. The watch restarts whenever the state changes, or data was downloaded
+func (w *stallWatch) check(settings payload.StallSettings, state payload.ContentState, completed int64, now time.Time) (string, int) {
+	if state != w.state || completed != w.completed {
+		*w = stallWatch{state: state, completed: completed, since: now}
+		return "", 0
+	}
+
+	var key string
+	var timeout int
+	switch state {
+	case payload.ContentStateLoading:
+		key, timeout = "stalled-torrent-metadata", settings.MetadataTimeout
+	case payload.ContentStateDownloading:
+		key, timeout = "stalled-torrent-progress", settings.ProgressTimeout
+	default:
+		return "", 0
+	}
+
+	if timeout <= 0 || now.Sub(w.since) < time.Duration(timeout)*time.Minute {
+		return "", 0
+	}
+
+	return key, timeout
+}
+
+// stallLoop checks for stalled torrents, so they don't keep using a download slot
+func (y *yoitsu) stallLoop() {
+	watches := make(map[string]*stallWatch)
+
+	for range time.Tick(time.Second * 30) {
+		settings, err := y.settings.GetSettingsDto(context.Background())
+		if err != nil {
+			y.log.Debug().Err(err).Msg("failed to load settings, not checking for stalled torrents")
+			continue
+		}
+
+		y.checkStalled(settings.Torrent.Stall, watches, time.Now())
+	}
+}
+
+func (y *yoitsu) checkStalled(settings payload.StallSettings, watches map[string]*stallWatch, now time.Time) {
+	seen := make(map[string]struct{})
+	for _, tor := range y.torrents.Values() {
+		seen[tor.Id()] = struct{}{}
+
+		watch, ok := watches[tor.Id()]
+		if !ok {
+			watches[tor.Id()] = &stallWatch{state: tor.State(), completed: tor.BytesCompleted(), since: now}
+			continue
+		}
+
+		key, timeout := watch.check(settings, tor.State(), tor.BytesCompleted(), now)
+		if key == "" {
+			continue
+		}
+
+		delete(watches, tor.Id())
+		y.handleStalled(tor, settings.Action, key, timeout)
+	}
+
+	for id := range watches {
+		if _, ok := seen[id]; !ok {
+			delete(watches, id)
+		}
+	}
+}
+
+// handleStalled applies the stall action to the torrent, and notifies its owner
+func (y *yoitsu) handleStalled(tor Torrent, action payload.StallAction, key string, timeout int) {
+	y.log.Warn().Str("infoHash", tor.Id()).
+		Str("title", tor.Title()).
+		Str("action", string(action)).
+		Int("timeout", timeout).
+		Msg("torrent stalled")
+
+	var outcome string
+	switch action {
+	case payload.StallRemove:
+		outcome = "stalled-torrent-removed"
+		err := y.RemoveDownload(payload.StopRequest{Provider: tor.Provider(), Id: tor.Id(), DeleteFiles: true})
+		if err != nil {
+			y.log.Error().Err(err).Str("infoHash", tor.Id()).Msg("failed to remove stalled torrent")
+			return
+		}
+	case payload.StallPause:
+		outcome = "stalled-torrent-paused"
+		tor.Stop()
+		tor.SetState(payload.ContentStatePaused)
+		go y.startNext()
+	default:
+		outcome = "stalled-torrent-requeued"
+		tor.Stop()
+		y.Requeue(tor)
+	}
+
+	y.notify.Notify(context.Background(), models.NewNotification().
+		WithTitle(y.transLoco.GetTranslation("stalled-torrent-title")).
+		WithSummary(y.transLoco.GetTranslation(key, tor.Title(), timeout)).
+		WithBody(y.transLoco.GetTranslation(outcome)).
+		WithGroup(models.GroupContent).
+		WithColour(models.Warning).
+		WithOwner(tor.Request().OwnerId).
+		WithRequiredRoles(models.ViewAllDownloads).
+		Build())
+}
@@ -0,0 +1,117 @@
+package yoitsu
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/Fesaa/Media-Provider/utils"
+)
+
+func TestStallWatch_Check(t *testing.T) {
+	settings := payload.StallSettings{MetadataTimeout: 10, ProgressTimeout: 30, Action: payload.StallPause}
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		watch     stallWatch
+		state     payload.ContentState
+		completed int64
+		after     time.Duration
+		wantKey   string
+	}{
+		{
+			name:    "metadata in time",
+			watch:   stallWatch{state: payload.ContentStateLoading},
+			state:   payload.ContentStateLoading,
+			after:   9 * time.Minute,
+			wantKey: "",
+		},
+		{
+			name:    "metadata timeout",
+			watch:   stallWatch{state: payload.ContentStateLoading},
+			state:   payload.ContentStateLoading,
+			after:   10 * time.Minute,
+			wantKey: "stalled-torrent-metadata",
+		},
+		{
+			name:      "no progress",
+			watch:     stallWatch{state: payload.ContentStateDownloading, completed: 100},
+			state:     payload.ContentStateDownloading,
+			completed: 100,
+			after:     time.Hour,
+			wantKey:   "stalled-torrent-progress",
+		},
+		{
+			name:      "progress restarts the watch",
+			watch:     stallWatch{state: payload.ContentStateDownloading, completed: 100},
+			state:     payload.ContentStateDownloading,
+			completed: 200,
+			after:     time.Hour,
+			wantKey:   "",
+		},
+		{
+			name:    "state change restarts the watch",
+			watch:   stallWatch{state: payload.ContentStateLoading},
+			state:   payload.ContentStateDownloading,
+			after:   time.Hour,
+			wantKey: "",
+		},
+		{
+			name:    "waiting on the user",
+			watch:   stallWatch{state: payload.ContentStateWaiting},
+			state:   payload.ContentStateWaiting,
+			after:   time.Hour,
+			wantKey: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			watch := tt.watch
+			watch.since = start
+
+			key, _ := watch.check(settings, tt.state, tt.completed, start.Add(tt.after))
+			if key != tt.wantKey {
+				t.Errorf("Got %q; expected %q", key, tt.wantKey)
+			}
+		})
+	}
+
+	watch := stallWatch{state: payload.ContentStateLoading, since: start}
+	if key, _ := watch.check(payload.StallSettings{}, payload.ContentStateLoading, 0, start.Add(24*time.Hour)); key != "" {
+		t.Errorf("Got %q; expected a timeout of 0 to disable the check", key)
+	}
+}
+
+func TestYoitsu_NextInQueue(t *testing.T) {
+	y := &yoitsu{
+		torrents: utils.NewSafeMap[string, Torrent](),
+		queuedAt: utils.NewSafeMap[string, time.Time](),
+	}
+
+	now := time.Now()
+	for i, id := range []string{"first", "second", "third"} {
+		y.torrents.Set(id, &remoteTorrent{key: id, state: payload.ContentStateQueued})
+		y.queuedAt.Set(id, now.Add(time.Duration(i)*time.Minute))
+	}
+	y.torrents.Set("ready", &remoteTorrent{key: "ready", state: payload.ContentStateReady})
+
+	if next, ok := y.nextInQueue(payload.ContentStateQueued); !ok || next.Id() != "first" {
+		t.Errorf("Expected the torrent queued first to be next")
+	}
+
+	// Requeueing moves a torrent to the back
+	y.queuedAt.Set("first", now.Add(time.Hour))
+	if next, ok := y.nextInQueue(payload.ContentStateQueued); !ok || next.Id() != "second" {
+		t.Errorf("Expected the requeued torrent to be skipped")
+	}
+
+	if next, ok := y.nextInQueue(payload.ContentStateReady); !ok || next.Id() != "ready" {
+		t.Errorf("Expected the ready torrent to be found")
+	}
+
+	if _, ok := y.nextInQueue(payload.ContentStatePaused); ok {
+		t.Errorf("Expected no paused torrent")
+	}
+}
@@ -64,13 +64,7 @@ func (b *Builder) Transform(ctx context.Context, s payload.SearchRequest) Search
 
 func (b *Builder) DownloadMetadata() payload.DownloadMetadata {
 	return payload.DownloadMetadata{
-		Definitions: []payload.DownloadMetadataDefinition{
-			{
-				Key:           "no-sub-dir",
-				FormType:      payload.SWITCH,
-				DefaultOption: "",
-			},
-		},
+		Definitions: yoitsu.DownloadMetadataDefinitions(),
 	}
 }
 
@@ -25,6 +25,7 @@ type torrentImpl struct {
 	client Client
 
 	signalR services.SignalRService
+	queue   services.QueueService
 	fs      afero.Afero
 
 	req       payload.DownloadRequest
@@ -44,14 +45,17 @@ type torrentImpl struct {
 
 	lastTime time.Time
 	lastRead int64
+
+	seedStart time.Time
 }
 
 func newTorrent(t *torrent.Torrent, req payload.DownloadRequest, log zerolog.Logger, client Client,
-	signalR services.SignalRService, fs afero.Afero) Torrent {
+	signalR services.SignalRService, queue services.QueueService, fs afero.Afero) Torrent {
 	tor := &torrentImpl{
 		t:         t,
 		client:    client,
 		signalR:   signalR,
+		queue:     queue,
 		fs:        fs,
 		key:       t.InfoHash().HexString(),
 		req:       req,
@@ -97,6 +101,27 @@ func (t *torrentImpl) State() payload.ContentState {
 func (t *torrentImpl) SetState(state payload.ContentState) {
 	t.state = state
 	t.signalR.StateUpdate(t.req.OwnerId, t.Id(), t.state)
+	t.persist()
+}
+
+// persist saves the current state, so the torrent can be resumed after a restart. Torrents that have
+// already been removed from the client are not saved again
+func (t *torrentImpl) persist() {
+	if t.client.Content(t.Id()) == nil {
+		return
+	}
+
+	if err := t.queue.Save(context.Background(), t, t.userFilter); err != nil {
+		t.log.Warn().Err(err).Msg("failed to persist torrent, it will not be resumed after a restart")
+	}
+}
+
+func (t *torrentImpl) UserSelection() []string {
+	return t.userFilter
+}
+
+func (t *torrentImpl) RestoreUserSelection(selection []string) {
+	t.userFilter = selection
 }
 
 func (t *torrentImpl) Message(msg payload.Message) (payload.Message, error) {
@@ -109,6 +134,8 @@ func (t *torrentImpl) Message(msg payload.Message) (payload.Message, error) {
 		err = t.SetUserFiltered(msg.Data)
 	case payload.StartDownload:
 		err = t.MarkReady()
+	case payload.PreviewEpisodes:
+		jsonData, err = previewEpisodes(t)
 	default:
 		err = services.ErrUnknownMessageType
 	}
@@ -126,6 +153,11 @@ func (t *torrentImpl) Message(msg payload.Message) (payload.Message, error) {
 }
 
 func (t *torrentImpl) MarkReady() error {
+	if t.state == payload.ContentStatePaused {
+		t.client.Requeue(t)
+		return nil
+	}
+
 	if t.state != payload.ContentStateWaiting {
 		return services.ErrWrongState
 	}
@@ -151,6 +183,7 @@ func (t *torrentImpl) SetUserFiltered(data json.RawMessage) error {
 
 	t.userFilter = filter
 	t.signalR.SizeUpdate(t.req.OwnerId, t.Id(), utils.BytesToSize(float64(t.size())))
+	t.persist()
 	return nil
 }
 
@@ -283,6 +316,15 @@ func (t *torrentImpl) StartDownload() {
 		Str("into", t.GetDownloadDir()).
 		Str("title", t.Title()).
 		Msg("downloading torrent")
+
+	if len(t.userFilter) == 0 {
+		selection, err := autoSelect(t.req, t.t.Files())
+		if err != nil {
+			t.log.Warn().Err(err).Msg("invalid file rules, downloading all files")
+		}
+		t.userFilter = selection
+	}
+
 	t.SetState(payload.ContentStateDownloading)
 	t.startProgressLoop()
 
@@ -292,6 +334,7 @@ func (t *torrentImpl) StartDownload() {
 		return
 	}
 
+	t.files = 0
 	for _, file := range t.t.Files() {
 		if slices.Contains(t.userFilter, file.Path()) {
 			file.SetPriority(torrent.PiecePriorityNormal)
@@ -314,6 +357,37 @@ func (t *torrentImpl) Cancel() {
 	}
 }
 
+// Stop cancels loading info, or stops downloading. Torrents that were downloading start downloading right away
+// once their info is loaded again
+func (t *torrentImpl) Stop() {
+	t.Cancel()
+	t.cancel = nil
+
+	if t.t.Info() == nil {
+		return
+	}
+
+	if t.state == payload.ContentStateDownloading {
+		t.req.DownloadMetadata.StartImmediately = true
+	}
+
+	for _, file := range t.t.Files() {
+		file.SetPriority(torrent.PiecePriorityNone)
+	}
+}
+
+func (t *torrentImpl) BytesCompleted() int64 {
+	return t.t.BytesCompleted()
+}
+
+func (t *torrentImpl) Drop() {
+	t.t.Drop()
+}
+
+func (t *torrentImpl) FilePaths() []string {
+	return utils.Map(t.t.Files(), (*torrent.File).Path)
+}
+
 func (t *torrentImpl) GetDownloadDir() string {
 	return path.Join(t.baseDir, t.key)
 }
@@ -332,9 +406,35 @@ func (t *torrentImpl) GetInfo() payload.InfoStat {
 		SpeedType:    payload.BYTES,
 		Speed:        speed,
 		DownloadDir:  t.GetDownloadDir(),
+		Seeding:      t.state == payload.ContentStateSeeding,
+		Ratio:        utils.Ternary(t.state == payload.ContentStateSeeding, t.ratio(), 0),
 	}
 }
 
+func (t *torrentImpl) StartSeeding() {
+	t.seedStart = time.Now()
+	t.SetState(payload.ContentStateSeeding)
+}
+
+func (t *torrentImpl) SeedStats() (time.Duration, float64) {
+	if t.state != payload.ContentStateSeeding {
+		return 0, 0
+	}
+
+	return time.Since(t.seedStart), t.ratio()
+}
+
+// ratio returns the bytes uploaded relative to the size of the wanted files
+func (t *torrentImpl) ratio() float64 {
+	size := t.size()
+	if size == 0 {
+		return 0
+	}
+
+	stats := t.t.Stats()
+	return float64(stats.BytesWrittenData.Int64()) / float64(size)
+}
+
 func (t *torrentImpl) Progress() (int64, int64, int64) {
 	c := t.t.Stats().BytesReadData
 	bytesRead := c.Int64()
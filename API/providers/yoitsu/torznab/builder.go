@@ -0,0 +1,83 @@
+package torznab
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/Fesaa/Media-Provider/db"
+	"github.com/Fesaa/Media-Provider/db/models"
+	"github.com/Fesaa/Media-Provider/http/menou"
+	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/Fesaa/Media-Provider/providers/yoitsu"
+	"github.com/Fesaa/Media-Provider/services"
+	"github.com/Fesaa/Media-Provider/utils"
+	"github.com/rs/zerolog"
+)
+
+type Builder struct {
+	log        zerolog.Logger
+	httpClient *menou.Client
+	ys         yoitsu.Client
+	unitOfWork *db.UnitOfWork
+}
+
+func (b *Builder) Provider() models.Provider {
+	return models.TORZNAB
+}
+
+func (b *Builder) Logger() zerolog.Logger {
+	return b.log
+}
+
+func (b *Builder) Normalize(ctx context.Context, torrents []SearchResult) []payload.Info {
+	torrentsInfo := make([]payload.Info, len(torrents))
+	for i, t := range torrents {
+		torrentsInfo[i] = payload.Info{
+			Name: t.Title,
+			Size: utils.BytesToSize(float64(t.Size)),
+			Tags: []payload.InfoTag{
+				payload.Of("Date", t.PubDate),
+				payload.Of("Seeders", t.Seeders),
+				payload.Of("Leechers", t.Peers-t.Seeders),
+				payload.Of("Indexer", t.Indexer),
+			},
+			Link:     t.Link,
+			InfoHash: t.DownloadId(),
+			RefUrl:   t.Comments,
+			Provider: models.TORZNAB,
+		}
+	}
+	return torrentsInfo
+}
+
+func (b *Builder) Transform(ctx context.Context, s payload.SearchRequest) SearchOptions {
+	options := SearchOptions{
+		Query:      s.Query,
+		Categories: s.Modifiers[CategoriesModifier],
+	}
+
+	for _, indexer := range s.Modifiers[IndexersModifier] {
+		id, err := strconv.Atoi(indexer)
+		if err != nil {
+			b.log.Debug().Str("indexer", indexer).Msg("ignoring invalid indexer id")
+			continue
+		}
+		options.Indexers = append(options.Indexers, id)
+	}
+
+	return options
+}
+
+func (b *Builder) DownloadMetadata() payload.DownloadMetadata {
+	return payload.DownloadMetadata{
+		Definitions: yoitsu.DownloadMetadataDefinitions(),
+	}
+}
+
+func (b *Builder) Client() services.Client {
+	return b.ys
+}
+
+func NewBuilder(log zerolog.Logger, httpClient *menou.Client, ys yoitsu.Client, unitOfWork *db.UnitOfWork) *Builder {
+	return &Builder{log.With().Str("handler", "torznab-provider").Logger(), httpClient, ys, unitOfWork}
+}
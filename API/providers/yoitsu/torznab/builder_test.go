@@ -0,0 +1,328 @@
+package torznab
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/Fesaa/Media-Provider/config"
+	"github.com/Fesaa/Media-Provider/db"
+	"github.com/Fesaa/Media-Provider/db/models"
+	"github.com/Fesaa/Media-Provider/http/menou"
+	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/rs/zerolog"
+	"github.com/spf13/afero"
+)
+
+const testApiKey = "secret"
+
+const searchResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:torznab="http://torznab.com/schemas/2015/feed">
+  <channel>
+    <title>Stand-in</title>
+    <item>
+      <title>Spice and Wolf S01 1080p</title>
+      <guid>https://indexer.example.org/details/1</guid>
+      <link>https://indexer.example.org/download/1.torrent</link>
+      <comments>https://indexer.example.org/details/1#comments</comments>
+      <pubDate>Mon, 04 Mar 2024 12:00:00 +0000</pubDate>
+      <size>1073741824</size>
+      <enclosure url="https://indexer.example.org/download/1.torrent" length="1073741824" type="application/x-bittorrent"/>
+      <torznab:attr name="category" value="5000"/>
+      <torznab:attr name="category" value="5070"/>
+      <torznab:attr name="seeders" value="12"/>
+      <torznab:attr name="peers" value="15"/>
+      <torznab:attr name="infohash" value="C9E15763F722F23E98A29DECDFAE341B98D53056"/>
+    </item>
+    <item>
+      <title>Spice and Wolf S02 720p</title>
+      <guid>https://indexer.example.org/details/2</guid>
+      <link>https://indexer.example.org/download/2.torrent</link>
+      <enclosure url="https://indexer.example.org/download/2.torrent" length="524288000" type="application/x-bittorrent"/>
+      <torznab:attr name="seeders" value="30"/>
+      <torznab:attr name="peers" value="31"/>
+      <torznab:attr name="magneturl" value="magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567"/>
+    </item>
+  </channel>
+</rss>`
+
+const capsResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<caps>
+  <searching>
+    <search available="yes" supportedParams="q"/>
+  </searching>
+  <categories>
+    <category id="5000" name="TV">
+      <subcat id="5070" name="Anime"/>
+    </category>
+    <category id="7000" name="Books"/>
+  </categories>
+</caps>`
+
+const errorResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<error code="100" description="Incorrect user credentials"/>`
+
+// standInIndexer serves a Torznab api, the last request is stored in query
+func standInIndexer(t *testing.T, query *map[string][]string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/torznab/api" {
+			http.NotFound(w, r)
+			return
+		}
+
+		if query != nil {
+			*query = r.URL.Query()
+		}
+
+		if r.URL.Query().Get("apikey") != testApiKey {
+			_, _ = w.Write([]byte(errorResponse))
+			return
+		}
+
+		switch r.URL.Query().Get("t") {
+		case "caps":
+			_, _ = w.Write([]byte(capsResponse))
+		case "search":
+			_, _ = w.Write([]byte(searchResponse))
+		default:
+			http.Error(w, "unsupported function", http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func tempBuilder(t *testing.T) *Builder {
+	t.Helper()
+	log := zerolog.Nop()
+
+	// The config is loaded by the migrations, its default path is relative to the working directory
+	dir := t.TempDir()
+	config.Dir = dir
+	t.Chdir(dir)
+
+	database, err := db.DatabaseProvider(t.Context(), log, afero.Afero{Fs: afero.NewOsFs()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		d, err := database.DB()
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.Close()
+	})
+
+	return NewBuilder(log, menou.DefaultClient, nil, db.NewUnitOfWork(database))
+}
+
+func addIndexer(t *testing.T, b *Builder, name, url, apiKey string) models.TorznabIndexer {
+	t.Helper()
+
+	indexer := models.TorznabIndexer{Name: name, Url: url, ApiKey: apiKey}
+	if err := b.unitOfWork.Torznab.Create(t.Context(), &indexer); err != nil {
+		t.Fatal(err)
+	}
+	return indexer
+}
+
+func TestBuilder_Search(t *testing.T) {
+	b := tempBuilder(t)
+
+	var query map[string][]string
+	server := standInIndexer(t, &query)
+	addIndexer(t, b, "Stand-in", server.URL+"/torznab/", testApiKey)
+
+	results, err := b.Search(t.Context(), SearchOptions{
+		Query:      "Spice and Wolf",
+		Categories: []string{"5070", "7000"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := query["q"]; !slices.Equal(got, []string{"Spice and Wolf"}) {
+		t.Errorf("Got query %v; expected the search query", got)
+	}
+	if got := query["cat"]; !slices.Equal(got, []string{"5070,7000"}) {
+		t.Errorf("Got categories %v; expected 5070,7000", got)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("Got %d results; expected 2", len(results))
+	}
+
+	// Sorted by seeders
+	if results[0].Title != "Spice and Wolf S02 720p" {
+		t.Errorf("Got %s first; expected the result with the most seeders", results[0].Title)
+	}
+
+	got := results[1]
+	if got.Size != 1073741824 || got.Seeders != 12 || got.Peers != 15 || got.Indexer != "Stand-in" {
+		t.Errorf("Got %+v; expected the parsed attributes", got)
+	}
+	if !slices.Equal(got.Categories, []string{"5000", "5070"}) {
+		t.Errorf("Got categories %v; expected 5000 and 5070", got.Categories)
+	}
+	if results[0].Size != 524288000 {
+		t.Errorf("Got size %d; expected the enclosure length", results[0].Size)
+	}
+}
+
+func TestBuilder_SearchFailingIndexer(t *testing.T) {
+	b := tempBuilder(t)
+
+	server := standInIndexer(t, nil)
+	addIndexer(t, b, "Wrong key", server.URL+"/torznab", "wrong")
+
+	if _, err := b.Search(t.Context(), SearchOptions{Query: "Spice and Wolf"}); err == nil {
+		t.Fatal("Expected an error when all indexers fail")
+	}
+
+	working := addIndexer(t, b, "Stand-in", server.URL+"/torznab", testApiKey)
+	results, err := b.Search(t.Context(), SearchOptions{Query: "Spice and Wolf"})
+	if err != nil {
+		t.Fatalf("Got %v; expected a failing indexer to be skipped", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("Got %d results; expected the results of the working indexer", len(results))
+	}
+
+	unreachable := addIndexer(t, b, "Unreachable", "http://127.0.0.1:1/torznab", testApiKey)
+	results, err = b.Search(t.Context(), SearchOptions{Query: "Spice and Wolf", Indexers: []int{unreachable.ID}})
+	if err == nil {
+		t.Errorf("Got %d results; expected only the selected, unreachable, indexer to be searched", len(results))
+	}
+
+	results, err = b.Search(t.Context(), SearchOptions{Query: "Spice and Wolf", Indexers: []int{working.ID}})
+	if err != nil || len(results) != 2 {
+		t.Errorf("Got (%d, %v); expected the results of the selected indexer", len(results), err)
+	}
+}
+
+func TestBuilder_Normalize(t *testing.T) {
+	b := tempBuilder(t)
+
+	server := standInIndexer(t, nil)
+	addIndexer(t, b, "Stand-in", server.URL+"/torznab", testApiKey)
+
+	results, err := b.Search(t.Context(), SearchOptions{Query: "Spice and Wolf"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	infos := b.Normalize(t.Context(), results)
+	if len(infos) != 2 {
+		t.Fatalf("Got %d infos; expected 2", len(infos))
+	}
+
+	magnet := infos[0]
+	if magnet.InfoHash != "magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567" {
+		t.Errorf("Got %s; expected the magnet uri when no infohash is present", magnet.InfoHash)
+	}
+	if magnet.RefUrl != "https://indexer.example.org/details/2" {
+		t.Errorf("Got %s; expected the guid without comments", magnet.RefUrl)
+	}
+
+	info := infos[1]
+	if info.InfoHash != "c9e15763f722f23e98a29decdfae341b98d53056" {
+		t.Errorf("Got %s; expected the lowercase infohash", info.InfoHash)
+	}
+	if info.Provider != models.TORZNAB || info.Size != "1.00 GB" {
+		t.Errorf("Got %+v; expected a Torznab result of 1 GB", info)
+	}
+
+	leechers := slices.IndexFunc(info.Tags, func(tag payload.InfoTag) bool {
+		return tag.Name == "Leechers"
+	})
+	if leechers == -1 || info.Tags[leechers].Value != 3 {
+		t.Errorf("Got tags %v; expected 3 leechers", info.Tags)
+	}
+}
+
+func TestBuilder_Transform(t *testing.T) {
+	b := NewBuilder(zerolog.Nop(), menou.DefaultClient, nil, nil)
+
+	options := b.Transform(t.Context(), payload.SearchRequest{
+		Query: "Spice and Wolf",
+		Modifiers: map[string][]string{
+			IndexersModifier:   {"1", "not-an-id", "3"},
+			CategoriesModifier: {"5070"},
+		},
+	})
+
+	if !slices.Equal(options.Indexers, []int{1, 3}) {
+		t.Errorf("Got indexers %v; expected the valid ids", options.Indexers)
+	}
+	if !slices.Equal(options.Categories, []string{"5070"}) {
+		t.Errorf("Got categories %v; expected 5070", options.Categories)
+	}
+}
+
+func TestModifiers(t *testing.T) {
+	var query map[string][]string
+	server := standInIndexer(t, &query)
+
+	indexer := models.TorznabIndexer{Name: "Stand-in", Url: server.URL + "/torznab", ApiKey: testApiKey}
+	indexer.ID = 4
+
+	modifiers, err := Modifiers(t.Context(), menou.DefaultClient, indexer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := query["t"]; !slices.Equal(got, []string{"caps"}) {
+		t.Errorf("Got function %v; expected caps", got)
+	}
+
+	if len(modifiers) != 2 {
+		t.Fatalf("Got %d modifiers; expected 2", len(modifiers))
+	}
+
+	indexers := modifiers[0]
+	if indexers.Key != IndexersModifier || len(indexers.Values) != 1 || indexers.Values[0].Key != strconv.Itoa(indexer.ID) {
+		t.Errorf("Got %+v; expected the indexer as only value", indexers)
+	}
+
+	categories := modifiers[1]
+	want := []models.ModifierValue{
+		{Key: "5000", Value: "TV"},
+		{Key: "5070", Value: "TV / Anime"},
+		{Key: "7000", Value: "Books"},
+	}
+	if categories.Key != CategoriesModifier || !slices.Equal(categories.Values, want) {
+		t.Errorf("Got %+v; expected %+v", categories.Values, want)
+	}
+
+	indexer.ApiKey = "wrong"
+	if _, err = Modifiers(t.Context(), menou.DefaultClient, indexer); err == nil {
+		t.Error("Expected the indexer error to be returned")
+	}
+}
+
+func TestApiUrl(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "http://localhost:9696/1/", want: "http://localhost:9696/1/api?apikey=key&t=caps"},
+		{url: "http://localhost:9696/1/api", want: "http://localhost:9696/1/api?apikey=key&t=caps"},
+		{url: "http://localhost:9117/torznab/api?extra=1", want: "http://localhost:9117/torznab/api?apikey=key&extra=1&t=caps"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			got, err := apiUrl(models.TorznabIndexer{Url: tt.url, ApiKey: "key"}, map[string][]string{"t": {"caps"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Got %s; expected %s", got, tt.want)
+			}
+		})
+	}
+}
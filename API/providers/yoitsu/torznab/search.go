@@ -0,0 +1,227 @@
+package torznab
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Fesaa/Media-Provider/db/models"
+	"github.com/Fesaa/Media-Provider/http/menou"
+	"github.com/Fesaa/Media-Provider/utils"
+)
+
+const (
+	IndexersModifier   = "indexers"
+	CategoriesModifier = "categories"
+)
+
+func (b *Builder) Search(ctx context.Context, options SearchOptions) ([]SearchResult, error) {
+	indexers, err := b.unitOfWork.Torznab.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(options.Indexers) > 0 {
+		indexers = slices.DeleteFunc(indexers, func(indexer models.TorznabIndexer) bool {
+			return !slices.Contains(options.Indexers, indexer.ID)
+		})
+	}
+
+	if len(indexers) == 0 {
+		return []SearchResult{}, nil
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		results = make([]SearchResult, 0)
+		errs    = make([]error, 0)
+	)
+
+	for _, indexer := range indexers {
+		wg.Add(1)
+		go func(indexer models.TorznabIndexer) {
+			defer wg.Done()
+
+			res, err := search(ctx, b.httpClient, indexer, options)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				b.log.Warn().Err(err).Str("indexer", indexer.Name).Msg("failed to search indexer")
+				errs = append(errs, fmt.Errorf("%s: %w", indexer.Name, err))
+				return
+			}
+			results = append(results, res...)
+		}(indexer)
+	}
+
+	wg.Wait()
+
+	// A single unreachable indexer shouldn't hide the results of the others
+	if len(errs) == len(indexers) {
+		return nil, errors.Join(errs...)
+	}
+
+	slices.SortStableFunc(results, func(a, b SearchResult) int {
+		return b.Seeders - a.Seeders
+	})
+
+	return results, nil
+}
+
+// Capabilities returns the capabilities of the indexer, only the categories are used
+func Capabilities(ctx context.Context, httpClient *menou.Client, indexer models.TorznabIndexer) (*Caps, error) {
+	uri, err := apiUrl(indexer, url.Values{"t": {"caps"}})
+	if err != nil {
+		return nil, err
+	}
+
+	var caps Caps
+	if err = get(ctx, httpClient, uri, &caps); err != nil {
+		return nil, err
+	}
+
+	return &caps, nil
+}
+
+// Modifiers returns the modifiers a page needs to search the indexer; selecting the indexer, and its categories
+func Modifiers(ctx context.Context, httpClient *menou.Client, indexer models.TorznabIndexer) ([]models.Modifier, error) {
+	caps, err := Capabilities(ctx, httpClient, indexer)
+	if err != nil {
+		return nil, err
+	}
+
+	categories := make([]models.ModifierValue, 0, len(caps.Categories))
+	for _, category := range caps.Categories {
+		categories = append(categories, models.ModifierValue{Key: category.Id, Value: category.Name})
+		for _, subCat := range category.SubCats {
+			categories = append(categories, models.ModifierValue{
+				Key:   subCat.Id,
+				Value: category.Name + " / " + subCat.Name,
+			})
+		}
+	}
+
+	return []models.Modifier{
+		{
+			Title: "Indexers",
+			Type:  models.MULTI,
+			Key:   IndexersModifier,
+			Values: []models.ModifierValue{
+				{Key: strconv.Itoa(indexer.ID), Value: indexer.Name, Default: true},
+			},
+		},
+		{
+			Title:  "Categories",
+			Type:   models.MULTI,
+			Key:    CategoriesModifier,
+			Values: categories,
+			Sort:   1,
+		},
+	}, nil
+}
+
+func search(ctx context.Context, httpClient *menou.Client, indexer models.TorznabIndexer, options SearchOptions) ([]SearchResult, error) {
+	params := url.Values{
+		"t": {"search"},
+		"q": {options.Query},
+	}
+	if len(options.Categories) > 0 {
+		params.Set("cat", strings.Join(options.Categories, ","))
+	}
+
+	uri, err := apiUrl(indexer, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var feed rss
+	if err = get(ctx, httpClient, uri, &feed); err != nil {
+		return nil, err
+	}
+
+	results := make([]SearchResult, 0, len(feed.Channel.Items))
+	for _, i := range feed.Channel.Items {
+		results = append(results, i.toResult(indexer.Name))
+	}
+	return results, nil
+}
+
+func (i item) toResult(indexer string) SearchResult {
+	size := i.Size
+	if size == 0 {
+		size = i.Enclosure.Length
+	}
+
+	seeders := i.intAttr("seeders")
+	return SearchResult{
+		Indexer:    indexer,
+		Title:      i.Title,
+		Link:       utils.NonEmpty(i.Link, i.Enclosure.Url),
+		Comments:   utils.NonEmpty(i.Comments, i.Guid),
+		PubDate:    i.PubDate,
+		Size:       size,
+		Seeders:    seeders,
+		Peers:      max(i.intAttr("peers"), seeders),
+		InfoHash:   i.attr("infohash"),
+		MagnetUrl:  i.attr("magneturl"),
+		Categories: i.attrs("category"),
+	}
+}
+
+// apiUrl adds the params and api key to the indexer url, keeping any query already present
+func apiUrl(indexer models.TorznabIndexer, params url.Values) (string, error) {
+	u, err := url.Parse(indexer.Url)
+	if err != nil {
+		return "", err
+	}
+
+	if !strings.HasSuffix(u.Path, "/api") {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/api"
+	}
+
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	if indexer.ApiKey != "" {
+		query.Set("apikey", indexer.ApiKey)
+	}
+
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// get decodes the xml response into dst, returning the error reported by the indexer if present
+func get(ctx context.Context, httpClient *menou.Client, uri string, dst any) error {
+	res, err := httpClient.GetWithContext(ctx, uri)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("status code error: %d %s", res.StatusCode, res.Status)
+	}
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	var apiErr apiError
+	if err = xml.Unmarshal(data, &apiErr); err == nil {
+		return fmt.Errorf("indexer error %d: %s", apiErr.Code, apiErr.Description)
+	}
+
+	return xml.Unmarshal(data, dst)
+}
@@ -0,0 +1,116 @@
+package torznab
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
+type SearchOptions struct {
+	Query string
+	// Indexers are the ids of the indexers to search, all are searched if empty
+	Indexers []int
+	// Categories are Torznab category ids, e.g. 5070 for anime
+	Categories []string
+}
+
+type SearchResult struct {
+	Indexer    string
+	Title      string
+	Link       string
+	Comments   string
+	PubDate    string
+	Size       int64
+	Seeders    int
+	Peers      int
+	InfoHash   string
+	MagnetUrl  string
+	Categories []string
+}
+
+// DownloadId returns the id to download the result with; the infohash or magnet uri if known. Falls back to
+// the link to the .torrent file
+func (r SearchResult) DownloadId() string {
+	if r.InfoHash != "" {
+		return strings.ToLower(r.InfoHash)
+	}
+
+	if r.MagnetUrl != "" {
+		return r.MagnetUrl
+	}
+
+	return r.Link
+}
+
+// rss is the response to a search request. Torznab and Newznab only differ in the namespace of their attributes
+type rss struct {
+	XMLName xml.Name `xml:"rss"`
+	Channel struct {
+		Items []item `xml:"item"`
+	} `xml:"channel"`
+}
+
+type item struct {
+	Title     string    `xml:"title"`
+	Guid      string    `xml:"guid"`
+	Link      string    `xml:"link"`
+	Comments  string    `xml:"comments"`
+	PubDate   string    `xml:"pubDate"`
+	Size      int64     `xml:"size"`
+	Enclosure enclosure `xml:"enclosure"`
+	Attrs     []attr    `xml:"attr"`
+}
+
+type enclosure struct {
+	Url    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type attr struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+func (i item) attr(name string) string {
+	for _, a := range i.Attrs {
+		if strings.EqualFold(a.Name, name) {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func (i item) intAttr(name string) int {
+	v, _ := strconv.Atoi(i.attr(name))
+	return v
+}
+
+func (i item) attrs(name string) []string {
+	var values []string
+	for _, a := range i.Attrs {
+		if strings.EqualFold(a.Name, name) {
+			values = append(values, a.Value)
+		}
+	}
+	return values
+}
+
+// Caps is the response to a capabilities request
+type Caps struct {
+	XMLName    xml.Name   `xml:"caps"`
+	Categories []Category `xml:"categories>category"`
+}
+
+type Category struct {
+	Id      string     `xml:"id,attr"`
+	Name    string     `xml:"name,attr"`
+	SubCats []Category `xml:"subcat"`
+}
+
+// apiError is returned by indexers, with a 200 status code, when a request fails
+type apiError struct {
+	XMLName     xml.Name `xml:"error"`
+	Code        int      `xml:"code,attr"`
+	Description string   `xml:"description,attr"`
+}
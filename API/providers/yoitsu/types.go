@@ -1,6 +1,9 @@
 package yoitsu
 
 import (
+	"time"
+
+	"github.com/Fesaa/Media-Provider/http/payload"
 	"github.com/Fesaa/Media-Provider/services"
 	"github.com/Fesaa/Media-Provider/utils"
 	"github.com/anacrolix/torrent"
@@ -11,16 +14,32 @@ type Config interface {
 	GetMaxConcurrentTorrents() int
 }
 
-// Torrent wrapper around torrent.Torrent
+// Torrent wrapper around torrent.Torrent, or a torrent managed by a remote client
 type Torrent interface {
 	services.Content
 	GetTorrent() *torrent.Torrent
 	LoadInfo()
 	StartDownload()
 	Cancel()
+	// Stop stops loading info or downloading, without removing the torrent. LoadInfo may be called again afterward
+	Stop()
+	// BytesCompleted returns the amount of bytes downloaded, 0 while the info is being loaded
+	BytesCompleted() int64
 	IsDone() bool
 	Cleanup(root string)
+	// Drop removes the torrent from the client backing it, downloaded files are kept
+	Drop()
+	// FilePaths returns the paths of all files in the torrent, relative to its download dir
+	FilePaths() []string
+	// StartSeeding marks the torrent as seeding, must be called after its files have been linked to their final location
+	StartSeeding()
+	// SeedStats returns how long the torrent has been seeding, and its upload ratio
+	SeedStats() (time.Duration, float64)
 	Files() int
+	// UserSelection returns the paths of the files selected by the user, empty if no selection was made
+	UserSelection() []string
+	// RestoreUserSelection restores a selection made before a restart, must be called before downloading starts
+	RestoreUserSelection([]string)
 }
 
 // Client wrapper around the torrent.Client struct
@@ -28,5 +47,9 @@ type Client interface {
 	services.Client
 	GetTorrents() utils.SafeMap[string, Torrent]
 	CanStartNext() bool
+	// Requeue moves the torrent to the back of the queue, and starts the next torrent if possible
+	Requeue(tor Torrent)
+	// UpdateTorrentSettings applies changed rate limits, without restarting the client
+	UpdateTorrentSettings(settings payload.TorrentSettings)
 	Shutdown() error
 }
@@ -4,35 +4,60 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"math/rand"
+	"os"
 	"path"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/Fesaa/Media-Provider/config"
 	"github.com/Fesaa/Media-Provider/db/models"
+	"github.com/Fesaa/Media-Provider/http/menou"
 	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/Fesaa/Media-Provider/providers/yoitsu/remote"
 	"github.com/Fesaa/Media-Provider/services"
 	"github.com/Fesaa/Media-Provider/utils"
 	"github.com/anacrolix/torrent"
 	"github.com/anacrolix/torrent/metainfo"
 	"github.com/anacrolix/torrent/storage"
-	"github.com/anacrolix/torrent/types/infohash"
 	"github.com/rs/zerolog"
 	"github.com/spf13/afero"
+	"golang.org/x/time/rate"
 )
 
 const (
 	KeyNoSubDir string = "no-sub-dir"
+	// KeyMagnet holds the magnet uri the torrent was added with, so its trackers are used again after a restart
+	KeyMagnet string = "magnet"
 )
 
 type yoitsu struct {
 	dir         string
 	maxTorrents int
+	// metaInfoDir is where the metainfo of torrents is cached while they're in the queue
+	metaInfoDir string
+
+	client *torrent.Client
+	// remote is set when torrents are delegated to an external client, client is nil in that case
+	remote remote.Client
+	// remoteDir is the root dir as seen by the remote client
+	remoteDir string
+	// uploadLimiter and downloadLimiter are the global rate limiters of the client
+	uploadLimiter   *rate.Limiter
+	downloadLimiter *rate.Limiter
+	// altSpeed is true while the alternative speed profile is in use
+	altSpeed bool
+	// torrentSettings are the settings the bandwidth loop applies, refreshed by UpdateTorrentSettings
+	torrentSettings atomic.Pointer[payload.TorrentSettings]
 
-	client   *torrent.Client
 	torrents utils.SafeMap[string, Torrent]
 	baseDirs utils.SafeMap[string, string]
+	// queuedAt is when torrents were added, or requeued. Queued torrents are loaded in this order
+	queuedAt utils.SafeMap[string, time.Time]
+	// seedRules are the rules seeding torrents seed until, set once when they complete
+	seedRules utils.SafeMap[string, seedingRules]
 
 	log zerolog.Logger
 
@@ -40,6 +65,9 @@ type yoitsu struct {
 	notify     services.NotificationService
 	dirService services.DirectoryService
 	transLoco  services.TranslocoService
+	queue      services.QueueService
+	settings   services.SettingsService
+	httpClient *menou.Client
 	fs         afero.Afero
 
 	deletionWg *sync.WaitGroup
@@ -48,6 +76,7 @@ type yoitsu struct {
 func New(log zerolog.Logger, signalR services.SignalRService,
 	dirService services.DirectoryService, notify services.NotificationService,
 	transLoco services.TranslocoService, fs afero.Afero, settingsService services.SettingsService,
+	queueService services.QueueService, httpClient *menou.Client,
 ) (Client, error) {
 	settings, err := settingsService.GetSettingsDto(context.Background())
 	if err != nil {
@@ -59,19 +88,48 @@ func New(log zerolog.Logger, signalR services.SignalRService,
 	impl := &yoitsu{
 		dir:         dir,
 		maxTorrents: settings.MaxConcurrentTorrents,
+		metaInfoDir: path.Join(config.Dir, "torrents"),
 
 		torrents: utils.NewSafeMap[string, Torrent](),
 		baseDirs: utils.NewSafeMap[string, string](),
+		queuedAt: utils.NewSafeMap[string, time.Time](),
+
+		seedRules: utils.NewSafeMap[string, seedingRules](),
 
 		log:        log.With().Str("handler", "yoitsu").Logger(),
 		signalR:    signalR,
 		notify:     notify,
 		dirService: dirService,
 		transLoco:  transLoco,
+		queue:      queueService,
+		settings:   settingsService,
+		httpClient: httpClient,
 		fs:         fs,
 
 		deletionWg: &sync.WaitGroup{},
 	}
+	impl.torrentSettings.Store(&settings.Torrent)
+
+	if settings.Seeding.Ratio > 0 || settings.Seeding.MinSeedTime > 0 {
+		impl.log.Info().Int("maxTorrents", settings.Seeding.MaxTorrents).
+			Msg("seeding is enabled, seeding torrents are not persisted and stop seeding on shutdown")
+	}
+
+	if settings.Torrent.Backend != "" && settings.Torrent.Backend != payload.BackendEmbedded {
+		impl.remote, err = remote.New(settings.Torrent, httpClient)
+		if err != nil {
+			return nil, err
+		}
+		impl.remoteDir = utils.OrElse(settings.Torrent.Remote.RootDir, dir)
+		impl.log.Info().Str("backend", string(settings.Torrent.Backend)).
+			Str("url", settings.Torrent.Remote.Url).
+			Msg("delegating torrents to remote client")
+
+		// Rate limits and client settings are managed by the remote client itself
+		go impl.cleaner()
+		go impl.stallLoop()
+		return impl, nil
+	}
 
 	opts := storage.NewFileClientOpts{
 		ClientBaseDir:   dir,
@@ -79,8 +137,8 @@ func New(log zerolog.Logger, signalR services.SignalRService,
 	}
 	conf := torrent.NewDefaultClientConfig()
 	conf.DefaultStorage = storage.NewFileOpts(opts)
-	conf.ListenPort = rand.Intn(65535-49152) + 49152 //nolint:gosec
 	conf.DisableIPv6 = settings.DisableIpv6
+	applyClientSettings(conf, settings.Torrent)
 
 	client, err := torrent.NewClient(conf)
 	if err != nil {
@@ -88,24 +146,51 @@ func New(log zerolog.Logger, signalR services.SignalRService,
 	}
 
 	impl.client = client
+	impl.uploadLimiter = conf.UploadRateLimiter
+	impl.downloadLimiter = conf.DownloadRateLimiter
 
 	go impl.cleaner()
+	go impl.stallLoop()
+	go impl.bandwidthLoop()
 
 	return impl, nil
 }
 
+// Shutdown persists all torrents, and closes the client. Downloaded files are kept, the torrents are
+// resumed on the next start. Seeding torrents are stopped
 func (y *yoitsu) Shutdown() error {
 	y.log.Debug().Msg("yoitsu shutting down")
 
-	y.torrents.ForEach(func(k string, v Torrent) {
-		if err := y.RemoveDownload(payload.StopRequest{Provider: v.Provider(), Id: k, DeleteFiles: true}); err != nil {
-			y.log.Error().Err(err).Msg("failed to remove download")
+	torrents := y.torrents.Values()
+	y.torrents.Clear()
+
+	seeding := 0
+	for _, tor := range torrents {
+		if tor.State() == payload.ContentStateSeeding {
+			seeding++
+			y.stopSeeding(tor)
+			continue
 		}
-	})
 
-	y.log.Debug().Msg("Stop requests send out, waiting for all deletion to finish")
+		if err := y.queue.Save(context.Background(), tor, tor.UserSelection()); err != nil {
+			y.log.Warn().Err(err).Str("infoHash", tor.Id()).Msg("failed to persist torrent")
+		}
+		tor.Cancel()
+	}
+
+	if seeding > 0 {
+		y.log.Warn().Int("amount", seeding).Msg("stopped seeding torrents, seeding is not resumed after a restart")
+	}
+
+	y.log.Debug().Msg("Torrents persisted, waiting for all deletion to finish")
 	y.deletionWg.Wait()
 
+	if y.client != nil {
+		for _, err := range y.client.Close() {
+			y.log.Warn().Err(err).Msg("error while closing torrent client")
+		}
+	}
+
 	y.log.Debug().Msg("yoitsu shutdown complete")
 
 	return nil
@@ -127,15 +212,167 @@ func (y *yoitsu) CanStartNext() bool {
 	return inUse < y.maxTorrents
 }
 
+// Download adds the torrent, the id of the request may be an infohash, a magnet uri or a link to a .torrent file
 func (y *yoitsu) Download(req payload.DownloadRequest) error {
-	torrentInfo, nTorrent := y.client.AddTorrentInfoHash(infohash.FromHexString(strings.ToLower(req.Id)))
+	if IsTorrentUrl(req.Id) {
+		magnet, data, err := y.fetchTorrentUrl(req.Id)
+		if err != nil {
+			return err
+		}
+		if magnet == "" {
+			return y.DownloadTorrentFile(req, data)
+		}
+		req.Id = magnet
+	}
+
+	spec, err := y.requestSpec(&req)
+	if err != nil {
+		return err
+	}
+
+	return y.download(req, spec, nil)
+}
+
+// DownloadTorrentFile adds the torrent described by the .torrent file, the id of the request is replaced by
+// its infohash. The metainfo is cached, so it's never fetched from peers
+func (y *yoitsu) DownloadTorrentFile(req payload.DownloadRequest, data []byte) error {
+	mi, spec, err := parseTorrentFile(data)
+	if err != nil {
+		return err
+	}
+
+	req.Id = spec.InfoHash.HexString()
+	req.TempTitle = utils.NonEmpty(req.TempTitle, spec.DisplayName, req.Id)
+
+	if y.torrents.Has(req.Id) {
+		return services.ErrContentAlreadyExists
+	}
+
+	if err = y.saveMetaInfo(mi); err != nil {
+		y.log.Warn().Err(err).Str("infoHash", req.Id).
+			Msg("failed to cache metainfo, it will be fetched from peers after a restart")
+	}
+
+	err = y.download(req, spec, nil)
+	// The cached metainfo belongs to the existing torrent when it was added in the meantime
+	if err != nil && !errors.Is(err, services.ErrContentAlreadyExists) {
+		y.removeMetaInfo(req.Id)
+	}
+	return err
+}
+
+// Resume adds a torrent persisted before a restart. Pieces already on disk are verified, and not downloaded again
+func (y *yoitsu) Resume(req services.QueuedRequest) error {
+	spec, err := y.requestSpec(&req.Request)
+	if err != nil {
+		return err
+	}
+
+	return y.download(req.Request, spec, req.UserSelection)
+}
+
+// requestSpec returns the spec to add the torrent of the request with, and replaces its id with the hex infohash.
+// Magnet uris are stored in the request, and cached metainfo is used when present
+func (y *yoitsu) requestSpec(req *payload.DownloadRequest) (*torrent.TorrentSpec, error) {
+	if isMagnet(req.Id) {
+		if req.DownloadMetadata.Extra == nil {
+			req.DownloadMetadata.Extra = utils.SmartMap{}
+		}
+		req.DownloadMetadata.Extra.SetValue(KeyMagnet, req.Id)
+	}
+
+	spec, err := torrentSpec(req.DownloadMetadata.Extra.GetStringOrDefault(KeyMagnet, req.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Id = spec.InfoHash.HexString()
+
+	cached, ok := y.loadMetaInfo(req.Id)
+	if !ok {
+		return spec, nil
+	}
+
+	cached.Trackers = append(cached.Trackers, spec.Trackers...)
+	cached.Webseeds = append(cached.Webseeds, spec.Webseeds...)
+	cached.PeerAddrs = spec.PeerAddrs
+	cached.Sources = spec.Sources
+	return cached, nil
+}
+
+func (y *yoitsu) download(req payload.DownloadRequest, spec *torrent.TorrentSpec, userSelection []string) error {
+	// Fail early on invalid templates and rules, rather than once the torrent starts or completes
+	if _, err := planEpisodes(req, "", nil); err != nil {
+		return err
+	}
+	if _, err := fileRulesFromRequest(req); err != nil {
+		return err
+	}
+
+	if y.remote != nil {
+		return y.downloadRemote(req, spec, userSelection)
+	}
+
+	torrentInfo, nTorrent, err := y.client.AddTorrentSpec(spec)
+	if err != nil {
+		return err
+	}
 	if !nTorrent {
 		return services.ErrContentAlreadyExists
 	}
 
-	torrentWrapper := newTorrent(torrentInfo, req, y.log, y, y.signalR, y.fs)
-	y.torrents.Set(torrentInfo.InfoHash().String(), torrentWrapper)
-	y.baseDirs.Set(torrentInfo.InfoHash().String(), req.BaseDir)
+	go y.cacheMetaInfo(torrentInfo)
+
+	torrentWrapper := newTorrent(torrentInfo, req, y.log, y, y.signalR, y.queue, y.fs)
+	return y.track(req, torrentWrapper, userSelection)
+}
+
+// downloadRemote sends the torrent to the remote client. Its metainfo is sent along if cached, otherwise
+// the remote client fetches it from peers
+func (y *yoitsu) downloadRemote(req payload.DownloadRequest, spec *torrent.TorrentSpec, userSelection []string) error {
+	infoHash := spec.InfoHash.HexString()
+	if y.torrents.Has(infoHash) {
+		return services.ErrContentAlreadyExists
+	}
+
+	magnet := metainfo.Magnet{
+		InfoHash:    spec.InfoHash,
+		Trackers:    slices.Concat(spec.Trackers...),
+		DisplayName: spec.DisplayName,
+	}
+
+	addReq := remote.AddRequest{
+		InfoHash: infoHash,
+		Magnet:   magnet.String(),
+		Dir:      path.Join(y.remoteDir, req.BaseDir, infoHash),
+	}
+	if data, err := y.fs.ReadFile(y.metaInfoPath(infoHash)); err == nil {
+		addReq.MetaInfo = data
+	}
+
+	// Torrents already present in the remote client, i.e. after a restart, are adopted
+	if err := y.remote.Add(context.Background(), addReq); err != nil {
+		return err
+	}
+
+	torrentWrapper := newRemoteTorrent(infoHash, req, y.log, y, y.remote, y.signalR, y.queue, y.fs)
+	return y.track(req, torrentWrapper, userSelection)
+}
+
+// track starts tracking the added torrent, and loads its info if another torrent can start
+func (y *yoitsu) track(req payload.DownloadRequest, torrentWrapper Torrent, userSelection []string) error {
+	if len(userSelection) > 0 {
+		torrentWrapper.RestoreUserSelection(userSelection)
+	}
+
+	y.torrents.Set(torrentWrapper.Id(), torrentWrapper)
+	if err := y.queue.Save(context.Background(), torrentWrapper, torrentWrapper.UserSelection()); err != nil {
+		y.log.Warn().Err(err).Str("infoHash", torrentWrapper.Id()).
+			Msg("failed to persist torrent, it will not be resumed after a restart")
+	}
+
+	y.baseDirs.Set(torrentWrapper.Id(), req.BaseDir)
+	y.queuedAt.Set(torrentWrapper.Id(), time.Now())
 	y.signalR.AddContent(torrentWrapper.Request().OwnerId, torrentWrapper.GetInfo())
 
 	if !y.CanStartNext() {
@@ -166,6 +403,11 @@ func (y *yoitsu) RemoveDownload(req payload.StopRequest) error {
 		return services.ErrContentNotFound
 	}
 
+	if tor.State() == payload.ContentStateSeeding {
+		y.stopSeeding(tor)
+		return nil
+	}
+
 	defer func() {
 		go y.startNext()
 	}()
@@ -174,18 +416,22 @@ func (y *yoitsu) RemoveDownload(req payload.StopRequest) error {
 	baseDir, _ := y.baseDirs.Get(infoHashString)
 
 	tor.Cancel()
-	backingTorrent := tor.GetTorrent()
 	y.log.Info().
-		Str("name", backingTorrent.Name()).
-		Str("infoHash", backingTorrent.InfoHash().HexString()).
+		Str("name", tor.Title()).
+		Str("infoHash", tor.Id()).
 		Bool("deleteFiles", req.DeleteFiles).
-		Int64("downloaded", backingTorrent.BytesCompleted()).
-		Int64("total", backingTorrent.Length()).
+		Int64("progress", tor.GetInfo().Progress).
 		Msg("dropping torrent")
-	backingTorrent.Drop()
+	tor.Drop()
 
 	y.torrents.Delete(infoHashString)
 	y.baseDirs.Delete(infoHashString)
+	y.queuedAt.Delete(infoHashString)
+	y.removeMetaInfo(infoHashString)
+
+	if err := y.queue.Remove(context.Background(), tor); err != nil {
+		y.log.Warn().Err(err).Str("infoHash", infoHashString).Msg("failed to remove persisted torrent")
+	}
 
 	y.signalR.StateUpdate(tor.Request().OwnerId, tor.Id(), payload.ContentStateCleanup)
 
@@ -221,16 +467,37 @@ func (y *yoitsu) notifier(req payload.DownloadRequest) services.Notifier {
 	return y.signalR
 }
 
+func (y *yoitsu) Requeue(tor Torrent) {
+	y.queuedAt.Set(tor.Id(), time.Now())
+	tor.SetState(payload.ContentStateQueued)
+	go y.startNext()
+}
+
+// nextInQueue returns the torrent in the given state that was queued first
+func (y *yoitsu) nextInQueue(state payload.ContentState) (Torrent, bool) {
+	var next Torrent
+	var nextQueuedAt time.Time
+	for _, tor := range y.torrents.Values() {
+		if tor.State() != state {
+			continue
+		}
+
+		queuedAt, _ := y.queuedAt.Get(tor.Id())
+		if next == nil || queuedAt.Before(nextQueuedAt) {
+			next, nextQueuedAt = tor, queuedAt
+		}
+	}
+
+	return next, next != nil
+}
+
 func (y *yoitsu) loadNext() {
 	for y.CanStartNext() {
-		inext, ok := y.torrents.Find(func(k string, v Torrent) bool {
-			return v.State() == payload.ContentStateQueued
-		})
+		next, ok := y.nextInQueue(payload.ContentStateQueued)
 		if !ok {
 			return
 		}
 
-		next := *inext
 		next.LoadInfo()
 	}
 }
@@ -238,15 +505,11 @@ func (y *yoitsu) loadNext() {
 func (y *yoitsu) startNext() {
 	y.loadNext()
 
-	inext, ok := y.torrents.Find(func(k string, v Torrent) bool {
-		return v.State() == payload.ContentStateReady
-	})
-
+	next, ok := y.nextInQueue(payload.ContentStateReady)
 	if !ok {
 		return
 	}
 
-	next := *inext
 	next.StartDownload()
 
 	if y.CanStartNext() {
@@ -259,10 +522,6 @@ func (y *yoitsu) startNext() {
 //nolint:funlen
 func (y *yoitsu) cleanup(t Torrent, baseDir string) {
 	defer y.signalR.DeleteContent(t.Request().OwnerId, t.Id())
-	tor := t.GetTorrent()
-	if tor == nil {
-		return
-	}
 
 	var cleanupErrs []error
 	defer func() {
@@ -271,7 +530,7 @@ func (y *yoitsu) cleanup(t Torrent, baseDir string) {
 		}
 	}()
 
-	infoHash := tor.InfoHash().HexString()
+	infoHash := t.Id()
 	hashDir := path.Join(y.dir, baseDir, infoHash)
 	info, err := y.fs.ReadDir(hashDir)
 	if err != nil {
@@ -292,22 +551,27 @@ func (y *yoitsu) cleanup(t Torrent, baseDir string) {
 	// Calling cleanup beforehand, as it removed unwanted files. And the path might be incorrect after moving stuff
 	t.Cleanup(hashDir)
 
-	var src, dest string
-	noSubDir := t.Request().GetBool(KeyNoSubDir, false)
+	handled, _, err := y.organiseEpisodes(t, baseDir, hashDir, false)
+	if err != nil {
+		y.log.Error().Err(err).Str("infoHash", infoHash).Msg("error renaming episodes")
+		cleanupErrs = append(cleanupErrs, err)
+	}
 
-	firstDirEntry := info[0]
-	if len(info) == 1 && firstDirEntry.IsDir() {
-		src = path.Join(hashDir, firstDirEntry.Name())
-		dest = utils.Ternary(noSubDir, path.Join(y.dir, baseDir), path.Join(y.dir, baseDir, firstDirEntry.Name()))
-		y.log.Debug().Str("infoHash", infoHash).Str("src", src).Str("dest", dest).
-			Msg("torrent only has one directory, moving everything up")
-	} else {
-		src = hashDir
-		dest = utils.Ternary(noSubDir, path.Join(y.dir, baseDir), path.Join(y.dir, baseDir, tor.Name()))
-		y.log.Debug().Str("infoHash", infoHash).Str("src", src).Str("dest", dest).
-			Msg("torrent downloaded more than one dirEntry, or a file; renaming directory")
+	if len(handled) > 0 {
+		y.removeEmptyDirs(hashDir)
+		if info, err = y.fs.ReadDir(hashDir); err != nil || len(info) == 0 {
+			y.log.Debug().Str("infoHash", infoHash).Msg("all files were episodes, removing torrent dir")
+			if err = y.fs.RemoveAll(hashDir); err != nil {
+				y.log.Error().Err(err).Str("dir", hashDir).Msg("error removing torrent dir")
+				cleanupErrs = append(cleanupErrs, err)
+			}
+			return
+		}
 	}
 
+	noSubDir := t.Request().GetBool(KeyNoSubDir, false)
+	src, dest := y.contentDirs(t, baseDir, info)
+
 	if noSubDir {
 		if err = y.dirService.MoveDirectoryContent(src, dest); err != nil {
 			y.log.Error().Err(err).Str("src", src).Str("dest", dest).Msg("error moving directory contents")
@@ -325,7 +589,12 @@ func (y *yoitsu) cleanup(t Torrent, baseDir string) {
 		}
 	}
 
-	if len(info) == 1 && firstDirEntry.IsDir() {
+	if err = y.extractArchives(t, hashDir, src, dest, handled); err != nil {
+		y.log.Error().Err(err).Str("infoHash", infoHash).Msg("error extracting archives")
+		cleanupErrs = append(cleanupErrs, err)
+	}
+
+	if len(info) == 1 && info[0].IsDir() {
 		if err = y.fs.RemoveAll(hashDir); err != nil {
 			y.log.Error().Err(err).Str("dir", hashDir).Msg("error removing torrent dir")
 			cleanupErrs = append(cleanupErrs, err)
@@ -333,12 +602,34 @@ func (y *yoitsu) cleanup(t Torrent, baseDir string) {
 	}
 }
 
+// contentDirs returns the directory in the torrents' hash dir holding its content, and where the content should
+// end up. entries are the contents of the hash dir
+func (y *yoitsu) contentDirs(t Torrent, baseDir string, entries []os.FileInfo) (string, string) {
+	infoHash := t.Id()
+	hashDir := path.Join(y.dir, baseDir, infoHash)
+	noSubDir := t.Request().GetBool(KeyNoSubDir, false)
+
+	firstDirEntry := entries[0]
+	if len(entries) == 1 && firstDirEntry.IsDir() {
+		src := path.Join(hashDir, firstDirEntry.Name())
+		dest := utils.Ternary(noSubDir, path.Join(y.dir, baseDir), path.Join(y.dir, baseDir, firstDirEntry.Name()))
+		y.log.Debug().Str("infoHash", infoHash).Str("src", src).Str("dest", dest).
+			Msg("torrent only has one directory, moving everything up")
+		return src, dest
+	}
+
+	dest := utils.Ternary(noSubDir, path.Join(y.dir, baseDir), path.Join(y.dir, baseDir, t.Title()))
+	y.log.Debug().Str("infoHash", infoHash).Str("src", hashDir).Str("dest", dest).
+		Msg("torrent downloaded more than one dirEntry, or a file; renaming directory")
+	return hashDir, dest
+}
+
 func (y *yoitsu) deleteTorrentFiles(tor Torrent, baseDir string) {
 	if tor == nil {
 		return
 	}
 
-	infoHash := tor.GetTorrent().InfoHash().HexString()
+	infoHash := tor.Id()
 	defer y.signalR.DeleteContent(tor.Request().OwnerId, infoHash)
 
 	dir := path.Join(y.dir, baseDir, infoHash)
@@ -387,14 +678,15 @@ func (y *yoitsu) cleaner() {
 		y.torrents.ForEach(func(s string, m Torrent) {
 			if m.IsDone() {
 				i++
-				err := y.RemoveDownload(payload.StopRequest{
-					Provider:    -1,
-					Id:          s,
-					DeleteFiles: false,
-				})
-				if err != nil {
+				if err := y.complete(s, m); err != nil {
 					y.log.Error().Err(err).Str("file", s).Msg("error while cleaning up torrent")
 				}
+				return
+			}
+
+			if m.State() == payload.ContentStateSeeding && y.seedingDone(m) {
+				i++
+				y.stopSeeding(m)
 			}
 		})
 		if i > 0 {
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/Fesaa/Media-Provider/internal/comicinfo"
+	"github.com/Fesaa/Media-Provider/internal/epub"
 	"github.com/rs/zerolog"
 	"github.com/spf13/afero"
 )
@@ -17,6 +18,8 @@ var ErrNoMatch = errors.New("zip file does not the wanted content")
 type ArchiveService interface {
 	GetComicInfo(archive string) (*comicinfo.ComicInfo, error)
 	GetCover(archive string) ([]byte, error)
+	// GetEpubMetadata reads the package document of an epub
+	GetEpubMetadata(archive string) (*epub.Metadata, error)
 }
 
 type archiveService struct {
@@ -47,6 +50,14 @@ func (a *archiveService) GetCover(archive string) ([]byte, error) {
 	return io.ReadAll(rc)
 }
 
+func (a *archiveService) GetEpubMetadata(archive string) (*epub.Metadata, error) {
+	rc, err := a.findInArchive(archive, ".opf")
+	if err != nil {
+		return nil, err
+	}
+	return epub.ReadMetadata(rc)
+}
+
 func (a *archiveService) findInArchive(archive string, match string) (io.Reader, error) {
 	f, err := a.fs.Open(archive)
 	if err != nil {
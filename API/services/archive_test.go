@@ -2,9 +2,11 @@ package services
 
 import (
 	"bytes"
+	"io"
 	"testing"
 
 	"github.com/Fesaa/Media-Provider/internal/comicinfo"
+	"github.com/Fesaa/Media-Provider/internal/epub"
 	"github.com/rs/zerolog"
 	"github.com/spf13/afero"
 )
@@ -66,3 +68,36 @@ func TestArchiveService_GetCover(t *testing.T) {
 		t.Fatalf("Got %s; expected %s", foundCover, cover)
 	}
 }
+
+func TestArchiveService_GetEpubMetadata(t *testing.T) {
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+	as := ArchiveServiceProvider(zerolog.Nop(), fs)
+
+	book := &epub.Book{
+		Metadata: epub.Metadata{
+			Identifier:  "urn:uuid:1",
+			Title:       "Spice and Wolf Vol. 2",
+			Series:      "Spice and Wolf",
+			SeriesIndex: "2",
+		},
+		Pages: []epub.Image{{
+			Name: "page 0001.jpg",
+			Open: func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader([]byte{1, 2, 3})), nil
+			},
+		}},
+	}
+
+	if err := epub.Save(fs, book, "/testFile.epub"); err != nil {
+		t.Fatal(err)
+	}
+
+	metadata, err := as.GetEpubMetadata("/testFile.epub")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if metadata.Series != "Spice and Wolf" || metadata.SeriesIndex != "2" {
+		t.Fatalf("Got %s (%s); expected %s (%s)", metadata.Series, metadata.SeriesIndex, "Spice and Wolf", "2")
+	}
+}
@@ -15,22 +15,31 @@ import (
 )
 
 var (
-	ErrProviderNotSupported = errors.New("provider not supported")
-	ErrContentAlreadyExists = errors.New("content already exists")
-	ErrContentNotFound      = errors.New("content not found")
-	ErrUnknownMessageType   = errors.New("unknown message type")
-	ErrWrongState           = errors.New("message not allowed in current state")
-	ErrQueueFull            = errors.New("queue is full")
+	ErrProviderNotSupported    = errors.New("provider not supported")
+	ErrContentAlreadyExists    = errors.New("content already exists")
+	ErrContentNotFound         = errors.New("content not found")
+	ErrUnknownMessageType      = errors.New("unknown message type")
+	ErrWrongState              = errors.New("message not allowed in current state")
+	ErrQueueFull               = errors.New("queue is full")
+	ErrReorganiseNotSupported  = errors.New("provider does not support reorganising content")
+	ErrTorrentFileNotSupported = errors.New("provider does not support torrent files")
 )
 
 type ContentService interface {
 	Search(context.Context, payload.SearchRequest) ([]payload.Info, error)
 	Download(payload.DownloadRequest) error
+	// DownloadTorrentFile adds the content described by the .torrent file, the id of the request is ignored
+	DownloadTorrentFile(payload.DownloadRequest, []byte) error
 	DownloadSubscription(*models.Subscription, ...bool) error
 	Stop(payload.StopRequest) error
 	RegisterProvider(models.Provider, ProviderAdapter)
 	DownloadMetadata(models.Provider) (payload.DownloadMetadata, error)
 	Message(payload.Message) (payload.Message, error)
+	// Resume adds persisted content back to its providers' queue
+	Resume(QueuedRequest) error
+	// ReorganiseSubscription renames the content of the subscription on disk to the current naming scheme.
+	// Nothing is moved when dryRun is true
+	ReorganiseSubscription(context.Context, *models.Subscription, bool) (payload.ReorganiseReport, error)
 }
 
 type Content interface {
@@ -66,6 +75,18 @@ type Client interface {
 	Shutdown() error
 }
 
+// ReorganisingClient is a Client that can rename content on disk to its current naming scheme
+type ReorganisingClient interface {
+	Client
+	Reorganise(context.Context, payload.DownloadRequest, bool) (payload.ReorganiseReport, error)
+}
+
+// TorrentFileClient is a Client that can add content from an uploaded .torrent file
+type TorrentFileClient interface {
+	Client
+	DownloadTorrentFile(payload.DownloadRequest, []byte) error
+}
+
 type ProviderAdapter interface {
 	Search(context.Context, payload.SearchRequest) ([]payload.Info, error)
 	DownloadMetadata() payload.DownloadMetadata
@@ -174,6 +195,29 @@ func (s *contentService) DownloadSubscription(sub *models.Subscription, isSub ..
 	})
 }
 
+func (s *contentService) ReorganiseSubscription(ctx context.Context, sub *models.Subscription, dryRun bool) (payload.ReorganiseReport, error) {
+	adapter, ok := s.providers.Get(sub.Provider)
+	if !ok {
+		return payload.ReorganiseReport{}, ErrProviderNotSupported
+	}
+
+	client, ok := adapter.Client().(ReorganisingClient)
+	if !ok {
+		return payload.ReorganiseReport{}, ErrReorganiseNotSupported
+	}
+
+	s.log.Trace().Int("id", sub.ID).Bool("dryRun", dryRun).Msg("reorganising subscription")
+	return client.Reorganise(ctx, payload.DownloadRequest{
+		Provider:         sub.Provider,
+		Id:               sub.ContentId,
+		BaseDir:          path.Clean(sub.BaseDir),
+		TempTitle:        sub.Title,
+		DownloadMetadata: sub.Payload,
+		OwnerId:          sub.Owner,
+		Sub:              sub,
+	}, dryRun)
+}
+
 func (s *contentService) Download(req payload.DownloadRequest) error {
 	req.BaseDir = path.Clean(req.BaseDir)
 
@@ -186,6 +230,38 @@ func (s *contentService) Download(req payload.DownloadRequest) error {
 	return adapter.Client().Download(req)
 }
 
+func (s *contentService) DownloadTorrentFile(req payload.DownloadRequest, data []byte) error {
+	req.BaseDir = path.Clean(req.BaseDir)
+
+	adapter, ok := s.providers.Get(req.Provider)
+	if !ok {
+		return ErrProviderNotSupported
+	}
+
+	client, ok := adapter.Client().(TorrentFileClient)
+	if !ok {
+		return ErrTorrentFileNotSupported
+	}
+
+	s.log.Trace().Str("req", fmt.Sprintf("%+v", req)).Int("size", len(data)).Msg("downloading torrent file")
+	return client.DownloadTorrentFile(req, data)
+}
+
+func (s *contentService) Resume(req QueuedRequest) error {
+	adapter, ok := s.providers.Get(req.Request.Provider)
+	if !ok {
+		return ErrProviderNotSupported
+	}
+
+	client, ok := adapter.Client().(ResumableClient)
+	if !ok {
+		return adapter.Client().Download(req.Request)
+	}
+
+	s.log.Trace().Str("req", fmt.Sprintf("%+v", req.Request)).Msg("resuming")
+	return client.Resume(req)
+}
+
 func (s *contentService) Stop(req payload.StopRequest) error {
 	adapter, ok := s.providers.Get(req.Provider)
 	if !ok {
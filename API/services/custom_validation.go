@@ -4,6 +4,7 @@ import (
 	"reflect"
 
 	"github.com/Fesaa/Media-Provider/db/models"
+	"github.com/Fesaa/Media-Provider/internal/naming"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -12,6 +13,19 @@ func isValidProvider(fl validator.FieldLevel) bool {
 	return provider >= models.MinProvider && provider <= models.MaxProvider
 }
 
+func isValidNamingTemplate(fl validator.FieldLevel) bool {
+	return naming.Validate(fl.Field().String()) == nil
+}
+
+func isValidFileNameTemplate(fl validator.FieldLevel) bool {
+	return naming.ValidateFileName(fl.Field().String()) == nil
+}
+
+func isValidFileRules(fl validator.FieldLevel) bool {
+	rules, ok := fl.Field().Interface().(models.FileRules)
+	return ok && rules.Validate() == nil
+}
+
 func diffValidator(fl validator.FieldLevel) bool {
 	currentValue := fl.Field().Interface()
 
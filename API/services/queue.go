@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/Fesaa/Media-Provider/db"
+	"github.com/Fesaa/Media-Provider/db/models"
+	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+// QueuedRequest is content restored from the database
+type QueuedRequest struct {
+	Request       payload.DownloadRequest
+	State         payload.ContentState
+	UserSelection []string
+}
+
+// ResumableClient is a Client whose queue is persisted with the QueueService
+type ResumableClient interface {
+	Client
+	// Resume adds the content back to the queue, restoring the users' selection
+	Resume(QueuedRequest) error
+}
+
+type QueueService interface {
+	// Save persists the content with its current state, and the sub content selected by the user.
+	// Content that is being cleaned up is not saved, as it can no longer be resumed
+	Save(ctx context.Context, content Content, userSelection []string) error
+	// Remove deletes the persisted content, call once the content has left the queue
+	Remove(ctx context.Context, content Content) error
+	// All returns all persisted content, oldest first. Content for deleted subscriptions is removed
+	All(ctx context.Context) ([]QueuedRequest, error)
+}
+
+type queueService struct {
+	unitOfWork *db.UnitOfWork
+	log        zerolog.Logger
+}
+
+func QueueServiceProvider(unitOfWork *db.UnitOfWork, log zerolog.Logger) QueueService {
+	return &queueService{
+		unitOfWork: unitOfWork,
+		log:        log.With().Str("handler", "queue-service").Logger(),
+	}
+}
+
+func (q *queueService) Save(ctx context.Context, content Content, userSelection []string) error {
+	if content.State() >= payload.ContentStateCleanup {
+		return nil
+	}
+
+	req := content.Request()
+	sub := req.Sub
+	// The subscription is loaded again when resuming, no need to store it twice
+	req.Sub = nil
+
+	reqJson, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	selectionJson, err := json.Marshal(userSelection)
+	if err != nil {
+		return err
+	}
+
+	queued := models.QueuedContent{
+		Provider:       req.Provider,
+		ContentId:      req.Id,
+		Owner:          req.OwnerId,
+		State:          int(content.State()),
+		IsSubscription: req.IsSubscription,
+		Request:        reqJson,
+		UserSelection:  selectionJson,
+	}
+	if sub != nil {
+		queued.SubscriptionId = sub.ID
+	}
+
+	return q.unitOfWork.QueuedContent.Upsert(ctx, queued)
+}
+
+func (q *queueService) Remove(ctx context.Context, content Content) error {
+	req := content.Request()
+	return q.unitOfWork.QueuedContent.Delete(ctx, req.Provider, req.Id)
+}
+
+func (q *queueService) All(ctx context.Context) ([]QueuedRequest, error) {
+	all, err := q.unitOfWork.QueuedContent.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]QueuedRequest, 0, len(all))
+	for _, queued := range all {
+		log := q.log.With().Str("id", queued.ContentId).Any("provider", queued.Provider).Logger()
+
+		req, err := q.toRequest(ctx, queued)
+		if err != nil {
+			log.Warn().Err(err).Msg("unable to restore queued content, removing")
+			if err = q.unitOfWork.QueuedContent.Delete(ctx, queued.Provider, queued.ContentId); err != nil {
+				log.Error().Err(err).Msg("failed to remove queued content")
+			}
+			continue
+		}
+
+		out = append(out, req)
+	}
+
+	return out, nil
+}
+
+func (q *queueService) toRequest(ctx context.Context, queued models.QueuedContent) (QueuedRequest, error) {
+	var req payload.DownloadRequest
+	if err := json.Unmarshal(queued.Request, &req); err != nil {
+		return QueuedRequest{}, err
+	}
+
+	var selection []string
+	if len(queued.UserSelection) > 0 {
+		if err := json.Unmarshal(queued.UserSelection, &selection); err != nil {
+			return QueuedRequest{}, err
+		}
+	}
+
+	req.OwnerId = queued.Owner
+	req.IsSubscription = queued.IsSubscription
+
+	if queued.SubscriptionId != 0 {
+		sub, err := q.unitOfWork.Subscriptions.Get(ctx, queued.SubscriptionId)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return QueuedRequest{}, errors.New("subscription has been deleted")
+			}
+			return QueuedRequest{}, err
+		}
+		req.Sub = sub
+	}
+
+	state := payload.ContentState(queued.State)
+	// The user already confirmed the download, don't ask again
+	if state == payload.ContentStateReady || state == payload.ContentStateDownloading {
+		req.DownloadMetadata.StartImmediately = true
+	}
+
+	return QueuedRequest{
+		Request:       req,
+		State:         state,
+		UserSelection: selection,
+	}, nil
+}
+
+// ResumeQueuedContent adds all persisted content back to their providers' queue. Failures are logged,
+// and never prevent startup
+func ResumeQueuedContent(ctx context.Context, log zerolog.Logger, queueService QueueService, contentService ContentService) {
+	log = log.With().Str("handler", "queue-service").Logger()
+
+	all, err := queueService.All(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load queued content, downloads will not be resumed")
+		return
+	}
+
+	for _, req := range all {
+		if err = contentService.Resume(req); err != nil {
+			log.Warn().Err(err).Str("id", req.Request.Id).Any("provider", req.Request.Provider).
+				Msg("failed to resume queued content")
+		}
+	}
+
+	if len(all) > 0 {
+		log.Info().Int("amount", len(all)).Msg("resumed queued content")
+	}
+}
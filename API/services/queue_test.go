@@ -0,0 +1,190 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/Fesaa/Media-Provider/config"
+	"github.com/Fesaa/Media-Provider/db"
+	"github.com/Fesaa/Media-Provider/db/models"
+	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/Fesaa/Media-Provider/utils"
+	"github.com/rs/zerolog"
+	"github.com/spf13/afero"
+)
+
+func tempQueueService(t *testing.T) (QueueService, *db.UnitOfWork) {
+	t.Helper()
+	log := zerolog.Nop()
+
+	tempDir := t.TempDir()
+	config.Dir = tempDir
+
+	database, err := db.DatabaseProvider(t.Context(), log, afero.Afero{Fs: afero.NewOsFs()})
+	if err != nil {
+		t.Fatal(fmt.Errorf("create temp database failed: %w", err))
+	}
+	t.Cleanup(func() {
+		d, err := database.DB()
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.Close()
+	})
+
+	unitOfWork := db.NewUnitOfWork(database)
+	return QueueServiceProvider(unitOfWork, log), unitOfWork
+}
+
+type queuedContentMock struct {
+	req   payload.DownloadRequest
+	state payload.ContentState
+}
+
+func (q queuedContentMock) Id() string                       { return q.req.Id }
+func (q queuedContentMock) Title() string                    { return q.req.TempTitle }
+func (q queuedContentMock) Provider() models.Provider        { return q.req.Provider }
+func (q queuedContentMock) GetInfo() payload.InfoStat        { return payload.InfoStat{} }
+func (q queuedContentMock) State() payload.ContentState      { return q.state }
+func (q queuedContentMock) SetState(payload.ContentState)    {}
+func (q queuedContentMock) Request() payload.DownloadRequest { return q.req }
+func (q queuedContentMock) Message(msg payload.Message) (payload.Message, error) {
+	return msg, nil
+}
+
+func TestQueueService_SaveAndAll(t *testing.T) {
+	qs, _ := tempQueueService(t)
+
+	content := queuedContentMock{
+		req: payload.DownloadRequest{
+			Provider:  models.MANGADEX,
+			Id:        "spice-and-wolf",
+			BaseDir:   "Manga",
+			TempTitle: "Spice and Wolf",
+			OwnerId:   1,
+			DownloadMetadata: models.DownloadRequestMetadata{
+				Extra: utils.SmartMap{"include_cover": {"false"}},
+			},
+		},
+		state: payload.ContentStateWaiting,
+	}
+
+	if err := qs.Save(t.Context(), content, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Saving again updates the existing entry
+	content.state = payload.ContentStateDownloading
+	if err := qs.Save(t.Context(), content, []string{"ch-1", "ch-2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := qs.All(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(all) != 1 {
+		t.Fatalf("Got %d queued requests; expected %d", len(all), 1)
+	}
+
+	got := all[0]
+	if got.State != payload.ContentStateDownloading {
+		t.Errorf("Got state %d; expected %d", got.State, payload.ContentStateDownloading)
+	}
+	if !got.Request.DownloadMetadata.StartImmediately {
+		t.Error("downloading content should start immediately when resumed")
+	}
+	if got.Request.OwnerId != 1 || got.Request.BaseDir != "Manga" {
+		t.Errorf("Got %+v; expected %+v", got.Request, content.req)
+	}
+	if got.Request.GetBool("include_cover", true) {
+		t.Error("download metadata was not restored")
+	}
+	if !slices.Equal(got.UserSelection, []string{"ch-1", "ch-2"}) {
+		t.Errorf("Got %v; expected %v", got.UserSelection, []string{"ch-1", "ch-2"})
+	}
+
+	if err = qs.Remove(t.Context(), content); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err = qs.All(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 0 {
+		t.Fatalf("Got %d queued requests; expected %d", len(all), 0)
+	}
+}
+
+func TestQueueService_SkipCleanup(t *testing.T) {
+	qs, _ := tempQueueService(t)
+
+	content := queuedContentMock{
+		req:   payload.DownloadRequest{Provider: models.NYAA, Id: "abc"},
+		state: payload.ContentStateCleanup,
+	}
+
+	if err := qs.Save(t.Context(), content, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := qs.All(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 0 {
+		t.Fatalf("Got %d queued requests; expected %d", len(all), 0)
+	}
+}
+
+func TestQueueService_DeletedSubscription(t *testing.T) {
+	qs, unitOfWork := tempQueueService(t)
+
+	sub, err := unitOfWork.Subscriptions.New(t.Context(), models.Subscription{
+		Provider:         models.MANGADEX,
+		ContentId:        "spice-and-wolf",
+		RefreshFrequency: models.Week,
+		Title:            "Spice and Wolf",
+		BaseDir:          "Manga",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := queuedContentMock{
+		req: payload.DownloadRequest{
+			Provider:       models.MANGADEX,
+			Id:             sub.ContentId,
+			IsSubscription: true,
+			Sub:            sub,
+		},
+		state: payload.ContentStateQueued,
+	}
+
+	if err = qs.Save(t.Context(), content, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := qs.All(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 || all[0].Request.Sub == nil || all[0].Request.Sub.ID != sub.ID {
+		t.Fatalf("subscription was not restored, got %+v", all)
+	}
+
+	if err = unitOfWork.Subscriptions.Delete(t.Context(), sub.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err = qs.All(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 0 {
+		t.Fatalf("Got %d queued requests; expected %d", len(all), 0)
+	}
+}
@@ -120,8 +120,68 @@ func (s *settingsService) serializeSetting(setting *models.ServerSetting, dto pa
 		setting.Value = strconv.FormatBool(dto.DisableIpv6)
 	case models.MaxConcurrentImages:
 		setting.Value = strconv.Itoa(dto.MaxConcurrentImages)
+	case models.MaxConcurrentChapters:
+		setting.Value = strconv.Itoa(dto.MaxConcurrentChapters)
 	case models.MaxConcurrentTorrents:
 		setting.Value = strconv.Itoa(dto.MaxConcurrentTorrents)
+	case models.SeedRatio:
+		setting.Value = strconv.FormatFloat(dto.Seeding.Ratio, 'f', -1, 64)
+	case models.MinSeedTime:
+		setting.Value = strconv.Itoa(dto.Seeding.MinSeedTime)
+	case models.MaxSeedingTorrents:
+		setting.Value = strconv.Itoa(dto.Seeding.MaxTorrents)
+	case models.UploadRateLimit:
+		setting.Value = strconv.Itoa(dto.Torrent.UploadLimit)
+	case models.DownloadRateLimit:
+		setting.Value = strconv.Itoa(dto.Torrent.DownloadLimit)
+	case models.TorrentUploadRateLimit:
+		setting.Value = strconv.Itoa(dto.Torrent.TorrentUploadLimit)
+	case models.TorrentDownloadRateLimit:
+		setting.Value = strconv.Itoa(dto.Torrent.TorrentDownloadLimit)
+	case models.AltUploadRateLimit:
+		setting.Value = strconv.Itoa(dto.Torrent.AltSpeed.UploadLimit)
+	case models.AltDownloadRateLimit:
+		setting.Value = strconv.Itoa(dto.Torrent.AltSpeed.DownloadLimit)
+	case models.AltSpeedScheduled:
+		setting.Value = strconv.FormatBool(dto.Torrent.AltSpeed.Scheduled)
+	case models.AltSpeedDays:
+		setting.Value = formatWeekdays(dto.Torrent.AltSpeed.Days)
+	case models.AltSpeedFrom:
+		setting.Value = dto.Torrent.AltSpeed.From
+	case models.AltSpeedTo:
+		setting.Value = dto.Torrent.AltSpeed.To
+	case models.TorrentListenPort:
+		setting.Value = strconv.Itoa(dto.Torrent.ListenPort)
+	case models.MaxPeersPerTorrent:
+		setting.Value = strconv.Itoa(dto.Torrent.MaxPeers)
+	case models.EncryptionPolicy:
+		setting.Value = string(dto.Torrent.Encryption)
+	case models.DisableDht:
+		setting.Value = strconv.FormatBool(dto.Torrent.DisableDht)
+	case models.DisablePex:
+		setting.Value = strconv.FormatBool(dto.Torrent.DisablePex)
+	case models.TorrentBackend:
+		setting.Value = string(dto.Torrent.Backend)
+	case models.RemoteTorrentUrl:
+		setting.Value = dto.Torrent.Remote.Url
+	case models.RemoteTorrentUsername:
+		setting.Value = dto.Torrent.Remote.Username
+	case models.RemoteTorrentPassword:
+		if dto.Torrent.Remote.Password != strings.Repeat("*", len(setting.Value)) {
+			setting.Value = dto.Torrent.Remote.Password
+		}
+	case models.RemoteTorrentDir:
+		setting.Value = dto.Torrent.Remote.RootDir
+	case models.StallMetadataTimeout:
+		setting.Value = strconv.Itoa(dto.Torrent.Stall.MetadataTimeout)
+	case models.StallProgressTimeout:
+		setting.Value = strconv.Itoa(dto.Torrent.Stall.ProgressTimeout)
+	case models.StallAction:
+		setting.Value = string(dto.Torrent.Stall.Action)
+	case models.SubscriptionHistoryDays:
+		setting.Value = strconv.Itoa(dto.SubscriptionHistory.MaxAge)
+	case models.SubscriptionHistoryRuns:
+		setting.Value = strconv.Itoa(dto.SubscriptionHistory.MaxRuns)
 	case models.OidcAuthority:
 		setting.Value = dto.Oidc.Authority
 	case models.OidcClientID:
@@ -162,8 +222,66 @@ func (s *settingsService) parseSetting(setting models.ServerSetting, dto *payloa
 		dto.DisableIpv6, err = strconv.ParseBool(setting.Value)
 	case models.MaxConcurrentImages:
 		dto.MaxConcurrentImages, err = strconv.Atoi(setting.Value)
+	case models.MaxConcurrentChapters:
+		dto.MaxConcurrentChapters, err = strconv.Atoi(setting.Value)
 	case models.MaxConcurrentTorrents:
 		dto.MaxConcurrentTorrents, err = strconv.Atoi(setting.Value)
+	case models.SeedRatio:
+		dto.Seeding.Ratio, err = strconv.ParseFloat(setting.Value, 64)
+	case models.MinSeedTime:
+		dto.Seeding.MinSeedTime, err = strconv.Atoi(setting.Value)
+	case models.MaxSeedingTorrents:
+		dto.Seeding.MaxTorrents, err = strconv.Atoi(setting.Value)
+	case models.UploadRateLimit:
+		dto.Torrent.UploadLimit, err = strconv.Atoi(setting.Value)
+	case models.DownloadRateLimit:
+		dto.Torrent.DownloadLimit, err = strconv.Atoi(setting.Value)
+	case models.TorrentUploadRateLimit:
+		dto.Torrent.TorrentUploadLimit, err = strconv.Atoi(setting.Value)
+	case models.TorrentDownloadRateLimit:
+		dto.Torrent.TorrentDownloadLimit, err = strconv.Atoi(setting.Value)
+	case models.AltUploadRateLimit:
+		dto.Torrent.AltSpeed.UploadLimit, err = strconv.Atoi(setting.Value)
+	case models.AltDownloadRateLimit:
+		dto.Torrent.AltSpeed.DownloadLimit, err = strconv.Atoi(setting.Value)
+	case models.AltSpeedScheduled:
+		dto.Torrent.AltSpeed.Scheduled, err = strconv.ParseBool(setting.Value)
+	case models.AltSpeedDays:
+		dto.Torrent.AltSpeed.Days, err = parseWeekdays(setting.Value)
+	case models.AltSpeedFrom:
+		dto.Torrent.AltSpeed.From = setting.Value
+	case models.AltSpeedTo:
+		dto.Torrent.AltSpeed.To = setting.Value
+	case models.TorrentListenPort:
+		dto.Torrent.ListenPort, err = strconv.Atoi(setting.Value)
+	case models.MaxPeersPerTorrent:
+		dto.Torrent.MaxPeers, err = strconv.Atoi(setting.Value)
+	case models.EncryptionPolicy:
+		dto.Torrent.Encryption = payload.EncryptionPolicy(setting.Value)
+	case models.DisableDht:
+		dto.Torrent.DisableDht, err = strconv.ParseBool(setting.Value)
+	case models.DisablePex:
+		dto.Torrent.DisablePex, err = strconv.ParseBool(setting.Value)
+	case models.TorrentBackend:
+		dto.Torrent.Backend = payload.TorrentBackend(setting.Value)
+	case models.RemoteTorrentUrl:
+		dto.Torrent.Remote.Url = setting.Value
+	case models.RemoteTorrentUsername:
+		dto.Torrent.Remote.Username = setting.Value
+	case models.RemoteTorrentPassword:
+		dto.Torrent.Remote.Password = setting.Value
+	case models.RemoteTorrentDir:
+		dto.Torrent.Remote.RootDir = setting.Value
+	case models.StallMetadataTimeout:
+		dto.Torrent.Stall.MetadataTimeout, err = strconv.Atoi(setting.Value)
+	case models.StallProgressTimeout:
+		dto.Torrent.Stall.ProgressTimeout, err = strconv.Atoi(setting.Value)
+	case models.StallAction:
+		dto.Torrent.Stall.Action = payload.StallAction(setting.Value)
+	case models.SubscriptionHistoryDays:
+		dto.SubscriptionHistory.MaxAge, err = strconv.Atoi(setting.Value)
+	case models.SubscriptionHistoryRuns:
+		dto.SubscriptionHistory.MaxRuns, err = strconv.Atoi(setting.Value)
 	case models.OidcAuthority:
 		dto.Oidc.Authority = setting.Value
 	case models.OidcClientID:
@@ -190,3 +308,26 @@ func (s *settingsService) parseSetting(setting models.ServerSetting, dto *payloa
 
 	return err
 }
+
+func formatWeekdays(days []time.Weekday) string {
+	return strings.Join(utils.Map(days, func(day time.Weekday) string {
+		return strconv.Itoa(int(day))
+	}), ",")
+}
+
+func parseWeekdays(s string) ([]time.Weekday, error) {
+	if s == "" {
+		return []time.Weekday{}, nil
+	}
+
+	days := make([]time.Weekday, 0, 7)
+	for _, part := range strings.Split(s, ",") {
+		day, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		days = append(days, time.Weekday(day))
+	}
+
+	return days, nil
+}
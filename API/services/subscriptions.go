@@ -4,10 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Fesaa/Media-Provider/db"
 	"github.com/Fesaa/Media-Provider/db/models"
+	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/Fesaa/Media-Provider/internal/tachiyomi"
 	"github.com/Fesaa/Media-Provider/internal/tracing"
 	"github.com/Fesaa/Media-Provider/utils"
 	"github.com/go-co-op/gocron/v2"
@@ -16,6 +19,11 @@ import (
 	"go.opentelemetry.io/otel/trace"
 )
 
+var (
+	errIncompleteImport  = errors.New("content id, title and base dir are required")
+	errImportOutsideRoot = errors.New("base dir must be inside the root dir")
+)
+
 type SubscriptionService interface {
 	// Add a new subscription, saved to DB and starts the cron job
 	// Subscription is normalized in the process
@@ -24,6 +32,21 @@ type SubscriptionService interface {
 	Update(context.Context, models.Subscription) error
 	// Delete the subscription with ID
 	Delete(context.Context, int) error
+	// Reorganise moves the content of the subscription on disk to the current naming scheme in the background.
+	// The result is sent as a notification to the owner
+	Reorganise(models.Subscription)
+	// RunOnce downloads new content for the subscription outside its schedule. Torrent subscriptions search for
+	// new matches, other subscriptions download their content. The run is recorded in the history with the trigger
+	RunOnce(context.Context, *models.Subscription, models.RunTrigger) error
+	// History returns the recorded runs of the subscription, most recent first
+	History(context.Context, int, utils.UserParams) (utils.PagedList[models.SubscriptionRun], error)
+	// Import adds the subscriptions of the export for the owner. Each subscription is checked by validate, and
+	// skipped if a subscription for its content already exists. The id of an existing subscription is only reported
+	// if it's owned by the owner, or allowAny is true. Imported subscriptions first run on their schedule
+	Import(ctx context.Context, owner int, allowAny bool, subs []payload.ExportedSubscription, validate func(models.Subscription) error) payload.SubscriptionImportReport
+	// ProposeTachiyomi proposes subscriptions for the library of a Tachiyomi backup, for the owner. Entries that
+	// can't be mapped to a provider are returned for review. The proposals are created through Import
+	ProposeTachiyomi(ctx context.Context, owner int, backup tachiyomi.Backup) (payload.TachiyomiImport, error)
 
 	// UpdateHour recreates the underlying cronjob. Generally only called when the hour to run subscriptions changes
 	UpdateHour(ctx context.Context) error
@@ -40,6 +63,12 @@ type subscriptionService struct {
 	log        zerolog.Logger
 
 	job gocron.Job
+	// jobs are the jobs of subscriptions with a schedule of their own, by subscription id
+	jobs utils.SafeMap[int, gocron.Job]
+	// adaptiveJob runs adaptive subscriptions once they're due
+	adaptiveJob gocron.Job
+	// historyJob removes runs older, or more, than the retention settings allow
+	historyJob gocron.Job
 }
 
 func SubscriptionServiceProvider(unitOfWork *db.UnitOfWork, provider ContentService,
@@ -58,6 +87,7 @@ func SubscriptionServiceProvider(unitOfWork *db.UnitOfWork, provider ContentServ
 		transloco:      transloco,
 		unitOfWork:     unitOfWork,
 		log:            log.With().Str("handler", "subscription-service").Logger(),
+		jobs:           utils.NewSafeMap[int, gocron.Job](),
 	}
 
 	if err := service.OnStartUp(ctx); err != nil {
@@ -84,7 +114,11 @@ func (s *subscriptionService) OnStartUp(ctx context.Context) error {
 
 	err = s.unitOfWork.Transaction(func(unitOfWork *db.UnitOfWork) error {
 		for _, sub := range subs {
-			sub.NextExecution = sub.GetNextExecution(settings.SubscriptionRefreshHour)
+			if err = s.schedule(sub); err != nil {
+				s.log.Error().Err(err).Int("id", sub.ID).Msg("failed to schedule subscription")
+			}
+
+			sub.NextExecution = s.nextExecution(sub, settings.SubscriptionRefreshHour)
 			if err = unitOfWork.Subscriptions.Update(ctx, sub); err != nil {
 				return err
 			}
@@ -96,9 +130,87 @@ func (s *subscriptionService) OnStartUp(ctx context.Context) error {
 		return err
 	}
 
+	if err = s.scheduleAdaptive(); err != nil {
+		return err
+	}
+
+	if err = s.scheduleHistoryCleanup(); err != nil {
+		return err
+	}
+
 	return s.UpdateTask(ctx, settings.SubscriptionRefreshHour)
 }
 
+// scheduleAdaptive creates the job checking for due adaptive subscriptions, if it doesn't exist yet
+func (s *subscriptionService) scheduleAdaptive() error {
+	if s.adaptiveJob != nil {
+		return nil
+	}
+
+	job, err := s.cronService.NewJob(gocron.DurationJob(models.MinInterval*time.Minute), s.adaptiveTask(),
+		gocron.WithName("adaptive-subscriptions"),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule))
+	if err != nil {
+		return err
+	}
+
+	s.adaptiveJob = job
+	return nil
+}
+
+// scheduleHistoryCleanup creates the daily job applying the history retention settings, if it doesn't exist yet
+func (s *subscriptionService) scheduleHistoryCleanup() error {
+	if s.historyJob != nil {
+		return nil
+	}
+
+	job, err := s.cronService.NewJob(gocron.DurationJob(24*time.Hour), gocron.NewTask(s.cleanupHistory),
+		gocron.WithName("subscription-history-cleanup"),
+		gocron.WithStartAt(gocron.WithStartImmediately()),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule))
+	if err != nil {
+		return err
+	}
+
+	s.historyJob = job
+	return nil
+}
+
+// cleanupHistory removes runs older than the max age, and all but the most recent max runs of each subscription
+func (s *subscriptionService) cleanupHistory(ctx context.Context) {
+	settings, err := s.settings.GetSettingsDto(ctx)
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to load settings")
+		return
+	}
+
+	retention := settings.SubscriptionHistory
+	if retention.MaxAge > 0 {
+		removed, err := s.unitOfWork.SubscriptionRuns.DeleteBefore(ctx, time.Now().AddDate(0, 0, -retention.MaxAge))
+		if err != nil {
+			s.log.Error().Err(err).Msg("failed to remove old subscription runs")
+		} else if removed > 0 {
+			s.log.Debug().Int64("removed", removed).Msg("removed old subscription runs")
+		}
+	}
+
+	if retention.MaxRuns <= 0 {
+		return
+	}
+
+	subs, err := s.unitOfWork.Subscriptions.All(ctx)
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to get subscriptions")
+		return
+	}
+
+	for _, sub := range subs {
+		if _, err = s.unitOfWork.SubscriptionRuns.Prune(ctx, sub.ID, retention.MaxRuns); err != nil {
+			s.log.Error().Err(err).Int("id", sub.ID).Msg("failed to prune subscription runs")
+		}
+	}
+}
+
 func (s *subscriptionService) orFromPreferences(ctx context.Context, hours ...int) (int, error) {
 	if len(hours) > 0 {
 		return hours[0], nil
@@ -161,9 +273,76 @@ func (s *subscriptionService) Add(ctx context.Context, sub models.Subscription)
 		return nil, err
 	}
 
+	if err = s.schedule(*newSub); err != nil {
+		return nil, err
+	}
+
 	return newSub, nil
 }
 
+// schedule creates, or replaces, the job of a subscription with a schedule of its own. Any existing job is removed
+// if the subscription no longer has one
+func (s *subscriptionService) schedule(sub models.Subscription) error {
+	s.unschedule(sub.ID)
+
+	if !sub.HasSchedule() {
+		return nil
+	}
+
+	var definition gocron.JobDefinition
+	options := []gocron.JobOption{
+		gocron.WithName(fmt.Sprintf("subscription-%d", sub.ID)),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	}
+
+	if sub.CronExpression != "" {
+		definition = gocron.CronJob(sub.CronExpression, false)
+	} else {
+		definition = gocron.DurationJob(time.Duration(sub.Interval) * time.Minute)
+
+		// Continue the interval from the last check, overdue subscriptions run right away
+		next := sub.GetNextExecution(0)
+		if next.After(time.Now()) {
+			options = append(options, gocron.WithStartAt(gocron.WithStartDateTime(next)))
+		} else {
+			options = append(options, gocron.WithStartAt(gocron.WithStartImmediately()))
+		}
+	}
+
+	job, err := s.cronService.NewJob(definition, s.scheduledTask(sub.ID), options...)
+	if err != nil {
+		return err
+	}
+
+	s.log.Debug().Int("id", sub.ID).Str("cron", sub.CronExpression).Int("interval", sub.Interval).
+		Msg("scheduled subscription")
+	s.jobs.Set(sub.ID, job)
+	return nil
+}
+
+func (s *subscriptionService) unschedule(id int) {
+	job, ok := s.jobs.Get(id)
+	if !ok {
+		return
+	}
+
+	s.jobs.Delete(id)
+	if err := s.cronService.RemoveJob(job.ID()); err != nil {
+		s.log.Warn().Err(err).Int("id", id).Msg("failed to remove subscription job")
+	}
+}
+
+// nextExecution returns the next run of the subscription, as seen by its job if it has one
+func (s *subscriptionService) nextExecution(sub models.Subscription, hour int) time.Time {
+	if job, ok := s.jobs.Get(sub.ID); ok {
+		if next, err := job.NextRun(); err == nil && !next.IsZero() {
+			return next
+		}
+	}
+
+	return sub.GetNextExecution(hour)
+}
+
 func (s *subscriptionService) Update(ctx context.Context, sub models.Subscription) error {
 	cur, err := s.unitOfWork.Subscriptions.GetByContentID(ctx, sub.ContentId)
 	if err != nil {
@@ -179,28 +358,184 @@ func (s *subscriptionService) Update(ctx context.Context, sub models.Subscriptio
 		return err
 	}
 
-	// Reset no download count when refresh frequency changes
-	if cur.RefreshFrequency != sub.RefreshFrequency {
+	// Reset no download count when the schedule changes
+	if cur.RefreshFrequency != sub.RefreshFrequency || cur.CronExpression != sub.CronExpression ||
+		cur.Interval != sub.Interval || cur.Adaptive != sub.Adaptive {
 		cur.NoDownloadCount = 0
 	}
 
 	cur.Title = sub.Title
 	cur.BaseDir = sub.BaseDir
 	cur.RefreshFrequency = sub.RefreshFrequency
+	cur.CronExpression = sub.CronExpression
+	cur.Interval = sub.Interval
+	cur.Adaptive = sub.Adaptive
+	cur.AdaptiveMin = sub.AdaptiveMin
+	cur.AdaptiveMax = sub.AdaptiveMax
 	cur.Provider = sub.Provider
 	cur.Payload = sub.Payload
+	cur.TorrentSearch = sub.TorrentSearch
 	cur.LastDownloadDir = sub.LastDownloadDir
 
-	cur.Normalize(settings.SubscriptionRefreshHour)
-	cur.NextExecution = sub.GetNextExecution(settings.SubscriptionRefreshHour)
-	s.log.Debug().Time("nextExecution", sub.NextExecution).
+	// The last check is kept as is for subscriptions with their own schedule, intervals continue from it
+	if !cur.HasSchedule() {
+		cur.Normalize(settings.SubscriptionRefreshHour)
+	}
+
+	if err = s.schedule(*cur); err != nil {
+		return err
+	}
+
+	cur.NextExecution = s.nextExecution(*cur, settings.SubscriptionRefreshHour)
+	s.log.Debug().Time("nextExecution", cur.NextExecution).
 		Msg("subscription will run next on")
 
 	return s.unitOfWork.Subscriptions.Update(ctx, *cur)
 }
 
 func (s *subscriptionService) Delete(ctx context.Context, id int) error {
-	return s.unitOfWork.Subscriptions.Delete(ctx, id)
+	if err := s.unitOfWork.Subscriptions.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.unschedule(id)
+
+	if err := s.unitOfWork.SubscriptionRuns.DeleteForSubscription(ctx, id); err != nil {
+		s.log.Warn().Err(err).Int("id", id).Msg("failed to remove subscription history")
+	}
+	return nil
+}
+
+func (s *subscriptionService) History(ctx context.Context, id int, params utils.UserParams) (utils.PagedList[models.SubscriptionRun], error) {
+	return s.unitOfWork.SubscriptionRuns.ForSubscription(ctx, id, params)
+}
+
+func (s *subscriptionService) Import(ctx context.Context, owner int, allowAny bool, subs []payload.ExportedSubscription, validate func(models.Subscription) error) payload.SubscriptionImportReport {
+	report := payload.SubscriptionImportReport{Entries: make([]payload.SubscriptionImportResult, 0, len(subs))}
+
+	for i, exported := range subs {
+		sub := exported.Subscription()
+		sub.Owner = owner
+
+		result := payload.SubscriptionImportResult{
+			Index:     i,
+			ContentId: sub.ContentId,
+			Title:     sub.Title,
+		}
+
+		err := validate(sub)
+		if sub.ContentId == "" || sub.Title == "" || sub.BaseDir == "." {
+			err = errIncompleteImport
+		} else if sub.BaseDir == ".." || strings.HasPrefix(sub.BaseDir, "../") {
+			err = errImportOutsideRoot
+		}
+
+		if err != nil {
+			result.Status, result.Error = payload.ImportInvalid, err.Error()
+			report.Add(result)
+			continue
+		}
+
+		// Subscriptions are unique per content, across all users. See Add
+		existing, err := s.unitOfWork.Subscriptions.GetByContentID(ctx, sub.ContentId)
+		if err != nil {
+			result.Status, result.Error = payload.ImportFailed, err.Error()
+			report.Add(result)
+			continue
+		}
+
+		if existing != nil {
+			result.Status = payload.ImportDuplicate
+			if existing.Owner == owner || allowAny {
+				result.ID = existing.ID
+			}
+			report.Add(result)
+			continue
+		}
+
+		created, err := s.Add(ctx, sub)
+		if err != nil {
+			s.log.Warn().Err(err).Str("contentId", sub.ContentId).Msg("failed to import subscription")
+			result.Status, result.Error = payload.ImportFailed, err.Error()
+		} else {
+			result.Status, result.ID = payload.ImportCreated, created.ID
+		}
+		report.Add(result)
+	}
+
+	s.log.Info().Int("owner", owner).Int("created", report.Created).Int("duplicates", report.Duplicates).
+		Int("failed", report.Failed).Msg("imported subscriptions")
+	return report
+}
+
+func (s *subscriptionService) RunOnce(ctx context.Context, sub *models.Subscription, trigger models.RunTrigger) error {
+	return s.download(ctx, sub, trigger)
+}
+
+// download starts a run of the subscription. Torrent runs finish once the torrents have been queued, other runs
+// are finished by the content downloading the subscription
+func (s *subscriptionService) download(ctx context.Context, sub *models.Subscription, trigger models.RunTrigger) error {
+	isSub := trigger == models.TriggerCron
+
+	sub.Run = models.NewSubscriptionRun(sub.ID, trigger)
+	if err := s.unitOfWork.SubscriptionRuns.New(ctx, sub.Run); err != nil {
+		s.log.Warn().Err(err).Int("id", sub.ID).Msg("failed to record subscription run")
+	}
+
+	var err error
+	if sub.Provider.IsTorrent() {
+		err = s.grabTorrents(ctx, sub, isSub)
+	} else {
+		err = s.contentService.DownloadSubscription(sub, isSub)
+	}
+
+	if err != nil || sub.Provider.IsTorrent() {
+		sub.Run.Finish(err)
+		if err := s.unitOfWork.SubscriptionRuns.Update(ctx, *sub.Run); err != nil {
+			s.log.Warn().Err(err).Int("id", sub.ID).Msg("failed to record subscription run")
+		}
+	}
+
+	return err
+}
+
+func (s *subscriptionService) Reorganise(sub models.Subscription) {
+	go func() {
+		ctx := context.Background()
+
+		report, err := s.contentService.ReorganiseSubscription(ctx, &sub, false)
+		if err != nil {
+			s.log.Error().Err(err).Int("id", sub.ID).Msg("failed to reorganise subscription")
+			s.notifier.Notify(ctx, models.NewNotification().
+				WithTitle(s.transloco.GetTranslation("reorganise-failed-title")).
+				WithBody(s.transloco.GetTranslation("reorganise-failed", sub.Title, err)).
+				WithGroup(models.GroupError).
+				WithColour(models.Error).
+				WithOwner(sub.Owner).
+				WithRequiredRoles(models.ManageSubscriptions).
+				Build())
+			return
+		}
+
+		s.log.Info().Int("id", sub.ID).Int("moved", len(report.Moves)).Int("conflicts", len(report.Conflicts)).
+			Int("unmatched", len(report.Unmatched)).Msg("reorganised subscription")
+
+		body := s.transloco.GetTranslation("reorganise-finished-body",
+			len(report.Moves), len(report.Conflicts), len(report.Unmatched))
+		for _, e := range report.Errors {
+			body += s.transloco.GetTranslation("content-line", e)
+		}
+
+		s.notifier.Notify(ctx, models.NewNotification().
+			WithTitle(s.transloco.GetTranslation("reorganise-finished-title")).
+			WithSummary(s.transloco.GetTranslation("reorganise-finished", report.Title)).
+			WithBody(body).
+			WithGroup(models.GroupContent).
+			WithColour(utils.Ternary(len(report.Errors) > 0, models.Warning, models.Primary)).
+			WithOwner(sub.Owner).
+			WithRequiredRoles(models.ViewAllDownloads).
+			Build())
+	}()
 }
 
 func (s *subscriptionService) subscriptionTask(hour int) gocron.Task {
@@ -227,6 +562,11 @@ func (s *subscriptionService) subscriptionTask(hour int) gocron.Task {
 		counter := 0
 		now := time.Now()
 		for _, sub := range subs {
+			if sub.HasSchedule() || sub.IsAdaptive() {
+				// Runs on its own job, see subscriptionService.schedule and subscriptionService.adaptiveTask
+				continue
+			}
+
 			nextExec := sub.NextExecution.In(time.Local)
 			if !utils.IsSameDay(now, nextExec) {
 				s.log.Debug().Time("nextExec", nextExec).
@@ -243,34 +583,97 @@ func (s *subscriptionService) subscriptionTask(hour int) gocron.Task {
 	})
 }
 
+// adaptiveTask runs the adaptive subscriptions whose next execution has passed
+func (s *subscriptionService) adaptiveTask() gocron.Task {
+	return gocron.NewTask(func(ctx context.Context) {
+		ctx, span := tracing.TracerServices.Start(ctx, tracing.SpanServicesSubscriptionTask+".adaptive")
+		defer span.End()
+
+		subs, err := s.unitOfWork.Subscriptions.All(ctx)
+		if err != nil {
+			s.log.Error().Err(err).Msg("failed to get subscriptions")
+			return
+		}
+
+		hour, err := s.orFromPreferences(ctx)
+		if err != nil {
+			s.log.Error().Err(err).Msg("failed to load settings")
+			return
+		}
+
+		now := time.Now()
+		for _, sub := range subs {
+			if !sub.IsAdaptive() || sub.NextExecution.After(now) {
+				continue
+			}
+
+			s.log.Debug().Int("id", sub.ID).Int("cadence", sub.Cadence).Msg("running adaptive subscription")
+			s.handleSub(ctx, sub, hour)
+		}
+	})
+}
+
+// scheduledTask runs the subscription with id on its own schedule. The subscription is loaded on each run, so
+// changes made since it was scheduled are picked up
+func (s *subscriptionService) scheduledTask(id int) gocron.Task {
+	return gocron.NewTask(func(ctx context.Context) {
+		ctx, span := tracing.TracerServices.Start(ctx, tracing.SpanServicesSubscriptionTask,
+			trace.WithAttributes(attribute.Int("id", id)))
+		defer span.End()
+
+		sub, err := s.unitOfWork.Subscriptions.Get(ctx, id)
+		if err != nil {
+			s.log.Error().Err(err).Int("id", id).Msg("failed to get subscription")
+			return
+		}
+
+		hour, err := s.orFromPreferences(ctx)
+		if err != nil {
+			s.log.Error().Err(err).Int("id", id).Msg("failed to load settings")
+			return
+		}
+
+		s.log.Debug().Int("id", id).Msg("running scheduled subscription")
+		s.handleSub(ctx, *sub, hour)
+	})
+}
+
 func (s *subscriptionService) handleSub(ctx context.Context, sub models.Subscription, hour int) {
 	ctx, span := tracing.TracerServices.Start(ctx, tracing.SpanServicesSubscriptionTask+".run",
 		trace.WithAttributes(attribute.Int("id", sub.ID)))
 	defer span.End()
 
-	err := s.contentService.DownloadSubscription(&sub)
+	// The run columns are written before the download starts, the content saves the subscription once it's done.
+	// Only the columns owned here are updated, so that save, and the cadence it observed, is never overwritten
 	sub.LastCheck = time.Now()
-	sub.LastCheckSuccess = err == nil
-	sub.NextExecution = sub.GetNextExecution(hour)
+	sub.LastCheckSuccess = true
+	sub.NextExecution = s.nextExecution(sub, hour)
+	s.updateRunColumns(ctx, sub, "last_check", "last_check_success", "next_execution")
 
-	if err != nil {
-		s.log.Error().Err(err).
-			Int("id", sub.ID).
-			Str("contentId", sub.ContentId).
-			Msg("failed to download content")
-		s.notifier.Notify(ctx, models.NewNotification().
-			WithTitle(s.transloco.GetTranslation("failed-sub")).
-			WithBody(s.transloco.GetTranslation("failed-start-sub-download", sub.Title, err)).
-			WithGroup(models.GroupError).
-			WithColour(models.Error).
-			WithRequiredRoles(models.ManageSubscriptions).
-			Build())
+	err := s.download(ctx, &sub, models.TriggerCron)
+	if err == nil {
 		return
 	}
 
-	if err = s.unitOfWork.Subscriptions.Update(ctx, sub); err != nil {
+	s.log.Error().Err(err).
+		Int("id", sub.ID).
+		Str("contentId", sub.ContentId).
+		Msg("failed to download content")
+	s.notifier.Notify(ctx, models.NewNotification().
+		WithTitle(s.transloco.GetTranslation("failed-sub")).
+		WithBody(s.transloco.GetTranslation("failed-start-sub-download", sub.Title, err)).
+		WithGroup(models.GroupError).
+		WithColour(models.Error).
+		WithRequiredRoles(models.ManageSubscriptions).
+		Build())
+
+	s.updateRunColumns(ctx, models.Subscription{Model: models.Model{ID: sub.ID}}, "last_check_success")
+}
+
+func (s *subscriptionService) updateRunColumns(ctx context.Context, sub models.Subscription, columns ...string) {
+	if err := s.unitOfWork.Subscriptions.UpdateColumns(ctx, sub, columns...); err != nil {
 		s.log.Warn().Err(err).Int("id", sub.ID).Msg("failed to update subscription")
 	} else {
-		s.log.Debug().Int("id", sub.ID).Msg("updated subscription")
+		s.log.Debug().Int("id", sub.ID).Strs("columns", columns).Msg("updated subscription")
 	}
 }
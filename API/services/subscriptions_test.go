@@ -0,0 +1,337 @@
+package services
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Fesaa/Media-Provider/db/models"
+	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/Fesaa/Media-Provider/utils"
+	"github.com/Fesaa/Media-Provider/utils/mock"
+	"github.com/go-co-op/gocron/v2"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog"
+)
+
+func TestSubscriptionService_Schedule(t *testing.T) {
+	_, unitOfWork := tempQueueService(t)
+
+	cronService, err := CronServiceProvider(zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &subscriptionService{
+		cronService:    cronService,
+		contentService: ContentServiceProvider(zerolog.Nop()),
+		settings:       &mock.Settings{},
+		notifier:       mock.Notifications{},
+		transloco:      mock.Transloco{},
+		unitOfWork:     unitOfWork,
+		log:            zerolog.Nop(),
+		jobs:           utils.NewSafeMap[int, gocron.Job](),
+	}
+
+	sub, err := s.Add(t.Context(), models.Subscription{
+		Provider:         models.MANGADEX,
+		ContentId:        "weekly",
+		Title:            "Weekly",
+		BaseDir:          "Manga",
+		RefreshFrequency: models.Week,
+		CronExpression:   "0 17 * * 2",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job, ok := s.jobs.Get(sub.ID)
+	if !ok {
+		t.Fatal("Expected the subscription to have its own job")
+	}
+
+	next, err := job.NextRun()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Weekday() != time.Tuesday || next.Hour() != 17 || next.Minute() != 0 {
+		t.Errorf("Got %v; expected the job to run on Tuesday at 17:00", next)
+	}
+	if !sub.NextExecution.Equal(next) {
+		t.Errorf("Got next execution %v; expected the next run of the job %v", sub.NextExecution, next)
+	}
+
+	sub.CronExpression = ""
+	sub.Interval = 360
+	if err = s.Update(t.Context(), *sub); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := unitOfWork.Subscriptions.Get(t.Context(), sub.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newJob, ok := s.jobs.Get(sub.ID)
+	if !ok || newJob.ID() == job.ID() {
+		t.Fatal("Expected the job to be replaced")
+	}
+	if want := saved.LastCheck.Add(6 * time.Hour); saved.NextExecution.Sub(want).Abs() > time.Second {
+		t.Errorf("Got next execution %v; expected 6 hours after the last check %v", saved.NextExecution, want)
+	}
+
+	saved.Interval = 0
+	if err = s.Update(t.Context(), *saved); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok = s.jobs.Get(sub.ID); ok {
+		t.Error("Expected the job to be removed without a schedule")
+	}
+
+	saved.Interval = 60
+	if err = s.Update(t.Context(), *saved); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Delete(t.Context(), sub.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok = s.jobs.Get(sub.ID); ok {
+		t.Error("Expected the job to be removed with the subscription")
+	}
+}
+
+func TestSubscriptionService_HandleSubKeepsConcurrentChanges(t *testing.T) {
+	_, unitOfWork := tempQueueService(t)
+
+	s := &subscriptionService{
+		contentService: ContentServiceProvider(zerolog.Nop()),
+		settings:       &mock.Settings{},
+		notifier:       mock.Notifications{},
+		transloco:      mock.Transloco{},
+		unitOfWork:     unitOfWork,
+		log:            zerolog.Nop(),
+		jobs:           utils.NewSafeMap[int, gocron.Job](),
+	}
+
+	sub, err := unitOfWork.Subscriptions.New(t.Context(), models.Subscription{
+		Provider:         models.MANGADEX,
+		ContentId:        "concurrent",
+		Title:            "Concurrent",
+		BaseDir:          "Manga",
+		RefreshFrequency: models.Week,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stale := *sub
+	sub.Cadence = 168
+	sub.NoDownloadCount = 3
+	if err = unitOfWork.Subscriptions.Update(t.Context(), *sub); err != nil {
+		t.Fatal(err)
+	}
+
+	// No provider is registered, so the download fails
+	s.handleSub(t.Context(), stale, 0)
+
+	got, err := unitOfWork.Subscriptions.Get(t.Context(), sub.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Cadence != 168 || got.NoDownloadCount != 3 {
+		t.Errorf("Got cadence %d and no download count %d; expected the changes made during the run to be kept",
+			got.Cadence, got.NoDownloadCount)
+	}
+	if got.LastCheckSuccess || time.Since(got.LastCheck) > time.Minute || !got.NextExecution.After(time.Now()) {
+		t.Errorf("Got %+v; expected a failed, recent, check and a next execution in the future", got)
+	}
+}
+
+func TestSubscriptionService_CleanupHistory(t *testing.T) {
+	_, unitOfWork := tempQueueService(t)
+
+	settings := &mock.Settings{}
+	if err := settings.UpdateSettingsDto(t.Context(), payload.Settings{
+		SubscriptionHistory: payload.HistorySettings{MaxAge: 30, MaxRuns: 2},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &subscriptionService{
+		settings:   settings,
+		unitOfWork: unitOfWork,
+		log:        zerolog.Nop(),
+	}
+
+	var subs []*models.Subscription
+	for _, id := range []string{"one", "two"} {
+		sub, err := unitOfWork.Subscriptions.New(t.Context(), models.Subscription{
+			Provider:         models.MANGADEX,
+			ContentId:        id,
+			Title:            id,
+			BaseDir:          "Manga",
+			RefreshFrequency: models.Week,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		subs = append(subs, sub)
+	}
+
+	now := time.Now()
+	for _, started := range []time.Time{now.AddDate(0, 0, -40), now.Add(-3 * time.Hour), now.Add(-2 * time.Hour), now.Add(-time.Hour)} {
+		run := models.NewSubscriptionRun(subs[0].ID, models.TriggerCron)
+		run.StartedAt = started
+		run.Skip("Chapter 1", models.SkipOnDisk)
+		if err := unitOfWork.SubscriptionRuns.New(t.Context(), run); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	other := models.NewSubscriptionRun(subs[1].ID, models.TriggerRunOnce)
+	if err := unitOfWork.SubscriptionRuns.New(t.Context(), other); err != nil {
+		t.Fatal(err)
+	}
+
+	s.cleanupHistory(t.Context())
+
+	history, err := s.History(t.Context(), subs[0].ID, utils.UserParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history.Items) != 2 {
+		t.Fatalf("Got %d runs; expected the 2 most recent runs to be kept", len(history.Items))
+	}
+	if want := now.Add(-time.Hour); !history.Items[0].StartedAt.Equal(want) {
+		t.Errorf("Got %v; expected the most recent run first", history.Items[0].StartedAt)
+	}
+	if len(history.Items[0].Skipped) != 1 || history.Items[0].Skipped[0].Reason != models.SkipOnDisk {
+		t.Errorf("Got skipped %v; expected the skipped chapters to be saved", history.Items[0].Skipped)
+	}
+
+	history, err = s.History(t.Context(), subs[1].ID, utils.UserParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history.Items) != 1 {
+		t.Errorf("Got %d runs; expected runs of other subscriptions to be kept", len(history.Items))
+	}
+}
+
+func TestSubscriptionService_Import(t *testing.T) {
+	_, unitOfWork := tempQueueService(t)
+
+	cronService, err := CronServiceProvider(zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &subscriptionService{
+		cronService: cronService,
+		settings:    &mock.Settings{},
+		unitOfWork:  unitOfWork,
+		log:         zerolog.Nop(),
+		jobs:        utils.NewSafeMap[int, gocron.Job](),
+	}
+
+	existing, err := unitOfWork.Subscriptions.New(t.Context(), models.Subscription{
+		Provider:         models.MANGADEX,
+		ContentId:        "existing",
+		Title:            "Existing",
+		BaseDir:          "Manga",
+		RefreshFrequency: models.Week,
+		Owner:            2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	others, err := unitOfWork.Subscriptions.New(t.Context(), models.Subscription{
+		Provider:         models.MANGADEX,
+		ContentId:        "others",
+		Title:            "Others",
+		BaseDir:          "Manga",
+		RefreshFrequency: models.Week,
+		Owner:            3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	export := payload.NewSubscriptionExport([]models.Subscription{
+		*existing,
+		{
+			Provider:         models.MANGADEX,
+			ContentId:        "new",
+			Title:            "New",
+			BaseDir:          "Manga/",
+			RefreshFrequency: models.Day,
+			Payload: models.DownloadRequestMetadata{
+				Extra: utils.SmartMap{"language": {"en"}},
+			},
+		},
+		{Provider: models.MANGADEX, ContentId: "invalid", Title: "Invalid", BaseDir: "Manga", Interval: 1},
+		{Provider: models.MANGADEX, Title: "No content id", BaseDir: "Manga"},
+		{
+			Provider:         models.NYAA,
+			ContentId:        "frieren",
+			Title:            "Frieren",
+			BaseDir:          "Anime",
+			RefreshFrequency: models.Day,
+			Grabbed:          pq.StringArray{"c9e15763f722f23e98a29decdfae341b98d53056"},
+		},
+		{Provider: models.MANGADEX, ContentId: "outside", Title: "Outside", BaseDir: "Manga/../../etc", RefreshFrequency: models.Day},
+		*others,
+	})
+
+	validate := func(sub models.Subscription) error {
+		return sub.ValidateSchedule()
+	}
+
+	report := s.Import(t.Context(), 2, false, export.Subscriptions, validate)
+	if report.Created != 2 || report.Duplicates != 2 || report.Failed != 3 || len(report.Entries) != 7 {
+		t.Fatalf("Got %+v; expected two created, two duplicates and three failures", report)
+	}
+
+	want := []payload.ImportStatus{payload.ImportDuplicate, payload.ImportCreated, payload.ImportInvalid,
+		payload.ImportInvalid, payload.ImportCreated, payload.ImportInvalid, payload.ImportDuplicate}
+	for i, entry := range report.Entries {
+		if entry.Index != i || entry.Status != want[i] {
+			t.Errorf("Got %+v; expected entry %d to be %s", entry, i, want[i])
+		}
+	}
+	if report.Entries[0].ID != existing.ID {
+		t.Errorf("Got id %d; expected the id of the existing subscription", report.Entries[0].ID)
+	}
+	if report.Entries[6].ID != 0 {
+		t.Errorf("Got id %d; expected subscriptions of other users not to be reported", report.Entries[6].ID)
+	}
+
+	created, err := unitOfWork.Subscriptions.Get(t.Context(), report.Entries[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Owner != 2 || created.BaseDir != "Manga" || created.Payload.Extra.GetStringOrDefault("language", "") != "en" {
+		t.Errorf("Got %+v; expected the importing user to own the subscription, with its extras", created)
+	}
+
+	torrent, err := unitOfWork.Subscriptions.Get(t.Context(), report.Entries[4].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(torrent.Grabbed, []string{"c9e15763f722f23e98a29decdfae341b98d53056"}) {
+		t.Errorf("Got grabbed %v; expected the grabbed torrents to be imported", torrent.Grabbed)
+	}
+
+	// Importing the same document again doesn't create anything
+	report = s.Import(t.Context(), 2, false, export.Subscriptions, validate)
+	if report.Created != 0 || report.Duplicates != 4 {
+		t.Errorf("Got %+v; expected all valid entries to be duplicates", report)
+	}
+
+	// Users managing all subscriptions see the id of subscriptions of other users
+	report = s.Import(t.Context(), 2, true, export.Subscriptions, validate)
+	if report.Entries[6].Status != payload.ImportDuplicate || report.Entries[6].ID != others.ID {
+		t.Errorf("Got %+v; expected the id of the subscription of the other user", report.Entries[6])
+	}
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Fesaa/Media-Provider/db/models"
+	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/Fesaa/Media-Provider/internal/tachiyomi"
+)
+
+var (
+	mangadexUrlRegex = regexp.MustCompile(`(?i)/(?:manga|title)/([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})(?:/|$)`)
+	// Bato has used both /series/<id>/<slug> and /title/<id>-<slug>, the latter is what the provider uses
+	batoSeriesRegex = regexp.MustCompile(`^/series/(\d+)(?:/|$)`)
+	batoTitleRegex  = regexp.MustCompile(`^/title/([^/]+)/?$`)
+	// Webtoon urls are /<language>/<genre>/<slug>/list?title_no=<id>
+	webtoonPathRegex = regexp.MustCompile(`^/[a-z-]+/[^/]+/[^/]+/list$`)
+	mangaBuddyRegex  = regexp.MustCompile(`^/[^/]+$`)
+	sourceNameRegex  = regexp.MustCompile(`[^a-z]`)
+)
+
+// tachiyomiProvider returns the provider of the source, by its name, or the host of the url. Backups of older app
+// versions don't include the sources, and some sources store the full url
+func tachiyomiProvider(source string, u *url.URL) (models.Provider, bool) {
+	name := sourceNameRegex.ReplaceAllString(strings.ToLower(source), "")
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+
+	switch {
+	case strings.HasPrefix(name, "mangadex"), host == "mangadex.org":
+		return models.MANGADEX, true
+	case strings.HasPrefix(name, "mangabuddy"), host == "mangabuddy.com":
+		return models.MANGA_BUDDY, true
+	case strings.HasPrefix(name, "webtoon"), host == "webtoons.com", host == "m.webtoons.com":
+		return models.WEBTOON, true
+	case strings.HasPrefix(name, "bato"), strings.HasPrefix(host, "bato"):
+		return models.BATO, true
+	default:
+		return 0, false
+	}
+}
+
+// tachiyomiContentId returns the content id, as used by the provider, of the url of an entry in the backup
+func tachiyomiContentId(provider models.Provider, u *url.URL) (string, bool) {
+	switch provider {
+	case models.MANGADEX:
+		if match := mangadexUrlRegex.FindStringSubmatch(u.Path); match != nil {
+			return strings.ToLower(match[1]), true
+		}
+	case models.BATO:
+		if match := batoSeriesRegex.FindStringSubmatch(u.Path); match != nil {
+			return match[1], true
+		}
+		if match := batoTitleRegex.FindStringSubmatch(u.Path); match != nil {
+			return match[1], true
+		}
+	case models.MANGA_BUDDY:
+		if mangaBuddyRegex.MatchString(u.Path) {
+			return u.Path, true
+		}
+	case models.WEBTOON:
+		titleNo := u.Query().Get("title_no")
+		if _, err := strconv.Atoi(titleNo); err == nil && webtoonPathRegex.MatchString(u.Path) {
+			return u.Path + "?title_no=" + titleNo, true
+		}
+	default:
+	}
+
+	return "", false
+}
+
+// suggestedBaseDirs returns the first dir of the first page, by sort value, downloading from the provider
+func (s *subscriptionService) suggestedBaseDirs(ctx context.Context) (map[models.Provider]string, error) {
+	pages, err := s.unitOfWork.Pages.GetAllPages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(pages, func(a, b models.Page) int {
+		return a.SortValue - b.SortValue
+	})
+
+	dirs := make(map[models.Provider]string)
+	for _, page := range pages {
+		if len(page.Dirs) == 0 {
+			continue
+		}
+
+		for _, provider := range page.Providers {
+			if _, ok := dirs[models.Provider(provider)]; !ok {
+				dirs[models.Provider(provider)] = page.Dirs[0]
+			}
+		}
+	}
+
+	return dirs, nil
+}
+
+func (s *subscriptionService) ProposeTachiyomi(ctx context.Context, owner int, backup tachiyomi.Backup) (payload.TachiyomiImport, error) {
+	proposals := payload.TachiyomiImport{
+		Proposals: []payload.ProposedSubscription{},
+		Unmapped:  []payload.UnmappedEntry{},
+	}
+
+	dirs, err := s.suggestedBaseDirs(ctx)
+	if err != nil {
+		return payload.TachiyomiImport{}, err
+	}
+
+	seen := make(map[string]struct{})
+	for _, manga := range backup.Manga {
+		// Entries that aren't favorites have been removed from the library
+		if !manga.Favorite {
+			continue
+		}
+
+		source := backup.SourceName(manga.Source)
+		if source == "" {
+			source = strconv.FormatInt(manga.Source, 10)
+		}
+
+		unmapped := payload.UnmappedEntry{
+			Title:  manga.Title,
+			Source: source,
+			Url:    manga.Url,
+			Reason: payload.UnmappedUnsupportedSource,
+		}
+
+		u, err := url.Parse(strings.TrimSpace(manga.Url))
+		if err != nil {
+			unmapped.Reason = payload.UnmappedInvalidUrl
+			proposals.Unmapped = append(proposals.Unmapped, unmapped)
+			continue
+		}
+
+		provider, ok := tachiyomiProvider(source, u)
+		if !ok {
+			proposals.Unmapped = append(proposals.Unmapped, unmapped)
+			continue
+		}
+
+		contentId, ok := tachiyomiContentId(provider, u)
+		if !ok {
+			unmapped.Reason = payload.UnmappedInvalidUrl
+			proposals.Unmapped = append(proposals.Unmapped, unmapped)
+			continue
+		}
+
+		// The same series may be in the library from different language versions of the source
+		key := provider.String() + "/" + contentId
+		if _, ok = seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		proposal := payload.ProposedSubscription{
+			ExportedSubscription: payload.ExportedSubscription{
+				Provider:         provider,
+				ContentId:        contentId,
+				Title:            manga.Title,
+				BaseDir:          dirs[provider],
+				RefreshFrequency: models.Week,
+				Metadata:         models.DownloadRequestMetadata{},
+			},
+			Source:     source,
+			Categories: backup.CategoryNames(manga),
+		}
+
+		existing, err := s.unitOfWork.Subscriptions.GetByContentIDForUser(ctx, contentId, owner)
+		if err != nil {
+			return payload.TachiyomiImport{}, err
+		}
+		if existing != nil {
+			proposal.SubscriptionID = existing.ID
+		}
+
+		proposals.Proposals = append(proposals.Proposals, proposal)
+	}
+
+	s.log.Debug().Int("proposals", len(proposals.Proposals)).Int("unmapped", len(proposals.Unmapped)).
+		Msg("proposed subscriptions for Tachiyomi backup")
+	return proposals, nil
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/Fesaa/Media-Provider/db/models"
+	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/Fesaa/Media-Provider/internal/tachiyomi"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog"
+)
+
+func TestTachiyomiContentId(t *testing.T) {
+	tests := []struct {
+		name         string
+		source       string
+		url          string
+		wantProvider models.Provider
+		wantId       string
+		wantOk       bool
+	}{
+		{
+			name:         "mangadex",
+			source:       "MangaDex",
+			url:          "/manga/A96676E5-8AE2-425E-B549-7F15DD34A6D8",
+			wantProvider: models.MANGADEX,
+			wantId:       "a96676e5-8ae2-425e-b549-7f15dd34a6d8",
+			wantOk:       true,
+		},
+		{
+			name:         "mangadex full url without source",
+			url:          "https://mangadex.org/title/a96676e5-8ae2-425e-b549-7f15dd34a6d8/komi-san",
+			wantProvider: models.MANGADEX,
+			wantId:       "a96676e5-8ae2-425e-b549-7f15dd34a6d8",
+			wantOk:       true,
+		},
+		{
+			name:         "mangadex without uuid",
+			source:       "MangaDex",
+			url:          "/manga/komi-san",
+			wantProvider: models.MANGADEX,
+		},
+		{
+			name:         "bato series",
+			source:       "Bato.to",
+			url:          "/series/72315/the-greatest-estate-developer",
+			wantProvider: models.BATO,
+			wantId:       "72315",
+			wantOk:       true,
+		},
+		{
+			name:         "bato title",
+			source:       "Bato.to",
+			url:          "/title/110100-en-the-greatest-estate-developer",
+			wantProvider: models.BATO,
+			wantId:       "110100-en-the-greatest-estate-developer",
+			wantOk:       true,
+		},
+		{
+			name:         "mangabuddy",
+			source:       "MangaBuddy",
+			url:          "/the-greatest-estate-developer",
+			wantProvider: models.MANGA_BUDDY,
+			wantId:       "/the-greatest-estate-developer",
+			wantOk:       true,
+		},
+		{
+			name:         "mangabuddy chapter",
+			source:       "MangaBuddy",
+			url:          "/the-greatest-estate-developer/chapter-1",
+			wantProvider: models.MANGA_BUDDY,
+		},
+		{
+			name:         "webtoon",
+			source:       "Webtoons.com",
+			url:          "https://www.webtoons.com/en/fantasy/the-greatest-estate-developer/list?title_no=3596&page=2",
+			wantProvider: models.WEBTOON,
+			wantId:       "/en/fantasy/the-greatest-estate-developer/list?title_no=3596",
+			wantOk:       true,
+		},
+		{
+			name:         "webtoon without title",
+			source:       "Webtoons.com",
+			url:          "/en/fantasy/the-greatest-estate-developer/list",
+			wantProvider: models.WEBTOON,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			provider, ok := tachiyomiProvider(tt.source, u)
+			if !ok || provider != tt.wantProvider {
+				t.Fatalf("Got provider %v (%v); expected %v", provider, ok, tt.wantProvider)
+			}
+
+			id, ok := tachiyomiContentId(provider, u)
+			if id != tt.wantId || ok != tt.wantOk {
+				t.Errorf("Got %q (%v); expected %q (%v)", id, ok, tt.wantId, tt.wantOk)
+			}
+		})
+	}
+
+	if _, ok := tachiyomiProvider("Asura Scans", &url.URL{Path: "/series/solo-leveling"}); ok {
+		t.Error("Expected sources without provider not to be mapped")
+	}
+}
+
+func TestSubscriptionService_ProposeTachiyomi(t *testing.T) {
+	_, unitOfWork := tempQueueService(t)
+
+	s := &subscriptionService{
+		unitOfWork: unitOfWork,
+		log:        zerolog.Nop(),
+	}
+
+	for _, page := range []models.Page{
+		{Title: "Webtoons", SortValue: 2, Providers: pq.Int64Array{int64(models.WEBTOON), int64(models.MANGADEX)}, Dirs: pq.StringArray{"Webtoons"}},
+		{Title: "Manga", SortValue: 1, Providers: pq.Int64Array{int64(models.MANGADEX), int64(models.BATO)}, Dirs: pq.StringArray{"Manga", "Manhwa"}},
+		{Title: "Empty", SortValue: 0, Providers: pq.Int64Array{int64(models.MANGADEX)}},
+	} {
+		if err := unitOfWork.Pages.Create(t.Context(), &page); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	existing, err := unitOfWork.Subscriptions.New(t.Context(), models.Subscription{
+		Provider:         models.BATO,
+		ContentId:        "72315",
+		Title:            "The Greatest Estate Developer",
+		BaseDir:          "Manhwa",
+		RefreshFrequency: models.Week,
+		Owner:            1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Subscriptions of other users don't count as already subscribed
+	if _, err = unitOfWork.Subscriptions.New(t.Context(), models.Subscription{
+		Provider:         models.MANGADEX,
+		ContentId:        "a96676e5-8ae2-425e-b549-7f15dd34a6d8",
+		Title:            "Komi-san",
+		BaseDir:          "Manga",
+		RefreshFrequency: models.Week,
+		Owner:            2,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	backup := tachiyomi.Backup{
+		Manga: []tachiyomi.Manga{
+			{Source: 1, Url: "/manga/a96676e5-8ae2-425e-b549-7f15dd34a6d8", Title: "Komi-san", Categories: []int64{0}, Favorite: true},
+			// Another language version of the source
+			{Source: 4, Url: "/manga/a96676e5-8ae2-425e-b549-7f15dd34a6d8", Title: "Komi-san", Favorite: true},
+			{Source: 2, Url: "/series/72315/the-greatest-estate-developer", Title: "The Greatest Estate Developer", Favorite: true},
+			{Source: 3, Url: "/en/fantasy/tower-of-god/list?title_no=95", Title: "Tower of God", Favorite: true},
+			{Source: 5, Url: "/series/solo-leveling", Title: "Solo Leveling", Favorite: true},
+			{Source: 1, Url: "/manga/komi-san", Title: "Broken", Favorite: true},
+			{Source: 1, Url: "/manga/6b1eb93e-473a-4ab3-9922-1a66d2a29a4a", Title: "Removed"},
+		},
+		Categories: []tachiyomi.Category{{Name: "Reading", Order: 0}},
+		Sources: []tachiyomi.Source{
+			{Name: "MangaDex", ID: 1},
+			{Name: "Bato.to", ID: 2},
+			{Name: "Webtoons.com", ID: 3},
+			{Name: "MangaDex", ID: 4},
+			{Name: "Asura Scans", ID: 5},
+		},
+	}
+
+	proposals, err := s.ProposeTachiyomi(t.Context(), 1, backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(proposals.Proposals) != 3 || len(proposals.Unmapped) != 2 {
+		t.Fatalf("Got %+v; expected 3 proposals and 2 unmapped entries", proposals)
+	}
+
+	want := []struct {
+		provider models.Provider
+		baseDir  string
+	}{
+		{models.MANGADEX, "Manga"},
+		{models.BATO, "Manga"},
+		{models.WEBTOON, "Webtoons"},
+	}
+	for i, proposal := range proposals.Proposals {
+		if proposal.Provider != want[i].provider || proposal.BaseDir != want[i].baseDir {
+			t.Errorf("Got %s in %q; expected %s in %q", proposal.Provider, proposal.BaseDir, want[i].provider, want[i].baseDir)
+		}
+	}
+
+	if got := proposals.Proposals[0]; len(got.Categories) != 1 || got.Categories[0] != "Reading" || got.Source != "MangaDex" {
+		t.Errorf("Got %+v; expected the source and categories of the entry", got)
+	}
+	if got := proposals.Proposals[0].SubscriptionID; got != 0 {
+		t.Errorf("Got subscription id %d; expected the subscription of another user to be ignored", got)
+	}
+	if got := proposals.Proposals[1].SubscriptionID; got != existing.ID {
+		t.Errorf("Got subscription id %d; expected the existing subscription %d", got, existing.ID)
+	}
+
+	if got := proposals.Unmapped[0]; got.Reason != payload.UnmappedUnsupportedSource || got.Source != "Asura Scans" {
+		t.Errorf("Got %+v; expected the entry to be from an unsupported source", got)
+	}
+	if got := proposals.Unmapped[1]; got.Reason != payload.UnmappedInvalidUrl || got.Title != "Broken" {
+		t.Errorf("Got %+v; expected the entry to have an invalid url", got)
+	}
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Fesaa/Media-Provider/db/models"
+	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/Fesaa/Media-Provider/internal/release"
+	"github.com/Fesaa/Media-Provider/utils"
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+var infoHashRegex = regexp.MustCompile(`^[0-9a-fA-F]{40}$`)
+
+// torrentFilter is the compiled form of models.TorrentFilter
+type torrentFilter struct {
+	models.TorrentFilter
+	titleRegex *regexp.Regexp
+}
+
+func newTorrentFilter(f models.TorrentFilter) (*torrentFilter, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+
+	filter := &torrentFilter{TorrentFilter: f}
+	if f.TitleRegex != "" {
+		filter.titleRegex = regexp.MustCompile("(?i)" + f.TitleRegex)
+	}
+
+	return filter, nil
+}
+
+// matches returns true if the search result passes the filter. Results with an unknown amount of seeders or
+// size are not filtered on them, as not all providers return them
+func (f *torrentFilter) matches(info payload.Info) bool {
+	if f.titleRegex != nil && !f.titleRegex.MatchString(info.Name) {
+		return false
+	}
+
+	if f.Resolution != "" && !strings.EqualFold(release.Resolution(info.Name), f.Resolution) {
+		return false
+	}
+
+	if f.ReleaseGroup != "" && !strings.EqualFold(release.Group(info.Name), f.ReleaseGroup) {
+		return false
+	}
+
+	if seeders, ok := infoSeeders(info); ok && seeders < f.MinSeeders {
+		return false
+	}
+
+	size, ok := utils.SizeToBytes(info.Size)
+	if !ok {
+		return true
+	}
+
+	const mib = 1024 * 1024
+	if f.MinSize > 0 && size < f.MinSize*mib {
+		return false
+	}
+
+	return f.MaxSize == 0 || size <= f.MaxSize*mib
+}
+
+func infoSeeders(info payload.Info) (int, bool) {
+	for _, tag := range info.Tags {
+		if tag.Name != "Seeders" {
+			continue
+		}
+
+		seeders, err := strconv.Atoi(strings.TrimSpace(fmt.Sprint(tag.Value)))
+		return seeders, err == nil
+	}
+
+	return 0, false
+}
+
+// torrentKey returns the lowercase infohash of the search result. Falls back to its link if the result has none,
+// this is the case for some Torznab indexers
+func torrentKey(info payload.Info) string {
+	if infoHashRegex.MatchString(info.InfoHash) {
+		return strings.ToLower(info.InfoHash)
+	}
+
+	if m, err := metainfo.ParseMagnetUri(info.InfoHash); err == nil {
+		return m.InfoHash.HexString()
+	}
+
+	return utils.NonEmpty(info.InfoHash, info.Link)
+}
+
+// torrentSkipReason returns why the search result isn't downloaded, empty if it should be
+func torrentSkipReason(sub *models.Subscription, filter *torrentFilter, key string, info payload.Info) models.SkipReason {
+	if slices.Contains(sub.Grabbed, key) {
+		return models.SkipGrabbed
+	}
+
+	if !filter.matches(info) {
+		return models.SkipFiltered
+	}
+
+	return ""
+}
+
+// grabTorrents searches for the query of the torrent subscription, and downloads all matches that haven't been
+// downloaded before. Only fails if nothing could be downloaded
+func (s *subscriptionService) grabTorrents(ctx context.Context, sub *models.Subscription, isSub bool) error {
+	filter, err := newTorrentFilter(sub.TorrentSearch.Filter)
+	if err != nil {
+		return err
+	}
+
+	results, err := s.contentService.Search(ctx, payload.SearchRequest{
+		Provider:  []models.Provider{sub.Provider},
+		Query:     sub.ContentId,
+		Modifiers: sub.TorrentSearch.Modifiers,
+	})
+	if err != nil {
+		return err
+	}
+
+	if sub.Run != nil {
+		sub.Run.Found = len(results)
+	}
+
+	var grabbed []string
+	var errs []error
+	for _, info := range results {
+		key := torrentKey(info)
+		if key == "" {
+			continue
+		}
+
+		if reason := torrentSkipReason(sub, filter, key, info); reason != "" {
+			if sub.Run != nil {
+				sub.Run.Skip(info.Name, reason)
+			}
+			continue
+		}
+
+		err = s.contentService.Download(payload.DownloadRequest{
+			Provider:         info.Provider,
+			Id:               utils.NonEmpty(info.InfoHash, info.Link),
+			BaseDir:          sub.BaseDir,
+			TempTitle:        info.Name,
+			DownloadMetadata: sub.Payload,
+			OwnerId:          sub.Owner,
+
+			IsSubscription: isSub,
+			Sub:            sub,
+		})
+		if err != nil && !errors.Is(err, ErrContentAlreadyExists) {
+			s.log.Warn().Err(err).Int("id", sub.ID).Str("name", info.Name).Msg("failed to download torrent")
+			errs = append(errs, err)
+			continue
+		}
+
+		sub.Grabbed = append(sub.Grabbed, key)
+		grabbed = append(grabbed, info.Name)
+	}
+
+	s.log.Debug().Int("id", sub.ID).Int("results", len(results)).Int("grabbed", len(grabbed)).
+		Msg("ran torrent subscription")
+
+	if len(grabbed) == 0 {
+		return errors.Join(errs...)
+	}
+
+	// Failures are only returned when nothing was downloaded, record them on the run otherwise
+	if sub.Run != nil {
+		sub.Run.Downloaded = len(grabbed)
+		for _, err = range errs {
+			sub.Run.AddError(err)
+		}
+	}
+
+	if err = s.unitOfWork.Subscriptions.Update(ctx, *sub); err != nil {
+		s.log.Error().Err(err).Int("id", sub.ID).Msg("failed to save grabbed torrents, they may be downloaded again")
+	}
+
+	body := s.transloco.GetTranslation("torrent-sub-grabbed-body")
+	for _, name := range grabbed {
+		body += s.transloco.GetTranslation("content-line", name)
+	}
+
+	s.notifier.Notify(ctx, models.NewNotification().
+		WithTitle(s.transloco.GetTranslation("torrent-sub-grabbed-title")).
+		WithSummary(s.transloco.GetTranslation("torrent-sub-grabbed", sub.Title, len(grabbed))).
+		WithBody(body).
+		WithGroup(models.GroupContent).
+		WithColour(utils.Ternary(len(errs) > 0, models.Warning, models.Primary)).
+		WithOwner(sub.Owner).
+		WithRequiredRoles(models.ViewAllDownloads).
+		Build())
+
+	return nil
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/Fesaa/Media-Provider/db/models"
+	"github.com/Fesaa/Media-Provider/http/payload"
+	"github.com/Fesaa/Media-Provider/utils"
+	"github.com/Fesaa/Media-Provider/utils/mock"
+	"github.com/rs/zerolog"
+)
+
+func TestTorrentFilter_Matches(t *testing.T) {
+	info := payload.Info{
+		Name: "[SubsPlease] Sousou no Frieren - 12 (1080p) [ABCD1234].mkv",
+		Size: "1.40 GiB",
+		Tags: []payload.InfoTag{payload.Of("Seeders", "42")},
+	}
+
+	tests := []struct {
+		name   string
+		filter models.TorrentFilter
+		want   bool
+	}{
+		{name: "empty filter", filter: models.TorrentFilter{}, want: true},
+		{name: "title regex", filter: models.TorrentFilter{TitleRegex: `frieren - \d+`}, want: true},
+		{name: "title regex mismatch", filter: models.TorrentFilter{TitleRegex: `batch`}, want: false},
+		{name: "resolution", filter: models.TorrentFilter{Resolution: "1080P"}, want: true},
+		{name: "resolution mismatch", filter: models.TorrentFilter{Resolution: "720p"}, want: false},
+		{name: "release group", filter: models.TorrentFilter{ReleaseGroup: "subsplease"}, want: true},
+		{name: "release group mismatch", filter: models.TorrentFilter{ReleaseGroup: "Erai-raws"}, want: false},
+		{name: "seeders", filter: models.TorrentFilter{MinSeeders: 42}, want: true},
+		{name: "too few seeders", filter: models.TorrentFilter{MinSeeders: 50}, want: false},
+		{name: "size range", filter: models.TorrentFilter{MinSize: 1024, MaxSize: 2048}, want: true},
+		{name: "too large", filter: models.TorrentFilter{MaxSize: 1024}, want: false},
+		{name: "too small", filter: models.TorrentFilter{MinSize: 2048}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := newTorrentFilter(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := filter.matches(info); got != tt.want {
+				t.Errorf("Got %v; expected %v", got, tt.want)
+			}
+		})
+	}
+
+	// Unknown sizes and seeders aren't filtered on
+	filter, _ := newTorrentFilter(models.TorrentFilter{MinSeeders: 10, MinSize: 1})
+	if !filter.matches(payload.Info{Name: "Frieren", Size: "Unknown"}) {
+		t.Error("Expected a result without size and seeders to match")
+	}
+}
+
+func TestTorrentKey(t *testing.T) {
+	const hash = "c9e15763f722f23e98a29decdfae341b98d53056"
+
+	tests := []struct {
+		info payload.Info
+		want string
+	}{
+		{info: payload.Info{InfoHash: "C9E15763F722F23E98A29DECDFAE341B98D53056"}, want: hash},
+		{info: payload.Info{InfoHash: "magnet:?xt=urn:btih:" + hash + "&dn=Frieren"}, want: hash},
+		{info: payload.Info{Link: "https://indexer.example.org/download/1.torrent"}, want: "https://indexer.example.org/download/1.torrent"},
+		{info: payload.Info{}, want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := torrentKey(tt.info); got != tt.want {
+			t.Errorf("torrentKey(%+v) = %q; want %q", tt.info, got, tt.want)
+		}
+	}
+}
+
+type torrentSearchMock struct {
+	results    []payload.Info
+	downloaded *[]payload.DownloadRequest
+}
+
+func (m torrentSearchMock) DownloadMetadata() payload.DownloadMetadata {
+	return payload.DownloadMetadata{}
+}
+
+func (m torrentSearchMock) Search(ctx context.Context, request payload.SearchRequest) ([]payload.Info, error) {
+	return m.results, nil
+}
+
+func (m torrentSearchMock) Client() Client {
+	return recordingClient{downloaded: m.downloaded}
+}
+
+type recordingClient struct {
+	mockClient
+	downloaded *[]payload.DownloadRequest
+}
+
+func (c recordingClient) Download(req payload.DownloadRequest) error {
+	*c.downloaded = append(*c.downloaded, req)
+	return nil
+}
+
+func TestSubscriptionService_GrabTorrents(t *testing.T) {
+	_, unitOfWork := tempQueueService(t)
+
+	var downloaded []payload.DownloadRequest
+	cs := ContentServiceProvider(zerolog.Nop())
+	cs.RegisterProvider(models.NYAA, torrentSearchMock{
+		downloaded: &downloaded,
+		results: []payload.Info{
+			{Name: "[SubsPlease] Frieren - 11 (1080p)", InfoHash: "C9E15763F722F23E98A29DECDFAE341B98D53056", Provider: models.NYAA},
+			{Name: "[SubsPlease] Frieren - 11 (720p)", InfoHash: "0123456789abcdef0123456789abcdef01234567", Provider: models.NYAA},
+		},
+	})
+
+	s := &subscriptionService{
+		contentService: cs,
+		notifier:       mock.Notifications{},
+		transloco:      mock.Transloco{},
+		unitOfWork:     unitOfWork,
+		log:            zerolog.Nop(),
+	}
+
+	sub, err := unitOfWork.Subscriptions.New(t.Context(), models.Subscription{
+		Provider:         models.NYAA,
+		ContentId:        "Frieren",
+		Title:            "Frieren",
+		BaseDir:          "Anime",
+		RefreshFrequency: models.Week,
+		TorrentSearch: models.TorrentSearch{
+			Filter: models.TorrentFilter{Resolution: "1080p"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = s.RunOnce(t.Context(), sub, models.TriggerRunOnce); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(downloaded) != 1 || downloaded[0].Id != "C9E15763F722F23E98A29DECDFAE341B98D53056" {
+		t.Fatalf("Got %+v; expected only the 1080p release to be downloaded", downloaded)
+	}
+	if downloaded[0].BaseDir != "Anime" {
+		t.Errorf("Got dir %s; expected the dir of the subscription", downloaded[0].BaseDir)
+	}
+
+	saved, err := unitOfWork.Subscriptions.Get(t.Context(), sub.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(saved.Grabbed, []string{"c9e15763f722f23e98a29decdfae341b98d53056"}) {
+		t.Errorf("Got grabbed %v; expected the infohash to be remembered", saved.Grabbed)
+	}
+	if saved.TorrentSearch.Filter.Resolution != "1080p" {
+		t.Errorf("Got %+v; expected the torrent search to be saved", saved.TorrentSearch)
+	}
+
+	if err = s.RunOnce(t.Context(), saved, models.TriggerRunOnce); err != nil {
+		t.Fatal(err)
+	}
+	if len(downloaded) != 1 {
+		t.Errorf("Got %d downloads; expected grabbed torrents not to be downloaded again", len(downloaded))
+	}
+
+	history, err := s.History(t.Context(), sub.ID, utils.UserParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history.Items) != 2 {
+		t.Fatalf("Got %d runs; expected both runs to be recorded", len(history.Items))
+	}
+
+	last := history.Items[0]
+	if last.Trigger != models.TriggerRunOnce || last.FinishedAt.IsZero() || last.Found != 2 || last.Downloaded != 0 {
+		t.Errorf("Got %+v; expected a finished run without downloads", last)
+	}
+
+	want := []models.SkippedContent{
+		{Name: "[SubsPlease] Frieren - 11 (1080p)", Reason: models.SkipGrabbed},
+		{Name: "[SubsPlease] Frieren - 11 (720p)", Reason: models.SkipFiltered},
+	}
+	if !slices.Equal(last.Skipped, want) {
+		t.Errorf("Got skipped %v; expected %v", last.Skipped, want)
+	}
+	if history.Items[1].Downloaded != 1 {
+		t.Errorf("Got %d downloaded; expected the first run to download the 1080p release", history.Items[1].Downloaded)
+	}
+}
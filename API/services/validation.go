@@ -22,6 +22,9 @@ func ValidatorProvider() (*validator.Validate, error) {
 	err := utils.Errs(
 		val.RegisterValidation("provider", isValidProvider),
 		val.RegisterValidation("diff", diffValidator),
+		val.RegisterValidation("naming_template", isValidNamingTemplate),
+		val.RegisterValidation("file_name_template", isValidFileNameTemplate),
+		val.RegisterValidation("file_rules", isValidFileRules),
 	)
 
 	return val, err
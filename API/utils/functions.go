@@ -120,6 +120,36 @@ func BytesToSize(bytes float64) string {
 	return fmt.Sprintf("%.2f %s", bytes/math.Pow(1024, i), sizes[int(i)])
 }
 
+// SizeToBytes parses a human-readable size like "1.5 GiB" or "700 MB", units are always powers of 1024.
+// Returns false if s isn't a size
+func SizeToBytes(s string) (int64, bool) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return 0, false
+	}
+
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil || value < 0 {
+		return 0, false
+	}
+
+	unit := strings.ToUpper(strings.Replace(fields[1], "i", "", 1))
+	switch unit {
+	case "B", "BYTE", "BYTES":
+		return int64(value), true
+	case "KB":
+		return int64(value * 1024), true
+	case "MB":
+		return int64(value * math.Pow(1024, 2)), true
+	case "GB":
+		return int64(value * math.Pow(1024, 3)), true
+	case "TB":
+		return int64(value * math.Pow(1024, 4)), true
+	}
+
+	return 0, false
+}
+
 type Invoker interface {
 	Invoke(function interface{}, opts ...dig.InvokeOption) (err error)
 }
@@ -70,6 +70,28 @@ func TestBytesToSize(t *testing.T) {
 	}
 }
 
+func TestSizeToBytes(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+		ok   bool
+	}{
+		{in: "1.00 KB", want: 1024, ok: true},
+		{in: "833.46 MB", want: 873946152, ok: true},
+		{in: "1.5 GiB", want: 1610612736, ok: true},
+		{in: "0 Byte", want: 0, ok: true},
+		{in: "Unknown", ok: false},
+		{in: "12 parsecs", ok: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := SizeToBytes(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("SizeToBytes(%q) = (%d, %v); want (%d, %v)", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
 func TestStringify(t *testing.T) {
 	if Stringify(1) != "1" {
 		t.Errorf("Stringify(1) = %v; want \"1\"", Stringify(1))
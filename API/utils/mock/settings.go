@@ -23,6 +23,7 @@ func (s *Settings) GetSettingsDto(context.Context) (payload.Settings, error) {
 			RedisAddr:             "",
 			MaxConcurrentTorrents: 5,
 			MaxConcurrentImages:   5,
+			MaxConcurrentChapters: 1,
 			DisableIpv6:           false,
 			RootDir:               "temp",
 			Oidc: payload.OidcSettings{
@@ -14,8 +14,8 @@ type SpeedTracker struct {
 	maxItem   int
 	cur       int
 
-	// For tracking intermediate progress of current work item
-	intermediate     *SpeedTracker
+	// For tracking intermediate progress of work items in progress, keyed by work item
+	intermediates    map[string]*SpeedTracker
 	intermediateLock sync.RWMutex
 }
 
@@ -23,9 +23,10 @@ type SpeedTracker struct {
 func NewSpeedTracker(maxItem int) *SpeedTracker {
 	now := time.Now()
 	return &SpeedTracker{
-		maxItem:   maxItem,
-		lastCheck: now,
-		startTime: now,
+		maxItem:       maxItem,
+		lastCheck:     now,
+		startTime:     now,
+		intermediates: make(map[string]*SpeedTracker),
 	}
 }
 
@@ -40,16 +41,21 @@ func (s *SpeedTracker) Increment() {
 
 // IncrementIntermediate increments the intermediate tracker if it exists
 func (s *SpeedTracker) IncrementIntermediate() {
-	s.intermediateLock.Lock()
-	defer s.intermediateLock.Unlock()
+	s.IncrementIntermediateFor("")
+}
+
+// IncrementIntermediateFor increments the intermediate tracker of the work item if it exists
+func (s *SpeedTracker) IncrementIntermediateFor(key string) {
+	s.intermediateLock.RLock()
+	defer s.intermediateLock.RUnlock()
 
-	if s.intermediate != nil {
-		s.intermediate.Increment()
+	if intermediate, ok := s.intermediates[key]; ok {
+		intermediate.Increment()
 	}
 }
 
 // Progress returns the completion percentage (0-100)
-// If an intermediate tracker exists, includes its fractional progress
+// If intermediate trackers exist, includes their fractional progress
 func (s *SpeedTracker) Progress() float64 {
 	s.lock.RLock()
 	cur := s.cur
@@ -62,10 +68,10 @@ func (s *SpeedTracker) Progress() float64 {
 	progress := float64(cur)
 	intermediateProgress := 0.0
 
-	// Add fractional progress from intermediate tracker
+	// Add fractional progress from intermediate trackers
 	s.intermediateLock.RLock()
-	if s.intermediate != nil {
-		intermediateProgress = s.intermediate.Progress() / float64(s.maxItem)
+	for _, intermediate := range s.intermediates {
+		intermediateProgress += intermediate.Progress() / float64(s.maxItem)
 	}
 	s.intermediateLock.RUnlock()
 
@@ -84,30 +90,43 @@ func (s *SpeedTracker) Speed() float64 {
 	return float64(s.cur) / elapsed
 }
 
-// IntermediateSpeed returns the speed of the intermediate tracker if it exists
+// IntermediateSpeed returns the combined speed of all intermediate trackers
 func (s *SpeedTracker) IntermediateSpeed() float64 {
 	s.intermediateLock.RLock()
 	defer s.intermediateLock.RUnlock()
-	if s.intermediate != nil {
-		return s.intermediate.Speed()
+
+	speed := 0.0
+	for _, intermediate := range s.intermediates {
+		speed += intermediate.Speed()
 	}
-	return 0
+	return speed
 }
 
 // SetIntermediate sets the intermediate progress tracker for the current work item
 func (s *SpeedTracker) SetIntermediate(maxItem int) {
+	s.SetIntermediateFor("", maxItem)
+}
+
+// SetIntermediateFor sets the intermediate progress tracker for the work item, use when several work items
+// are in progress at once
+func (s *SpeedTracker) SetIntermediateFor(key string, maxItem int) {
 	s.intermediateLock.Lock()
 	defer s.intermediateLock.Unlock()
 
-	s.intermediate = NewSpeedTracker(maxItem)
+	s.intermediates[key] = NewSpeedTracker(maxItem)
 }
 
 // ClearIntermediate removes the intermediate tracker (call when work item completes)
 func (s *SpeedTracker) ClearIntermediate() {
+	s.ClearIntermediateFor("")
+}
+
+// ClearIntermediateFor removes the intermediate tracker of the work item
+func (s *SpeedTracker) ClearIntermediateFor(key string) {
 	s.intermediateLock.Lock()
 	defer s.intermediateLock.Unlock()
 
-	s.intermediate = nil
+	delete(s.intermediates, key)
 }
 
 func (s *SpeedTracker) EstimatedTimeRemaining() float64 {
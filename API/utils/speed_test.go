@@ -138,3 +138,42 @@ func TestProgressMonotonicallyIncreasing(t *testing.T) {
 		t.Errorf("Expected final progress=100%%, got %f%%", lastProgress)
 	}
 }
+
+func TestProgressWithKeyedIntermediates(t *testing.T) {
+	tracker := NewSpeedTracker(10)
+
+	tracker.SetIntermediateFor("a", 10)
+	tracker.SetIntermediateFor("b", 20)
+
+	for range 5 {
+		tracker.IncrementIntermediateFor("a")
+	}
+	for range 10 {
+		tracker.IncrementIntermediateFor("b")
+	}
+	// Unknown work items are ignored
+	tracker.IncrementIntermediateFor("c")
+
+	// Half of two items out of 10 = 10%
+	expected := 10.0
+	if progress := tracker.Progress(); progress != expected {
+		t.Errorf("Expected %f%%, got %f%%", expected, progress)
+	}
+
+	for range 5 {
+		tracker.IncrementIntermediateFor("a")
+	}
+	tracker.Increment()
+	tracker.ClearIntermediateFor("a")
+
+	// One item done, and half of another = 15%
+	expected = 15.0
+	if progress := tracker.Progress(); progress != expected {
+		t.Errorf("Expected %f%%, got %f%%", expected, progress)
+	}
+
+	tracker.ClearIntermediateFor("b")
+	if speed := tracker.IntermediateSpeed(); speed != 0 {
+		t.Errorf("Expected no intermediate speed, got %f", speed)
+	}
+}
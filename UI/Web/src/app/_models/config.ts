@@ -4,13 +4,90 @@ export type Config = {
   redisAddr: string;
   maxConcurrentTorrents: number;
   maxConcurrentImages: number;
+  maxConcurrentChapters: number;
   disableIpv6: boolean;
   rootDir: string;
+  seeding: SeedingConfig;
+  torrent: TorrentConfig;
   oidc: OidcConfig;
   subscriptionRefreshHour: number;
+  subscriptionHistory: HistoryConfig;
   metadata: Metadata;
 }
 
+export type HistoryConfig = {
+  maxAge: number;
+  maxRuns: number;
+}
+
+export type SeedingConfig = {
+  ratio: number;
+  minSeedTime: number;
+  maxTorrents: number;
+}
+
+export type TorrentConfig = {
+  backend: TorrentBackend;
+  remote: RemoteClientConfig;
+  stall: StallConfig;
+  uploadLimit: number;
+  downloadLimit: number;
+  torrentUploadLimit: number;
+  torrentDownloadLimit: number;
+  altSpeed: AltSpeedConfig;
+  listenPort: number;
+  maxPeers: number;
+  encryption: EncryptionPolicy;
+  disableDht: boolean;
+  disablePex: boolean;
+}
+
+export enum TorrentBackend {
+  Embedded = "embedded",
+  QBittorrent = "qbittorrent",
+  Transmission = "transmission",
+}
+
+export const TorrentBackends = [TorrentBackend.Embedded, TorrentBackend.QBittorrent, TorrentBackend.Transmission];
+
+export type RemoteClientConfig = {
+  url: string;
+  username: string;
+  password: string;
+  rootDir: string;
+}
+
+export type StallConfig = {
+  metadataTimeout: number;
+  progressTimeout: number;
+  action: StallAction;
+}
+
+export enum StallAction {
+  Pause = "pause",
+  Requeue = "requeue",
+  Remove = "remove",
+}
+
+export const StallActions = [StallAction.Pause, StallAction.Requeue, StallAction.Remove];
+
+export type AltSpeedConfig = {
+  uploadLimit: number;
+  downloadLimit: number;
+  scheduled: boolean;
+  days: number[];
+  from: string;
+  to: string;
+}
+
+export enum EncryptionPolicy {
+  Prefer = "prefer",
+  Require = "require",
+  Disable = "disable",
+}
+
+export const EncryptionPolicies = [EncryptionPolicy.Prefer, EncryptionPolicy.Require, EncryptionPolicy.Disable];
+
 export type OidcConfig = {
   authority: string;
   clientId: string;
@@ -14,8 +14,15 @@ export type ListContentData = {
   children: ListContentData[];
 }
 
+export type EpisodeMove = {
+  episode: string;
+  from: string;
+  to: string;
+}
+
 export enum MessageType {
   MessageListContent = 0,
   SetToDownload = 1,
   StartDownload = 2,
+  PreviewEpisodes = 3,
 }
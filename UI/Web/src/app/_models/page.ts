@@ -7,6 +7,31 @@ export type Page = {
   modifiers: Modifier[];
   dirs: string[];
   customRootDir: string;
+  fileNameTemplate: string;
+  volumeDirTemplate: string;
+  seedRatio?: number | null;
+  seedTime?: number | null;
+  episodeTemplate: string;
+  seasonDirTemplate: string;
+  fileRules: FileRules;
+}
+
+export type FileRules = {
+  include: string[] | null;
+  exclude: string[] | null;
+  extensions: string[] | null;
+  minSize: number;
+  maxSize: number;
+  largestVideoOnly: boolean;
+}
+
+export const EmptyFileRules: FileRules = {
+  include: [],
+  exclude: [],
+  extensions: [],
+  minSize: 0,
+  maxSize: 0,
+  largestVideoOnly: false,
 }
 
 export type Modifier = {
@@ -31,7 +56,8 @@ export enum Provider {
   WEBTOON,
   DYNASTY,
   BATO,
-  MANGABUDDY
+  MANGABUDDY,
+  TORZNAB
 }
 
 export const Providers = [
@@ -70,12 +96,18 @@ export const Providers = [
   {
     label:"Manga buddy",
     value: Provider.MANGABUDDY
+  },
+  {
+    label: "Torznab",
+    value: Provider.TORZNAB
   }
 ];
 
 
 export const AllProviders = Object.values(Provider).filter(value => typeof value === 'number') as number[];
 
+export const TorrentProviders = [Provider.NYAA, Provider.YTS, Provider.LIMETORRENTS, Provider.SUBSPLEASE, Provider.TORZNAB];
+
 export enum ModifierType {
   DROPDOWN = 1,
   MULTI,
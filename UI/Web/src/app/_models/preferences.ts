@@ -3,6 +3,7 @@ export type Preferences = {
   logEmptyDownloads: boolean,
   logSubNoDownloads: boolean,
   convertToWebp: boolean,
+  writeMetronInfo: boolean,
   coverFallbackMethod: CoverFallbackMethod,
   genreList: string[],
   blackList: string[],
@@ -31,6 +32,7 @@ export type TagMap = {
 export type AgeRatingMap = {
   tag: string
   comicInfoAgeRating: ComicInfoAgeRating
+  metronAgeRating?: MetronAgeRating
 }
 
 export enum ComicInfoAgeRating {
@@ -114,4 +116,43 @@ export const ComicInfoAgeRatings = [
   }
 ];
 
+export enum MetronAgeRating {
+  Unknown = "Unknown",
+  Everyone = "Everyone",
+  Teen = "Teen",
+  TeenPlus = "Teen Plus",
+  Mature = "Mature",
+  Explicit = "Explicit",
+  Adult = "Adult",
+}
 
+export const MetronAgeRatings = [
+  {
+    label: "Unknown",
+    value: MetronAgeRating.Unknown,
+  },
+  {
+    label: "Everyone",
+    value: MetronAgeRating.Everyone,
+  },
+  {
+    label: "Teen",
+    value: MetronAgeRating.Teen,
+  },
+  {
+    label: "Teen Plus",
+    value: MetronAgeRating.TeenPlus,
+  },
+  {
+    label: "Mature",
+    value: MetronAgeRating.Mature,
+  },
+  {
+    label: "Explicit",
+    value: MetronAgeRating.Explicit,
+  },
+  {
+    label: "Adult",
+    value: MetronAgeRating.Adult,
+  },
+];
@@ -13,6 +13,8 @@ export type InfoStat = {
   speed_type: SpeedType;
   speed: number;
   download_dir: string;
+  seeding: boolean;
+  ratio?: number;
 }
 
 export enum ContentState {
@@ -22,6 +24,8 @@ export enum ContentState {
   Ready = 3,
   Downloading = 4,
   Cleanup = 5,
+  Seeding = 6,
+  Paused = 7,
 }
 
 export enum SpeedType {
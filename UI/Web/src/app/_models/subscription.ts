@@ -6,6 +6,17 @@ export type Subscription = {
   provider: Provider;
   contentId: string;
   refreshFrequency: RefreshFrequency;
+  /** Runs the subscription on its own schedule, takes precedence over interval */
+  cronExpression: string;
+  /** In minutes, 0 means the refresh frequency is used */
+  interval: number;
+  /** Runs around the predicted release, between adaptiveMin and adaptiveMax hours */
+  adaptive: boolean;
+  adaptiveMin: number;
+  adaptiveMax: number;
+  /** Estimated hours between releases, 0 if unknown */
+  cadence: number;
+  predictedRelease: Date;
   title: string;
   description?: string;
   baseDir: string;
@@ -14,6 +25,143 @@ export type Subscription = {
   lastCheckSuccess: boolean;
   nextExecution: Date;
   metadata: DownloadRequestMetadata;
+  torrentSearch?: TorrentSearch;
+  grabbed?: string[];
+}
+
+export type TorrentSearch = {
+  modifiers?: { [key: string]: string[] };
+  filter: TorrentFilter;
+}
+
+export type TorrentFilter = {
+  titleRegex: string;
+  resolution: string;
+  releaseGroup: string;
+  minSeeders: number;
+  /** In MiB, 0 means no limit */
+  minSize: number;
+  /** In MiB, 0 means no limit */
+  maxSize: number;
+}
+
+export enum RunTrigger {
+  Cron = "cron",
+  RunOnce = "run-once",
+  RunAll = "run-all",
+}
+
+export type SkippedContent = {
+  name: string;
+  reason: "on-disk" | "no-chapter" | "failed" | "grabbed" | "filtered";
+}
+
+export type SubscriptionRun = {
+  ID: number;
+  subscriptionId: number;
+  trigger: RunTrigger;
+  startedAt: Date;
+  /** Zero while the run is in progress */
+  finishedAt: Date;
+  /** In milliseconds */
+  duration: number;
+  found: number;
+  downloaded: number;
+  skipped?: SkippedContent[];
+  errors?: string[];
+  /** Relative to the root dir */
+  files?: string[];
+}
+
+export type SubscriptionExport = {
+  version: number;
+  exportedAt: Date;
+  subscriptions: ExportedSubscription[];
+}
+
+export type ExportedSubscription = {
+  provider: Provider;
+  contentId: string;
+  title: string;
+  baseDir: string;
+  refreshFrequency: RefreshFrequency;
+  cronExpression?: string;
+  interval?: number;
+  adaptive?: boolean;
+  adaptiveMin?: number;
+  adaptiveMax?: number;
+  metadata: DownloadRequestMetadata;
+  torrentSearch?: TorrentSearch;
+  grabbed?: string[];
+}
+
+export enum ImportStatus {
+  Created = "created",
+  Duplicate = "duplicate",
+  Invalid = "invalid",
+  Failed = "failed",
+}
+
+export type SubscriptionImportResult = {
+  index: number;
+  contentId: string;
+  title: string;
+  status: ImportStatus;
+  id?: number;
+  error?: string;
+}
+
+export type SubscriptionImportReport = {
+  created: number;
+  duplicates: number;
+  failed: number;
+  entries: SubscriptionImportResult[];
+}
+
+export type ProposedSubscription = ExportedSubscription & {
+  source: string;
+  categories: string[];
+  subscriptionId?: number;
+}
+
+export enum UnmappedReason {
+  UnsupportedSource = "unsupported-source",
+  InvalidUrl = "invalid-url",
+}
+
+export type UnmappedEntry = {
+  title: string;
+  source: string;
+  url: string;
+  reason: UnmappedReason;
+}
+
+export type TachiyomiImport = {
+  proposals: ProposedSubscription[];
+  unmapped: UnmappedEntry[];
+}
+
+export type PagedList<T> = {
+  items?: T[];
+  currentPage: number;
+  pageSize: number;
+  totalPages: number;
+}
+
+export type ReorganiseMove = {
+  chapter: string;
+  from: string;
+  to: string;
+}
+
+export type ReorganiseReport = {
+  title: string;
+  dryRun: boolean;
+  upToDate: number;
+  moves: ReorganiseMove[];
+  conflicts: ReorganiseMove[];
+  unmatched: string[];
+  errors: string[];
 }
 
 export enum RefreshFrequency {
@@ -0,0 +1,9 @@
+export type TorznabIndexer = {
+  ID: number;
+  name: string;
+  url: string;
+  /**
+   * Masked by the server, sending the masked value back keeps the stored key
+   */
+  apiKey: string;
+}
@@ -20,6 +20,10 @@ export class ContentStatePipe implements PipeTransform {
         return "Waiting";
       case ContentState.Cleanup:
         return "Cleanup";
+      case ContentState.Seeding:
+        return "Seeding";
+      case ContentState.Paused:
+        return "Paused";
       default:
         return "Unknown";
     }
@@ -26,6 +26,8 @@ export class ProviderNamePipe implements PipeTransform {
         return "Bato";
       case Provider.MANGABUDDY:
         return "Manga buddy"
+      case Provider.TORZNAB:
+        return "Torznab";
       default:
         return "Unknown";
     }
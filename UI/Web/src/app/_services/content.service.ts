@@ -5,7 +5,7 @@ import {map, Observable} from "rxjs";
 import {StatsResponse} from "../_models/stats";
 import {DownloadRequest, SearchRequest, StopRequest} from "../_models/search";
 import {SearchInfo} from "../_models/Info";
-import {ListContentData, Message, MessageType} from "../_models/messages";
+import {EpisodeMove, ListContentData, Message, MessageType} from "../_models/messages";
 import {Provider} from "../_models/page";
 
 @Injectable({
@@ -43,6 +43,14 @@ export class ContentService {
     }).pipe(map(list => list || []));
   }
 
+  previewEpisodes(provider: Provider, contentId: string): Observable<EpisodeMove[]> {
+    return this.sendMessage<void, EpisodeMove[]>({
+      provider: provider,
+      contentId: contentId,
+      type: MessageType.PreviewEpisodes,
+    }).pipe(map(moves => moves || []));
+  }
+
   search(req: SearchRequest): Observable<SearchInfo[]> {
     return this.httpClient.post<SearchInfo[]>(this.baseUrl + 'search', req)
   }
@@ -51,6 +59,13 @@ export class ContentService {
     return this.httpClient.post(this.baseUrl + 'download', req);
   }
 
+  downloadTorrent(req: DownloadRequest, file: File) {
+    const formData = new FormData();
+    formData.append('torrent', file, file.name);
+    formData.append('request', JSON.stringify(req));
+    return this.httpClient.post(this.baseUrl + 'download/torrent', formData);
+  }
+
   stop(req: StopRequest) {
     return this.httpClient.post(this.baseUrl + 'stop', req)
   }
@@ -1,7 +1,15 @@
 import {Injectable} from '@angular/core';
 import {environment} from "../../environments/environment";
 import {HttpClient} from "@angular/common/http";
-import {Subscription} from "../_models/subscription";
+import {
+  PagedList,
+  ReorganiseReport,
+  Subscription,
+  SubscriptionExport,
+  SubscriptionImportReport,
+  SubscriptionRun,
+  TachiyomiImport
+} from "../_models/subscription";
 import {Observable} from "rxjs";
 import {Provider} from "../_models/page";
 
@@ -19,10 +27,18 @@ export class SubscriptionService {
     return this.httpClient.get<Subscription>(`${this.baseUrl}/${id}`);
   }
 
+  history(id: number, pageNumber: number = 0, pageSize: number = 10): Observable<PagedList<SubscriptionRun>> {
+    return this.httpClient.get<PagedList<SubscriptionRun>>(`${this.baseUrl}/${id}/history?pageNumber=${pageNumber}&pageSize=${pageSize}`);
+  }
+
   runOnce(id: number) {
     return this.httpClient.post(`${this.baseUrl}/run-once/${id}`, {}, {responseType: 'text'})
   }
 
+  reorganise(id: number, dryRun: boolean) {
+    return this.httpClient.post<ReorganiseReport>(`${this.baseUrl}/${id}/reorganise?dryRun=${dryRun}`, {});
+  }
+
   runAll() {
     return this.httpClient.post(`${this.baseUrl}/run-all`, {});
   }
@@ -31,6 +47,20 @@ export class SubscriptionService {
     return this.httpClient.delete(`${this.baseUrl}/${id}`, {responseType: 'text'});
   }
 
+  export(allUsers: boolean): Observable<SubscriptionExport> {
+    return this.httpClient.get<SubscriptionExport>(`${this.baseUrl}/export?allUsers=${allUsers}`);
+  }
+
+  import(data: SubscriptionExport): Observable<SubscriptionImportReport> {
+    return this.httpClient.post<SubscriptionImportReport>(`${this.baseUrl}/import`, data);
+  }
+
+  importTachiyomi(file: File): Observable<TachiyomiImport> {
+    const formData = new FormData();
+    formData.append('backup', file, file.name);
+    return this.httpClient.post<TachiyomiImport>(`${this.baseUrl}/import/tachiyomi`, formData);
+  }
+
   all(): Observable<Subscription[]> {
     return this.httpClient.get<Subscription[]>(`${this.baseUrl}/all`);
   }
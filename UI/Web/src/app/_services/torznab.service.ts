@@ -0,0 +1,36 @@
+import {inject, Injectable} from '@angular/core';
+import {HttpClient} from "@angular/common/http";
+import {environment} from "../../environments/environment";
+import {TorznabIndexer} from "../_models/torznab";
+import {Modifier} from "../_models/page";
+
+@Injectable({
+  providedIn: 'root'
+})
+export class TorznabService {
+
+  private readonly httpClient = inject(HttpClient);
+
+  baseUrl = environment.apiUrl + "torznab/";
+
+  all() {
+    return this.httpClient.get<TorznabIndexer[]>(this.baseUrl);
+  }
+
+  new(indexer: TorznabIndexer) {
+    return this.httpClient.post<TorznabIndexer>(this.baseUrl + 'new', indexer);
+  }
+
+  update(indexer: TorznabIndexer) {
+    return this.httpClient.post<TorznabIndexer>(this.baseUrl + 'update', indexer);
+  }
+
+  delete(id: number) {
+    return this.httpClient.delete(this.baseUrl + id);
+  }
+
+  modifiers(id: number) {
+    return this.httpClient.get<Modifier[]>(this.baseUrl + `${id}/modifiers`);
+  }
+
+}
@@ -1,13 +1,14 @@
 import {ChangeDetectionStrategy, Component, computed, inject, model, OnInit, signal} from '@angular/core';
 import {InfoStat} from '../../../_models/stats';
 import {ContentService} from '../../../_services/content.service';
-import {ListContentData} from '../../../_models/messages';
+import {EpisodeMove, ListContentData} from '../../../_models/messages';
 import {ToastService} from '../../../_services/toast.service';
 import {TranslocoDirective} from '@jsverse/transloco';
 import {NgbActiveModal} from "@ng-bootstrap/ng-bootstrap";
 import {LoadingSpinnerComponent} from "../../../shared/_component/loading-spinner/loading-spinner.component";
 import {NgTemplateOutlet} from "@angular/common";
 import {BadgeComponent} from "../../../shared/_component/badge/badge.component";
+import {TorrentProviders} from "../../../_models/page";
 
 @Component({
   selector: 'app-content-picker-dialog',
@@ -29,6 +30,12 @@ export class ContentPickerDialogComponent implements OnInit {
   selection = signal<string[]>([]);
   loading = signal(true);
 
+  /**
+   * Where episodes end up once the torrent completes, for the last saved selection
+   */
+  episodes = signal<EpisodeMove[] | undefined>(undefined);
+  isTorrent = computed(() => TorrentProviders.includes(this.info().provider));
+
   toggles = signal<Set<string>>(new Set());
   allToggled = computed(() => this.content().length === this.toggles().size);
 
@@ -133,6 +140,20 @@ export class ContentPickerDialogComponent implements OnInit {
     this.content.update(c => [...c].reverse());
   }
 
+  togglePreview(): void {
+    if (this.episodes() !== undefined) {
+      this.episodes.set(undefined);
+      return;
+    }
+
+    this.contentService.previewEpisodes(this.info().provider, this.info().id).subscribe({
+      next: moves => this.episodes.set(moves),
+      error: err => {
+        this.toastService.genericError(err?.error?.message ?? 'Unknown error');
+      }
+    });
+  }
+
   close(): void {
     this.modal.close();
   }
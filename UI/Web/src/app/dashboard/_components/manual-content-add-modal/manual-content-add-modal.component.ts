@@ -1,4 +1,4 @@
-import {ChangeDetectionStrategy, Component, inject} from '@angular/core';
+import {ChangeDetectionStrategy, Component, inject, signal} from '@angular/core';
 import {ContentService} from "../../../_services/content.service";
 import {ToastService} from "../../../_services/toast.service";
 import {NgbActiveModal} from "@ng-bootstrap/ng-bootstrap";
@@ -6,7 +6,7 @@ import {ModalService} from "../../../_services/modal.service";
 import {DownloadModalComponent} from "../../../page/_components/download-modal/download-modal.component";
 import {DefaultModalOptions} from "../../../_models/default-modal-options";
 import {FormControl, FormGroup, NonNullableFormBuilder, ReactiveFormsModule, Validators} from "@angular/forms";
-import {AllProviders, Provider} from "../../../_models/page";
+import {AllProviders, Provider, TorrentProviders} from "../../../_models/page";
 import {PageService} from "../../../_services/page.service";
 import {catchError, of, tap} from "rxjs";
 import {SettingsItemComponent} from "../../../shared/form/settings-item/settings-item.component";
@@ -40,6 +40,8 @@ export class ManualContentAddModalComponent {
     provider: FormControl<Provider>
   }>;
 
+  torrentFile = signal<File | undefined>(undefined);
+
   constructor() {
     this.form = this.fb.group({
       id: this.fb.control<string>('', [Validators.required]),
@@ -52,10 +54,24 @@ export class ManualContentAddModalComponent {
     this.modal.close();
   }
 
+  selectTorrentFile(event: Event) {
+    const file = (event.target as HTMLInputElement).files?.[0];
+    this.torrentFile.set(file);
+
+    const id = this.form.get('id')!;
+    id.setValidators(file ? [] : [Validators.required]);
+    id.updateValueAndValidity();
+
+    if (file && !TorrentProviders.includes(this.form.getRawValue().provider)) {
+      this.form.get('provider')!.setValue(Provider.NYAA);
+    }
+  }
+
   submit() {
     if (!this.form.valid) return
 
     const data = this.form.getRawValue();
+    const file = this.torrentFile();
     this.pageService.metadata(data.provider).pipe(
       tap(metadata => {
         this.close();
@@ -64,8 +80,9 @@ export class ManualContentAddModalComponent {
         component.metadata.set(metadata);
         component.defaultDir.set('');
         component.rootDir.set('');
+        component.torrentFile.set(file);
         component.info.set({
-          Name: data.name ?? data.id,
+          Name: data.name || file?.name || data.id,
           Description: "",
           Size: "",
           Tags: [],
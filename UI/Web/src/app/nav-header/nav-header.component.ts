@@ -15,7 +15,7 @@ import {NotificationService} from "../_services/notification.service";
 import {EventType, SignalRService} from "../_services/signal-r.service";
 import {TranslocoService} from "@jsverse/transloco";
 import {User} from "../_models/user";
-import {Page} from "../_models/page";
+import {EmptyFileRules, Page} from "../_models/page";
 import {AsyncPipe, TitleCasePipe} from "@angular/common";
 import {animate, style, transition, trigger} from "@angular/animations";
 import {catchError, filter, fromEvent, of, take, tap, timeout} from "rxjs";
@@ -153,6 +153,13 @@ export class NavHeaderComponent implements OnInit {
         icon: "fa-home",
         dirs: [],
         customRootDir: '',
+        fileNameTemplate: '',
+        volumeDirTemplate: '',
+        seedRatio: null,
+        seedTime: null,
+        episodeTemplate: '',
+        seasonDirTemplate: '',
+        fileRules: {...EmptyFileRules},
         modifiers: [],
         providers: [],
         sortValue: -100,
@@ -44,6 +44,10 @@ export class DownloadModalComponent implements OnInit {
   rootDir = model.required<string>();
   dirs = model<string[]>([]);
   metadata = model.required<DownloadMetadata>();
+  /**
+   * Uploaded .torrent file, the id in info is ignored when set
+   */
+  torrentFile = model<File | undefined>(undefined);
 
   generalDef = computed(() =>
     this.metadata().definitions.filter(d => !d.advanced))
@@ -140,8 +144,10 @@ export class DownloadModalComponent implements OnInit {
 
   download() {
     const req = this.packData();
+    const file = this.torrentFile();
 
-    this.contentService.download(req).subscribe({
+    const request$ = file ? this.contentService.downloadTorrent(req, file) : this.contentService.download(req);
+    request$.subscribe({
       next: () => {
         this.toastService.successLoco("page.download-dialog.toasts.download-success", {}, {name: this.info().Name});
       },
@@ -1,6 +1,6 @@
 import {Component, inject, input, OnInit, signal} from '@angular/core';
 import {SearchInfo} from "../../../_models/Info";
-import {DownloadMetadata, Page, Provider} from "../../../_models/page";
+import {DownloadMetadata, Page, Provider, TorrentProviders} from "../../../_models/page";
 import {bounceIn200ms} from "../../../_animations/bounce-in";
 import {dropAnimation} from "../../../_animations/drop-animation";
 import {ImageService} from "../../../_services/image.service";
@@ -43,6 +43,14 @@ export class SearchResultComponent implements OnInit{
     this.loadImage();
   }
 
+  /**
+   * Torrent subscriptions are on a search, not on a single result. See the page component
+   */
+  canSubscribe() {
+    const provider = this.searchResult().Provider;
+    return this.providers().includes(provider) && !TorrentProviders.includes(provider);
+  }
+
   addAsSub() {
     const [_, component] = this.modalService.open(EditSubscriptionModalComponent, DefaultModalOptions);
 
@@ -51,6 +59,13 @@ export class SearchResultComponent implements OnInit{
       contentId: this.searchResult().InfoHash,
       provider: this.searchResult().Provider,
       refreshFrequency: RefreshFrequency.Week,
+      cronExpression: '',
+      interval: 0,
+      adaptive: false,
+      adaptiveMin: 6,
+      adaptiveMax: 168,
+      cadence: 0,
+      predictedRelease: null!,
       title: this.searchResult().Name,
       baseDir: this.page().dirs[0],
       lastDownloadDir: '',
@@ -64,7 +79,7 @@ export class SearchResultComponent implements OnInit{
     };
 
     component.subscription.set(newSub);
-    component.metadata.set(this.metadata());
+    component.metadata.set(this.withPageDefaults(this.metadata()));
     component.providers.set(this.providers());
   }
 
@@ -76,13 +91,40 @@ export class SearchResultComponent implements OnInit{
     const defaultDir = (page.dirs.length === 0 ? '' : page.dirs[0]) || page.customRootDir;
 
     const [_, component] = this.modalService.open(DownloadModalComponent, DefaultModalOptions);
-    component.metadata.set(metadata);
+    component.metadata.set(this.withPageDefaults(metadata));
     component.defaultDir.set(defaultDir);
     component.rootDir.set(page.customRootDir);
     component.dirs.set(page.dirs);
     component.info.set(this.searchResult());
   }
 
+  /**
+   * Use the naming templates, episode templates, seeding and file rules configured on the page as default options
+   */
+  private withPageDefaults(metadata: DownloadMetadata): DownloadMetadata {
+    const page = this.page();
+    const defaults: { [key: string]: string } = {
+      file_name_template: page.fileNameTemplate,
+      volume_dir_template: page.volumeDirTemplate,
+      seed_ratio: page.seedRatio != null ? String(page.seedRatio) : '',
+      seed_time: page.seedTime != null ? String(page.seedTime) : '',
+      episode_template: page.episodeTemplate,
+      season_dir_template: page.seasonDirTemplate,
+      include_files: (page.fileRules?.include ?? []).join(','),
+      exclude_files: (page.fileRules?.exclude ?? []).join(','),
+      file_extensions: (page.fileRules?.extensions ?? []).join(','),
+      min_file_size: page.fileRules?.minSize ? String(page.fileRules.minSize) : '',
+      max_file_size: page.fileRules?.maxSize ? String(page.fileRules.maxSize) : '',
+      largest_video_only: page.fileRules?.largestVideoOnly ? 'true' : '',
+    };
+
+    return {
+      ...metadata,
+      definitions: metadata.definitions.map(def =>
+        defaults[def.key] ? {...def, defaultOption: defaults[def.key]} : def),
+    };
+  }
+
   loadImage() {
     if (this.searchResult().ImageUrl === "") {
       return;
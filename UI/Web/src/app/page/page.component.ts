@@ -1,7 +1,7 @@
 import {Component, effect, inject, OnInit, signal} from '@angular/core';
 import {NavService} from "../_services/nav.service";
 import {PageService} from "../_services/page.service";
-import {DownloadMetadata, Page, Provider} from "../_models/page";
+import {DownloadMetadata, Page, Provider, TorrentProviders} from "../_models/page";
 import {FormsModule, ReactiveFormsModule} from "@angular/forms";
 import {SearchRequest} from "../_models/search";
 import {SearchInfo} from "../_models/Info";
@@ -15,6 +15,12 @@ import {PaginatorComponent} from "../shared/_component/paginator/paginator.compo
 import {SearchFormComponent} from "./_components/search-form/search-form.component";
 import {fadeOut} from "../_animations/fade-out";
 import {LoadingSpinnerComponent} from "../shared/_component/loading-spinner/loading-spinner.component";
+import {ModalService} from "../_services/modal.service";
+import {
+  EditSubscriptionModalComponent
+} from "../subscription-manager/_components/edit-subscription-modal/edit-subscription-modal.component";
+import {DefaultModalOptions} from "../_models/default-modal-options";
+import {RefreshFrequency, Subscription} from "../_models/subscription";
 
 @Component({
   selector: 'app-page',
@@ -39,6 +45,7 @@ export class PageComponent implements OnInit {
   private readonly toastService = inject(ToastService);
   private readonly subscriptionService = inject(SubscriptionService);
   private readonly providerNamePipe = inject(ProviderNamePipe);
+  private readonly modalService = inject(ModalService);
 
   page = signal<Page | undefined>(undefined);
   providers = signal<Provider[]>([]);
@@ -47,6 +54,7 @@ export class PageComponent implements OnInit {
   loading = signal(false);
   showForm = signal(true);
   searchResults = signal<SearchInfo[]>([]);
+  lastSearch = signal<SearchRequest | undefined>(undefined);
 
   constructor() {
     effect(() => {
@@ -64,6 +72,7 @@ export class PageComponent implements OnInit {
       this.pageService.getPage(index).subscribe(page => {
         this.page.set(page);
         this.searchResults.set([]);
+        this.lastSearch.set(undefined);
         this.showForm.set(true);
       });
     })
@@ -95,6 +104,7 @@ export class PageComponent implements OnInit {
           this.toastService.successLoco("page.toasts.search-success", {}, {amount: info.length});
         }
         this.searchResults.set(info ?? [])
+        this.lastSearch.set(req);
       },
       error: error => {
         this.toastService.genericError(error.error.message);
@@ -102,6 +112,63 @@ export class PageComponent implements OnInit {
     }).add(() => this.loading.set(false));
   }
 
+  /**
+   * The torrent provider a search on this page can be subscribed to, torrent subscriptions re-run the search
+   */
+  torrentProvider() {
+    const page = this.page();
+    if (!page) return undefined;
+
+    return page.providers.find(p => TorrentProviders.includes(p) && this.providers().includes(p));
+  }
+
+  subscribeToSearch() {
+    const req = this.lastSearch();
+    const provider = this.torrentProvider();
+    if (!req || provider === undefined) return;
+
+    const [_, component] = this.modalService.open(EditSubscriptionModalComponent, DefaultModalOptions);
+
+    const newSub: Subscription = {
+      ID: -1,
+      contentId: req.query,
+      provider: provider,
+      refreshFrequency: RefreshFrequency.Day,
+      cronExpression: '',
+      interval: 0,
+      adaptive: false,
+      adaptiveMin: 6,
+      adaptiveMax: 168,
+      cadence: 0,
+      predictedRelease: null!,
+      title: req.query,
+      baseDir: this.page()!.dirs[0],
+      lastDownloadDir: '',
+      lastCheck: null!,
+      lastCheckSuccess: null!,
+      nextExecution: null!,
+      metadata: {
+        startImmediately: true,
+        extra: {}
+      },
+      torrentSearch: {
+        modifiers: req.modifiers,
+        filter: {
+          titleRegex: '',
+          resolution: '',
+          releaseGroup: '',
+          minSeeders: 0,
+          minSize: 0,
+          maxSize: 0,
+        },
+      },
+    };
+
+    component.subscription.set(newSub);
+    component.metadata.set(this.getDownloadMetadata(provider));
+    component.providers.set(this.providers());
+  }
+
   private loadMetadata(page: Page) {
     for (const provider of page.providers) {
       this.pageService.metadata(provider).subscribe({
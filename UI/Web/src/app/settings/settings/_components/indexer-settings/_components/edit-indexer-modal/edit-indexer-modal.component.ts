@@ -0,0 +1,69 @@
+import {ChangeDetectionStrategy, Component, inject, model, OnInit} from '@angular/core';
+import {NgbActiveModal} from "@ng-bootstrap/ng-bootstrap";
+import {TranslocoDirective} from "@jsverse/transloco";
+import {FormControl, FormGroup, NonNullableFormBuilder, ReactiveFormsModule, Validators} from "@angular/forms";
+import {SettingsItemComponent} from "../../../../../../shared/form/settings-item/settings-item.component";
+import {DefaultValuePipe} from "../../../../../../_pipes/default-value.pipe";
+import {ToastService} from "../../../../../../_services/toast.service";
+import {TorznabService} from "../../../../../../_services/torznab.service";
+import {TorznabIndexer} from "../../../../../../_models/torznab";
+
+@Component({
+  selector: 'app-edit-indexer-modal',
+  imports: [
+    TranslocoDirective,
+    ReactiveFormsModule,
+    SettingsItemComponent,
+    DefaultValuePipe
+  ],
+  templateUrl: './edit-indexer-modal.component.html',
+  styleUrl: './edit-indexer-modal.component.scss',
+  changeDetection: ChangeDetectionStrategy.OnPush
+})
+export class EditIndexerModalComponent implements OnInit {
+
+  private readonly toastService = inject(ToastService);
+  private readonly torznabService = inject(TorznabService);
+  private readonly modal = inject(NgbActiveModal);
+  private readonly fb = inject(NonNullableFormBuilder);
+
+  indexer = model.required<TorznabIndexer>();
+
+  indexerForm!: FormGroup<{
+    ID: FormControl<number>,
+    name: FormControl<string>,
+    url: FormControl<string>,
+    apiKey: FormControl<string>,
+  }>;
+
+  ngOnInit() {
+    const indexer = this.indexer();
+
+    this.indexerForm = this.fb.group({
+      ID: this.fb.control(indexer.ID),
+      name: this.fb.control(indexer.name, [Validators.required]),
+      url: this.fb.control(indexer.url, [Validators.required, Validators.pattern(/^https?:\/\/.+/)]),
+      apiKey: this.fb.control(indexer.apiKey),
+    });
+  }
+
+  close() {
+    this.modal.close();
+  }
+
+  save() {
+    const indexer = this.indexerForm.getRawValue();
+    const isNew = indexer.ID === -1;
+    if (isNew) {
+      indexer.ID = 0;
+    }
+
+    const obs = isNew ? this.torznabService.new(indexer) : this.torznabService.update(indexer);
+    obs.subscribe({
+      next: () => this.toastService.successLoco("settings.indexers.toasts.update.success", {name: indexer.name}),
+      error: (err) => this.toastService.errorLoco("settings.indexers.toasts.update.error",
+        {name: indexer.name}, {msg: err.error.message}),
+    }).add(() => this.close());
+  }
+
+}
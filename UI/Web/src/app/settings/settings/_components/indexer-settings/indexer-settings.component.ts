@@ -0,0 +1,75 @@
+import {Component, inject, OnInit, signal} from '@angular/core';
+import {translate, TranslocoDirective} from "@jsverse/transloco";
+import {TableComponent} from "../../../../shared/_component/table/table.component";
+import {NgbTooltip} from "@ng-bootstrap/ng-bootstrap";
+import {ModalService} from "../../../../_services/modal.service";
+import {ToastService} from "../../../../_services/toast.service";
+import {TorznabService} from "../../../../_services/torznab.service";
+import {TorznabIndexer} from "../../../../_models/torznab";
+import {DefaultModalOptions} from "../../../../_models/default-modal-options";
+import {EditIndexerModalComponent} from "./_components/edit-indexer-modal/edit-indexer-modal.component";
+
+@Component({
+  selector: 'app-indexer-settings',
+  imports: [
+    TranslocoDirective,
+    TableComponent,
+    NgbTooltip
+  ],
+  templateUrl: './indexer-settings.component.html',
+  styleUrl: './indexer-settings.component.scss'
+})
+export class IndexerSettingsComponent implements OnInit {
+
+  private readonly modalService = inject(ModalService);
+  private readonly torznabService = inject(TorznabService);
+  private readonly toastService = inject(ToastService);
+
+  indexers = signal<TorznabIndexer[]>([]);
+
+  ngOnInit(): void {
+    this.loadIndexers();
+  }
+
+  loadIndexers() {
+    this.torznabService.all().subscribe({
+      next: indexers => this.indexers.set(indexers),
+      error: err => this.toastService.genericError(err.error.message),
+    });
+  }
+
+  async deleteIndexer(indexer: TorznabIndexer) {
+    if (!await this.modalService.confirm({
+      question: translate("settings.indexers.confirm-delete", {name: indexer.name})
+    })) {
+      return;
+    }
+
+    this.torznabService.delete(indexer.ID).subscribe({
+      next: _ => {
+        this.indexers.update(indexers => indexers.filter(i => i.ID !== indexer.ID));
+        this.toastService.successLoco("settings.indexers.toasts.delete.success", {name: indexer.name});
+      },
+      error: err => {
+        this.toastService.errorLoco("settings.indexers.toasts.delete.error",
+          {name: indexer.name}, {msg: err.error.message});
+      }
+    });
+  }
+
+  trackBy(idx: number, indexer: TorznabIndexer) {
+    return `${indexer.ID}`
+  }
+
+  edit(indexer: TorznabIndexer | null) {
+    const [modal, component] = this.modalService.open(EditIndexerModalComponent, DefaultModalOptions);
+    component.indexer.set(indexer ?? {
+      ID: -1,
+      name: '',
+      url: '',
+      apiKey: '',
+    });
+
+    modal.result.then(() => this.loadIndexers());
+  }
+}
@@ -1,5 +1,5 @@
-import {ChangeDetectionStrategy, Component, inject, model, OnInit, signal} from '@angular/core';
-import {AllProviders, Modifier, ModifierType, Page, Provider} from "../../../../../../_models/page";
+import {ChangeDetectionStrategy, Component, computed, inject, model, OnInit, signal} from '@angular/core';
+import {AllProviders, EmptyFileRules, FileRules, Modifier, ModifierType, Page, Provider} from "../../../../../../_models/page";
 import {
   NgbActiveModal,
   NgbNav,
@@ -25,6 +25,9 @@ import {TableComponent} from "../../../../../../shared/_component/table/table.co
 import {EditPageModifierModalComponent} from "../edit-page-modifier-modal/edit-page-modifier-modal.component";
 import {DefaultModalOptions} from "../../../../../../_models/default-modal-options";
 import {ToastService} from "../../../../../../_services/toast.service";
+import {TorznabService} from "../../../../../../_services/torznab.service";
+import {TorznabIndexer} from "../../../../../../_models/torznab";
+import {SettingsSwitchComponent} from "../../../../../../shared/form/settings-switch/settings-switch.component";
 
 @Component({
   selector: 'app-edit-page-modal',
@@ -44,7 +47,8 @@ import {ToastService} from "../../../../../../_services/toast.service";
     TypeaheadComponent,
     ProviderNamePipe,
     CdkDragHandle,
-    TableComponent
+    TableComponent,
+    SettingsSwitchComponent
   ],
   templateUrl: './edit-page-modal.component.html',
   styleUrl: './edit-page-modal.component.scss',
@@ -57,11 +61,15 @@ export class EditPageModalComponent implements OnInit {
   private readonly pageService = inject(PageService);
   private readonly providerNamePipe = inject(ProviderNamePipe);
   private readonly toastService = inject(ToastService);
+  private readonly torznabService = inject(TorznabService);
 
   page = model.required<Page>();
 
   pageForm = new FormGroup({});
   selectedProviders = signal<Provider[]>([]);
+  indexers = signal<TorznabIndexer[]>([]);
+  hasTorznab = computed(() => this.selectedProviders().includes(Provider.TORZNAB));
+  selectedIndexer = new FormControl<number | null>(null);
 
   activeTab = 'general';
 
@@ -72,9 +80,20 @@ export class EditPageModalComponent implements OnInit {
     this.pageForm.addControl('title', new FormControl(page.title, [Validators.required]));
     this.pageForm.addControl('icon', new FormControl(page.icon, []));
     this.pageForm.addControl('customRootDir', new FormControl(page.customRootDir, []));
+    this.pageForm.addControl('fileNameTemplate', new FormControl(page.fileNameTemplate ?? '', []));
+    this.pageForm.addControl('volumeDirTemplate', new FormControl(page.volumeDirTemplate ?? '', []));
+    this.pageForm.addControl('seedRatio', new FormControl(page.seedRatio ?? null, [Validators.min(0)]));
+    this.pageForm.addControl('seedTime', new FormControl(page.seedTime ?? null, [Validators.min(0)]));
+    this.pageForm.addControl('episodeTemplate', new FormControl(page.episodeTemplate ?? '', []));
+    this.pageForm.addControl('seasonDirTemplate', new FormControl(page.seasonDirTemplate ?? '', []));
+    this.pageForm.addControl('fileRules', this.fileRulesFormGroup(page.fileRules ?? EmptyFileRules));
     this.pageForm.addControl('providers', new FormControl(page.providers, []));
     this.pageForm.addControl('dirs', new FormControl(page.dirs.join(','), []));
     this.pageForm.addControl('modifiers', new FormArray(page.modifiers.map(m => this.modifierFormGroup(m))))
+
+    this.torznabService.all().subscribe({
+      next: indexers => this.indexers.set(indexers),
+    });
   }
 
   private modifierFormGroup(m: Modifier) {
@@ -92,6 +111,17 @@ export class EditPageModalComponent implements OnInit {
     })
   }
 
+  private fileRulesFormGroup(rules: FileRules) {
+    return new FormGroup({
+      include: new FormControl((rules.include ?? []).join(','), []),
+      exclude: new FormControl((rules.exclude ?? []).join(','), []),
+      extensions: new FormControl((rules.extensions ?? []).join(','), []),
+      minSize: new FormControl(rules.minSize || null, [Validators.min(0)]),
+      maxSize: new FormControl(rules.maxSize || null, [Validators.min(0)]),
+      largestVideoOnly: new FormControl(rules.largestVideoOnly, []),
+    });
+  }
+
   get modifiersFormArray(): FormArray {
     return this.pageForm.get('modifiers') as unknown as FormArray;
   }
@@ -147,6 +177,14 @@ export class EditPageModalComponent implements OnInit {
     const page = this.pageForm.value as any;
     page.ID = this.page().ID;
     page.dirs = this.break(page.dirs);
+    page.fileRules = {
+      include: this.break(page.fileRules.include).map((s: string) => s.trim()),
+      exclude: this.break(page.fileRules.exclude).map((s: string) => s.trim()),
+      extensions: this.break(page.fileRules.extensions).map((s: string) => s.trim()),
+      minSize: page.fileRules.minSize || 0,
+      maxSize: page.fileRules.maxSize || 0,
+      largestVideoOnly: Boolean(page.fileRules.largestVideoOnly),
+    };
     page.modifiers.forEach((m: Modifier) => {
       m.type = parseInt(m.type+'');
     });
@@ -195,6 +233,42 @@ export class EditPageModalComponent implements OnInit {
     }));
   }
 
+  /**
+   * Adds the modifiers of the indexer to the page, values are merged into existing modifiers with the same key
+   */
+  importIndexerModifiers() {
+    const id = this.selectedIndexer.value;
+    if (id === null) return;
+
+    this.torznabService.modifiers(id).subscribe({
+      next: modifiers => {
+        for (const modifier of modifiers) {
+          const existing = this.modifiersFormArray.controls.find(c => c.get('key')?.value === modifier.key);
+          if (!existing) {
+            this.modifiersFormArray.push(this.modifierFormGroup(modifier));
+            continue;
+          }
+
+          const values = existing.get('values') as FormArray;
+          for (const mv of modifier.values) {
+            if (values.controls.some(c => c.get('key')?.value === mv.key)) continue;
+
+            values.push(new FormGroup({
+              key: new FormControl(mv.key, [Validators.required]),
+              value: new FormControl(mv.value, [Validators.required]),
+              default: new FormControl(mv.default, []),
+            }));
+          }
+        }
+
+        this.toastService.successLoco("edit-page-modal.toasts.import.success");
+      },
+      error: err => {
+        this.toastService.errorLoco("edit-page-modal.toasts.import.error", {}, {msg: err.error.message});
+      }
+    });
+  }
+
   sortModifiers($event: CdkDragDrop<AbstractControl[], any>) {
     const controls = this.modifiersFormArray.controls;
     moveItemInArray(controls, $event.previousIndex, $event.currentIndex)
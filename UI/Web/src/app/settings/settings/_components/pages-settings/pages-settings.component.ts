@@ -1,5 +1,5 @@
 import {Component, computed, effect, inject, OnInit, signal} from '@angular/core';
-import {Page} from "../../../../_models/page";
+import {EmptyFileRules, Page} from "../../../../_models/page";
 import {PageService} from "../../../../_services/page.service";
 import {RouterLink} from "@angular/router";
 import {dropAnimation} from "../../../../_animations/drop-animation";
@@ -48,6 +48,13 @@ export class PagesSettingsComponent {
     component.page.set(page ?? {
       ID: -1,
       customRootDir: '',
+      fileNameTemplate: '',
+      volumeDirTemplate: '',
+      seedRatio: null,
+      seedTime: null,
+      episodeTemplate: '',
+      seasonDirTemplate: '',
+      fileRules: {...EmptyFileRules},
       title: '',
       dirs: [],
       providers: [],
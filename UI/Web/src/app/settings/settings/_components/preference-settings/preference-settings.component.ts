@@ -6,6 +6,7 @@ import {
   ComicInfoAgeRating,
   ComicInfoAgeRatings,
   CoverFallbackMethods,
+  MetronAgeRatings,
   Preferences,
   TagMap
 } from '../../../../_models/preferences';
@@ -62,6 +63,7 @@ export class PreferenceSettingsComponent implements OnInit {
         logEmptyDownloads: new FormControl(preferences.logEmptyDownloads),
         logSubNoDownloads: new FormControl(preferences.logSubNoDownloads),
         convertToWebp: new FormControl(preferences.convertToWebp),
+        writeMetronInfo: new FormControl(preferences.writeMetronInfo),
         coverFallbackMethod: [preferences.coverFallbackMethod],
         blackList: new FormControl(preferences.blackList.join(',')),
         whiteList: new FormControl(preferences.whiteList.join(',')),
@@ -126,6 +128,7 @@ export class PreferenceSettingsComponent implements OnInit {
     return new FormGroup({
       tag: new FormControl(agm.tag, [Validators.required]),
       comicInfoAgeRating: new FormControl(agm.comicInfoAgeRating, [Validators.required]),
+      metronAgeRating: new FormControl(agm.metronAgeRating ?? ''),
     })
   }
 
@@ -158,4 +161,5 @@ export class PreferenceSettingsComponent implements OnInit {
   }
 
   protected readonly ComicInfoAgeRatings = ComicInfoAgeRatings;
+  protected readonly MetronAgeRatings = MetronAgeRatings;
 }
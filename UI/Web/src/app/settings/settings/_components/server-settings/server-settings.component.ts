@@ -1,5 +1,15 @@
 import {ChangeDetectionStrategy, ChangeDetectorRef, Component, DestroyRef, effect, inject} from '@angular/core';
-import {CacheType, CacheTypes, Config} from '../../../../_models/config';
+import {
+  CacheType,
+  CacheTypes,
+  Config,
+  EncryptionPolicies,
+  EncryptionPolicy,
+  StallAction,
+  StallActions,
+  TorrentBackend,
+  TorrentBackends
+} from '../../../../_models/config';
 import {
   FormBuilder,
   FormControl,
@@ -46,8 +56,45 @@ export class ServerSettingsComponent {
     cacheType: FormControl<CacheType>
     redisAddr: FormControl<string>
     maxConcurrentImages: FormControl<number>
+    maxConcurrentChapters: FormControl<number>
     maxConcurrentTorrents: FormControl<number>
     disableIpv6: FormControl<boolean>
+    seeding: FormGroup<{
+      ratio: FormControl<number>;
+      minSeedTime: FormControl<number>;
+      maxTorrents: FormControl<number>;
+    }>
+    torrent: FormGroup<{
+      backend: FormControl<TorrentBackend>;
+      remote: FormGroup<{
+        url: FormControl<string>;
+        username: FormControl<string>;
+        password: FormControl<string>;
+        rootDir: FormControl<string>;
+      }>;
+      stall: FormGroup<{
+        metadataTimeout: FormControl<number>;
+        progressTimeout: FormControl<number>;
+        action: FormControl<StallAction>;
+      }>;
+      uploadLimit: FormControl<number>;
+      downloadLimit: FormControl<number>;
+      torrentUploadLimit: FormControl<number>;
+      torrentDownloadLimit: FormControl<number>;
+      altSpeed: FormGroup<{
+        uploadLimit: FormControl<number>;
+        downloadLimit: FormControl<number>;
+        scheduled: FormControl<boolean>;
+        days: FormControl<number[]>;
+        from: FormControl<string>;
+        to: FormControl<string>;
+      }>;
+      listenPort: FormControl<number>;
+      maxPeers: FormControl<number>;
+      encryption: FormControl<EncryptionPolicy>;
+      disableDht: FormControl<boolean>;
+      disablePex: FormControl<boolean>;
+    }>
     oidc: FormGroup<{
       authority: FormControl<string>;
       clientId: FormControl<string>;
@@ -56,6 +103,10 @@ export class ServerSettingsComponent {
       autoLogin:FormControl <boolean>;
     }>
     subscriptionRefreshHour: FormControl<number>;
+    subscriptionHistory: FormGroup<{
+      maxAge: FormControl<number>;
+      maxRuns: FormControl<number>;
+    }>
   }> | undefined;
 
   constructor() {
@@ -69,8 +120,45 @@ export class ServerSettingsComponent {
         cacheType: this.fb.control(config.cacheType, [Validators.required]),
         redisAddr: this.fb.control(config.redisAddr),
         maxConcurrentImages: this.fb.control(config.maxConcurrentImages, [Validators.required, Validators.min(1), Validators.max(5)]),
+        maxConcurrentChapters: this.fb.control(config.maxConcurrentChapters, [Validators.required, Validators.min(1), Validators.max(5)]),
         maxConcurrentTorrents: this.fb.control(config.maxConcurrentTorrents, [Validators.required, Validators.min(1), Validators.max(10)]),
         disableIpv6: this.fb.control(config.disableIpv6),
+        seeding: this.fb.group({
+          ratio: this.fb.control(config.seeding.ratio, [Validators.required, Validators.min(0)]),
+          minSeedTime: this.fb.control(config.seeding.minSeedTime, [Validators.required, Validators.min(0)]),
+          maxTorrents: this.fb.control(config.seeding.maxTorrents, [Validators.required, Validators.min(0), Validators.max(100)]),
+        }),
+        torrent: this.fb.group({
+          backend: this.fb.control(config.torrent.backend, [Validators.required]),
+          remote: this.fb.group({
+            url: this.fb.control(config.torrent.remote.url),
+            username: this.fb.control(config.torrent.remote.username),
+            password: this.fb.control(config.torrent.remote.password),
+            rootDir: this.fb.control(config.torrent.remote.rootDir),
+          }),
+          stall: this.fb.group({
+            metadataTimeout: this.fb.control(config.torrent.stall.metadataTimeout, [Validators.required, Validators.min(0)]),
+            progressTimeout: this.fb.control(config.torrent.stall.progressTimeout, [Validators.required, Validators.min(0)]),
+            action: this.fb.control(config.torrent.stall.action, [Validators.required]),
+          }),
+          uploadLimit: this.fb.control(config.torrent.uploadLimit, [Validators.required, Validators.min(0)]),
+          downloadLimit: this.fb.control(config.torrent.downloadLimit, [Validators.required, Validators.min(0)]),
+          torrentUploadLimit: this.fb.control(config.torrent.torrentUploadLimit, [Validators.required, Validators.min(0)]),
+          torrentDownloadLimit: this.fb.control(config.torrent.torrentDownloadLimit, [Validators.required, Validators.min(0)]),
+          altSpeed: this.fb.group({
+            uploadLimit: this.fb.control(config.torrent.altSpeed.uploadLimit, [Validators.required, Validators.min(0)]),
+            downloadLimit: this.fb.control(config.torrent.altSpeed.downloadLimit, [Validators.required, Validators.min(0)]),
+            scheduled: this.fb.control(config.torrent.altSpeed.scheduled),
+            days: this.fb.control(config.torrent.altSpeed.days ?? []),
+            from: this.fb.control(config.torrent.altSpeed.from, [Validators.required]),
+            to: this.fb.control(config.torrent.altSpeed.to, [Validators.required]),
+          }),
+          listenPort: this.fb.control(config.torrent.listenPort, [Validators.required, Validators.min(0), Validators.max(65535)]),
+          maxPeers: this.fb.control(config.torrent.maxPeers, [Validators.required, Validators.min(0), Validators.max(1000)]),
+          encryption: this.fb.control(config.torrent.encryption, [Validators.required]),
+          disableDht: this.fb.control(config.torrent.disableDht),
+          disablePex: this.fb.control(config.torrent.disablePex),
+        }),
         oidc: this.fb.group({
           authority: this.fb.control(config.oidc.authority),
           clientId: this.fb.control(config.oidc.clientId),
@@ -79,6 +167,10 @@ export class ServerSettingsComponent {
           clientSecret: this.fb.control(config.oidc.clientSecret),
         }),
         subscriptionRefreshHour: this.fb.control(config.subscriptionRefreshHour),
+        subscriptionHistory: this.fb.group({
+          maxAge: this.fb.control(config.subscriptionHistory.maxAge, [Validators.required, Validators.min(0)]),
+          maxRuns: this.fb.control(config.subscriptionHistory.maxRuns, [Validators.required, Validators.min(0)]),
+        }),
       });
       this.cdRef.detectChanges();
 
@@ -131,7 +223,19 @@ export class ServerSettingsComponent {
       ...this.settingsForm.getRawValue(),
     };
     dto.maxConcurrentImages = parseInt(String(dto.maxConcurrentImages))
+    dto.maxConcurrentChapters = parseInt(String(dto.maxConcurrentChapters))
     dto.maxConcurrentTorrents = parseInt(String(dto.maxConcurrentTorrents))
+    dto.seeding.ratio = parseFloat(String(dto.seeding.ratio))
+    dto.seeding.minSeedTime = parseInt(String(dto.seeding.minSeedTime))
+    dto.seeding.maxTorrents = parseInt(String(dto.seeding.maxTorrents))
+    dto.torrent.uploadLimit = parseInt(String(dto.torrent.uploadLimit))
+    dto.torrent.downloadLimit = parseInt(String(dto.torrent.downloadLimit))
+    dto.torrent.torrentUploadLimit = parseInt(String(dto.torrent.torrentUploadLimit))
+    dto.torrent.torrentDownloadLimit = parseInt(String(dto.torrent.torrentDownloadLimit))
+    dto.torrent.altSpeed.uploadLimit = parseInt(String(dto.torrent.altSpeed.uploadLimit))
+    dto.torrent.altSpeed.downloadLimit = parseInt(String(dto.torrent.altSpeed.downloadLimit))
+    dto.torrent.listenPort = parseInt(String(dto.torrent.listenPort))
+    dto.torrent.maxPeers = parseInt(String(dto.torrent.maxPeers))
 
     if (dto.cacheType != CacheType.REDIS) {
       dto.redisAddr = ""
@@ -150,6 +254,15 @@ export class ServerSettingsComponent {
     });
   }
 
+  toggleAltSpeedDay(day: number) {
+    const control = this.getFormControl('torrent.altSpeed.days');
+    if (!control) return;
+
+    const days: number[] = control.value ?? [];
+    control.setValue(days.includes(day) ? days.filter(d => d !== day) : [...days, day].sort());
+    control.markAsDirty();
+  }
+
   private errors() {
     let count = 0;
     Object.keys(this.settingsForm!.controls).forEach(key => {
@@ -165,5 +278,10 @@ export class ServerSettingsComponent {
 
   protected readonly CacheType = CacheType;
   protected readonly CacheTypes = CacheTypes;
+  protected readonly EncryptionPolicies = EncryptionPolicies;
+  protected readonly TorrentBackend = TorrentBackend;
+  protected readonly TorrentBackends = TorrentBackends;
+  protected readonly StallActions = StallActions;
+  protected readonly Weekdays = [1, 2, 3, 4, 5, 6, 0];
   protected readonly translate = translate;
 }
@@ -9,10 +9,12 @@ import {ServerSettingsComponent} from "./_components/server-settings/server-sett
 import {UserSettingsComponent} from "./_components/user-settings/user-settings.component";
 import {TranslocoDirective} from "@jsverse/transloco";
 import {AccountSettingsComponent} from "./_components/account-settings/account-settings.component";
+import {IndexerSettingsComponent} from "./_components/indexer-settings/indexer-settings.component";
 
 export enum SettingsID {
   Account = "account",
   Server = "server",
+  Indexers = "indexers",
   Preferences = "preferences",
   Pages = "pages",
   User = "user"
@@ -37,8 +39,8 @@ interface SettingsTab {
     ServerSettingsComponent,
     UserSettingsComponent,
     TranslocoDirective,
-    AccountSettingsComponent
-
+    AccountSettingsComponent,
+    IndexerSettingsComponent
   ],
   templateUrl: './settings.component.html',
   styleUrls: ['./settings.component.scss']
@@ -62,6 +64,7 @@ export class SettingsComponent {
     { id: SettingsID.Preferences, title: "Preferences", icon: 'fa fa-heart', roles: [Role.ManagePreferences] },
     { id: SettingsID.Pages, title: 'Pages', icon: 'fa fa-thumbtack', roles: [Role.ManagePages] },
     { id: SettingsID.Server, title: 'Server', icon: 'fa fa-server', roles: [Role.ManageServerConfigs] },
+    { id: SettingsID.Indexers, title: 'Indexers', icon: 'fa fa-satellite-dish', roles: [Role.ManageServerConfigs] },
     { id: SettingsID.User, title: 'Users', icon: 'fa fa-users', roles: [Role.ManageUsers] },
   ];
 
@@ -1,11 +1,19 @@
 import {ChangeDetectionStrategy, Component, inject, model, OnInit} from '@angular/core';
 import {RefreshFrequencies, Subscription} from "../../../_models/subscription";
-import {DownloadMetadata, DownloadMetadataDefinition, DownloadMetadataFormType, Provider} from "../../../_models/page";
+import {
+  DownloadMetadata,
+  DownloadMetadataDefinition,
+  DownloadMetadataFormType,
+  Provider,
+  TorrentProviders
+} from "../../../_models/page";
 import {FormControl, FormGroup, ReactiveFormsModule} from '@angular/forms';
 import {ToastService} from "../../../_services/toast.service";
 import {ModalService} from "../../../_services/modal.service";
-import {NgbActiveModal, NgbNav, NgbNavContent, NgbNavItem, NgbNavLink, NgbNavOutlet} from "@ng-bootstrap/ng-bootstrap";
+import {NgbActiveModal, NgbNav, NgbNavContent, NgbNavItem, NgbNavLink, NgbNavOutlet, NgbTooltip} from "@ng-bootstrap/ng-bootstrap";
 import {SettingsItemComponent} from "../../../shared/form/settings-item/settings-item.component";
+import {SettingsSwitchComponent} from "../../../shared/form/settings-switch/settings-switch.component";
+import {UtcToLocalTimePipe} from "../../../_pipes/utc-to-local.pipe";
 import {DefaultValuePipe} from "../../../_pipes/default-value.pipe";
 import {TranslocoDirective} from "@jsverse/transloco";
 import {ProviderNamePipe} from "../../../_pipes/provider-name.pipe";
@@ -18,6 +26,8 @@ import {SubscriptionService} from "../../../_services/subscription.service";
   imports: [
     ReactiveFormsModule,
     SettingsItemComponent,
+    SettingsSwitchComponent,
+    UtcToLocalTimePipe,
     DefaultValuePipe,
     TranslocoDirective,
     NgbNav,
@@ -25,6 +35,7 @@ import {SubscriptionService} from "../../../_services/subscription.service";
     NgbNavLink,
     NgbNavItem,
     NgbNavOutlet,
+    NgbTooltip,
     ProviderNamePipe,
     RefreshFrequencyPipe
   ],
@@ -44,7 +55,7 @@ export class EditSubscriptionModalComponent implements OnInit {
   providers = model.required<Provider[]>();
   metadata = model.required<DownloadMetadata>();
 
-  activeTab: 'general' | 'extra' = 'general';
+  activeTab: 'general' | 'extra' | 'torrent' = 'general';
 
   subscriptionForm = new FormGroup({});
 
@@ -56,6 +67,11 @@ export class EditSubscriptionModalComponent implements OnInit {
     this.subscriptionForm.addControl('provider', new FormControl(subscription.provider));
     this.subscriptionForm.addControl('contentId', new FormControl(subscription.contentId));
     this.subscriptionForm.addControl('refreshFrequency', new FormControl(subscription.refreshFrequency));
+    this.subscriptionForm.addControl('cronExpression', new FormControl(subscription.cronExpression ?? ''));
+    this.subscriptionForm.addControl('interval', new FormControl(subscription.interval ?? 0));
+    this.subscriptionForm.addControl('adaptive', new FormControl(subscription.adaptive ?? false));
+    this.subscriptionForm.addControl('adaptiveMin', new FormControl(subscription.adaptiveMin || 6));
+    this.subscriptionForm.addControl('adaptiveMax', new FormControl(subscription.adaptiveMax || 168));
     this.subscriptionForm.addControl('title', new FormControl(subscription.title));
     this.subscriptionForm.addControl('description', new FormControl(subscription.description));
     this.subscriptionForm.addControl('baseDir', new FormControl(subscription.baseDir));
@@ -70,6 +86,24 @@ export class EditSubscriptionModalComponent implements OnInit {
     }
 
     this.subscriptionForm.addControl('metadata', metadataFormGroup);
+
+    if (this.isTorrent()) {
+      const filter = subscription.torrentSearch?.filter;
+      this.subscriptionForm.addControl('torrentSearch', new FormGroup({
+        filter: new FormGroup({
+          titleRegex: new FormControl(filter?.titleRegex ?? ''),
+          resolution: new FormControl(filter?.resolution ?? ''),
+          releaseGroup: new FormControl(filter?.releaseGroup ?? ''),
+          minSeeders: new FormControl(filter?.minSeeders ?? 0),
+          minSize: new FormControl(filter?.minSize ?? 0),
+          maxSize: new FormControl(filter?.maxSize ?? 0),
+        }),
+      }));
+    }
+  }
+
+  isTorrent() {
+    return TorrentProviders.includes(this.subscription().provider);
   }
 
   private getDefaultValue(sub: Subscription, def: DownloadMetadataDefinition) {
@@ -135,6 +169,23 @@ export class EditSubscriptionModalComponent implements OnInit {
 
     data.provider = parseInt(data.provider+'');
     data.refreshFrequency = parseInt(data.refreshFrequency+'');
+    data.cronExpression = (data.cronExpression ?? '').trim();
+    data.interval = parseInt(data.interval+'') || 0;
+    data.adaptiveMin = parseInt(data.adaptiveMin+'') || 0;
+    data.adaptiveMax = parseInt(data.adaptiveMax+'') || 0;
+
+    if (data.torrentSearch) {
+      const filter = data.torrentSearch.filter;
+      data.torrentSearch = {
+        modifiers: this.subscription().torrentSearch?.modifiers,
+        filter: {
+          ...filter,
+          minSeeders: parseInt(filter.minSeeders+'') || 0,
+          minSize: parseInt(filter.minSize+'') || 0,
+          maxSize: parseInt(filter.maxSize+'') || 0,
+        },
+      };
+    }
 
     return data;
   }
@@ -0,0 +1,71 @@
+import {ChangeDetectionStrategy, Component, inject, model, OnInit, signal} from '@angular/core';
+import {NgbActiveModal} from "@ng-bootstrap/ng-bootstrap";
+import {TranslocoDirective} from "@jsverse/transloco";
+import {PagedList, Subscription, SubscriptionRun} from "../../../_models/subscription";
+import {SubscriptionService} from "../../../_services/subscription.service";
+import {ToastService} from "../../../_services/toast.service";
+import {UtcToLocalTimePipe} from "../../../_pipes/utc-to-local.pipe";
+import {BadgeComponent} from "../../../shared/_component/badge/badge.component";
+
+@Component({
+  selector: 'app-subscription-history-modal',
+  imports: [
+    TranslocoDirective,
+    UtcToLocalTimePipe,
+    BadgeComponent,
+  ],
+  templateUrl: './subscription-history-modal.component.html',
+  styleUrl: './subscription-history-modal.component.scss',
+  changeDetection: ChangeDetectionStrategy.OnPush
+})
+export class SubscriptionHistoryModalComponent implements OnInit {
+
+  private readonly modal = inject(NgbActiveModal);
+  private readonly subscriptionService = inject(SubscriptionService);
+  private readonly toastService = inject(ToastService);
+
+  subscription = model.required<Subscription>();
+
+  history = signal<PagedList<SubscriptionRun> | undefined>(undefined);
+  expanded = signal<number | undefined>(undefined);
+
+  ngOnInit(): void {
+    this.load(0);
+  }
+
+  load(page: number) {
+    this.subscriptionService.history(this.subscription().ID, page).subscribe({
+      next: history => {
+        this.history.set(history);
+        this.expanded.set(undefined);
+      },
+      error: err => {
+        this.toastService.errorLoco("subscriptions.toasts.history.error", {name: this.subscription().title}, {msg: err.error.message});
+      }
+    });
+  }
+
+  toggle(run: SubscriptionRun) {
+    this.expanded.update(id => id === run.ID ? undefined : run.ID);
+  }
+
+  finished(run: SubscriptionRun) {
+    return new Date(run.finishedAt).getFullYear() > 1;
+  }
+
+  colour(run: SubscriptionRun): "primary" | "secondary" | "error" | "warning" {
+    if (!this.finished(run)) {
+      return "secondary";
+    }
+
+    if ((run.errors ?? []).length === 0) {
+      return "primary";
+    }
+
+    return run.downloaded > 0 ? "warning" : "error";
+  }
+
+  close() {
+    this.modal.close();
+  }
+}
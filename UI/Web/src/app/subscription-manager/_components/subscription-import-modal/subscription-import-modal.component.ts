@@ -0,0 +1,39 @@
+import {ChangeDetectionStrategy, Component, inject, model} from '@angular/core';
+import {NgbActiveModal} from "@ng-bootstrap/ng-bootstrap";
+import {TranslocoDirective} from "@jsverse/transloco";
+import {ImportStatus, SubscriptionImportReport} from "../../../_models/subscription";
+import {BadgeComponent} from "../../../shared/_component/badge/badge.component";
+
+@Component({
+  selector: 'app-subscription-import-modal',
+  imports: [
+    TranslocoDirective,
+    BadgeComponent,
+  ],
+  templateUrl: './subscription-import-modal.component.html',
+  styleUrl: './subscription-import-modal.component.scss',
+  changeDetection: ChangeDetectionStrategy.OnPush
+})
+export class SubscriptionImportModalComponent {
+
+  private readonly modal = inject(NgbActiveModal);
+
+  report = model.required<SubscriptionImportReport>();
+
+  colour(status: ImportStatus): "primary" | "secondary" | "error" | "warning" {
+    switch (status) {
+      case ImportStatus.Created:
+        return "primary";
+      case ImportStatus.Duplicate:
+        return "secondary";
+      case ImportStatus.Invalid:
+        return "warning";
+      case ImportStatus.Failed:
+        return "error";
+    }
+  }
+
+  close() {
+    this.modal.close();
+  }
+}
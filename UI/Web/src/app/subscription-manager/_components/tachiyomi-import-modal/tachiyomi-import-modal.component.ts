@@ -0,0 +1,93 @@
+import {ChangeDetectionStrategy, Component, computed, inject, model, OnInit, signal} from '@angular/core';
+import {NgbActiveModal} from "@ng-bootstrap/ng-bootstrap";
+import {TranslocoDirective} from "@jsverse/transloco";
+import {ExportedSubscription, ProposedSubscription, TachiyomiImport} from "../../../_models/subscription";
+import {SubscriptionService} from "../../../_services/subscription.service";
+import {ToastService} from "../../../_services/toast.service";
+import {ProviderNamePipe} from "../../../_pipes/provider-name.pipe";
+import {BadgeComponent} from "../../../shared/_component/badge/badge.component";
+
+@Component({
+  selector: 'app-tachiyomi-import-modal',
+  imports: [
+    TranslocoDirective,
+    ProviderNamePipe,
+    BadgeComponent,
+  ],
+  templateUrl: './tachiyomi-import-modal.component.html',
+  styleUrl: './tachiyomi-import-modal.component.scss',
+  changeDetection: ChangeDetectionStrategy.OnPush
+})
+export class TachiyomiImportModalComponent implements OnInit {
+
+  private readonly modal = inject(NgbActiveModal);
+  private readonly subscriptionService = inject(SubscriptionService);
+  private readonly toastService = inject(ToastService);
+
+  proposals = model.required<TachiyomiImport>();
+
+  /**
+   * Indexes of the proposals to import
+   */
+  selected = signal<Set<number>>(new Set());
+  /**
+   * Base dirs of the proposals, by index. Start as the suggested dir
+   */
+  baseDirs = signal<string[]>([]);
+  importing = signal(false);
+
+  selectedCount = computed(() => this.selected().size);
+
+  ngOnInit(): void {
+    const proposals = this.proposals().proposals;
+
+    this.baseDirs.set(proposals.map(p => p.baseDir));
+    this.selected.set(new Set(proposals
+      .map((p, i) => [p, i] as const)
+      .filter(([p]) => !p.subscriptionId && p.baseDir !== '')
+      .map(([_, i]) => i)));
+  }
+
+  toggle(index: number) {
+    this.selected.update(selected => {
+      const next = new Set(selected);
+      if (!next.delete(index)) {
+        next.add(index);
+      }
+      return next;
+    });
+  }
+
+  updateBaseDir(index: number, event: Event) {
+    const value = (event.target as HTMLInputElement).value;
+    this.baseDirs.update(dirs => dirs.map((dir, i) => i === index ? value : dir));
+  }
+
+  import() {
+    const baseDirs = this.baseDirs();
+    const subscriptions: ExportedSubscription[] = this.proposals().proposals
+      .map((p, i) => [p, i] as const)
+      .filter(([_, i]) => this.selected().has(i))
+      .map(([p, i]) => this.toExported(p, baseDirs[i]));
+
+    if (subscriptions.length === 0) return;
+
+    this.importing.set(true);
+    this.subscriptionService.import({version: 1, exportedAt: new Date(), subscriptions}).subscribe({
+      next: report => this.modal.close(report),
+      error: err => {
+        this.importing.set(false);
+        this.toastService.errorLoco("subscriptions.toasts.import.error", {}, {msg: err.error.message});
+      }
+    });
+  }
+
+  private toExported(proposal: ProposedSubscription, baseDir: string): ExportedSubscription {
+    const {source, categories, subscriptionId, ...sub} = proposal;
+    return {...sub, baseDir};
+  }
+
+  close() {
+    this.modal.close();
+  }
+}
@@ -1,7 +1,13 @@
 import {Component, computed, effect, inject, OnInit, signal} from '@angular/core';
 import {NavService} from "../_services/nav.service";
 import {SubscriptionService} from '../_services/subscription.service';
-import {RefreshFrequency, Subscription} from "../_models/subscription";
+import {
+  RefreshFrequency,
+  ReorganiseReport,
+  Subscription,
+  SubscriptionExport,
+  SubscriptionImportReport
+} from "../_models/subscription";
 import {DownloadMetadata, Provider} from "../_models/page";
 import {dropAnimation} from "../_animations/drop-animation";
 import {SubscriptionExternalUrlPipe} from "../_pipes/subscription-external-url.pipe";
@@ -14,12 +20,21 @@ import {TableComponent} from "../shared/_component/table/table.component";
 import {BadgeComponent} from "../shared/_component/badge/badge.component";
 import {NgbTooltip} from "@ng-bootstrap/ng-bootstrap";
 import {ModalService} from "../_services/modal.service";
-import {forkJoin} from "rxjs";
+import {firstValueFrom, forkJoin} from "rxjs";
 import {EditSubscriptionModalComponent} from "./_components/edit-subscription-modal/edit-subscription-modal.component";
+import {
+  SubscriptionHistoryModalComponent
+} from "./_components/subscription-history-modal/subscription-history-modal.component";
 import {DefaultModalOptions} from "../_models/default-modal-options";
 import {PageService} from "../_services/page.service";
 import {ProviderNamePipe} from "../_pipes/provider-name.pipe";
 import {UtilityService} from "../_services/utility.service";
+import {AccountService} from "../_services/account.service";
+import {hasRole, Role} from "../_models/user";
+import {
+  SubscriptionImportModalComponent
+} from "./_components/subscription-import-modal/subscription-import-modal.component";
+import {TachiyomiImportModalComponent} from "./_components/tachiyomi-import-modal/tachiyomi-import-modal.component";
 
 @Component({
   selector: 'app-subscription-manager',
@@ -46,6 +61,7 @@ export class SubscriptionManagerComponent implements OnInit {
   private readonly pageService = inject(PageService);
   private readonly providerNamePipe = inject(ProviderNamePipe);
   private readonly utilityService = inject(UtilityService);
+  private readonly accountService = inject(AccountService);
 
   metadata = signal<Map<Provider, DownloadMetadata>>(new Map());
   allowedProviders = signal<Provider[]>([]);
@@ -115,6 +131,84 @@ export class SubscriptionManagerComponent implements OnInit {
     })
   }
 
+  async export() {
+    const user = this.accountService.currentUser();
+    const allUsers = user !== undefined && hasRole(user, Role.ManageSubscriptions) &&
+      await this.modalService.confirm({question: translate("subscriptions.confirm-export-all")});
+
+    this.subscriptionService.export(allUsers).subscribe({
+      next: data => {
+        const blob = new Blob([JSON.stringify(data, null, 2)], {type: 'application/json'});
+        const url = URL.createObjectURL(blob);
+
+        const a = document.createElement('a');
+        a.href = url;
+        a.download = `subscriptions-${new Date().toISOString().slice(0, 10)}.json`;
+        a.click();
+        URL.revokeObjectURL(url);
+      },
+      error: err => {
+        this.toastService.errorLoco("subscriptions.toasts.export.error", {}, {msg: err.error.message});
+      }
+    });
+  }
+
+  async import(event: Event) {
+    const input = event.target as HTMLInputElement;
+    const file = input.files?.[0];
+    input.value = '';
+    if (!file) return;
+
+    let data: SubscriptionExport;
+    try {
+      data = JSON.parse(await file.text());
+    } catch (err: any) {
+      this.toastService.errorLoco("subscriptions.toasts.import.error", {}, {msg: err.message});
+      return;
+    }
+
+    this.subscriptionService.import(data).subscribe({
+      next: report => this.showImportReport(report),
+      error: err => {
+        this.toastService.errorLoco("subscriptions.toasts.import.error", {}, {msg: err.error.message});
+      }
+    });
+  }
+
+  /**
+   * Proposes subscriptions for the library of a Tachiyomi, or Mihon, backup. The proposals are reviewed in a modal
+   * before anything is imported
+   */
+  importTachiyomi(event: Event) {
+    const input = event.target as HTMLInputElement;
+    const file = input.files?.[0];
+    input.value = '';
+    if (!file) return;
+
+    this.subscriptionService.importTachiyomi(file).subscribe({
+      next: proposals => {
+        const [modal, component] = this.modalService.open(TachiyomiImportModalComponent, DefaultModalOptions);
+        component.proposals.set(proposals);
+
+        modal.result.then((report?: SubscriptionImportReport) => {
+          if (report) this.showImportReport(report);
+        });
+      },
+      error: err => {
+        this.toastService.errorLoco("subscriptions.toasts.tachiyomi.error", {}, {msg: err.error.message});
+      }
+    });
+  }
+
+  private showImportReport(report: SubscriptionImportReport) {
+    const [_, component] = this.modalService.open(SubscriptionImportModalComponent, DefaultModalOptions);
+    component.report.set(report);
+
+    if (report.created > 0) {
+      this.subscriptionService.all().subscribe(subs => this.subscriptions.set(subs));
+    }
+  }
+
   runOnce(sub: Subscription) {
     if (sub.ID == 0) {
       return
@@ -130,6 +224,54 @@ export class SubscriptionManagerComponent implements OnInit {
     })
   }
 
+  async reorganise(sub: Subscription) {
+    if (sub.ID == 0) {
+      return
+    }
+
+    let report: ReorganiseReport;
+    try {
+      report = await firstValueFrom(this.subscriptionService.reorganise(sub.ID, true));
+    } catch (err: any) {
+      this.toastService.errorLoco("subscriptions.toasts.reorganise.error", {name: sub.title}, {msg: err.error.message});
+      return;
+    }
+
+    if (report.moves.length === 0) {
+      this.toastService.successLoco("subscriptions.toasts.reorganise.nothing", {name: sub.title}, {
+        upToDate: report.upToDate,
+        conflicts: report.conflicts.length,
+        unmatched: report.unmatched.length,
+      });
+      return;
+    }
+
+    if (!await this.modalService.confirm({
+      question: translate("subscriptions.confirm-reorganise", {
+        title: sub.title,
+        moves: report.moves.length,
+        conflicts: report.conflicts.length,
+        unmatched: report.unmatched.length,
+      })
+    })) {
+      return;
+    }
+
+    this.subscriptionService.reorganise(sub.ID, false).subscribe({
+      next: () => {
+        this.toastService.successLoco("subscriptions.toasts.reorganise.success", {name: sub.title});
+      },
+      error: (err) => {
+        this.toastService.errorLoco("subscriptions.toasts.reorganise.error", {name: sub.title}, {msg: err.error.message});
+      }
+    })
+  }
+
+  history(sub: Subscription) {
+    const [_, component] = this.modalService.open(SubscriptionHistoryModalComponent, DefaultModalOptions);
+    component.subscription.set(sub);
+  }
+
   async delete(sub: Subscription) {
     if (!await this.modalService.confirm({
       question: translate("subscriptions.confirm-delete", {title: sub.title})
@@ -150,6 +292,10 @@ export class SubscriptionManagerComponent implements OnInit {
   }
 
   getSeverity(sub: Subscription): "primary" | "secondary" | "error" | "warning" {
+    if (sub.cronExpression || sub.interval > 0 || sub.adaptive) {
+      return "secondary";
+    }
+
     switch (sub.refreshFrequency) {
       case RefreshFrequency.Day:
         return "primary"